@@ -9,11 +9,15 @@ import (
 	"log/slog"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
+	"github.com/google/uuid"
+
 	"github.com/your-org/go-service-template/internal/app"
 	"github.com/your-org/go-service-template/internal/platform/config"
+	"github.com/your-org/go-service-template/internal/platform/messaging"
 )
 
 var (
@@ -78,6 +82,10 @@ func run(arguments []string) error {
 		return app.CheckHealth(address)
 	case "jobs":
 		return runJobs(arguments[1:], os.Stdout)
+	case "apikeys":
+		return runAPIKeys(arguments[1:], os.Stdout)
+	case "dlq":
+		return runDeadLetters(arguments[1:], os.Stdout)
 	default:
 		return usageError()
 	}
@@ -108,6 +116,121 @@ func runJobs(arguments []string, output io.Writer) error {
 	return app.ListJobs(ctx, databaseURL, output, app.JobListOptions{Queue: *queue, State: *state, Limit: *limit})
 }
 
+func runAPIKeys(arguments []string, output io.Writer) error {
+	if len(arguments) == 0 {
+		return usageError()
+	}
+	flags := flag.NewFlagSet("apikeys "+arguments[0], flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	var run func(context.Context, string) error
+	switch arguments[0] {
+	case "create":
+		name := flags.String("name", "", "unique name of the key")
+		subject := flags.String("subject", "", "ID of the active user the key authenticates as")
+		scopes := flags.String("scopes", "", "comma-separated scopes")
+		expiresIn := flags.Duration("expires-in", 0, "key lifetime; zero never expires")
+		run = func(ctx context.Context, databaseURL string) error {
+			return app.CreateAPIKey(ctx, databaseURL, output, app.APIKeyCreateOptions{
+				Name: *name, Subject: *subject, Scopes: splitList(*scopes), ExpiresIn: *expiresIn,
+			})
+		}
+	case "list":
+		run = func(ctx context.Context, databaseURL string) error {
+			return app.ListAPIKeys(ctx, databaseURL, output)
+		}
+	case "revoke":
+		id := flags.String("id", "", "ID of the key to revoke")
+		run = func(ctx context.Context, databaseURL string) error {
+			keyID, err := uuid.Parse(*id)
+			if err != nil {
+				return fmt.Errorf("parse API key ID: %w", err)
+			}
+			return app.RevokeAPIKey(ctx, databaseURL, output, keyID)
+		}
+	default:
+		return usageError()
+	}
+	if err := flags.Parse(arguments[1:]); err != nil {
+		return fmt.Errorf("parse apikeys %s flags: %w", arguments[0], err)
+	}
+	if flags.NArg() != 0 {
+		return usageError()
+	}
+
+	databaseURL, err := config.LoadDatabaseURL()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	return run(ctx, databaseURL)
+}
+
+func runDeadLetters(arguments []string, output io.Writer) error {
+	if len(arguments) == 0 {
+		return usageError()
+	}
+	flags := flag.NewFlagSet("dlq "+arguments[0], flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	var run func(context.Context, config.DeadLetterConfig) error
+	switch arguments[0] {
+	case "list":
+		limit := flags.Int("limit", 100, "maximum messages to return")
+		run = func(ctx context.Context, cfg config.DeadLetterConfig) error {
+			return app.ListDeadLetters(ctx, cfg, output, *limit)
+		}
+	case "show":
+		id := flags.String("id", "", "SQS message ID")
+		run = func(ctx context.Context, cfg config.DeadLetterConfig) error {
+			if *id == "" {
+				return usageError()
+			}
+			return app.ShowDeadLetter(ctx, cfg, output, *id)
+		}
+	case "redrive", "purge":
+		ids := flags.String("id", "", "comma-separated SQS message IDs")
+		all := flags.Bool("all", false, "select every message")
+		command := arguments[0]
+		run = func(ctx context.Context, cfg config.DeadLetterConfig) error {
+			selection := messaging.DeadLetterSelection{MessageIDs: splitList(*ids), All: *all}
+			if selection.All == (len(selection.MessageIDs) > 0) {
+				return usageError()
+			}
+			if command == "redrive" {
+				return app.RedriveDeadLetters(ctx, cfg, output, selection)
+			}
+			return app.PurgeDeadLetters(ctx, cfg, output, selection)
+		}
+	default:
+		return usageError()
+	}
+	if err := flags.Parse(arguments[1:]); err != nil {
+		return fmt.Errorf("parse dlq %s flags: %w", arguments[0], err)
+	}
+	if flags.NArg() != 0 {
+		return usageError()
+	}
+
+	cfg, err := config.LoadDeadLetter()
+	if err != nil {
+		return err
+	}
+	// Scans receive up to messaging.DeadLetterScanLimit messages.
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+	return run(ctx, cfg)
+}
+
+func splitList(value string) []string {
+	var items []string
+	for item := range strings.SplitSeq(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
 func newLogger(environment string, level slog.Level) *slog.Logger {
 	options := &slog.HandlerOptions{Level: level}
 	if environment == config.EnvironmentDevelopment {
@@ -117,5 +240,7 @@ func newLogger(environment string, level slog.Level) *slog.Logger {
 }
 
 func usageError() error {
-	return errors.New("usage: service <api|worker|migrate|healthcheck|jobs list [--queue name] [--state state] [--limit count]>")
+	return errors.New("usage: service <api|worker|migrate|healthcheck|jobs list [--queue name] [--state state] [--limit count]|" +
+		"apikeys create --name name --subject subject --scopes scope,... [--expires-in duration]|apikeys list|apikeys revoke --id id|" +
+		"dlq list [--limit count]|dlq show --id id|dlq redrive (--id id,... | --all)|dlq purge (--id id,... | --all)>")
 }
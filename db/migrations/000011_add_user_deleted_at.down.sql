@@ -0,0 +1,17 @@
+-- Soft-deleted users cannot be kept once the column is gone, and deleting them
+-- would lose their rows and everything that references them, so this fails
+-- until an operator has removed or restored them.
+DO
+$body$
+BEGIN
+    IF EXISTS (SELECT 1 FROM users WHERE deleted_at IS NOT NULL) THEN
+        RAISE EXCEPTION 'Found soft-deleted users; remove or restore them before reverting version 11.';
+    END IF;
+END;
+$body$;
+
+DROP INDEX users_active_email;
+
+ALTER TABLE users
+    ADD CONSTRAINT users_email_key UNIQUE (email),
+    DROP COLUMN deleted_at;
@@ -0,0 +1,7 @@
+ALTER TABLE users
+    ADD COLUMN deleted_at timestamptz,
+    DROP CONSTRAINT users_email_key;
+
+CREATE UNIQUE INDEX users_active_email
+    ON users (email)
+    WHERE deleted_at IS NULL;
@@ -0,0 +1,13 @@
+CREATE TABLE idempotency_keys (
+    subject text NOT NULL,
+    key text NOT NULL CHECK (key <> ''),
+    fingerprint bytea NOT NULL,
+    status_code integer CHECK (status_code BETWEEN 100 AND 599),
+    response_headers jsonb,
+    response_body bytea,
+    locked_at timestamptz NOT NULL DEFAULT now(),
+    created_at timestamptz NOT NULL DEFAULT now(),
+    PRIMARY KEY (subject, key)
+);
+
+CREATE INDEX idempotency_keys_cleanup ON idempotency_keys (created_at);
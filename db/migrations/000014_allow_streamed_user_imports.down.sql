@@ -0,0 +1,16 @@
+DROP INDEX user_import_entries_pending;
+
+-- Imports outside the old bounds would violate the restored check, and
+-- deleting them would lose their entries and results, so this fails until an
+-- operator has removed them.
+DO
+$body$
+BEGIN
+    IF EXISTS (SELECT 1 FROM user_imports WHERE total_count NOT BETWEEN 1 AND 100) THEN
+        RAISE EXCEPTION 'Found user imports with fewer than 1 or more than 100 entries; remove them before reverting version 14.';
+    END IF;
+END;
+$body$;
+
+ALTER TABLE user_imports DROP CONSTRAINT user_imports_total_count_check;
+ALTER TABLE user_imports ADD CONSTRAINT user_imports_total_count_check CHECK (total_count BETWEEN 1 AND 100);
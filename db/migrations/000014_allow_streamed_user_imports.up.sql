@@ -0,0 +1,6 @@
+ALTER TABLE user_imports DROP CONSTRAINT user_imports_total_count_check;
+ALTER TABLE user_imports ADD CONSTRAINT user_imports_total_count_check CHECK (total_count >= 0);
+
+CREATE INDEX user_import_entries_pending
+    ON user_import_entries (import_id, user_id)
+    WHERE state = 'pending';
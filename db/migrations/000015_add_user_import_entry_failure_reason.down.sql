@@ -0,0 +1,2 @@
+ALTER TABLE user_import_entries DROP COLUMN failure_reason;
+DROP TYPE user_import_entry_failure_reason;
@@ -0,0 +1,11 @@
+CREATE TYPE user_import_entry_failure_reason AS ENUM ('email_taken', 'invalid');
+
+ALTER TABLE user_import_entries
+    ADD COLUMN failure_reason user_import_entry_failure_reason;
+
+-- Before reasons were recorded, a taken email was the only way an entry failed.
+UPDATE user_import_entries SET failure_reason = 'email_taken' WHERE state = 'failed';
+
+ALTER TABLE user_import_entries
+    ADD CONSTRAINT user_import_entries_failure_reason_check
+        CHECK ((state = 'failed') = (failure_reason IS NOT NULL));
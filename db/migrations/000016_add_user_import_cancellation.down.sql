@@ -0,0 +1,19 @@
+DROP INDEX user_imports_cleanup;
+
+ALTER TABLE user_imports
+    DROP COLUMN retry_of,
+    DROP COLUMN job_id;
+
+UPDATE user_imports SET state = 'failed' WHERE state = 'cancelled';
+
+ALTER TYPE user_import_state RENAME TO user_import_state_old;
+CREATE TYPE user_import_state AS ENUM ('pending', 'running', 'completed', 'failed');
+ALTER TABLE user_imports
+    ALTER COLUMN state DROP DEFAULT,
+    ALTER COLUMN state TYPE user_import_state USING state::text::user_import_state,
+    ALTER COLUMN state SET DEFAULT 'pending';
+DROP TYPE user_import_state_old;
+
+CREATE INDEX user_imports_cleanup
+    ON user_imports (finished_at)
+    WHERE state IN ('completed', 'failed');
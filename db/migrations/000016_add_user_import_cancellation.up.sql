@@ -0,0 +1,11 @@
+ALTER TYPE user_import_state ADD VALUE 'cancelled';
+
+ALTER TABLE user_imports
+    ADD COLUMN job_id bigint,
+    ADD COLUMN retry_of uuid REFERENCES user_imports(id) ON DELETE SET NULL;
+
+-- Every terminal state sets finished_at, including the new cancelled state.
+DROP INDEX user_imports_cleanup;
+CREATE INDEX user_imports_cleanup
+    ON user_imports (finished_at)
+    WHERE finished_at IS NOT NULL;
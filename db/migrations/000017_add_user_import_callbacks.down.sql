@@ -0,0 +1,3 @@
+DROP TABLE user_import_callback_attempts;
+
+ALTER TABLE user_imports DROP COLUMN callback_url;
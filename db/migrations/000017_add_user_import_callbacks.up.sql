@@ -0,0 +1,11 @@
+ALTER TABLE user_imports
+    ADD COLUMN callback_url text CHECK (callback_url <> '');
+
+CREATE TABLE user_import_callback_attempts (
+    import_id uuid NOT NULL REFERENCES user_imports(id) ON DELETE CASCADE,
+    attempt integer NOT NULL CHECK (attempt > 0),
+    attempted_at timestamptz NOT NULL DEFAULT now(),
+    status_code integer,
+    error text,
+    PRIMARY KEY (import_id, attempt)
+);
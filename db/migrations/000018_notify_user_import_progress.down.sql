@@ -0,0 +1,2 @@
+DROP TRIGGER user_imports_notify_progress ON user_imports;
+DROP FUNCTION notify_user_import_progress();
@@ -0,0 +1,17 @@
+CREATE FUNCTION notify_user_import_progress() RETURNS trigger
+LANGUAGE plpgsql AS $$
+BEGIN
+    PERFORM pg_notify('user_import_progress', NEW.id::text);
+    RETURN NULL;
+END;
+$$;
+
+CREATE TRIGGER user_imports_notify_progress
+    AFTER UPDATE OF state, completed_count, failed_count ON user_imports
+    FOR EACH ROW
+    WHEN (
+        OLD.state IS DISTINCT FROM NEW.state
+        OR OLD.completed_count IS DISTINCT FROM NEW.completed_count
+        OR OLD.failed_count IS DISTINCT FROM NEW.failed_count
+    )
+    EXECUTE FUNCTION notify_user_import_progress();
@@ -0,0 +1,2 @@
+DROP TRIGGER user_permissions_notify_change ON user_permissions;
+DROP FUNCTION notify_user_permission_change();
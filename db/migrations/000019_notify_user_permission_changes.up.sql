@@ -0,0 +1,12 @@
+CREATE FUNCTION notify_user_permission_change() RETURNS trigger
+LANGUAGE plpgsql AS $$
+BEGIN
+    PERFORM pg_notify('user_permissions_changed', NEW.user_id::text || ':' || NEW.revision::text);
+    RETURN NULL;
+END;
+$$;
+
+CREATE TRIGGER user_permissions_notify_change
+    AFTER INSERT OR UPDATE OF revision ON user_permissions
+    FOR EACH ROW
+    EXECUTE FUNCTION notify_user_permission_change();
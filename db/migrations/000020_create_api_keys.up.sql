@@ -0,0 +1,12 @@
+CREATE TABLE api_keys (
+    id uuid PRIMARY KEY,
+    name text NOT NULL CHECK (name <> ''),
+    key_hash bytea NOT NULL UNIQUE,
+    subject text NOT NULL CHECK (subject <> ''),
+    scopes text[] NOT NULL,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    expires_at timestamptz,
+    revoked_at timestamptz
+);
+
+CREATE UNIQUE INDEX api_keys_active_name ON api_keys (name) WHERE revoked_at IS NULL;
@@ -0,0 +1,10 @@
+-- Buckets are cheap to lose: after a crash every subject starts with a full
+-- bucket, so the table skips the write-ahead log.
+CREATE UNLOGGED TABLE rate_limit_buckets (
+    key text PRIMARY KEY,
+    tokens double precision NOT NULL CHECK (tokens >= 0),
+    last_allowed boolean NOT NULL,
+    updated_at timestamptz NOT NULL
+);
+
+CREATE INDEX rate_limit_buckets_cleanup ON rate_limit_buckets (updated_at);
@@ -0,0 +1,13 @@
+CREATE TABLE audit_events (
+    id uuid PRIMARY KEY,
+    actor text NOT NULL CHECK (actor <> ''),
+    action text NOT NULL,
+    target_id text NOT NULL,
+    correlation_id text NOT NULL,
+    outcome text NOT NULL,
+    occurred_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX audit_events_actor ON audit_events (actor, id);
+CREATE INDEX audit_events_target_id ON audit_events (target_id, id);
+CREATE INDEX audit_events_occurred_at ON audit_events (occurred_at);
@@ -0,0 +1,10 @@
+CREATE TABLE outbox_messages (
+    sequence bigint GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    event_id text NOT NULL UNIQUE CHECK (event_id <> ''),
+    event_type text NOT NULL CHECK (event_type <> ''),
+    body text NOT NULL,
+    attempts integer NOT NULL DEFAULT 0,
+    last_error text,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    available_at timestamptz NOT NULL DEFAULT now()
+);
@@ -0,0 +1,3 @@
+DROP INDEX outbox_messages_group;
+
+ALTER TABLE outbox_messages DROP COLUMN group_id;
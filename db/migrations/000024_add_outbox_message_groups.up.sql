@@ -0,0 +1,3 @@
+ALTER TABLE outbox_messages ADD COLUMN group_id text NOT NULL DEFAULT '';
+
+CREATE INDEX outbox_messages_group ON outbox_messages (group_id, sequence) WHERE group_id <> '';
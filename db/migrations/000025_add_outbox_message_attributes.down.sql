@@ -0,0 +1,3 @@
+ALTER TABLE outbox_messages
+    DROP COLUMN trace_context,
+    DROP COLUMN schema_version;
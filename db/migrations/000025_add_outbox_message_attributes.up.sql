@@ -0,0 +1,3 @@
+ALTER TABLE outbox_messages
+    ADD COLUMN schema_version text NOT NULL DEFAULT '',
+    ADD COLUMN trace_context jsonb NOT NULL DEFAULT '{}';
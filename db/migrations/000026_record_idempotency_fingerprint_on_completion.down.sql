@@ -0,0 +1,4 @@
+-- Fails while a request without a fingerprint is still running; retry once it
+-- has completed or its claim has been released.
+ALTER TABLE idempotency_keys
+    ALTER COLUMN fingerprint SET NOT NULL;
@@ -0,0 +1,4 @@
+-- Streamed bodies are fingerprinted as the handler reads them, so a running
+-- request has no fingerprint until it completes.
+ALTER TABLE idempotency_keys
+    ALTER COLUMN fingerprint DROP NOT NULL;
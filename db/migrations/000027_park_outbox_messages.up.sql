@@ -0,0 +1,3 @@
+-- A message that keeps failing is parked rather than retried forever. It
+-- stays in the outbox for inspection but is no longer published.
+ALTER TABLE outbox_messages ADD COLUMN parked_at timestamptz;
@@ -0,0 +1,2 @@
+DROP TRIGGER users_notify_deletion ON users;
+DROP FUNCTION notify_user_deletion();
@@ -0,0 +1,15 @@
+-- Announces a soft-deleted user on the permission channel without a revision,
+-- which drops every cached permission set of the user.
+CREATE FUNCTION notify_user_deletion() RETURNS trigger
+LANGUAGE plpgsql AS $$
+BEGIN
+    PERFORM pg_notify('user_permissions_changed', NEW.id::text);
+    RETURN NULL;
+END;
+$$;
+
+CREATE TRIGGER users_notify_deletion
+    AFTER UPDATE OF deleted_at ON users
+    FOR EACH ROW
+    WHEN (OLD.deleted_at IS NULL AND NEW.deleted_at IS NOT NULL)
+    EXECUTE FUNCTION notify_user_deletion();
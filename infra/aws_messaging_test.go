@@ -23,6 +23,7 @@ func TestAWSMessagingTemplateOwnsRequiredTopology(t *testing.T) {
 		"PermissionsQueuePolicy",
 		"PermissionsSubscription",
 		"WorkerPolicy",
+		"DeadLetterOperatorPolicy",
 	} {
 		assert.Contains(t, resources, name)
 	}
@@ -48,8 +49,10 @@ func TestAWSMessagingTemplateOwnsRequiredTopology(t *testing.T) {
 		"UserEventsTopicArn",
 		"PermissionsQueueUrl",
 		"PermissionsQueueArn",
+		"PermissionsDeadLetterQueueUrl",
 		"PermissionsDeadLetterQueueArn",
 		"WorkerPolicyArn",
+		"DeadLetterOperatorPolicyArn",
 	} {
 		assert.Contains(t, outputs, name)
 	}
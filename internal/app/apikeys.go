@@ -0,0 +1,119 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/your-org/go-service-template/internal/platform/auth"
+	userspostgres "github.com/your-org/go-service-template/internal/users/postgres"
+)
+
+type APIKeyCreateOptions struct {
+	Name    string
+	Subject string
+	Scopes  []string
+	// ExpiresIn is the key's lifetime; zero creates a key that never expires.
+	ExpiresIn time.Duration
+}
+
+type listedAPIKey struct {
+	ID        uuid.UUID  `json:"id"`
+	Name      string     `json:"name"`
+	Subject   string     `json:"subject"`
+	Scopes    []string   `json:"scopes"`
+	CreatedAt time.Time  `json:"createdAt"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+	RevokedAt *time.Time `json:"revokedAt,omitempty"`
+}
+
+type createdAPIKey struct {
+	listedAPIKey
+	// Key is printed only once; just its hash is stored.
+	Key string `json:"key"`
+}
+
+func CreateAPIKey(ctx context.Context, databaseURL string, output io.Writer, options APIKeyCreateOptions) error {
+	options.Name, options.Subject = strings.TrimSpace(options.Name), strings.TrimSpace(options.Subject)
+	if options.Name == "" || options.Subject == "" || len(options.Scopes) == 0 {
+		return errors.New("API key name, subject, and at least one scope are required")
+	}
+	if options.ExpiresIn < 0 {
+		return errors.New("API key lifetime must not be negative")
+	}
+	id, err := uuid.NewV7()
+	if err != nil {
+		return fmt.Errorf("generate API key ID: %w", err)
+	}
+	rawKey, hash, err := auth.NewAPIKey()
+	if err != nil {
+		return err
+	}
+	key := auth.APIKey{ID: id, Name: options.Name, Subject: options.Subject, Scopes: options.Scopes}
+	if options.ExpiresIn > 0 {
+		expiresAt := time.Now().Add(options.ExpiresIn)
+		key.ExpiresAt = &expiresAt
+	}
+
+	return withAPIKeys(ctx, databaseURL, func(repository *userspostgres.APIKeyRepository) error {
+		created, err := repository.Create(ctx, key, hash)
+		if err != nil {
+			return err
+		}
+		return encodeAPIKeys(output, createdAPIKey{listedAPIKey: toListedAPIKey(created), Key: rawKey})
+	})
+}
+
+func ListAPIKeys(ctx context.Context, databaseURL string, output io.Writer) error {
+	return withAPIKeys(ctx, databaseURL, func(repository *userspostgres.APIKeyRepository) error {
+		keys, err := repository.List(ctx)
+		if err != nil {
+			return err
+		}
+		listed := make([]listedAPIKey, 0, len(keys))
+		for _, key := range keys {
+			listed = append(listed, toListedAPIKey(key))
+		}
+		return encodeAPIKeys(output, listed)
+	})
+}
+
+func RevokeAPIKey(ctx context.Context, databaseURL string, output io.Writer, id uuid.UUID) error {
+	return withAPIKeys(ctx, databaseURL, func(repository *userspostgres.APIKeyRepository) error {
+		revoked, err := repository.Revoke(ctx, id)
+		if err != nil {
+			return err
+		}
+		return encodeAPIKeys(output, toListedAPIKey(revoked))
+	})
+}
+
+func withAPIKeys(ctx context.Context, databaseURL string, run func(*userspostgres.APIKeyRepository) error) error {
+	pool, err := pgxpool.New(ctx, databaseURL)
+	if err != nil {
+		return fmt.Errorf("create PostgreSQL pool: %w", err)
+	}
+	defer pool.Close()
+	return run(userspostgres.NewAPIKeyRepository(pool))
+}
+
+func encodeAPIKeys(output io.Writer, value any) error {
+	if err := json.NewEncoder(output).Encode(value); err != nil {
+		return fmt.Errorf("encode API keys: %w", err)
+	}
+	return nil
+}
+
+func toListedAPIKey(key auth.APIKey) listedAPIKey {
+	return listedAPIKey{
+		ID: key.ID, Name: key.Name, Subject: key.Subject, Scopes: key.Scopes,
+		CreatedAt: key.CreatedAt, ExpiresAt: key.ExpiresAt, RevokedAt: key.RevokedAt,
+	}
+}
@@ -16,6 +16,7 @@ import (
 	"github.com/riverqueue/river/riverdriver/riverpgxv5"
 	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 
+	contractapi "github.com/your-org/go-service-template/internal/api"
 	"github.com/your-org/go-service-template/internal/platform/auth"
 	"github.com/your-org/go-service-template/internal/platform/config"
 	"github.com/your-org/go-service-template/internal/platform/database"
@@ -27,6 +28,8 @@ import (
 	userspostgres "github.com/your-org/go-service-template/internal/users/postgres"
 )
 
+const permissionCacheTTL = 30 * time.Second
+
 type BuildInfo struct {
 	Version string
 	Commit  string
@@ -57,7 +60,7 @@ func RunAPI(ctx context.Context, cfg config.Config, logger *slog.Logger, build B
 		return fmt.Errorf("connect to PostgreSQL: %w", err)
 	}
 
-	authentication, err := buildAuthentication(startupCtx, cfg)
+	authentication, err := buildAuthentication(ctx, cfg, pool, logger)
 	if err != nil {
 		return err
 	}
@@ -66,21 +69,46 @@ func RunAPI(ctx context.Context, cfg config.Config, logger *slog.Logger, build B
 	if err != nil {
 		return fmt.Errorf("create River enqueue client: %w", err)
 	}
-	jobEnqueuer := usersjobs.NewEnqueuer(jobClient)
+	jobEnqueuer := usersjobs.NewEnqueuer(jobClient, userspostgres.NewOutboxRepository(pool), cfg.ServiceName)
 	repository := userspostgres.NewUserRepository(pool, jobEnqueuer)
 	userService := users.NewService(repository)
 	importRepository := userspostgres.NewImportRepository(pool, jobEnqueuer)
 	importService := users.NewImportService(importRepository)
-	usersHandler := usershttp.NewHandler(logger, userService, importService)
+	importNotifier := userspostgres.NewImportNotifier(pool, logger)
+	importProgress := users.NewImportProgress(importRepository, importNotifier)
+	permissionRepository := userspostgres.NewPermissionRepository(pool)
+	permissionService := users.NewPermissionService(permissionRepository)
+	auditService := users.NewAuditService(userspostgres.NewAuditRepository(pool))
+	usersHandler := usershttp.NewHandler(logger, userService, importService, permissionService, auditService, importProgress)
+	// Permission sets are cached briefly and dropped as soon as the worker
+	// commits a newer revision.
+	var permissions httpserver.PermissionLoader
+	if cfg.AuthMode != config.AuthModeDisabled {
+		permissionCache := httpserver.NewPermissionCache(permissionRepository, permissionCacheTTL)
+		go userspostgres.NewPermissionNotifier(pool, logger, permissionCache).Run(ctx)
+		permissions = permissionCache
+	}
+	var rateLimits httpserver.RateLimitStore
+	switch cfg.RateLimitBackend {
+	case config.RateLimitBackendMemory:
+		rateLimits = httpserver.NewMemoryRateLimitStore()
+	case config.RateLimitBackendPostgres:
+		rateLimits = userspostgres.NewRateLimitRepository(pool)
+	}
 	readiness := httpserver.NewReadiness(pool, telemetryRuntime.RecordDatabaseCheck)
 	handler, err := httpserver.NewHandler(httpserver.HandlerOptions{
-		Logger:    logger,
-		API:       usersHandler,
-		Auth:      authentication,
-		Readiness: readiness,
-		Metrics:   telemetryRuntime.MetricsHandler,
-		Version:   build.Version,
-		Commit:    build.Commit,
+		Logger:           logger,
+		API:              usersHandler,
+		StrictMiddleware: []contractapi.StrictMiddlewareFunc{usershttp.BindStreamedImport, usershttp.ClearEventStreamDeadline, usershttp.RecordAuditActor},
+		StreamedRoutes:   usershttp.StreamedRoutes(),
+		Auth:             authentication,
+		Readiness:        readiness,
+		Metrics:          telemetryRuntime.MetricsHandler,
+		Version:          build.Version,
+		Commit:           build.Commit,
+		Idempotency:      userspostgres.NewIdempotencyRepository(pool),
+		Permissions:      permissions,
+		RateLimits:       rateLimits,
 	})
 	if err != nil {
 		return err
@@ -97,6 +125,10 @@ func RunAPI(ctx context.Context, cfg config.Config, logger *slog.Logger, build B
 		ErrorLog:          slog.NewLogLogger(logger.Handler(), slog.LevelError),
 	}
 
+	// Stopping the notifier with ctx ends open import event streams, which
+	// would otherwise hold up the graceful shutdown below.
+	go importNotifier.Run(ctx)
+
 	serverErrors := make(chan error, 1)
 	go func() {
 		logger.Info("server listening", "address", cfg.HTTPAddress)
@@ -158,14 +190,44 @@ func CheckHealth(address string) error {
 	return nil
 }
 
-func buildAuthentication(ctx context.Context, cfg config.Config) (httpserver.Authentication, error) {
+// buildAuthentication keeps refreshing offline JWKS keys until ctx ends.
+func buildAuthentication(ctx context.Context, cfg config.Config, pool *pgxpool.Pool, logger *slog.Logger) (httpserver.Authentication, error) {
 	if cfg.AuthMode == config.AuthModeDisabled {
 		return httpserver.DisabledAuthentication(), nil
 	}
 
-	verifier, err := auth.NewVerifier(ctx, cfg.OIDCIssuerURL, cfg.OIDCAudience)
+	apiKeys := auth.NewAPIKeyVerifier(userspostgres.NewAPIKeyRepository(pool))
+	if !cfg.AcceptsOIDC() {
+		return httpserver.TokenAuthentication(apiKeys)
+	}
+	verifier, err := buildTokenVerifier(ctx, cfg, logger)
 	if err != nil {
 		return httpserver.Authentication{}, err
 	}
+	if cfg.AcceptsAPIKeys() {
+		return httpserver.TokenAuthentication(auth.NewCombinedVerifier(verifier, apiKeys))
+	}
 	return httpserver.TokenAuthentication(verifier)
 }
+
+func buildTokenVerifier(ctx context.Context, cfg config.Config, logger *slog.Logger) (httpserver.TokenVerifier, error) {
+	startupCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	if cfg.OIDCJWKSSource == "" {
+		return auth.NewVerifier(startupCtx, cfg.OIDCIssuerURLs[0], cfg.OIDCAudiences[0], cfg.OIDCSigningAlgorithms, cfg.OIDCClockSkew)
+	}
+
+	verifier, err := auth.NewJWKSVerifier(startupCtx, auth.JWKSOptions{
+		Source:          cfg.OIDCJWKSSource,
+		Issuers:         cfg.OIDCIssuerURLs,
+		Audiences:       cfg.OIDCAudiences,
+		Algorithms:      cfg.OIDCSigningAlgorithms,
+		ClockSkew:       cfg.OIDCClockSkew,
+		RefreshInterval: cfg.OIDCJWKSRefreshInterval,
+	}, logger)
+	if err != nil {
+		return nil, err
+	}
+	go verifier.Run(ctx)
+	return verifier, nil
+}
@@ -0,0 +1,136 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+
+	"github.com/your-org/go-service-template/internal/platform/config"
+	"github.com/your-org/go-service-template/internal/platform/messaging"
+)
+
+type listedDeadLetter struct {
+	MessageID     string     `json:"messageId"`
+	SentAt        *time.Time `json:"sentAt,omitempty"`
+	Type          string     `json:"type,omitempty"`
+	EventID       string     `json:"eventId,omitempty"`
+	CorrelationID string     `json:"correlationId,omitempty"`
+	DecodeError   string     `json:"decodeError,omitempty"`
+}
+
+type shownDeadLetter struct {
+	listedDeadLetter
+	ProducedBy string            `json:"producedBy,omitempty"`
+	Payload    json.RawMessage   `json:"payload,omitempty"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+	Body       string            `json:"body"`
+}
+
+type removedDeadLetters struct {
+	Queue      string   `json:"queue"`
+	MessageIDs []string `json:"messageIds"`
+	Purged     bool     `json:"purged,omitempty"`
+}
+
+func ListDeadLetters(ctx context.Context, cfg config.DeadLetterConfig, output io.Writer, limit int) error {
+	return withDeadLetterQueue(ctx, cfg, func(queue *messaging.DeadLetterQueue) error {
+		messages, err := queue.List(ctx, limit)
+		if err != nil {
+			return err
+		}
+		listed := make([]listedDeadLetter, 0, len(messages))
+		for _, message := range messages {
+			listed = append(listed, toListedDeadLetter(message))
+		}
+		return encodeDeadLetters(output, listed)
+	})
+}
+
+func ShowDeadLetter(ctx context.Context, cfg config.DeadLetterConfig, output io.Writer, messageID string) error {
+	return withDeadLetterQueue(ctx, cfg, func(queue *messaging.DeadLetterQueue) error {
+		message, err := queue.Show(ctx, messageID)
+		if err != nil {
+			return err
+		}
+		return encodeDeadLetters(output, shownDeadLetter{
+			listedDeadLetter: toListedDeadLetter(message),
+			ProducedBy:       message.Envelope.Metadata.ProducedBy,
+			Payload:          message.Envelope.Payload,
+			Attributes:       message.Attributes,
+			Body:             message.Body,
+		})
+	})
+}
+
+// RedriveDeadLetters prints the messages it moved even when it fails part
+// way, so that the operator knows which ones remain.
+func RedriveDeadLetters(ctx context.Context, cfg config.DeadLetterConfig, output io.Writer, selection messaging.DeadLetterSelection) error {
+	return withDeadLetterQueue(ctx, cfg, func(queue *messaging.DeadLetterQueue) error {
+		moved, err := queue.Redrive(ctx, selection)
+		if encodeErr := encodeDeadLetters(output, removedDeadLetters{Queue: queue.URL(), MessageIDs: nonNil(moved)}); encodeErr != nil {
+			return encodeErr
+		}
+		return err
+	})
+}
+
+func PurgeDeadLetters(ctx context.Context, cfg config.DeadLetterConfig, output io.Writer, selection messaging.DeadLetterSelection) error {
+	return withDeadLetterQueue(ctx, cfg, func(queue *messaging.DeadLetterQueue) error {
+		deleted, err := queue.Purge(ctx, selection)
+		if encodeErr := encodeDeadLetters(output, removedDeadLetters{
+			Queue: queue.URL(), MessageIDs: nonNil(deleted), Purged: selection.All && err == nil,
+		}); encodeErr != nil {
+			return encodeErr
+		}
+		return err
+	})
+}
+
+func withDeadLetterQueue(ctx context.Context, cfg config.DeadLetterConfig, run func(*messaging.DeadLetterQueue) error) error {
+	loadOptions := []func(*awsconfig.LoadOptions) error{awsconfig.WithRegion(cfg.AWSRegion)}
+	if cfg.AWSEndpointURL != "" {
+		loadOptions = append(loadOptions, awsconfig.WithBaseEndpoint(cfg.AWSEndpointURL))
+	}
+	awsConfig, err := awsconfig.LoadDefaultConfig(ctx, loadOptions...)
+	if err != nil {
+		return fmt.Errorf("load AWS configuration: %w", err)
+	}
+	queue, err := messaging.NewDeadLetterQueue(ctx, sqs.NewFromConfig(awsConfig), cfg.PermissionsQueue, messaging.MessageFormat(cfg.PermissionsMessageFormat))
+	if err != nil {
+		return err
+	}
+	return run(queue)
+}
+
+func encodeDeadLetters(output io.Writer, value any) error {
+	if err := json.NewEncoder(output).Encode(value); err != nil {
+		return fmt.Errorf("encode dead-letter messages: %w", err)
+	}
+	return nil
+}
+
+func toListedDeadLetter(message messaging.DeadLetterMessage) listedDeadLetter {
+	listed := listedDeadLetter{
+		MessageID:     message.MessageID,
+		Type:          message.Envelope.Type,
+		EventID:       message.Envelope.ID,
+		CorrelationID: message.Envelope.Metadata.CorrelationID,
+		DecodeError:   message.DecodeError,
+	}
+	if !message.SentAt.IsZero() {
+		listed.SentAt = &message.SentAt
+	}
+	return listed
+}
+
+func nonNil(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
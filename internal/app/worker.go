@@ -23,7 +23,7 @@ import (
 	"github.com/your-org/go-service-template/internal/platform/messaging"
 	"github.com/your-org/go-service-template/internal/platform/telemetry"
 	"github.com/your-org/go-service-template/internal/users"
-	usersevents "github.com/your-org/go-service-template/internal/users/events"
+	usershttp "github.com/your-org/go-service-template/internal/users/http"
 	usersjobs "github.com/your-org/go-service-template/internal/users/jobs"
 	userspostgres "github.com/your-org/go-service-template/internal/users/postgres"
 )
@@ -51,9 +51,20 @@ func RunWorker(ctx context.Context, cfg config.WorkerConfig, logger *slog.Logger
 	startupCtx, startupCancel := context.WithTimeout(ctx, 10*time.Second)
 	defer startupCancel()
 
+	var consumers []workerConsumer
+	var queues []workerQueue
+	for _, consumer := range workerConsumers(cfg, pool, telemetryRuntime) {
+		if consumer.config.QueueURL == "" {
+			logger.Warn("external event consumption disabled", "consumer", consumer.name, "reason", "queue URL is empty")
+			continue
+		}
+		consumers = append(consumers, consumer)
+		queues = append(queues, consumer.queue())
+	}
+
 	var snsClient *sns.Client
 	var sqsClient *sqs.Client
-	if cfg.UserEventsTopic != "" || cfg.PermissionsQueue != "" {
+	if cfg.UserEventsTopic != "" || len(consumers) > 0 {
 		loadOptions := []func(*awsconfig.LoadOptions) error{awsconfig.WithRegion(cfg.AWSRegion)}
 		if cfg.AWSEndpointURL != "" {
 			loadOptions = append(loadOptions, awsconfig.WithBaseEndpoint(cfg.AWSEndpointURL))
@@ -69,48 +80,48 @@ func RunWorker(ctx context.Context, cfg config.WorkerConfig, logger *slog.Logger
 
 	dependencies := workerDependencies{
 		database: pool, sqs: sqsClient, sns: snsClient, observer: telemetryRuntime,
-		queueURL: cfg.PermissionsQueue, topicARN: cfg.UserEventsTopic,
+		queues: queues, topicARN: cfg.UserEventsTopic,
 	}
 	if err := dependencies.Ping(startupCtx); err != nil {
 		return fmt.Errorf("validate worker dependencies: %w", err)
 	}
 
 	workers := river.NewWorkers()
-	queues := map[string]river.QueueConfig{usersjobs.QueueUsers: {MaxWorkers: 5}}
+	riverQueues := map[string]river.QueueConfig{usersjobs.QueueUsers: {MaxWorkers: 5}, usersjobs.QueueCallbacks: {MaxWorkers: 5}}
 	var eventPublisher *messaging.SNSTopicPublisher
-	var permissionsConsumer *messaging.SQSConsumer
+	var outboxRelay *messaging.OutboxRelay
+	sqsConsumers := make([]*messaging.SQSConsumer, len(consumers))
+	for index, consumer := range consumers {
+		sqsConsumers[index] = consumer.build(sqsClient, logger, telemetryRuntime)
+	}
 	if snsClient != nil && cfg.UserEventsTopic != "" {
 		eventPublisher = messaging.NewSNSTopicPublisher(snsClient, cfg.UserEventsTopic, telemetryRuntime)
-		queues[usersjobs.QueueEvents] = river.QueueConfig{MaxWorkers: 10}
-	}
-	if sqsClient != nil && cfg.PermissionsQueue != "" {
-		permissionRepository := userspostgres.NewPermissionRepository(pool)
-		permissionService := users.NewPermissionService(permissionRepository)
-		permissionHandler := usersevents.NewPermissionHandler(permissionService, func(ctx context.Context, result users.PermissionChangeResult) {
-			telemetryRuntime.RecordPermissionOutcome(ctx, string(result))
-		})
-		permissionsConsumer = messaging.NewSQSConsumer(sqsClient, cfg.PermissionsQueue, permissionHandler, logger, telemetryRuntime)
+		outboxRelay = messaging.NewOutboxRelay(userspostgres.NewOutboxRepository(pool), eventPublisher, logger, telemetryRuntime)
+		riverQueues[usersjobs.QueueEvents] = river.QueueConfig{MaxWorkers: 10}
 	}
 	if eventPublisher == nil {
 		logger.Warn("external event publication disabled", "reason", "USER_EVENTS_TOPIC_ARN is empty")
 	}
-	if permissionsConsumer == nil {
-		logger.Warn("external event consumption disabled", "reason", "PERMISSIONS_QUEUE_URL is empty")
-	}
 
 	client, err := river.NewClient(riverpgxv5.New(pool), &river.Config{
-		Logger: logger, Queues: queues, PeriodicJobs: []*river.PeriodicJob{usersjobs.PeriodicCleanup()},
-		SkipUnknownJobCheck: eventPublisher == nil, Workers: workers,
+		Logger: logger, Queues: riverQueues, PeriodicJobs: []*river.PeriodicJob{usersjobs.PeriodicCleanup(), usersjobs.PeriodicIdempotencyCleanup(), usersjobs.PeriodicRateLimitCleanup()},
+		Workers: workers,
 	})
 	if err != nil {
 		return fmt.Errorf("create River worker client: %w", err)
 	}
-	importRepository := userspostgres.NewImportRepository(pool, usersjobs.NewEnqueuer(client))
+	importRepository := userspostgres.NewImportRepository(pool, usersjobs.NewEnqueuer(client, userspostgres.NewOutboxRepository(pool), cfg.ServiceName))
 	importService := users.NewImportService(importRepository)
 	river.AddWorker(workers, usersjobs.NewImportWorker(importService))
 	river.AddWorker(workers, usersjobs.NewCleanupImportsWorker(logger, importService))
+	river.AddWorker(workers, usersjobs.NewCleanupIdempotencyKeysWorker(logger, userspostgres.NewIdempotencyRepository(pool)))
+	river.AddWorker(workers, usersjobs.NewCleanupRateLimitBucketsWorker(logger, userspostgres.NewRateLimitRepository(pool)))
+	callbackSender := usershttp.NewCallbackSender([]byte(cfg.ImportCallbackSecret))
+	river.AddWorker(workers, usersjobs.NewDeliverImportCallbackWorker(users.NewCallbackService(importRepository, callbackSender)))
 	if eventPublisher != nil {
 		river.AddWorker(workers, usersjobs.NewPublishCreatedWorker(eventPublisher, cfg.ServiceName))
+		river.AddWorker(workers, usersjobs.NewPublishUpdatedWorker(eventPublisher, cfg.ServiceName))
+		river.AddWorker(workers, usersjobs.NewPublishDeletedWorker(eventPublisher, cfg.ServiceName))
 	}
 
 	readiness := httpserver.NewPausedReadiness(dependencies, nil)
@@ -153,36 +164,51 @@ func RunWorker(ctx context.Context, cfg config.WorkerConfig, logger *slog.Logger
 		logger.Info("worker operations server listening", "address", listener.Addr().String())
 		serverErrors <- operationsServer.Serve(listener)
 	}()
-	consumerErrors := make(chan error, 1)
-	if permissionsConsumer != nil {
-		go func() { consumerErrors <- permissionsConsumer.RunWithHandlerContext(receiveCtx, handlerCtx) }()
+	relayCtx, stopRelay := context.WithCancel(runtimeCtx)
+	defer stopRelay()
+	relayDone := make(chan struct{})
+	if outboxRelay != nil {
+		go func() {
+			defer close(relayDone)
+			outboxRelay.Run(relayCtx)
+		}()
+	} else {
+		close(relayDone)
+	}
+	consumerResults := make(chan consumerResult, len(consumers))
+	for index, consumer := range consumers {
+		go func() {
+			consumerResults <- consumerResult{name: consumer.name, err: sqsConsumers[index].RunWithHandlerContext(receiveCtx, handlerCtx)}
+		}()
 	}
 	readiness.StartAccepting()
-	workerFields := []any{"users_max_workers", 5}
+	workerFields := []any{"users_max_workers", 5, "callbacks_max_workers", riverQueues[usersjobs.QueueCallbacks].MaxWorkers, "consumers", len(consumers)}
 	if eventPublisher != nil {
-		workerFields = append(workerFields, "events_max_workers", queues[usersjobs.QueueEvents].MaxWorkers)
+		workerFields = append(workerFields, "events_max_workers", riverQueues[usersjobs.QueueEvents].MaxWorkers)
 	}
 	logger.Info("worker started", workerFields...)
 
 	var triggerErr error
-	consumerFinished := permissionsConsumer == nil
+	runningConsumers := len(consumers)
 	select {
 	case <-ctx.Done():
 	case serverErr := <-serverErrors:
 		if !errors.Is(serverErr, http.ErrServerClosed) {
 			triggerErr = fmt.Errorf("serve worker operations HTTP: %w", serverErr)
 		}
-	case consumerErr := <-consumerErrors:
-		consumerFinished = true
-		if consumerErr != nil {
-			triggerErr = fmt.Errorf("run permissions consumer: %w", consumerErr)
+	case result := <-consumerResults:
+		runningConsumers--
+		if result.err != nil {
+			triggerErr = fmt.Errorf("run %s consumer: %w", result.name, result.err)
 		} else {
-			triggerErr = errors.New("permissions consumer stopped unexpectedly")
+			triggerErr = fmt.Errorf("%s consumer stopped unexpectedly", result.name)
 		}
 	}
 
 	readiness.StopAccepting()
 	stopReceiving()
+	stopRelay()
+	<-relayDone
 	shutdownDeadline = time.Now().Add(cfg.ShutdownTimeout)
 	shutdownCtx, shutdownCancel := context.WithDeadline(context.Background(), shutdownDeadline)
 	defer shutdownCancel()
@@ -195,23 +221,22 @@ func RunWorker(ctx context.Context, cfg config.WorkerConfig, logger *slog.Logger
 		}
 		shutdownErrors <- nil
 	}()
-	if permissionsConsumer == nil {
-		shutdownErrors <- nil
-	} else {
-		go func() {
-			if !consumerFinished {
-				if consumerErr := <-consumerErrors; consumerErr != nil {
-					shutdownErrors <- fmt.Errorf("stop permissions consumer: %w", consumerErr)
-					return
-				}
+	go func() {
+		// Every consumer must stop receiving before any is drained, since
+		// Wait only covers messages already accepted.
+		var drainErr error
+		for range runningConsumers {
+			if result := <-consumerResults; result.err != nil {
+				drainErr = errors.Join(drainErr, fmt.Errorf("stop %s consumer: %w", result.name, result.err))
 			}
-			if waitErr := permissionsConsumer.Wait(shutdownCtx); waitErr != nil {
-				shutdownErrors <- fmt.Errorf("drain permissions consumer: %w", waitErr)
-				return
+		}
+		for index, consumer := range consumers {
+			if waitErr := sqsConsumers[index].Wait(shutdownCtx); waitErr != nil {
+				drainErr = errors.Join(drainErr, fmt.Errorf("drain %s consumer: %w", consumer.name, waitErr))
 			}
-			shutdownErrors <- nil
-		}()
-	}
+		}
+		shutdownErrors <- drainErr
+	}()
 
 	for remaining := 2; remaining > 0; remaining-- {
 		select {
@@ -235,6 +260,11 @@ func RunWorker(ctx context.Context, cfg config.WorkerConfig, logger *slog.Logger
 	return errors.Join(triggerErr, runErr)
 }
 
+type consumerResult struct {
+	name string
+	err  error
+}
+
 func telemetryShutdownContext(deadline time.Time) (context.Context, context.CancelFunc) {
 	if deadline.IsZero() {
 		return context.WithTimeout(context.Background(), 5*time.Second)
@@ -0,0 +1,52 @@
+package app
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/your-org/go-service-template/internal/platform/config"
+	"github.com/your-org/go-service-template/internal/platform/messaging"
+	"github.com/your-org/go-service-template/internal/platform/telemetry"
+	"github.com/your-org/go-service-template/internal/users"
+	usersevents "github.com/your-org/go-service-template/internal/users/events"
+	userspostgres "github.com/your-org/go-service-template/internal/users/postgres"
+)
+
+// workerConsumer declares an SQS consumer: the settings of its queue and the
+// handlers of the message types it routes.
+type workerConsumer struct {
+	name     string
+	config   config.ConsumerConfig
+	register func(*messaging.Router)
+}
+
+// workerConsumers lists every consumer the worker can run; one whose queue
+// URL is empty is disabled. Consuming another queue takes a ConsumerConfig in
+// config.WorkerConfig and an entry here.
+func workerConsumers(cfg config.WorkerConfig, pool *pgxpool.Pool, telemetryRuntime telemetry.Runtime) []workerConsumer {
+	return []workerConsumer{{
+		name: "permissions", config: cfg.Permissions,
+		register: func(router *messaging.Router) {
+			permissionService := users.NewPermissionService(userspostgres.NewPermissionRepository(pool))
+			usersevents.NewPermissionHandler(permissionService, func(ctx context.Context, result users.PermissionChangeResult) {
+				telemetryRuntime.RecordPermissionOutcome(ctx, string(result))
+			}).Register(router)
+		},
+	}}
+}
+
+func (w workerConsumer) queue() workerQueue {
+	return workerQueue{name: w.name, url: w.config.QueueURL}
+}
+
+func (w workerConsumer) build(client messaging.SQSClient, logger *slog.Logger, observer messaging.ConsumerObserver) *messaging.SQSConsumer {
+	router := messaging.NewRouter(messaging.MessageFormat(w.config.MessageFormat), messaging.UnknownTypePolicy(w.config.UnknownTypePolicy))
+	w.register(router)
+	return messaging.NewSQSConsumer(client, w.config.QueueURL, router, logger.With("consumer", w.name), observer, messaging.SQSConsumerOptions{
+		Concurrency: w.config.Concurrency, VisibilityTimeout: w.config.VisibilityTimeout, WaitTime: w.config.WaitTime,
+		Retry:              messaging.RetryPolicy{BaseDelay: w.config.RetryBaseDelay, MaxDelay: w.config.RetryMaxDelay},
+		DeadLetterQueueURL: w.config.DeadLetterQueueURL,
+	})
+}
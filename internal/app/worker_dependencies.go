@@ -27,7 +27,14 @@ type snsReadinessClient interface {
 type dependencyObserver interface {
 	RecordDatabaseCheck(context.Context, time.Duration, error)
 	RecordAWSCheck(context.Context, string, time.Duration, error)
-	RecordSQSBacklog(context.Context, int64, int64)
+	RecordSQSBacklog(ctx context.Context, queue string, visible, inFlight int64)
+}
+
+// workerQueue is a queue a worker consumer receives from, named for metrics
+// and errors.
+type workerQueue struct {
+	name string
+	url  string
 }
 
 type workerDependencies struct {
@@ -35,7 +42,7 @@ type workerDependencies struct {
 	sqs      sqsReadinessClient
 	sns      snsReadinessClient
 	observer dependencyObserver
-	queueURL string
+	queues   []workerQueue
 	topicARN string
 }
 
@@ -47,32 +54,10 @@ func (d workerDependencies) Ping(ctx context.Context) error {
 		return fmt.Errorf("check PostgreSQL: %w", err)
 	}
 
-	if d.queueURL != "" {
-		started = time.Now()
-		attributes, checkErr := d.sqs.GetQueueAttributes(ctx, &sqs.GetQueueAttributesInput{
-			QueueUrl: aws.String(d.queueURL),
-			AttributeNames: []types.QueueAttributeName{
-				types.QueueAttributeNameApproximateNumberOfMessages,
-				types.QueueAttributeNameApproximateNumberOfMessagesNotVisible,
-				types.QueueAttributeNameQueueArn,
-			},
-		})
-		if checkErr != nil {
-			d.observer.RecordAWSCheck(ctx, "sqs", time.Since(started), checkErr)
-			return fmt.Errorf("check permissions queue: %w", checkErr)
+	for _, queue := range d.queues {
+		if err := d.pingQueue(ctx, queue); err != nil {
+			return err
 		}
-		visible, parseErr := parseQueueCount(attributes, types.QueueAttributeNameApproximateNumberOfMessages)
-		if parseErr != nil {
-			d.observer.RecordAWSCheck(ctx, "sqs", time.Since(started), parseErr)
-			return parseErr
-		}
-		inFlight, parseErr := parseQueueCount(attributes, types.QueueAttributeNameApproximateNumberOfMessagesNotVisible)
-		if parseErr != nil {
-			d.observer.RecordAWSCheck(ctx, "sqs", time.Since(started), parseErr)
-			return parseErr
-		}
-		d.observer.RecordAWSCheck(ctx, "sqs", time.Since(started), nil)
-		d.observer.RecordSQSBacklog(ctx, visible, inFlight)
 	}
 
 	if d.topicARN != "" {
@@ -86,6 +71,35 @@ func (d workerDependencies) Ping(ctx context.Context) error {
 	return nil
 }
 
+func (d workerDependencies) pingQueue(ctx context.Context, queue workerQueue) error {
+	started := time.Now()
+	attributes, checkErr := d.sqs.GetQueueAttributes(ctx, &sqs.GetQueueAttributesInput{
+		QueueUrl: aws.String(queue.url),
+		AttributeNames: []types.QueueAttributeName{
+			types.QueueAttributeNameApproximateNumberOfMessages,
+			types.QueueAttributeNameApproximateNumberOfMessagesNotVisible,
+			types.QueueAttributeNameQueueArn,
+		},
+	})
+	if checkErr != nil {
+		d.observer.RecordAWSCheck(ctx, "sqs", time.Since(started), checkErr)
+		return fmt.Errorf("check %s queue: %w", queue.name, checkErr)
+	}
+	visible, parseErr := parseQueueCount(attributes, types.QueueAttributeNameApproximateNumberOfMessages)
+	if parseErr != nil {
+		d.observer.RecordAWSCheck(ctx, "sqs", time.Since(started), parseErr)
+		return parseErr
+	}
+	inFlight, parseErr := parseQueueCount(attributes, types.QueueAttributeNameApproximateNumberOfMessagesNotVisible)
+	if parseErr != nil {
+		d.observer.RecordAWSCheck(ctx, "sqs", time.Since(started), parseErr)
+		return parseErr
+	}
+	d.observer.RecordAWSCheck(ctx, "sqs", time.Since(started), nil)
+	d.observer.RecordSQSBacklog(ctx, queue.name, visible, inFlight)
+	return nil
+}
+
 func parseQueueCount(attributes *sqs.GetQueueAttributesOutput, name types.QueueAttributeName) (int64, error) {
 	value := attributes.Attributes[string(name)]
 	count, err := strconv.ParseInt(value, 10, 64)
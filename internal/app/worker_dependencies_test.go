@@ -23,13 +23,13 @@ func TestWorkerDependenciesCheckDatabaseAndMessaging(t *testing.T) {
 			string(types.QueueAttributeNameApproximateNumberOfMessages):           "4",
 			string(types.QueueAttributeNameApproximateNumberOfMessagesNotVisible): "2",
 		}}},
-		sns: &snsReadinessStub{}, observer: observer, queueURL: "queue", topicARN: "topic",
+		sns: &snsReadinessStub{}, observer: observer, topicARN: "topic",
+		queues: []workerQueue{{name: "permissions", url: "permissions-queue"}, {name: "imports", url: "imports-queue"}},
 	}
 
 	require.NoError(t, dependencies.Ping(t.Context()))
-	assert.Equal(t, int64(4), observer.visible)
-	assert.Equal(t, int64(2), observer.inFlight)
-	assert.Equal(t, []string{"sqs", "sns"}, observer.awsChecks)
+	assert.Equal(t, map[string][2]int64{"permissions": {4, 2}, "imports": {4, 2}}, observer.backlogs)
+	assert.Equal(t, []string{"sqs", "sqs", "sns"}, observer.awsChecks)
 }
 
 func TestWorkerDependenciesReportIndividualFailures(t *testing.T) {
@@ -41,7 +41,7 @@ func TestWorkerDependenciesReportIndividualFailures(t *testing.T) {
 		},
 		"queue": {
 			database: pingerStub{}, sqs: &sqsReadinessStub{err: errors.New("queue unavailable")},
-			observer: &dependencyObserverStub{}, queueURL: "queue",
+			observer: &dependencyObserverStub{}, queues: []workerQueue{{name: "permissions", url: "queue"}},
 		},
 		"topic": {
 			database: pingerStub{}, sns: &snsReadinessStub{err: errors.New("topic unavailable")},
@@ -80,8 +80,7 @@ func (s *snsReadinessStub) GetTopicAttributes(context.Context, *sns.GetTopicAttr
 
 type dependencyObserverStub struct {
 	awsChecks []string
-	visible   int64
-	inFlight  int64
+	backlogs  map[string][2]int64
 }
 
 func (*dependencyObserverStub) RecordDatabaseCheck(context.Context, time.Duration, error) {}
@@ -90,7 +89,9 @@ func (s *dependencyObserverStub) RecordAWSCheck(_ context.Context, dependency st
 	s.awsChecks = append(s.awsChecks, dependency)
 }
 
-func (s *dependencyObserverStub) RecordSQSBacklog(_ context.Context, visible, inFlight int64) {
-	s.visible = visible
-	s.inFlight = inFlight
+func (s *dependencyObserverStub) RecordSQSBacklog(_ context.Context, queue string, visible, inFlight int64) {
+	if s.backlogs == nil {
+		s.backlogs = make(map[string][2]int64)
+	}
+	s.backlogs[queue] = [2]int64{visible, inFlight}
 }
@@ -0,0 +1,96 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIKeyVerifierAcceptsOnlyActiveKeys(t *testing.T) {
+	t.Parallel()
+
+	rawKey, hash, err := NewAPIKey()
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(rawKey, APIKeyPrefix))
+	now := time.Date(2026, time.October, 18, 12, 0, 0, 0, time.UTC)
+	past, future := now.Add(-time.Minute), now.Add(time.Minute)
+	active := APIKey{ID: uuid.New(), Name: "nightly-sync", Subject: "0198a1f7-30b7-7dfe-8491-c47f6033525b", Scopes: []string{"users:read"}}
+
+	for name, testCase := range map[string]struct {
+		rawKey  string
+		key     APIKey
+		wantErr bool
+	}{
+		"active":          {rawKey: rawKey, key: active},
+		"not yet expired": {rawKey: rawKey, key: withExpiry(active, future)},
+		"expired":         {rawKey: rawKey, key: withExpiry(active, past), wantErr: true},
+		"revoked":         {rawKey: rawKey, key: withRevocation(active, past), wantErr: true},
+		"unknown":         {rawKey: APIKeyPrefix + "unknown", key: active, wantErr: true},
+		"missing prefix":  {rawKey: strings.TrimPrefix(rawKey, APIKeyPrefix), key: active, wantErr: true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			verifier := NewAPIKeyVerifier(apiKeyStoreStub{hash: hash, key: testCase.key})
+			verifier.now = func() time.Time { return now }
+
+			claims, err := verifier.Verify(t.Context(), testCase.rawKey)
+			if testCase.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, active.Subject, claims.Subject)
+			assert.True(t, claims.HasScope("users:read"))
+			assert.Equal(t, "nightly-sync", claims.Raw["api_key_name"])
+		})
+	}
+}
+
+func TestCombinedVerifierRoutesByKeyPrefix(t *testing.T) {
+	t.Parallel()
+
+	verifier := &CombinedVerifier{
+		tokens:  verifierFunc(func(string) (Claims, error) { return Claims{Subject: "oidc"}, nil }),
+		apiKeys: verifierFunc(func(string) (Claims, error) { return Claims{Subject: "api-key"}, nil }),
+	}
+	claims, err := verifier.Verify(t.Context(), "header.payload.signature")
+	require.NoError(t, err)
+	assert.Equal(t, "oidc", claims.Subject)
+	claims, err = verifier.Verify(t.Context(), APIKeyPrefix+"secret")
+	require.NoError(t, err)
+	assert.Equal(t, "api-key", claims.Subject)
+}
+
+func withExpiry(key APIKey, expiresAt time.Time) APIKey {
+	key.ExpiresAt = &expiresAt
+	return key
+}
+
+func withRevocation(key APIKey, revokedAt time.Time) APIKey {
+	key.RevokedAt = &revokedAt
+	return key
+}
+
+type apiKeyStoreStub struct {
+	hash []byte
+	key  APIKey
+}
+
+func (s apiKeyStoreStub) FindAPIKey(_ context.Context, hash []byte) (APIKey, error) {
+	if !bytes.Equal(hash, s.hash) {
+		return APIKey{}, ErrAPIKeyNotFound
+	}
+	return s.key, nil
+}
+
+type verifierFunc func(string) (Claims, error)
+
+func (f verifierFunc) Verify(_ context.Context, rawToken string) (Claims, error) {
+	return f(rawToken)
+}
@@ -0,0 +1,270 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/go-jose/go-jose/v4/jwt"
+)
+
+const (
+	// minKeyRefreshInterval limits how often tokens signed with an unknown
+	// key ID can trigger a refresh between scheduled ones.
+	minKeyRefreshInterval = 30 * time.Second
+	maxJWKSBytes          = 1 << 20
+)
+
+type JWKSOptions struct {
+	// Source is a file path or an http(s) URL serving a JSON Web Key Set.
+	Source string
+	// Issuers and Audiences list the accepted "iss" values and "aud" values;
+	// a token must match one of each.
+	Issuers   []string
+	Audiences []string
+	// Algorithms lists the accepted signature algorithms, such as RS256.
+	Algorithms []string
+	// ClockSkew is the leeway allowed when checking exp, nbf, and iat.
+	ClockSkew       time.Duration
+	RefreshInterval time.Duration
+}
+
+// JWKSVerifier verifies JWTs against keys loaded from a JWKS file or URL
+// rather than through OIDC discovery, so no identity provider needs to be
+// reachable at startup. Keys are refreshed in the background and the last
+// loaded set stays in use while refreshes fail.
+type JWKSVerifier struct {
+	options    JWKSOptions
+	algorithms []jose.SignatureAlgorithm
+	logger     *slog.Logger
+	client     *http.Client
+	now        func() time.Time
+
+	keys atomic.Pointer[jose.JSONWebKeySet]
+
+	refreshMu   sync.Mutex
+	refreshedAt time.Time
+}
+
+// NewJWKSVerifier loads the initial key set. A file that cannot be loaded is
+// a configuration error; an unreachable URL is only logged, and verification
+// fails until a later refresh succeeds.
+func NewJWKSVerifier(ctx context.Context, options JWKSOptions, logger *slog.Logger) (*JWKSVerifier, error) {
+	if options.Source == "" || len(options.Issuers) == 0 || len(options.Audiences) == 0 {
+		return nil, errors.New("JWKS source, issuers, and audiences are required")
+	}
+	if options.ClockSkew < 0 || options.RefreshInterval <= 0 {
+		return nil, errors.New("JWKS clock skew must not be negative and refresh interval must be positive")
+	}
+	algorithms, err := signingAlgorithms(options.Algorithms)
+	if err != nil {
+		return nil, err
+	}
+
+	v := &JWKSVerifier{
+		options:    options,
+		algorithms: algorithms,
+		logger:     logger,
+		client:     &http.Client{Timeout: 10 * time.Second},
+		now:        time.Now,
+	}
+	if err := v.refresh(ctx); err != nil {
+		if !remoteSource(options.Source) {
+			return nil, err
+		}
+		logger.WarnContext(ctx, "load JWKS; retrying in the background", "error", err)
+	}
+	return v, nil
+}
+
+// asymmetricAlgorithms excludes HMAC, which a published key set cannot use.
+var asymmetricAlgorithms = []jose.SignatureAlgorithm{
+	jose.RS256, jose.RS384, jose.RS512,
+	jose.PS256, jose.PS384, jose.PS512,
+	jose.ES256, jose.ES384, jose.ES512,
+	jose.EdDSA,
+}
+
+func signingAlgorithms(names []string) ([]jose.SignatureAlgorithm, error) {
+	algorithms := make([]jose.SignatureAlgorithm, 0, len(names))
+	for _, name := range names {
+		algorithm := jose.SignatureAlgorithm(name)
+		if !slices.Contains(asymmetricAlgorithms, algorithm) {
+			return nil, fmt.Errorf("unsupported JWT signing algorithm %q", name)
+		}
+		algorithms = append(algorithms, algorithm)
+	}
+	if len(algorithms) == 0 {
+		return nil, errors.New("at least one JWT signing algorithm is required")
+	}
+	return algorithms, nil
+}
+
+// Run refreshes the key set until ctx ends, so rotated keys are picked up.
+func (v *JWKSVerifier) Run(ctx context.Context) {
+	ticker := time.NewTicker(v.options.RefreshInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := v.refresh(ctx); err != nil && ctx.Err() == nil {
+				v.logger.WarnContext(ctx, "refresh JWKS; keeping cached keys", "error", err)
+			}
+		}
+	}
+}
+
+func (v *JWKSVerifier) Verify(ctx context.Context, rawToken string) (Claims, error) {
+	token, err := jwt.ParseSigned(rawToken, v.algorithms)
+	if err != nil {
+		return Claims{}, fmt.Errorf("parse token: %w", err)
+	}
+	keyID := token.Headers[0].KeyID
+	keys := v.signingKeys(keyID)
+	if len(keys) == 0 {
+		v.refreshUnknownKey(ctx)
+		keys = v.signingKeys(keyID)
+	}
+	if len(keys) == 0 {
+		return Claims{}, fmt.Errorf("no signing key matches key ID %q", keyID)
+	}
+
+	var registered jwt.Claims
+	var raw map[string]any
+	for _, key := range keys {
+		if err = token.Claims(key, &registered, &raw); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return Claims{}, fmt.Errorf("verify token: %w", err)
+	}
+	if !slices.Contains(v.options.Issuers, registered.Issuer) {
+		return Claims{}, fmt.Errorf("token issuer %q is not accepted", registered.Issuer)
+	}
+	if registered.Expiry == nil {
+		return Claims{}, errors.New("token has no expiry")
+	}
+	expected := jwt.Expected{AnyAudience: jwt.Audience(v.options.Audiences), Time: v.now()}
+	if err := registered.ValidateWithLeeway(expected, v.options.ClockSkew); err != nil {
+		return Claims{}, fmt.Errorf("validate token claims: %w", err)
+	}
+	if registered.Subject == "" {
+		return Claims{}, errors.New("token subject is empty")
+	}
+	return Claims{Subject: registered.Subject, Scopes: scopes(raw), Raw: raw}, nil
+}
+
+// signingKeys returns the keys for keyID, or every signing key for a token
+// without one.
+func (v *JWKSVerifier) signingKeys(keyID string) []jose.JSONWebKey {
+	set := v.keys.Load()
+	if set == nil {
+		return nil
+	}
+	candidates := set.Keys
+	if keyID != "" {
+		candidates = set.Key(keyID)
+	}
+	keys := make([]jose.JSONWebKey, 0, len(candidates))
+	for _, key := range candidates {
+		if key.Use == "" || key.Use == "sig" {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// refreshUnknownKey reloads the key set for a token signed with a key that
+// is not cached yet, which is how rotations show up before the next
+// scheduled refresh.
+func (v *JWKSVerifier) refreshUnknownKey(ctx context.Context) {
+	v.refreshMu.Lock()
+	defer v.refreshMu.Unlock()
+	if v.now().Sub(v.refreshedAt) < minKeyRefreshInterval {
+		return
+	}
+	if err := v.reload(ctx); err != nil {
+		v.logger.WarnContext(ctx, "refresh JWKS for unknown key; keeping cached keys", "error", err)
+	}
+}
+
+func (v *JWKSVerifier) refresh(ctx context.Context) error {
+	v.refreshMu.Lock()
+	defer v.refreshMu.Unlock()
+	return v.reload(ctx)
+}
+
+// reload must be called with refreshMu held.
+func (v *JWKSVerifier) reload(ctx context.Context) error {
+	v.refreshedAt = v.now()
+
+	data, err := v.load(ctx)
+	if err != nil {
+		return err
+	}
+	var set jose.JSONWebKeySet
+	if err := json.Unmarshal(data, &set); err != nil {
+		return fmt.Errorf("decode JWKS: %w", err)
+	}
+	if len(set.Keys) == 0 {
+		return errors.New("JWKS contains no keys")
+	}
+	// Private keys are accepted so that tests can share one key file with
+	// the signer; only their public halves are kept.
+	for index, key := range set.Keys {
+		if !key.Valid() {
+			return fmt.Errorf("JWKS key %q is invalid", key.KeyID)
+		}
+		if !key.IsPublic() {
+			set.Keys[index] = key.Public()
+		}
+	}
+	v.keys.Store(&set)
+	return nil
+}
+
+func (v *JWKSVerifier) load(ctx context.Context) ([]byte, error) {
+	if !remoteSource(v.options.Source) {
+		data, err := os.ReadFile(v.options.Source)
+		if err != nil {
+			return nil, fmt.Errorf("read JWKS file: %w", err)
+		}
+		return data, nil
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, v.options.Source, nil)
+	if err != nil {
+		return nil, fmt.Errorf("create JWKS request: %w", err)
+	}
+	response, err := v.client.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("fetch JWKS: %w", err)
+	}
+	defer func() { _ = response.Body.Close() }()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch JWKS: unexpected status %d", response.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(response.Body, maxJWKSBytes))
+	if err != nil {
+		return nil, fmt.Errorf("read JWKS response: %w", err)
+	}
+	return data, nil
+}
+
+func remoteSource(source string) bool {
+	return strings.HasPrefix(source, "https://") || strings.HasPrefix(source, "http://")
+}
@@ -0,0 +1,197 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/go-jose/go-jose/v4/jwt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testNow = time.Date(2026, time.October, 18, 12, 0, 0, 0, time.UTC)
+
+func TestJWKSVerifierValidatesClaims(t *testing.T) {
+	t.Parallel()
+
+	key := newSigningKey(t, "key-1")
+	verifier := newFileJWKSVerifier(t, writeJWKS(t, key))
+	valid := jwt.Claims{
+		Issuer: "https://identity.example.com/", Subject: "user-123", Audience: jwt.Audience{"service-api"},
+		Expiry: jwt.NewNumericDate(testNow.Add(time.Minute)),
+	}
+
+	for name, testCase := range map[string]struct {
+		change  func(*jwt.Claims)
+		wantErr bool
+	}{
+		"valid":                     {change: func(*jwt.Claims) {}},
+		"second issuer":             {change: func(c *jwt.Claims) { c.Issuer = "https://backup.example.com/" }},
+		"second audience":           {change: func(c *jwt.Claims) { c.Audience = jwt.Audience{"other", "batch-api"} }},
+		"expired within skew":       {change: func(c *jwt.Claims) { c.Expiry = jwt.NewNumericDate(testNow.Add(-20 * time.Second)) }},
+		"expired beyond skew":       {change: func(c *jwt.Claims) { c.Expiry = jwt.NewNumericDate(testNow.Add(-time.Minute)) }, wantErr: true},
+		"missing expiry":            {change: func(c *jwt.Claims) { c.Expiry = nil }, wantErr: true},
+		"unknown issuer":            {change: func(c *jwt.Claims) { c.Issuer = "https://evil.example.com/" }, wantErr: true},
+		"unknown audience":          {change: func(c *jwt.Claims) { c.Audience = jwt.Audience{"other"} }, wantErr: true},
+		"empty subject":             {change: func(c *jwt.Claims) { c.Subject = "" }, wantErr: true},
+		"not yet valid beyond skew": {change: func(c *jwt.Claims) { c.NotBefore = jwt.NewNumericDate(testNow.Add(time.Minute)) }, wantErr: true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			claims := valid
+			testCase.change(&claims)
+			verified, err := verifier.Verify(t.Context(), signToken(t, key, claims, map[string]any{"scope": "users:read"}))
+			if testCase.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "user-123", verified.Subject)
+			assert.Equal(t, []string{"users:read"}, verified.Scopes)
+		})
+	}
+
+	_, err := verifier.Verify(t.Context(), signToken(t, newSigningKey(t, "key-1"), valid, nil))
+	require.Error(t, err, "a token signed by another key with the same ID must be rejected")
+}
+
+func TestJWKSVerifierRejectsAlgorithmsNotAllowed(t *testing.T) {
+	t.Parallel()
+
+	key := newSigningKey(t, "key-1")
+	verifier, err := NewJWKSVerifier(t.Context(), JWKSOptions{
+		Source: writeJWKS(t, key), Issuers: []string{"issuer"}, Audiences: []string{"audience"},
+		Algorithms: []string{"RS256"}, RefreshInterval: time.Minute,
+	}, slog.New(slog.DiscardHandler))
+	require.NoError(t, err)
+	_, err = verifier.Verify(t.Context(), signToken(t, key, jwt.Claims{
+		Issuer: "issuer", Subject: "user-123", Audience: jwt.Audience{"audience"}, Expiry: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+	}, nil))
+	require.Error(t, err)
+
+	_, err = NewJWKSVerifier(t.Context(), JWKSOptions{
+		Source: writeJWKS(t, key), Issuers: []string{"issuer"}, Audiences: []string{"audience"},
+		Algorithms: []string{"HS256"}, RefreshInterval: time.Minute,
+	}, slog.New(slog.DiscardHandler))
+	require.Error(t, err)
+}
+
+func TestJWKSVerifierPicksUpRotatedKeysAndKeepsCachedKeysOnFailure(t *testing.T) {
+	t.Parallel()
+
+	oldKey, newKey := newSigningKey(t, "key-1"), newSigningKey(t, "key-2")
+	path := writeJWKS(t, oldKey)
+	verifier := newFileJWKSVerifier(t, path)
+	now := testNow
+	verifier.now = func() time.Time { return now }
+	claims := jwt.Claims{
+		Issuer: "https://identity.example.com/", Subject: "user-123", Audience: jwt.Audience{"service-api"},
+		Expiry: jwt.NewNumericDate(testNow.Add(time.Hour)),
+	}
+
+	require.NoError(t, os.WriteFile(path, []byte("not a key set"), 0o600))
+	require.Error(t, verifier.refresh(t.Context()))
+	_, err := verifier.Verify(t.Context(), signToken(t, oldKey, claims, nil))
+	require.NoError(t, err, "cached keys stay in use while refreshes fail")
+
+	require.NoError(t, os.WriteFile(path, mustMarshalJWKS(t, oldKey, newKey), 0o600))
+	_, err = verifier.Verify(t.Context(), signToken(t, newKey, claims, nil))
+	require.Error(t, err, "unknown keys trigger at most one refresh per interval")
+
+	now = now.Add(minKeyRefreshInterval)
+	_, err = verifier.Verify(t.Context(), signToken(t, newKey, claims, nil))
+	require.NoError(t, err)
+}
+
+func TestJWKSVerifierStartsWhileKeySetURLIsUnreachable(t *testing.T) {
+	t.Parallel()
+
+	key := newSigningKey(t, "key-1")
+	var available atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if !available.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write(mustMarshalJWKS(t, key))
+	}))
+	t.Cleanup(server.Close)
+
+	verifier, err := NewJWKSVerifier(t.Context(), JWKSOptions{
+		Source: server.URL, Issuers: []string{"issuer"}, Audiences: []string{"audience"},
+		Algorithms: []string{"ES256"}, RefreshInterval: time.Hour,
+	}, slog.New(slog.DiscardHandler))
+	require.NoError(t, err)
+	token := signToken(t, key, jwt.Claims{
+		Issuer: "issuer", Subject: "user-123", Audience: jwt.Audience{"audience"}, Expiry: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+	}, nil)
+	_, err = verifier.Verify(t.Context(), token)
+	require.Error(t, err)
+
+	available.Store(true)
+	verifier.now = func() time.Time { return time.Now().Add(minKeyRefreshInterval) }
+	_, err = verifier.Verify(t.Context(), token)
+	require.NoError(t, err)
+}
+
+func newFileJWKSVerifier(t *testing.T, path string) *JWKSVerifier {
+	t.Helper()
+	verifier, err := NewJWKSVerifier(t.Context(), JWKSOptions{
+		Source:          path,
+		Issuers:         []string{"https://identity.example.com/", "https://backup.example.com/"},
+		Audiences:       []string{"service-api", "batch-api"},
+		Algorithms:      []string{"ES256"},
+		ClockSkew:       30 * time.Second,
+		RefreshInterval: time.Hour,
+	}, slog.New(slog.DiscardHandler))
+	require.NoError(t, err)
+	verifier.now = func() time.Time { return testNow }
+	return verifier
+}
+
+func newSigningKey(t *testing.T, keyID string) jose.JSONWebKey {
+	t.Helper()
+	private, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	return jose.JSONWebKey{Key: private, KeyID: keyID, Algorithm: string(jose.ES256), Use: "sig"}
+}
+
+func signToken(t *testing.T, key jose.JSONWebKey, claims jwt.Claims, extra map[string]any) string {
+	t.Helper()
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.ES256, Key: key}, (&jose.SignerOptions{}).WithType("JWT"))
+	require.NoError(t, err)
+	builder := jwt.Signed(signer).Claims(claims)
+	if extra != nil {
+		builder = builder.Claims(extra)
+	}
+	token, err := builder.Serialize()
+	require.NoError(t, err)
+	return token
+}
+
+// writeJWKS stores the private keys, which the verifier reduces to their
+// public halves.
+func writeJWKS(t *testing.T, keys ...jose.JSONWebKey) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	require.NoError(t, os.WriteFile(path, mustMarshalJWKS(t, keys...), 0o600))
+	return path
+}
+
+func mustMarshalJWKS(t *testing.T, keys ...jose.JSONWebKey) []byte {
+	t.Helper()
+	data, err := json.Marshal(jose.JSONWebKeySet{Keys: keys})
+	require.NoError(t, err)
+	return data
+}
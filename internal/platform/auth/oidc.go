@@ -5,16 +5,44 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/go-jose/go-jose/v4/jwt"
 )
 
+// Claims are the verified claims of an access token.
+type Claims struct {
+	Subject string
+	// Scopes come from the space-separated "scope" claim or, for providers
+	// that issue a list instead, from the "scp" claim.
+	Scopes []string
+	// Raw holds every claim of the token as decoded from JSON.
+	Raw map[string]any
+}
+
+func (c Claims) HasScope(scope string) bool {
+	return slices.Contains(c.Scopes, scope)
+}
+
+// Verifier verifies JWTs with keys discovered from the issuer. Like
+// JWKSVerifier it accepts only the given algorithms and allows clockSkew
+// when checking exp, nbf, and iat.
 type Verifier struct {
-	verifier *oidc.IDTokenVerifier
+	verifier  *oidc.IDTokenVerifier
+	clockSkew time.Duration
+	now       func() time.Time
 }
 
-func NewVerifier(ctx context.Context, issuerURL, audience string) (*Verifier, error) {
+func NewVerifier(ctx context.Context, issuerURL, audience string, algorithms []string, clockSkew time.Duration) (*Verifier, error) {
+	if clockSkew < 0 {
+		return nil, errors.New("OIDC clock skew must not be negative")
+	}
+	if _, err := signingAlgorithms(algorithms); err != nil {
+		return nil, err
+	}
 	httpClient := &http.Client{Timeout: 10 * time.Second}
 	provider, err := oidc.NewProvider(oidc.ClientContext(ctx, httpClient), issuerURL)
 	if err != nil {
@@ -22,17 +50,56 @@ func NewVerifier(ctx context.Context, issuerURL, audience string) (*Verifier, er
 	}
 
 	return &Verifier{
-		verifier: provider.Verifier(&oidc.Config{ClientID: audience}),
+		// The expiry check is left to Verify, since the provider's verifier
+		// has no configurable leeway.
+		verifier: provider.Verifier(&oidc.Config{
+			ClientID: audience, SupportedSigningAlgs: algorithms, SkipExpiryCheck: true,
+		}),
+		clockSkew: clockSkew,
+		now:       time.Now,
 	}, nil
 }
 
-func (v *Verifier) Verify(ctx context.Context, rawToken string) (string, error) {
+func (v *Verifier) Verify(ctx context.Context, rawToken string) (Claims, error) {
 	token, err := v.verifier.Verify(ctx, rawToken)
 	if err != nil {
-		return "", fmt.Errorf("verify token: %w", err)
+		return Claims{}, fmt.Errorf("verify token: %w", err)
 	}
 	if token.Subject == "" {
-		return "", errors.New("token subject is empty")
+		return Claims{}, errors.New("token subject is empty")
+	}
+	var registered jwt.Claims
+	if err := token.Claims(&registered); err != nil {
+		return Claims{}, fmt.Errorf("decode token claims: %w", err)
+	}
+	if registered.Expiry == nil {
+		return Claims{}, errors.New("token has no expiry")
+	}
+	if err := registered.ValidateWithLeeway(jwt.Expected{Time: v.now()}, v.clockSkew); err != nil {
+		return Claims{}, fmt.Errorf("validate token claims: %w", err)
+	}
+	var raw map[string]any
+	if err := token.Claims(&raw); err != nil {
+		return Claims{}, fmt.Errorf("decode token claims: %w", err)
+	}
+	return Claims{Subject: token.Subject, Scopes: scopes(raw), Raw: raw}, nil
+}
+
+func scopes(raw map[string]any) []string {
+	if scope, ok := raw["scope"].(string); ok {
+		return strings.Fields(scope)
+	}
+	switch scp := raw["scp"].(type) {
+	case string:
+		return strings.Fields(scp)
+	case []any:
+		scopes := make([]string, 0, len(scp))
+		for _, value := range scp {
+			if scope, ok := value.(string); ok && scope != "" {
+				scopes = append(scopes, scope)
+			}
+		}
+		return scopes
 	}
-	return token.Subject, nil
+	return nil
 }
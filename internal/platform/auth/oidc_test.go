@@ -0,0 +1,111 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/go-jose/go-jose/v4/jwt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScopesReadsScopeAndScpClaims(t *testing.T) {
+	t.Parallel()
+
+	for name, testCase := range map[string]struct {
+		raw  map[string]any
+		want []string
+	}{
+		"scope string": {raw: map[string]any{"scope": "users:read  users:write"}, want: []string{"users:read", "users:write"}},
+		"scp list":     {raw: map[string]any{"scp": []any{"users:read", 7, ""}}, want: []string{"users:read"}},
+		"scp string":   {raw: map[string]any{"scp": "users:read"}, want: []string{"users:read"}},
+		"scope wins":   {raw: map[string]any{"scope": "users:write", "scp": []any{"users:read"}}, want: []string{"users:write"}},
+		"none":         {raw: map[string]any{"sub": "user-123"}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, testCase.want, scopes(testCase.raw))
+		})
+	}
+}
+
+func TestClaimsHasScope(t *testing.T) {
+	t.Parallel()
+
+	claims := Claims{Scopes: []string{"users:read"}}
+	assert.True(t, claims.HasScope("users:read"))
+	assert.False(t, claims.HasScope("users:write"))
+}
+
+func TestVerifierAppliesAlgorithmsAndClockSkew(t *testing.T) {
+	t.Parallel()
+
+	key := newSigningKey(t, "key-1")
+	issuer := newDiscoveryServer(t, key)
+	verifier, err := NewVerifier(t.Context(), issuer, "service-api", []string{"ES256"}, 30*time.Second)
+	require.NoError(t, err)
+	verifier.now = func() time.Time { return testNow }
+	valid := jwt.Claims{
+		Issuer: issuer, Subject: "user-123", Audience: jwt.Audience{"service-api"},
+		Expiry: jwt.NewNumericDate(testNow.Add(time.Minute)),
+	}
+
+	for name, testCase := range map[string]struct {
+		change  func(*jwt.Claims)
+		wantErr bool
+	}{
+		"valid":                     {change: func(*jwt.Claims) {}},
+		"expired within skew":       {change: func(c *jwt.Claims) { c.Expiry = jwt.NewNumericDate(testNow.Add(-20 * time.Second)) }},
+		"expired beyond skew":       {change: func(c *jwt.Claims) { c.Expiry = jwt.NewNumericDate(testNow.Add(-time.Minute)) }, wantErr: true},
+		"not yet valid beyond skew": {change: func(c *jwt.Claims) { c.NotBefore = jwt.NewNumericDate(testNow.Add(time.Minute)) }, wantErr: true},
+		"missing expiry":            {change: func(c *jwt.Claims) { c.Expiry = nil }, wantErr: true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			claims := valid
+			testCase.change(&claims)
+			_, err := verifier.Verify(t.Context(), signToken(t, key, claims, nil))
+			if testCase.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+
+	rsaOnly, err := NewVerifier(t.Context(), issuer, "service-api", []string{"RS256"}, 30*time.Second)
+	require.NoError(t, err)
+	rsaOnly.now = func() time.Time { return testNow }
+	_, err = rsaOnly.Verify(t.Context(), signToken(t, key, valid, nil))
+	require.Error(t, err, "ES256 is not among the accepted algorithms")
+
+	_, err = NewVerifier(t.Context(), issuer, "service-api", []string{"HS256"}, 0)
+	require.Error(t, err)
+}
+
+// newDiscoveryServer serves the OIDC discovery document and the public half
+// of key, and returns the issuer URL.
+func newDiscoveryServer(t *testing.T, key jose.JSONWebKey) string {
+	t.Helper()
+	private, ok := key.Key.(*ecdsa.PrivateKey)
+	require.True(t, ok)
+	public := key
+	public.Key = &private.PublicKey
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	mux.HandleFunc("GET /.well-known/openid-configuration", func(w http.ResponseWriter, _ *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"issuer": server.URL, "jwks_uri": server.URL + "/keys", "id_token_signing_alg_values_supported": []string{"ES256"},
+		})
+	})
+	mux.HandleFunc("GET /keys", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write(mustMarshalJWKS(t, public))
+	})
+	return server.URL
+}
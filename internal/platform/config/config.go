@@ -17,8 +17,23 @@ const (
 	EnvironmentTest        = "test"
 	EnvironmentProduction  = "production"
 
-	AuthModeDisabled = "disabled"
-	AuthModeOIDC     = "oidc"
+	AuthModeDisabled   = "disabled"
+	AuthModeOIDC       = "oidc"
+	AuthModeAPIKey     = "apikey"
+	AuthModeOIDCAPIKey = "oidc+apikey"
+
+	RateLimitBackendDisabled = "disabled"
+	RateLimitBackendMemory   = "memory"
+	RateLimitBackendPostgres = "postgres"
+
+	MessageFormatSNS         = "sns"
+	MessageFormatRaw         = "raw"
+	MessageFormatEventBridge = "eventbridge"
+	MessageFormatAuto        = "auto"
+
+	UnknownTypeAck        = "ack"
+	UnknownTypeRetry      = "retry"
+	UnknownTypeDeadLetter = "deadletter"
 
 	LogLevelDebug = LogLevel("debug")
 	LogLevelInfo  = LogLevel("info")
@@ -42,14 +57,24 @@ func (l LogLevel) SlogLevel() slog.Level {
 }
 
 type Config struct {
-	Environment      string        `env:"APP_ENV" envDefault:"development"`
-	ServiceName      string        `env:"SERVICE_NAME" envDefault:"go-service-template"`
-	LogLevel         LogLevel      `env:"LOG_LEVEL" envDefault:"info"`
-	HTTPAddress      string        `env:"HTTP_ADDRESS" envDefault:":8080"`
-	DatabaseURL      string        `env:"DATABASE_URL,required"`
-	AuthMode         string        `env:"AUTH_MODE" envDefault:"oidc"`
-	OIDCIssuerURL    string        `env:"OIDC_ISSUER_URL"`
-	OIDCAudience     string        `env:"OIDC_AUDIENCE"`
+	Environment string   `env:"APP_ENV" envDefault:"development"`
+	ServiceName string   `env:"SERVICE_NAME" envDefault:"go-service-template"`
+	LogLevel    LogLevel `env:"LOG_LEVEL" envDefault:"info"`
+	HTTPAddress string   `env:"HTTP_ADDRESS" envDefault:":8080"`
+	DatabaseURL string   `env:"DATABASE_URL,required"`
+	AuthMode    string   `env:"AUTH_MODE" envDefault:"oidc"`
+	// OIDC_ISSUER_URL and OIDC_AUDIENCE accept comma-separated lists when
+	// tokens are verified offline against OIDC_JWKS_SOURCE; discovery needs
+	// exactly one of each.
+	OIDCIssuerURLs          []string      `env:"OIDC_ISSUER_URL" envSeparator:","`
+	OIDCAudiences           []string      `env:"OIDC_AUDIENCE" envSeparator:","`
+	OIDCJWKSSource          string        `env:"OIDC_JWKS_SOURCE"`
+	OIDCJWKSRefreshInterval time.Duration `env:"OIDC_JWKS_REFRESH_INTERVAL" envDefault:"5m"`
+	OIDCSigningAlgorithms   []string      `env:"OIDC_SIGNING_ALGORITHMS" envSeparator:"," envDefault:"RS256"`
+	OIDCClockSkew           time.Duration `env:"OIDC_CLOCK_SKEW" envDefault:"30s"`
+	// RateLimitBackend holds the x-rate-limit token buckets in process or, so
+	// that limits hold across API replicas, in PostgreSQL.
+	RateLimitBackend string        `env:"RATE_LIMIT_BACKEND" envDefault:"memory"`
 	ShutdownTimeout  time.Duration `env:"SHUTDOWN_TIMEOUT" envDefault:"10s"`
 	OTLPHTTPEndpoint string        `env:"OTEL_EXPORTER_OTLP_ENDPOINT"`
 }
@@ -65,7 +90,60 @@ type WorkerConfig struct {
 	AWSRegion        string        `env:"AWS_REGION" envDefault:"eu-west-1"`
 	AWSEndpointURL   string        `env:"AWS_ENDPOINT_URL"`
 	UserEventsTopic  string        `env:"USER_EVENTS_TOPIC_ARN"`
-	PermissionsQueue string        `env:"PERMISSIONS_QUEUE_URL"`
+	// Permissions configures the consumer of permission changes.
+	Permissions ConsumerConfig `envPrefix:"PERMISSIONS_"`
+	// ImportCallbackSecret signs import completion callbacks. Any JSON import
+	// may request a callback, so the worker cannot run without it.
+	ImportCallbackSecret string `env:"IMPORT_CALLBACK_SECRET,required"`
+}
+
+// ConsumerConfig configures the SQS consumer of one queue. Its variables are
+// prefixed with the consumer's name, as in PERMISSIONS_QUEUE_URL; the
+// consumer does not run while the queue URL is empty.
+type ConsumerConfig struct {
+	QueueURL string `env:"QUEUE_URL"`
+	// MessageFormat is how the queue's messages wrap the envelope: an SNS
+	// notification, the raw envelope, an EventBridge event, or any of them
+	// detected per message.
+	MessageFormat string `env:"MESSAGE_FORMAT" envDefault:"sns"`
+	// UnknownTypePolicy is what the consumer does with messages of a type it
+	// has no handler for.
+	UnknownTypePolicy string        `env:"UNKNOWN_TYPE_POLICY" envDefault:"deadletter"`
+	Concurrency       int           `env:"CONCURRENCY" envDefault:"10"`
+	VisibilityTimeout time.Duration `env:"VISIBILITY_TIMEOUT" envDefault:"120s"`
+	WaitTime          time.Duration `env:"WAIT_TIME" envDefault:"20s"`
+	// RetryBaseDelay hides a failed message before its second attempt; the
+	// delay doubles with each further receive up to RetryMaxDelay.
+	RetryBaseDelay time.Duration `env:"RETRY_BASE_DELAY" envDefault:"5s"`
+	RetryMaxDelay  time.Duration `env:"RETRY_MAX_DELAY" envDefault:"15m"`
+	// DeadLetterQueueURL receives messages that fail permanently at once;
+	// without it they reach the dead-letter queue through the redrive policy.
+	DeadLetterQueueURL string `env:"DEAD_LETTER_QUEUE_URL"`
+}
+
+func (c ConsumerConfig) validate(prefix string) error {
+	if !oneOf(c.MessageFormat, MessageFormatSNS, MessageFormatRaw, MessageFormatEventBridge, MessageFormatAuto) {
+		return fmt.Errorf("%sMESSAGE_FORMAT must be sns, raw, eventbridge, or auto", prefix)
+	}
+	if !oneOf(c.UnknownTypePolicy, UnknownTypeAck, UnknownTypeRetry, UnknownTypeDeadLetter) {
+		return fmt.Errorf("%sUNKNOWN_TYPE_POLICY must be ack, retry, or deadletter", prefix)
+	}
+	if c.Concurrency < 1 || c.Concurrency > 100 {
+		return fmt.Errorf("%sCONCURRENCY must be between 1 and 100", prefix)
+	}
+	if c.VisibilityTimeout < time.Second || c.VisibilityTimeout > 12*time.Hour {
+		return fmt.Errorf("%sVISIBILITY_TIMEOUT must be between 1s and 12h", prefix)
+	}
+	if c.WaitTime < time.Second || c.WaitTime > 20*time.Second {
+		return fmt.Errorf("%sWAIT_TIME must be between 1s and 20s", prefix)
+	}
+	if c.RetryBaseDelay < time.Second || c.RetryBaseDelay > c.RetryMaxDelay {
+		return fmt.Errorf("%sRETRY_BASE_DELAY must be at least 1s and at most %sRETRY_MAX_DELAY", prefix, prefix)
+	}
+	if c.RetryMaxDelay > 12*time.Hour {
+		return fmt.Errorf("%sRETRY_MAX_DELAY must be at most 12h", prefix)
+	}
+	return nil
 }
 
 func Load() (Config, error) {
@@ -118,6 +196,29 @@ func LoadHTTPAddress() (string, error) {
 	return values.HTTPAddress, nil
 }
 
+// DeadLetterConfig is what the dlq commands need to reach the permissions
+// queue and its dead-letter queue.
+type DeadLetterConfig struct {
+	AWSRegion                string `env:"AWS_REGION" envDefault:"eu-west-1"`
+	AWSEndpointURL           string `env:"AWS_ENDPOINT_URL"`
+	PermissionsQueue         string `env:"PERMISSIONS_QUEUE_URL,notEmpty"`
+	PermissionsMessageFormat string `env:"PERMISSIONS_MESSAGE_FORMAT" envDefault:"sns"`
+}
+
+func LoadDeadLetter() (DeadLetterConfig, error) {
+	var cfg DeadLetterConfig
+	if err := env.Parse(&cfg); err != nil {
+		return DeadLetterConfig{}, fmt.Errorf("parse environment: %w", err)
+	}
+	if cfg.AWSRegion == "" {
+		return DeadLetterConfig{}, errors.New("AWS_REGION is required")
+	}
+	if !oneOf(cfg.PermissionsMessageFormat, MessageFormatSNS, MessageFormatRaw, MessageFormatEventBridge, MessageFormatAuto) {
+		return DeadLetterConfig{}, errors.New("PERMISSIONS_MESSAGE_FORMAT must be sns, raw, eventbridge, or auto")
+	}
+	return cfg, nil
+}
+
 func (c Config) Validate() error {
 	if !oneOf(c.Environment, EnvironmentDevelopment, EnvironmentTest, EnvironmentProduction) {
 		return fmt.Errorf("APP_ENV must be development, test, or production")
@@ -136,14 +237,29 @@ func (c Config) Validate() error {
 		return fmt.Errorf("DATABASE_URL: %w", err)
 	}
 
-	if !oneOf(c.AuthMode, AuthModeDisabled, AuthModeOIDC) {
-		return fmt.Errorf("AUTH_MODE must be disabled or oidc")
+	if !oneOf(c.AuthMode, AuthModeDisabled, AuthModeOIDC, AuthModeAPIKey, AuthModeOIDCAPIKey) {
+		return fmt.Errorf("AUTH_MODE must be disabled, oidc, apikey, or oidc+apikey")
 	}
 	if c.AuthMode == AuthModeDisabled && c.Environment == EnvironmentProduction {
 		return errors.New("AUTH_MODE=disabled is not allowed in production")
 	}
-	if c.AuthMode == AuthModeOIDC && (c.OIDCIssuerURL == "" || c.OIDCAudience == "") {
-		return errors.New("OIDC_ISSUER_URL and OIDC_AUDIENCE are required when AUTH_MODE=oidc")
+	if c.AcceptsOIDC() {
+		if len(c.OIDCIssuerURLs) == 0 || len(c.OIDCAudiences) == 0 {
+			return fmt.Errorf("OIDC_ISSUER_URL and OIDC_AUDIENCE are required when AUTH_MODE=%s", c.AuthMode)
+		}
+		if c.OIDCJWKSSource == "" && (len(c.OIDCIssuerURLs) > 1 || len(c.OIDCAudiences) > 1) {
+			return errors.New("OIDC_ISSUER_URL and OIDC_AUDIENCE accept several values only with OIDC_JWKS_SOURCE")
+		}
+		if c.OIDCJWKSRefreshInterval <= 0 {
+			return errors.New("OIDC_JWKS_REFRESH_INTERVAL must be greater than zero")
+		}
+		if c.OIDCClockSkew < 0 || len(c.OIDCSigningAlgorithms) == 0 {
+			return errors.New("OIDC_CLOCK_SKEW must not be negative and OIDC_SIGNING_ALGORITHMS must not be empty")
+		}
+	}
+
+	if !oneOf(c.RateLimitBackend, RateLimitBackendDisabled, RateLimitBackendMemory, RateLimitBackendPostgres) {
+		return errors.New("RATE_LIMIT_BACKEND must be disabled, memory, or postgres")
 	}
 
 	if c.ShutdownTimeout <= 0 {
@@ -153,6 +269,16 @@ func (c Config) Validate() error {
 	return nil
 }
 
+func (c Config) AcceptsOIDC() bool {
+	return c.AuthMode == AuthModeOIDC || c.AuthMode == AuthModeOIDCAPIKey
+}
+
+// AcceptsAPIKeys reports whether API keys stored in PostgreSQL authenticate
+// requests, either alone or alongside OIDC tokens.
+func (c Config) AcceptsAPIKeys() bool {
+	return c.AuthMode == AuthModeAPIKey || c.AuthMode == AuthModeOIDCAPIKey
+}
+
 func (c WorkerConfig) Validate() error {
 	if !oneOf(c.Environment, EnvironmentDevelopment, EnvironmentTest, EnvironmentProduction) {
 		return fmt.Errorf("APP_ENV must be development, test, or production")
@@ -181,9 +307,15 @@ func (c WorkerConfig) Validate() error {
 	if c.Environment == EnvironmentProduction && c.UserEventsTopic == "" {
 		return errors.New("USER_EVENTS_TOPIC_ARN is required in production")
 	}
-	if c.Environment == EnvironmentProduction && c.PermissionsQueue == "" {
+	if c.Environment == EnvironmentProduction && c.Permissions.QueueURL == "" {
 		return errors.New("PERMISSIONS_QUEUE_URL is required in production")
 	}
+	if err := c.Permissions.validate("PERMISSIONS_"); err != nil {
+		return err
+	}
+	if len(c.ImportCallbackSecret) < 32 {
+		return errors.New("IMPORT_CALLBACK_SECRET must be at least 32 bytes")
+	}
 	return nil
 }
 
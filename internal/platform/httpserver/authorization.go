@@ -0,0 +1,201 @@
+package httpserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+)
+
+// permissionsExtension lists the permissions an operation requires in
+// addition to its scopes. The authenticated subject must hold all of them.
+const permissionsExtension = "x-permissions"
+
+const maxCachedSubjects = 10_000
+
+// PermissionSet is the permission set of the user an authenticated subject
+// maps to. A subject without a user or without permissions has an empty set.
+type PermissionSet struct {
+	UserID      string
+	Revision    int64
+	Permissions []string
+}
+
+type PermissionLoader interface {
+	LoadPermissions(context.Context, string) (PermissionSet, error)
+}
+
+// missingPermissionError rejects a subject whose permission set lacks a
+// permission the operation declares in its x-permissions extension.
+type missingPermissionError struct {
+	permission string
+}
+
+func (e missingPermissionError) Error() string {
+	return fmt.Sprintf("subject lacks required permission %s", e.permission)
+}
+
+// permissionLoadError reports that the permission set could not be loaded,
+// which is a server error rather than an authentication failure.
+type permissionLoadError struct {
+	err error
+}
+
+func (e permissionLoadError) Error() string {
+	return fmt.Sprintf("load permissions: %v", e.err)
+}
+
+func (e permissionLoadError) Unwrap() error {
+	return e.err
+}
+
+type authorization struct {
+	loader   PermissionLoader
+	required map[*openapi3.Operation][]string
+}
+
+func newAuthorization(spec *openapi3.T, loader PermissionLoader) (*authorization, error) {
+	required := make(map[*openapi3.Operation][]string)
+	for path, item := range spec.Paths.Map() {
+		for method, operation := range item.Operations() {
+			value, ok := operation.Extensions[permissionsExtension]
+			if !ok {
+				continue
+			}
+			values, ok := value.([]any)
+			if !ok {
+				return nil, fmt.Errorf("%s %s: %s must be a list of strings", method, path, permissionsExtension)
+			}
+			permissions := make([]string, 0, len(values))
+			for _, value := range values {
+				permission, ok := value.(string)
+				if !ok || permission == "" {
+					return nil, fmt.Errorf("%s %s: %s must be a list of strings", method, path, permissionsExtension)
+				}
+				permissions = append(permissions, permission)
+			}
+			required[operation] = permissions
+		}
+	}
+	return &authorization{loader: loader, required: required}, nil
+}
+
+// authorize runs after authentication has stored the claims on the request.
+func (a *authorization) authorize(ctx context.Context, input *openapi3filter.AuthenticationInput) error {
+	route := input.RequestValidationInput.Route
+	if route == nil || len(a.required[route.Operation]) == 0 {
+		return nil
+	}
+	claims, ok := Claims(input.RequestValidationInput.Request.Context())
+	if !ok {
+		return errors.New("authorize request: claims are missing")
+	}
+	set, err := a.loader.LoadPermissions(ctx, claims.Subject)
+	if err != nil {
+		return permissionLoadError{err: err}
+	}
+	for _, permission := range a.required[route.Operation] {
+		if !slices.Contains(set.Permissions, permission) {
+			return missingPermissionError{permission: permission}
+		}
+	}
+	return nil
+}
+
+// PermissionCache keeps loaded permission sets for a short time. Invalidate
+// drops a user's sets as soon as a newer revision is known, so the lifetime
+// only bounds staleness when change notifications are lost.
+type PermissionCache struct {
+	loader PermissionLoader
+	ttl    time.Duration
+	now    func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]cachedPermissions
+	// generation counts invalidations, and invalidated holds the generation
+	// of each user's latest one. A set whose load started before its user
+	// was invalidated may be stale, so it is returned but not stored. Loads
+	// that started before floor, raised by Clear and by trimming invalidated,
+	// are not stored either.
+	generation  uint64
+	invalidated map[string]uint64
+	floor       uint64
+}
+
+type cachedPermissions struct {
+	set     PermissionSet
+	expires time.Time
+}
+
+func NewPermissionCache(loader PermissionLoader, ttl time.Duration) *PermissionCache {
+	return &PermissionCache{
+		loader: loader, ttl: ttl, now: time.Now,
+		entries: make(map[string]cachedPermissions), invalidated: make(map[string]uint64),
+	}
+}
+
+func (c *PermissionCache) LoadPermissions(ctx context.Context, subject string) (PermissionSet, error) {
+	c.mu.Lock()
+	entry, ok := c.entries[subject]
+	started := c.generation
+	c.mu.Unlock()
+	if ok && c.now().Before(entry.expires) {
+		return entry.set, nil
+	}
+
+	set, err := c.loader.LoadPermissions(ctx, subject)
+	if err != nil {
+		return PermissionSet{}, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if started < c.floor || c.invalidated[set.UserID] > started {
+		return set, nil
+	}
+	now := c.now()
+	if len(c.entries) >= maxCachedSubjects {
+		for cached, entry := range c.entries {
+			if !now.Before(entry.expires) {
+				delete(c.entries, cached)
+			}
+		}
+		if len(c.entries) >= maxCachedSubjects {
+			clear(c.entries)
+		}
+	}
+	c.entries[subject] = cachedPermissions{set: set, expires: now.Add(c.ttl)}
+	return set, nil
+}
+
+// Invalidate drops every cached set of the user older than revision and
+// keeps loads of the user already under way from storing their sets.
+func (c *PermissionCache) Invalidate(userID string, revision int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for subject, entry := range c.entries {
+		if entry.set.UserID == userID && entry.set.Revision < revision {
+			delete(c.entries, subject)
+		}
+	}
+	c.generation++
+	if len(c.invalidated) >= maxCachedSubjects {
+		clear(c.invalidated)
+		c.floor = c.generation
+	}
+	c.invalidated[userID] = c.generation
+}
+
+func (c *PermissionCache) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	clear(c.entries)
+	c.generation++
+	clear(c.invalidated)
+	c.floor = c.generation
+}
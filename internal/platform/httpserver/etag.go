@@ -0,0 +1,41 @@
+package httpserver
+
+import "strings"
+
+// StrongETag quotes an opaque value as a strong RFC 9110 entity tag.
+func StrongETag(value string) string {
+	return `"` + value + `"`
+}
+
+// IfMatch reports whether an If-Match header value permits a change to a
+// resource whose current entity tag is etag. It uses strong comparison, so
+// weak tags never match.
+func IfMatch(header, etag string) bool {
+	for _, candidate := range entityTags(header) {
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// IfNoneMatch reports whether an If-None-Match header value lists the current
+// entity tag. It uses weak comparison, as RFC 9110 requires for this header.
+func IfNoneMatch(header, etag string) bool {
+	for _, candidate := range entityTags(header) {
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+func entityTags(header string) []string {
+	var tags []string
+	for candidate := range strings.SplitSeq(header, ",") {
+		if candidate = strings.TrimSpace(candidate); candidate != "" {
+			tags = append(tags, candidate)
+		}
+	}
+	return tags
+}
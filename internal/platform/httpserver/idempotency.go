@@ -0,0 +1,224 @@
+package httpserver
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"hash"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// IdempotencyExtendInterval is how often a running request extends its claim.
+// A store may treat a claim that has not been extended for several intervals
+// as abandoned by a crashed request.
+const IdempotencyExtendInterval = 15 * time.Second
+
+// replayedHeaders are the recorded response headers returned on replay. Other
+// headers, such as X-Request-ID, describe the current request instead.
+var replayedHeaders = []string{"Content-Type", "ETag", "Location"}
+
+// IdempotencyKey scopes a client-supplied Idempotency-Key to the
+// authenticated subject, so different callers cannot observe each other.
+type IdempotencyKey struct {
+	Subject string
+	Key     string
+}
+
+type IdempotentResponse struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+}
+
+// IdempotencyRecord is the stored state of a key. Fingerprint and Response are
+// nil while the request that claimed the key is still running.
+type IdempotencyRecord struct {
+	Fingerprint []byte
+	Response    *IdempotentResponse
+}
+
+type IdempotencyStore interface {
+	// Claim reserves the key for a request. When the key is already held it
+	// returns false and the stored record.
+	Claim(context.Context, IdempotencyKey) (IdempotencyRecord, bool, error)
+	// Extend renews the claim of a running request.
+	Extend(context.Context, IdempotencyKey) error
+	// Complete records the fingerprint of the request, which is known only once
+	// its body has been read, with its response.
+	Complete(context.Context, IdempotencyKey, []byte, IdempotentResponse) error
+	Release(context.Context, IdempotencyKey) error
+}
+
+// idempotencyMiddleware records the response to a POST carrying an
+// Idempotency-Key and replays it for retries with the same method, path, and
+// body. Server errors release the key so that the client can retry. Bodies are
+// hashed as they are read rather than buffered, since streamed routes accept
+// uploads far larger than the default body limit.
+func idempotencyMiddleware(logger *slog.Logger, store IdempotencyStore, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get("Idempotency-Key")
+		if r.Method != http.MethodPost || key == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		subject, _ := Subject(r.Context())
+		scoped := IdempotencyKey{Subject: subject, Key: key}
+		record, claimed, err := store.Claim(r.Context(), scoped)
+		if err != nil {
+			logger.ErrorContext(r.Context(), "claim idempotency key", "error", err, "request_id", RequestID(r.Context()))
+			writeProblem(w, r, http.StatusInternalServerError, "Internal Server Error", "")
+			return
+		}
+		if !claimed {
+			if record.Response == nil {
+				writeProblem(w, r, http.StatusConflict, "Conflict", "a request with this Idempotency-Key is still in progress")
+				return
+			}
+			fingerprint := newRequestFingerprint(r)
+			if err := fingerprint.drain(); err != nil {
+				writeBodyProblem(w, r, err)
+				return
+			}
+			if !bytes.Equal(record.Fingerprint, fingerprint.sum()) {
+				writeProblem(w, r, http.StatusUnprocessableEntity, "Unprocessable Entity", "Idempotency-Key was already used for a different request")
+				return
+			}
+			replayResponse(w, *record.Response)
+			return
+		}
+		fingerprint := newRequestFingerprint(r)
+		r.Body = fingerprint
+
+		// The outcome is recorded even when the client has gone away, because
+		// the handler may already have committed its changes.
+		storeCtx := context.WithoutCancel(r.Context())
+		recorder := &recordingWriter{responseWriter: responseWriter{ResponseWriter: w, status: http.StatusOK}}
+		completed := false
+		defer func() {
+			if completed {
+				return
+			}
+			if err := store.Release(storeCtx, scoped); err != nil {
+				logger.ErrorContext(r.Context(), "release idempotency key", "error", err, "request_id", RequestID(r.Context()))
+			}
+		}()
+
+		stopExtending := extendClaim(logger, r, store, scoped)
+		next.ServeHTTP(recorder, r)
+		stopExtending()
+		if recorder.status >= http.StatusInternalServerError {
+			return
+		}
+		// A body the handler did not read to the end still belongs to the
+		// fingerprint. One that cannot be read cannot be matched by a retry.
+		if err := fingerprint.drain(); err != nil {
+			return
+		}
+		response := IdempotentResponse{StatusCode: recorder.status, Header: http.Header{}, Body: recorder.body.Bytes()}
+		for _, name := range replayedHeaders {
+			for _, value := range recorder.Header().Values(name) {
+				response.Header.Add(name, value)
+			}
+		}
+		if err := store.Complete(storeCtx, scoped, fingerprint.sum(), response); err != nil {
+			logger.ErrorContext(r.Context(), "record idempotent response", "error", err, "request_id", RequestID(r.Context()))
+			return
+		}
+		completed = true
+	})
+}
+
+// extendClaim extends the claim on key every IdempotencyExtendInterval until
+// the returned function is called, so that a long request, such as a large
+// streamed upload, keeps its key.
+func extendClaim(logger *slog.Logger, r *http.Request, store IdempotencyStore, key IdempotencyKey) func() {
+	ctx, cancel := context.WithCancel(context.WithoutCancel(r.Context()))
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(IdempotencyExtendInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := store.Extend(ctx, key); err != nil && ctx.Err() == nil {
+					logger.ErrorContext(ctx, "extend idempotency key", "error", err, "request_id", RequestID(ctx))
+				}
+			}
+		}
+	}()
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+// requestFingerprint hashes the method, path, and body of a request as its
+// body is read.
+type requestFingerprint struct {
+	body io.ReadCloser
+	hash hash.Hash
+}
+
+func newRequestFingerprint(r *http.Request) *requestFingerprint {
+	fingerprint := &requestFingerprint{body: r.Body, hash: sha256.New()}
+	_, _ = io.WriteString(fingerprint.hash, r.Method+" "+r.URL.Path+"\n")
+	return fingerprint
+}
+
+func (f *requestFingerprint) Read(p []byte) (int, error) {
+	n, err := f.body.Read(p)
+	_, _ = f.hash.Write(p[:n])
+	return n, err
+}
+
+func (f *requestFingerprint) Close() error {
+	return f.body.Close()
+}
+
+// drain hashes the rest of the body.
+func (f *requestFingerprint) drain() error {
+	_, err := io.Copy(io.Discard, f)
+	return err
+}
+
+func (f *requestFingerprint) sum() []byte {
+	return f.hash.Sum(nil)
+}
+
+func writeBodyProblem(w http.ResponseWriter, r *http.Request, err error) {
+	var maxBytesError *http.MaxBytesError
+	if errors.As(err, &maxBytesError) {
+		writeProblem(w, r, http.StatusRequestEntityTooLarge, "Request Entity Too Large", "")
+		return
+	}
+	writeProblem(w, r, http.StatusBadRequest, "Bad Request", "request body could not be read")
+}
+
+func replayResponse(w http.ResponseWriter, response IdempotentResponse) {
+	for _, name := range replayedHeaders {
+		for _, value := range response.Header.Values(name) {
+			w.Header().Add(name, value)
+		}
+	}
+	w.Header().Set("Idempotent-Replayed", "true")
+	w.WriteHeader(response.StatusCode)
+	_, _ = w.Write(response.Body)
+}
+
+type recordingWriter struct {
+	responseWriter
+	body bytes.Buffer
+}
+
+func (w *recordingWriter) Write(body []byte) (int, error) {
+	w.body.Write(body)
+	return w.responseWriter.Write(body)
+}
@@ -120,6 +120,15 @@ func (w *responseWriter) Write(body []byte) (int, error) {
 	return w.ResponseWriter.Write(body)
 }
 
+// Flush lets streamed responses reach the client through the middleware; the
+// generated server flushes only writers that implement http.Flusher.
+func (w *responseWriter) Flush() {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	_ = http.NewResponseController(w.ResponseWriter).Flush()
+}
+
 func (w *responseWriter) Unwrap() http.ResponseWriter {
 	return w.ResponseWriter
 }
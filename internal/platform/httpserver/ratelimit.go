@@ -0,0 +1,203 @@
+package httpserver
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+)
+
+// rateLimitExtension sets an operation's token bucket, for example
+// {burst: 30, perMinute: 120}. Operations without it are not limited.
+const rateLimitExtension = "x-rate-limit"
+
+// MaxRateLimitRefill bounds how long an idle bucket takes to refill. A
+// bucket idle for longer is full, so stores may forget it.
+const MaxRateLimitRefill = time.Hour
+
+const maxMemoryBuckets = 100_000
+
+// RateLimit is a token bucket holding up to Burst tokens that refills at
+// PerMinute tokens per minute. Each request takes one token.
+type RateLimit struct {
+	Burst     int
+	PerMinute int
+}
+
+func (l RateLimit) refillPerSecond() float64 {
+	return float64(l.PerMinute) / 60
+}
+
+// secondsUntil returns the whole seconds until the bucket holds tokens.
+func (l RateLimit) secondsUntil(current, tokens float64) int {
+	return int(math.Ceil(math.Max(tokens-current, 0) / l.refillPerSecond()))
+}
+
+// RateLimitDecision reports whether a token was taken and how many tokens
+// are left afterwards.
+type RateLimitDecision struct {
+	Allowed bool
+	Tokens  float64
+}
+
+type RateLimitStore interface {
+	Take(ctx context.Context, key string, limit RateLimit) (RateLimitDecision, error)
+}
+
+type rateLimiting struct {
+	logger       *slog.Logger
+	store        RateLimitStore
+	router       routers.Router
+	limits       map[*openapi3.Operation]RateLimit
+	keyBySubject bool
+}
+
+func newRateLimiting(logger *slog.Logger, spec *openapi3.T, store RateLimitStore, keyBySubject bool) (*rateLimiting, error) {
+	limits := make(map[*openapi3.Operation]RateLimit)
+	for path, item := range spec.Paths.Map() {
+		for method, operation := range item.Operations() {
+			value, ok := operation.Extensions[rateLimitExtension]
+			if !ok {
+				continue
+			}
+			limit, err := parseRateLimit(value)
+			if err != nil {
+				return nil, fmt.Errorf("%s %s: %s: %w", method, path, rateLimitExtension, err)
+			}
+			limits[operation] = limit
+		}
+	}
+	router, err := gorillamux.NewRouter(spec)
+	if err != nil {
+		return nil, fmt.Errorf("create rate limit router: %w", err)
+	}
+	return &rateLimiting{logger: logger, store: store, router: router, limits: limits, keyBySubject: keyBySubject}, nil
+}
+
+func parseRateLimit(value any) (RateLimit, error) {
+	fields, ok := value.(map[string]any)
+	if !ok {
+		return RateLimit{}, fmt.Errorf("must be an object with burst and perMinute")
+	}
+	burst, burstOK := fields["burst"].(float64)
+	perMinute, perMinuteOK := fields["perMinute"].(float64)
+	if !burstOK || !perMinuteOK || len(fields) != 2 || burst < 1 || perMinute < 1 || burst != math.Trunc(burst) || perMinute != math.Trunc(perMinute) {
+		return RateLimit{}, fmt.Errorf("burst and perMinute must be positive integers")
+	}
+	limit := RateLimit{Burst: int(burst), PerMinute: int(perMinute)}
+	if time.Duration(float64(limit.Burst)/limit.refillPerSecond()*float64(time.Second)) > MaxRateLimitRefill {
+		return RateLimit{}, fmt.Errorf("an empty bucket must refill within %s", MaxRateLimitRefill)
+	}
+	return limit, nil
+}
+
+// middleware runs after authentication, so rejected requests take no tokens.
+// Each operation has its own bucket per subject, or per client IP while
+// authentication is disabled. Store failures are logged and let requests
+// through rather than failing the API.
+func (l *rateLimiting) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route, _, err := l.router.FindRoute(r)
+		if err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		limit, ok := l.limits[route.Operation]
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		key := route.Operation.OperationID + ":" + l.principal(r)
+		decision, err := l.store.Take(r.Context(), key, limit)
+		if err != nil {
+			l.logger.ErrorContext(r.Context(), "take rate limit token", "error", err, "request_id", RequestID(r.Context()))
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		header := w.Header()
+		header.Set("RateLimit-Limit", strconv.Itoa(limit.Burst))
+		header.Set("RateLimit-Remaining", strconv.Itoa(int(math.Floor(decision.Tokens))))
+		header.Set("RateLimit-Reset", strconv.Itoa(limit.secondsUntil(decision.Tokens, float64(limit.Burst))))
+		if !decision.Allowed {
+			header.Set("Retry-After", strconv.Itoa(max(limit.secondsUntil(decision.Tokens, 1), 1)))
+			writeProblem(w, r, http.StatusTooManyRequests, "Too Many Requests",
+				fmt.Sprintf("rate limit of %d requests per minute exceeded for %s", limit.PerMinute, route.Operation.OperationID))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (l *rateLimiting) principal(r *http.Request) string {
+	if claims, ok := Claims(r.Context()); ok && l.keyBySubject {
+		return "subject:" + claims.Subject
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+// MemoryRateLimitStore keeps buckets in process, so each API replica
+// enforces its own limits.
+type MemoryRateLimitStore struct {
+	now func() time.Time
+
+	mu      sync.Mutex
+	buckets map[string]memoryBucket
+}
+
+type memoryBucket struct {
+	tokens    float64
+	updatedAt time.Time
+}
+
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{now: time.Now, buckets: make(map[string]memoryBucket)}
+}
+
+func (s *MemoryRateLimitStore) Take(_ context.Context, key string, limit RateLimit) (RateLimitDecision, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+
+	bucket, ok := s.buckets[key]
+	if !ok {
+		if len(s.buckets) >= maxMemoryBuckets {
+			s.prune(now)
+		}
+		bucket = memoryBucket{tokens: float64(limit.Burst), updatedAt: now}
+	}
+	tokens := math.Min(float64(limit.Burst), bucket.tokens+now.Sub(bucket.updatedAt).Seconds()*limit.refillPerSecond())
+	decision := RateLimitDecision{Allowed: tokens >= 1, Tokens: tokens}
+	if decision.Allowed {
+		decision.Tokens--
+	}
+	s.buckets[key] = memoryBucket{tokens: decision.Tokens, updatedAt: now}
+	return decision, nil
+}
+
+// prune forgets buckets that have refilled completely, and everything when
+// that is not enough.
+func (s *MemoryRateLimitStore) prune(now time.Time) {
+	for key, bucket := range s.buckets {
+		if now.Sub(bucket.updatedAt) >= MaxRateLimitRefill {
+			delete(s.buckets, key)
+		}
+	}
+	if len(s.buckets) >= maxMemoryBuckets {
+		clear(s.buckets)
+	}
+}
@@ -5,31 +5,55 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"log/slog"
+	"mime"
 	"net/http"
+	"net/url"
+	"slices"
 	"strings"
 	"sync/atomic"
 	"time"
 
+	"github.com/getkin/kin-openapi/openapi3"
 	"github.com/getkin/kin-openapi/openapi3filter"
 	"github.com/getkin/kin-openapi/routers"
 	nethttpmiddleware "github.com/oapi-codegen/nethttp-middleware"
 
 	contract "github.com/your-org/go-service-template/api"
 	contractapi "github.com/your-org/go-service-template/internal/api"
+	"github.com/your-org/go-service-template/internal/platform/auth"
 )
 
 const maxRequestBodyBytes = 1 << 20
 
 type TokenVerifier interface {
-	Verify(context.Context, string) (string, error)
+	Verify(context.Context, string) (auth.Claims, error)
 }
 
-type subjectKey struct{}
+type claimsKey struct{}
+
+// Claims returns the verified claims of the request's token. With
+// authentication disabled they name the "development" subject and grant every
+// scope the operation requires.
+func Claims(ctx context.Context) (auth.Claims, bool) {
+	claims, ok := ctx.Value(claimsKey{}).(auth.Claims)
+	return claims, ok
+}
 
 func Subject(ctx context.Context) (string, bool) {
-	subject, ok := ctx.Value(subjectKey{}).(string)
-	return subject, ok
+	claims, ok := Claims(ctx)
+	return claims.Subject, ok
+}
+
+// insufficientScopeError rejects a valid token that lacks a scope the
+// operation declares in its OpenAPI security requirement.
+type insufficientScopeError struct {
+	scope string
+}
+
+func (e insufficientScopeError) Error() string {
+	return fmt.Sprintf("token lacks required scope %s", e.scope)
 }
 
 type Authentication struct {
@@ -79,13 +103,40 @@ func (r *Readiness) StopAccepting() {
 }
 
 type HandlerOptions struct {
-	Logger    *slog.Logger
-	API       contractapi.StrictServerInterface
-	Auth      Authentication
-	Readiness *Readiness
-	Metrics   http.Handler
-	Version   string
-	Commit    string
+	Logger           *slog.Logger
+	API              contractapi.StrictServerInterface
+	StrictMiddleware []contractapi.StrictMiddlewareFunc
+	StreamedRoutes   []StreamedRoute
+	Auth             Authentication
+	Readiness        *Readiness
+	Metrics          http.Handler
+	Version          string
+	Commit           string
+	Idempotency      IdempotencyStore
+	// Permissions enables the x-permissions requirements of operations. They
+	// are not checked while authentication is disabled.
+	Permissions PermissionLoader
+	// RateLimits enables the x-rate-limit token buckets of operations.
+	RateLimits RateLimitStore
+}
+
+// StreamedRoute lets requests with one of the listed media types skip
+// buffered body validation, so that the handler can read a body larger than
+// the default limit incrementally. Authentication and parameters are still
+// validated, and the handler must validate the body itself.
+type StreamedRoute struct {
+	Method       string
+	Path         string
+	MediaTypes   []string
+	MaxBodyBytes int64
+}
+
+func (s StreamedRoute) matches(r *http.Request) bool {
+	if r.Method != s.Method || r.URL.Path != s.Path {
+		return false
+	}
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return err == nil && slices.Contains(s.MediaTypes, mediaType)
 }
 
 type OperationsHandlerOptions struct {
@@ -121,7 +172,9 @@ func NewHandler(options HandlerOptions) (http.Handler, error) {
 		return nil, errors.New("authentication is not configured")
 	}
 
-	spec, err := contractapi.GetSpec()
+	// Requests are validated against the published contract. The generated
+	// routes differ from it where the code generation overlay adjusts them.
+	spec, err := openapi3.NewLoader().LoadFromData(contract.OpenAPI)
 	if err != nil {
 		return nil, fmt.Errorf("load OpenAPI contract: %w", err)
 	}
@@ -129,7 +182,21 @@ func NewHandler(options HandlerOptions) (http.Handler, error) {
 		return nil, fmt.Errorf("validate OpenAPI contract: %w", err)
 	}
 
-	strictHandler := contractapi.NewStrictHandlerWithOptions(options.API, nil, contractapi.StrictHTTPServerOptions{
+	authenticate := options.Auth.authenticate
+	if options.Permissions != nil && !options.Auth.disabled {
+		authorization, err := newAuthorization(spec, options.Permissions)
+		if err != nil {
+			return nil, fmt.Errorf("read OpenAPI permissions: %w", err)
+		}
+		authenticate = func(ctx context.Context, input *openapi3filter.AuthenticationInput) error {
+			if err := options.Auth.authenticate(ctx, input); err != nil {
+				return err
+			}
+			return authorization.authorize(ctx, input)
+		}
+	}
+
+	strictHandler := contractapi.NewStrictHandlerWithOptions(options.API, options.StrictMiddleware, contractapi.StrictHTTPServerOptions{
 		RequestErrorHandlerFunc: func(w http.ResponseWriter, r *http.Request, err error) {
 			writeProblem(w, r, http.StatusBadRequest, "Bad Request", err.Error())
 		},
@@ -140,18 +207,40 @@ func NewHandler(options HandlerOptions) (http.Handler, error) {
 	})
 
 	apiMux := http.NewServeMux()
-	apiHandler := contractapi.HandlerFromMux(strictHandler, apiMux)
-	validator := nethttpmiddleware.OapiRequestValidatorWithOptions(spec, &nethttpmiddleware.Options{
-		Options: openapi3filter.Options{
-			AuthenticationFunc: options.Auth.authenticate,
-		},
-		DoNotValidateServers: true,
-		ErrorHandlerWithOpts: validationErrorHandler,
-	})
-	validatedAPI := requestBodyLimit(validator(apiHandler))
+	apiHandler := customMethods(contractapi.HandlerFromMux(strictHandler, apiMux))
+	if options.Idempotency != nil {
+		apiHandler = idempotencyMiddleware(options.Logger, options.Idempotency, apiHandler)
+	}
+	if options.RateLimits != nil {
+		rateLimiting, err := newRateLimiting(options.Logger, spec, options.RateLimits, !options.Auth.disabled)
+		if err != nil {
+			return nil, fmt.Errorf("read OpenAPI rate limits: %w", err)
+		}
+		apiHandler = rateLimiting.middleware(apiHandler)
+	}
+	validator := func(excludeRequestBody bool) func(http.Handler) http.Handler {
+		return nethttpmiddleware.OapiRequestValidatorWithOptions(spec, &nethttpmiddleware.Options{
+			Options: openapi3filter.Options{
+				AuthenticationFunc: authenticate,
+				ExcludeRequestBody: excludeRequestBody,
+			},
+			DoNotValidateServers: true,
+			ErrorHandlerWithOpts: validationErrorHandler(options.Logger),
+		})
+	}
+	validatedAPI := requestBodyLimit(maxRequestBodyBytes, validator(false)(apiHandler))
+	streamedAPI := withholdBody(validator(true), apiHandler)
 
 	root := http.NewServeMux()
-	root.Handle("/v1/", validatedAPI)
+	root.Handle("/v1/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, route := range options.StreamedRoutes {
+			if route.matches(r) {
+				requestBodyLimit(route.MaxBodyBytes, streamedAPI).ServeHTTP(w, r)
+				return
+			}
+		}
+		validatedAPI.ServeHTTP(w, r)
+	}))
 	root.HandleFunc("GET /livez", healthHandler(http.StatusOK, options.Version, options.Commit))
 	root.HandleFunc("GET /readyz", readinessHandler(options.Readiness, options.Version, options.Commit))
 	root.Handle("GET /metrics", options.Metrics)
@@ -177,7 +266,7 @@ func (a Authentication) authenticate(ctx context.Context, input *openapi3filter.
 		return fmt.Errorf("unsupported security scheme %q", input.SecuritySchemeName)
 	}
 	if a.disabled {
-		setSubject(input, "development")
+		setClaims(input, auth.Claims{Subject: "development", Scopes: input.Scopes})
 		return nil
 	}
 
@@ -189,46 +278,106 @@ func (a Authentication) authenticate(ctx context.Context, input *openapi3filter.
 	if err != nil {
 		return err
 	}
-	subject, err := a.verifier.Verify(ctx, token)
+	claims, err := a.verifier.Verify(ctx, token)
 	if err != nil {
 		return err
 	}
-	if subject == "" {
+	if claims.Subject == "" {
 		return errors.New("token subject is empty")
 	}
-	setSubject(input, subject)
+	for _, scope := range input.Scopes {
+		if !claims.HasScope(scope) {
+			return insufficientScopeError{scope: scope}
+		}
+	}
+	setClaims(input, claims)
 	return nil
 }
 
-func setSubject(input *openapi3filter.AuthenticationInput, subject string) {
+func setClaims(input *openapi3filter.AuthenticationInput, claims auth.Claims) {
 	request := input.RequestValidationInput.Request
-	*request = *request.WithContext(context.WithValue(request.Context(), subjectKey{}, subject))
+	*request = *request.WithContext(context.WithValue(request.Context(), claimsKey{}, claims))
 }
 
-func validationErrorHandler(_ context.Context, err error, w http.ResponseWriter, r *http.Request, options nethttpmiddleware.ErrorHandlerOpts) {
-	status := options.StatusCode
-	if errors.Is(err, routers.ErrMethodNotAllowed) {
-		status = http.StatusMethodNotAllowed
-	}
-	var maxBytesError *http.MaxBytesError
-	if errors.As(err, &maxBytesError) {
-		status = http.StatusRequestEntityTooLarge
-	}
+func validationErrorHandler(logger *slog.Logger) nethttpmiddleware.ErrorHandlerWithOpts {
+	return func(_ context.Context, err error, w http.ResponseWriter, r *http.Request, options nethttpmiddleware.ErrorHandlerOpts) {
+		status := options.StatusCode
+		if errors.Is(err, routers.ErrMethodNotAllowed) {
+			status = http.StatusMethodNotAllowed
+		}
+		var maxBytesError *http.MaxBytesError
+		if errors.As(err, &maxBytesError) {
+			status = http.StatusRequestEntityTooLarge
+		}
 
-	detail := err.Error()
-	if status >= http.StatusInternalServerError || status == http.StatusUnauthorized {
-		detail = ""
+		detail := err.Error()
+		var scopeError insufficientScopeError
+		var permissionError missingPermissionError
+		var loadError permissionLoadError
+		switch {
+		case errors.As(err, &scopeError):
+			status = http.StatusForbidden
+			detail = scopeError.Error()
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer error="insufficient_scope", scope=%q`, scopeError.scope))
+		case errors.As(err, &permissionError):
+			status = http.StatusForbidden
+			detail = permissionError.Error()
+		case errors.As(err, &loadError):
+			logger.ErrorContext(r.Context(), "authorize request", "error", loadError.err, "request_id", RequestID(r.Context()))
+			status = http.StatusInternalServerError
+		}
+		if status >= http.StatusInternalServerError || status == http.StatusUnauthorized {
+			detail = ""
+		}
+		writeProblem(w, r, status, statusTitle(status), detail)
 	}
-	writeProblem(w, r, status, statusTitle(status), detail)
 }
 
-func requestBodyLimit(next http.Handler) http.Handler {
+func requestBodyLimit(limit int64, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodyBytes)
+		r.Body = http.MaxBytesReader(w, r.Body, limit)
 		next.ServeHTTP(w, r)
 	})
 }
 
+type withheldBodyKey struct{}
+
+// withholdBody hides a streamed body from validate, which reads the body of
+// every authenticated request into memory even when it skips validating it,
+// and hands the body back to next.
+func withholdBody(validate func(http.Handler) http.Handler, next http.Handler) http.Handler {
+	validated := validate(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.Body = r.Context().Value(withheldBodyKey{}).(io.ReadCloser)
+		next.ServeHTTP(w, r)
+	}))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r = r.WithContext(context.WithValue(r.Context(), withheldBodyKey{}, r.Body))
+		r.Body = http.NoBody
+		validated.ServeHTTP(w, r)
+	})
+}
+
+// customMethods routes custom methods such as POST /v1/user-imports/{id}:cancel,
+// which ServeMux patterns cannot express, to the /v1/user-imports/{id}/cancel
+// routes generated for them.
+func customMethods(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		slash := strings.LastIndexByte(r.URL.Path, '/')
+		resource, method, found := strings.Cut(r.URL.Path[slash+1:], ":")
+		if !found || resource == "" || method == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		routed := new(http.Request)
+		*routed = *r
+		routed.URL = new(url.URL)
+		*routed.URL = *r.URL
+		routed.URL.Path = r.URL.Path[:slash+1] + resource + "/" + method
+		routed.URL.RawPath = ""
+		next.ServeHTTP(w, routed)
+	})
+}
+
 func healthHandler(status int, version, commit string) http.HandlerFunc {
 	return func(w http.ResponseWriter, _ *http.Request) {
 		writeHealth(w, status, version, commit)
@@ -8,7 +8,9 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"strings"
+	"sync"
 	"testing"
+	"testing/synctest"
 	"time"
 
 	"github.com/google/uuid"
@@ -18,6 +20,7 @@ import (
 
 	contract "github.com/your-org/go-service-template/api"
 	contractapi "github.com/your-org/go-service-template/internal/api"
+	"github.com/your-org/go-service-template/internal/platform/auth"
 	"github.com/your-org/go-service-template/internal/platform/httpserver"
 )
 
@@ -65,6 +68,31 @@ func TestHandlerVerifiesBearerToken(t *testing.T) {
 	assert.Equal(t, http.StatusOK, response.Code, response.Body.String())
 	assert.Equal(t, "signed.jwt.token", verifier.token)
 	assert.Equal(t, "user-123", api.receivedSubject)
+	assert.Equal(t, map[string]any{"sub": "user-123"}, api.receivedClaims.Raw)
+}
+
+func TestHandlerEnforcesOperationScopes(t *testing.T) {
+	t.Parallel()
+
+	authentication, err := httpserver.TokenAuthentication(&tokenVerifierStub{scopes: []string{"users:read"}})
+	require.NoError(t, err)
+	handler := newTestHandler(t, &apiStub{}, authentication, pingerStub{})
+
+	read := httptest.NewRequestWithContext(t.Context(), http.MethodGet, "/v1/users/8d37b313-f867-47bc-8e3d-0953db9c05c8", nil)
+	read.Header.Set("Authorization", "Bearer read.only.token")
+	response := httptest.NewRecorder()
+	handler.ServeHTTP(response, read)
+	assert.Equal(t, http.StatusOK, response.Code, response.Body.String())
+
+	write := httptest.NewRequestWithContext(t.Context(), http.MethodPost, "/v1/users", strings.NewReader(`{"email":"person@example.com"}`))
+	write.Header.Set("Authorization", "Bearer read.only.token")
+	write.Header.Set("Content-Type", "application/json")
+	response = httptest.NewRecorder()
+	handler.ServeHTTP(response, write)
+	assert.Equal(t, http.StatusForbidden, response.Code, response.Body.String())
+	assert.Equal(t, "application/problem+json", response.Header().Get("Content-Type"))
+	assert.Contains(t, response.Body.String(), "token lacks required scope users:write")
+	assert.Equal(t, `Bearer error="insufficient_scope", scope="users:write"`, response.Header().Get("WWW-Authenticate"))
 }
 
 func TestHandlerProbes(t *testing.T) {
@@ -174,6 +202,397 @@ func TestHandlerRequestID(t *testing.T) {
 	})
 }
 
+func TestHandlerReplaysIdempotentRequests(t *testing.T) {
+	t.Parallel()
+
+	api := &apiStub{}
+	handler, err := httpserver.NewHandler(httpserver.HandlerOptions{
+		Logger:      slog.New(slog.NewTextHandler(io.Discard, nil)),
+		API:         api,
+		Auth:        httpserver.DisabledAuthentication(),
+		Readiness:   httpserver.NewReadiness(pingerStub{}, nil),
+		Metrics:     http.NotFoundHandler(),
+		Idempotency: newIdempotencyStoreStub(),
+	})
+	require.NoError(t, err)
+	post := func(key, body string) *httptest.ResponseRecorder {
+		request := httptest.NewRequestWithContext(t.Context(), http.MethodPost, "/v1/users", strings.NewReader(body))
+		request.Header.Set("Content-Type", "application/json")
+		request.Header.Set("Idempotency-Key", key)
+		response := httptest.NewRecorder()
+		handler.ServeHTTP(response, request)
+		return response
+	}
+
+	first := post("retry-1", `{"email":"person@example.com"}`)
+	require.Equal(t, http.StatusCreated, first.Code, first.Body.String())
+	replayed := post("retry-1", `{"email":"person@example.com"}`)
+	assert.Equal(t, http.StatusCreated, replayed.Code)
+	assert.Equal(t, first.Body.String(), replayed.Body.String())
+	assert.Equal(t, first.Header().Get("ETag"), replayed.Header().Get("ETag"))
+	assert.Equal(t, "true", replayed.Header().Get("Idempotent-Replayed"))
+	assert.NotEqual(t, first.Header().Get("X-Request-ID"), replayed.Header().Get("X-Request-ID"))
+	assert.Equal(t, 1, api.createCalls)
+
+	mismatched := post("retry-1", `{"email":"other@example.com"}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, mismatched.Code)
+	assert.Equal(t, "application/problem+json", mismatched.Header().Get("Content-Type"))
+
+	assert.Equal(t, http.StatusCreated, post("retry-2", `{"email":"person@example.com"}`).Code)
+	assert.Equal(t, 2, api.createCalls)
+}
+
+func TestHandlerExtendsIdempotencyClaimWhileRequestRuns(t *testing.T) {
+	t.Parallel()
+
+	synctest.Test(t, func(t *testing.T) {
+		store := newIdempotencyStoreStub()
+		handler, err := httpserver.NewHandler(httpserver.HandlerOptions{
+			Logger:      slog.New(slog.NewTextHandler(io.Discard, nil)),
+			API:         &apiStub{createDelay: 3*httpserver.IdempotencyExtendInterval + time.Second},
+			Auth:        httpserver.DisabledAuthentication(),
+			Readiness:   httpserver.NewReadiness(pingerStub{}, nil),
+			Metrics:     http.NotFoundHandler(),
+			Idempotency: store,
+		})
+		require.NoError(t, err)
+
+		request := httptest.NewRequestWithContext(t.Context(), http.MethodPost, "/v1/users", strings.NewReader(`{"email":"person@example.com"}`))
+		request.Header.Set("Content-Type", "application/json")
+		request.Header.Set("Idempotency-Key", "slow-1")
+		response := httptest.NewRecorder()
+		handler.ServeHTTP(response, request)
+
+		require.Equal(t, http.StatusCreated, response.Code, response.Body.String())
+		assert.Equal(t, 3, store.extends)
+	})
+}
+
+func TestHandlerFingerprintsStreamedBodiesWithoutBuffering(t *testing.T) {
+	t.Parallel()
+
+	api := &apiStub{}
+	handler, err := httpserver.NewHandler(httpserver.HandlerOptions{
+		Logger:      slog.New(slog.NewTextHandler(io.Discard, nil)),
+		API:         api,
+		Auth:        httpserver.DisabledAuthentication(),
+		Readiness:   httpserver.NewReadiness(pingerStub{}, nil),
+		Metrics:     http.NotFoundHandler(),
+		Idempotency: newIdempotencyStoreStub(),
+		StreamedRoutes: []httpserver.StreamedRoute{{
+			Method: http.MethodPost, Path: "/v1/user-imports", MediaTypes: []string{"text/csv"}, MaxBodyBytes: 3 << 20,
+		}},
+	})
+	require.NoError(t, err)
+	post := func(body io.Reader) *httptest.ResponseRecorder {
+		request := httptest.NewRequestWithContext(t.Context(), http.MethodPost, "/v1/user-imports", body)
+		request.Header.Set("Content-Type", "text/csv")
+		request.Header.Set("Idempotency-Key", "upload-1")
+		response := httptest.NewRecorder()
+		handler.ServeHTTP(response, request)
+		return response
+	}
+
+	body := "email\n" + strings.Repeat("person@example.com\n", 2<<20/19)
+	upload := &readCounter{Reader: strings.NewReader(body)}
+	first := post(upload)
+	require.Equal(t, http.StatusAccepted, first.Code, first.Body.String())
+	assert.Equal(t, len(body), api.importBytes)
+	assert.LessOrEqual(t, upload.largestRead, 64<<10, "the body is read in chunks")
+
+	replayed := post(strings.NewReader(body))
+	assert.Equal(t, http.StatusAccepted, replayed.Code)
+	assert.Equal(t, "true", replayed.Header().Get("Idempotent-Replayed"))
+	mismatched := post(strings.NewReader(body + "other@example.com\n"))
+	assert.Equal(t, http.StatusUnprocessableEntity, mismatched.Code)
+	assert.Equal(t, 1, api.importCalls)
+}
+
+// readCounter records the largest single read from its reader.
+type readCounter struct {
+	io.Reader
+	largestRead int
+}
+
+func (r *readCounter) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	r.largestRead = max(r.largestRead, n)
+	return n, err
+}
+
+func TestEntityTagPreconditions(t *testing.T) {
+	t.Parallel()
+
+	etag := httpserver.StrongETag("3")
+	assert.Equal(t, `"3"`, etag)
+	assert.True(t, httpserver.IfMatch(`"2", "3"`, etag))
+	assert.True(t, httpserver.IfMatch("*", etag))
+	assert.False(t, httpserver.IfMatch(`W/"3"`, etag))
+	assert.False(t, httpserver.IfMatch(`"2"`, etag))
+	assert.True(t, httpserver.IfNoneMatch(`W/"3"`, etag))
+	assert.True(t, httpserver.IfNoneMatch("*", etag))
+	assert.False(t, httpserver.IfNoneMatch(`"2"`, etag))
+}
+
+func TestHandlerStreamsRouteBodies(t *testing.T) {
+	t.Parallel()
+
+	api := &apiStub{}
+	handler, err := httpserver.NewHandler(httpserver.HandlerOptions{
+		Logger:    slog.New(slog.NewTextHandler(io.Discard, nil)),
+		API:       api,
+		Auth:      httpserver.DisabledAuthentication(),
+		Readiness: httpserver.NewReadiness(pingerStub{}, nil),
+		Metrics:   http.NotFoundHandler(),
+		StreamedRoutes: []httpserver.StreamedRoute{{
+			Method: http.MethodPost, Path: "/v1/user-imports", MediaTypes: []string{"text/csv"}, MaxBodyBytes: 3 << 20,
+		}},
+	})
+	require.NoError(t, err)
+
+	body := "email\n" + strings.Repeat("person@example.com\n", 2<<20/19)
+	request := httptest.NewRequestWithContext(t.Context(), http.MethodPost, "/v1/user-imports", strings.NewReader(body))
+	request.Header.Set("Content-Type", "text/csv")
+	response := httptest.NewRecorder()
+	handler.ServeHTTP(response, request)
+	assert.Equal(t, http.StatusAccepted, response.Code, response.Body.String())
+	assert.Equal(t, len(body), api.importBytes)
+
+	request = httptest.NewRequestWithContext(t.Context(), http.MethodPost, "/v1/user-imports", strings.NewReader(body+body))
+	request.Header.Set("Content-Type", "text/csv")
+	response = httptest.NewRecorder()
+	handler.ServeHTTP(response, request)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, response.Code, response.Body.String())
+
+	request = httptest.NewRequestWithContext(t.Context(), http.MethodPost, "/v1/user-imports", strings.NewReader(`{"emails":[]}`))
+	request.Header.Set("Content-Type", "application/json")
+	response = httptest.NewRecorder()
+	handler.ServeHTTP(response, request)
+	assert.Equal(t, http.StatusBadRequest, response.Code, response.Body.String())
+}
+
+func TestHandlerRoutesCustomMethods(t *testing.T) {
+	t.Parallel()
+
+	handler := newTestHandler(t, &apiStub{}, httpserver.DisabledAuthentication(), pingerStub{})
+	importID := "0198a1f7-30b7-7df1-8491-c47f6033525b"
+	for path, wantStatus := range map[string]int{
+		"/v1/user-imports/" + importID + ":cancel":       http.StatusOK,
+		"/v1/user-imports/" + importID + ":retry-failed": http.StatusAccepted,
+		"/v1/user-imports/" + importID + ":unknown":      http.StatusMethodNotAllowed,
+		"/v1/user-imports/not-a-uuid:cancel":             http.StatusBadRequest,
+		"/v1/user-imports/" + importID + "/cancel":       http.StatusNotFound,
+	} {
+		request := httptest.NewRequestWithContext(t.Context(), http.MethodPost, path, nil)
+		response := httptest.NewRecorder()
+		handler.ServeHTTP(response, request)
+		assert.Equal(t, wantStatus, response.Code, "POST %s: %s", path, response.Body.String())
+	}
+}
+
+func TestHandlerFlushesEventStreams(t *testing.T) {
+	t.Parallel()
+
+	api := &apiStub{streamDone: make(chan struct{})}
+	server := httptest.NewServer(newTestHandler(t, api, httpserver.DisabledAuthentication(), pingerStub{}))
+	t.Cleanup(server.Close)
+	t.Cleanup(func() { close(api.streamDone) })
+
+	request, err := http.NewRequestWithContext(t.Context(), http.MethodGet, server.URL+"/v1/user-imports/0198a1f7-30b7-7df1-8491-c47f6033525b/events", nil)
+	require.NoError(t, err)
+	response, err := server.Client().Do(request)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = response.Body.Close() })
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Equal(t, "text/event-stream", response.Header.Get("Content-Type"))
+
+	// The stream is still open, so the event arrives only if it was flushed.
+	event := make([]byte, len("event: progress\ndata: {}\n\n"))
+	_, err = io.ReadFull(response.Body, event)
+	require.NoError(t, err)
+	assert.Equal(t, "event: progress\ndata: {}\n\n", string(event))
+}
+
+func TestHandlerEnforcesOperationPermissions(t *testing.T) {
+	t.Parallel()
+
+	authentication, err := httpserver.TokenAuthentication(&tokenVerifierStub{})
+	require.NoError(t, err)
+	for name, testCase := range map[string]struct {
+		loader     permissionLoaderStub
+		wantStatus int
+		wantDetail string
+	}{
+		"granted":         {loader: permissionLoaderStub{set: httpserver.PermissionSet{Permissions: []string{"users.read"}}}, wantStatus: http.StatusOK},
+		"missing":         {loader: permissionLoaderStub{set: httpserver.PermissionSet{Permissions: []string{"users.create"}}}, wantStatus: http.StatusForbidden, wantDetail: "subject lacks required permission users.read"},
+		"loader failure":  {loader: permissionLoaderStub{err: errors.New("database unavailable")}, wantStatus: http.StatusInternalServerError},
+		"unknown subject": {loader: permissionLoaderStub{}, wantStatus: http.StatusForbidden},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			handler, err := httpserver.NewHandler(httpserver.HandlerOptions{
+				Logger:      slog.New(slog.NewTextHandler(io.Discard, nil)),
+				API:         &apiStub{},
+				Auth:        authentication,
+				Readiness:   httpserver.NewReadiness(pingerStub{}, nil),
+				Metrics:     http.NotFoundHandler(),
+				Permissions: testCase.loader,
+			})
+			require.NoError(t, err)
+
+			request := httptest.NewRequestWithContext(t.Context(), http.MethodGet, "/v1/users/8d37b313-f867-47bc-8e3d-0953db9c05c8", nil)
+			request.Header.Set("Authorization", "Bearer signed.jwt.token")
+			response := httptest.NewRecorder()
+			handler.ServeHTTP(response, request)
+			assert.Equal(t, testCase.wantStatus, response.Code, response.Body.String())
+			if testCase.wantDetail != "" {
+				assert.Contains(t, response.Body.String(), testCase.wantDetail)
+			}
+		})
+	}
+}
+
+func TestPermissionCacheReloadsNewerRevisions(t *testing.T) {
+	t.Parallel()
+
+	loader := &countingPermissionLoader{set: httpserver.PermissionSet{UserID: "user-1", Revision: 2, Permissions: []string{"users.read"}}}
+	cache := httpserver.NewPermissionCache(loader, time.Hour)
+	load := func() {
+		t.Helper()
+		set, err := cache.LoadPermissions(t.Context(), "subject")
+		require.NoError(t, err)
+		assert.Equal(t, loader.set, set)
+	}
+
+	load()
+	load()
+	assert.Equal(t, 1, loader.calls)
+
+	cache.Invalidate("user-1", 2)
+	cache.Invalidate("user-2", 3)
+	load()
+	assert.Equal(t, 1, loader.calls)
+
+	cache.Invalidate("user-1", 3)
+	load()
+	assert.Equal(t, 2, loader.calls)
+
+	cache.Clear()
+	load()
+	assert.Equal(t, 3, loader.calls)
+}
+
+func TestPermissionCacheDoesNotStoreSetsInvalidatedWhileLoading(t *testing.T) {
+	t.Parallel()
+
+	loader := &countingPermissionLoader{set: httpserver.PermissionSet{UserID: "user-1", Revision: 2, Permissions: []string{"users.read"}}}
+	cache := httpserver.NewPermissionCache(loader, time.Hour)
+	load := func() {
+		t.Helper()
+		_, err := cache.LoadPermissions(t.Context(), "subject")
+		require.NoError(t, err)
+	}
+
+	loader.during = func() { cache.Invalidate("user-1", 3) }
+	load()
+	loader.during = nil
+	load()
+	assert.Equal(t, 2, loader.calls, "a set invalidated while loading is loaded again")
+	load()
+	assert.Equal(t, 2, loader.calls)
+
+	cache.Clear()
+	loader.during = cache.Clear
+	load()
+	loader.during = func() { cache.Invalidate("user-2", 2) }
+	load()
+	assert.Equal(t, 4, loader.calls, "a set cleared while loading is loaded again")
+	loader.during = nil
+	load()
+	assert.Equal(t, 4, loader.calls, "another user's invalidation does not prevent storing")
+}
+
+func TestHandlerRateLimitsOperationsPerSubject(t *testing.T) {
+	t.Parallel()
+
+	authentication, err := httpserver.TokenAuthentication(&tokenVerifierStub{})
+	require.NoError(t, err)
+	store := &rateLimitStoreStub{decision: httpserver.RateLimitDecision{Allowed: true, Tokens: 41.5}}
+	handler, err := httpserver.NewHandler(httpserver.HandlerOptions{
+		Logger:     slog.New(slog.NewTextHandler(io.Discard, nil)),
+		API:        &apiStub{},
+		Auth:       authentication,
+		Readiness:  httpserver.NewReadiness(pingerStub{}, nil),
+		Metrics:    http.NotFoundHandler(),
+		RateLimits: store,
+	})
+	require.NoError(t, err)
+	get := func() *httptest.ResponseRecorder {
+		request := httptest.NewRequestWithContext(t.Context(), http.MethodGet, "/v1/users/8d37b313-f867-47bc-8e3d-0953db9c05c8", nil)
+		request.Header.Set("Authorization", "Bearer signed.jwt.token")
+		response := httptest.NewRecorder()
+		handler.ServeHTTP(response, request)
+		return response
+	}
+
+	response := get()
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Equal(t, "getUser:subject:user-123", store.key)
+	assert.Equal(t, httpserver.RateLimit{Burst: 60, PerMinute: 600}, store.limit)
+	assert.Equal(t, "60", response.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "41", response.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "2", response.Header().Get("RateLimit-Reset"))
+	assert.Empty(t, response.Header().Get("Retry-After"))
+
+	store.decision = httpserver.RateLimitDecision{Tokens: 0.25}
+	response = get()
+	assert.Equal(t, http.StatusTooManyRequests, response.Code)
+	assert.Equal(t, "application/problem+json", response.Header().Get("Content-Type"))
+	assert.Equal(t, "1", response.Header().Get("Retry-After"))
+	assert.Equal(t, "0", response.Header().Get("RateLimit-Remaining"))
+	assert.Contains(t, response.Body.String(), "rate limit of 600 requests per minute exceeded for getUser")
+
+	store.err = errors.New("database unavailable")
+	assert.Equal(t, http.StatusOK, get().Code, "store failures must not reject requests")
+}
+
+func TestHandlerRateLimitsByClientIPWithoutAuthentication(t *testing.T) {
+	t.Parallel()
+
+	store := &rateLimitStoreStub{decision: httpserver.RateLimitDecision{Allowed: true}}
+	handler, err := httpserver.NewHandler(httpserver.HandlerOptions{
+		Logger:     slog.New(slog.NewTextHandler(io.Discard, nil)),
+		API:        &apiStub{},
+		Auth:       httpserver.DisabledAuthentication(),
+		Readiness:  httpserver.NewReadiness(pingerStub{}, nil),
+		Metrics:    http.NotFoundHandler(),
+		RateLimits: store,
+	})
+	require.NoError(t, err)
+	request := httptest.NewRequestWithContext(t.Context(), http.MethodGet, "/v1/users/8d37b313-f867-47bc-8e3d-0953db9c05c8", nil)
+	request.RemoteAddr = "203.0.113.7:51234"
+	handler.ServeHTTP(httptest.NewRecorder(), request)
+	assert.Equal(t, "getUser:ip:203.0.113.7", store.key)
+}
+
+func TestMemoryRateLimitStoreEmptiesBucket(t *testing.T) {
+	t.Parallel()
+
+	store := httpserver.NewMemoryRateLimitStore()
+	limit := httpserver.RateLimit{Burst: 2, PerMinute: 1}
+	for _, wantAllowed := range []bool{true, true, false} {
+		decision, err := store.Take(t.Context(), "key", limit)
+		require.NoError(t, err)
+		assert.Equal(t, wantAllowed, decision.Allowed)
+	}
+	decision, err := store.Take(t.Context(), "key", limit)
+	require.NoError(t, err)
+	assert.Less(t, decision.Tokens, 1.0)
+	decision, err = store.Take(t.Context(), "other", limit)
+	require.NoError(t, err)
+	assert.True(t, decision.Allowed)
+}
+
 func newTestHandler(t *testing.T, api contractapi.StrictServerInterface, authentication httpserver.Authentication, pinger httpserver.Pinger) http.Handler {
 	t.Helper()
 	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
@@ -194,9 +613,28 @@ func newTestHandler(t *testing.T, api contractapi.StrictServerInterface, authent
 
 type apiStub struct {
 	receivedSubject string
+	receivedClaims  auth.Claims
+	createCalls     int
+	createDelay     time.Duration
+	importCalls     int
+	importBytes     int
+	streamDone      chan struct{}
 }
 
-func (s *apiStub) CreateUserImport(context.Context, contractapi.CreateUserImportRequestObject) (contractapi.CreateUserImportResponseObject, error) {
+func (s *apiStub) CreateUserImport(ctx context.Context, request contractapi.CreateUserImportRequestObject) (contractapi.CreateUserImportResponseObject, error) {
+	s.importCalls++
+	if request.Body != nil {
+		read, err := io.Copy(io.Discard, request.Body)
+		var maxBytesError *http.MaxBytesError
+		if errors.As(err, &maxBytesError) {
+			return contractapi.CreateUserImport413ApplicationProblemPlusJSONResponse{
+				PayloadTooLargeApplicationProblemPlusJSONResponse: contractapi.PayloadTooLargeApplicationProblemPlusJSONResponse(
+					httpserver.NewProblem(httpserver.RequestID(ctx), 413, "Request Entity Too Large", ""),
+				),
+			}, nil
+		}
+		s.importBytes = int(read)
+	}
 	return contractapi.CreateUserImport202JSONResponse{}, nil
 }
 
@@ -204,23 +642,120 @@ func (s *apiStub) GetUserImport(context.Context, contractapi.GetUserImportReques
 	return contractapi.GetUserImport200JSONResponse{}, nil
 }
 
+func (s *apiStub) CancelUserImport(_ context.Context, request contractapi.CancelUserImportRequestObject) (contractapi.CancelUserImportResponseObject, error) {
+	return contractapi.CancelUserImport200JSONResponse{Id: request.ImportId, State: contractapi.UserImportStateCancelled}, nil
+}
+
+func (s *apiStub) RetryFailedUserImportEntries(_ context.Context, request contractapi.RetryFailedUserImportEntriesRequestObject) (contractapi.RetryFailedUserImportEntriesResponseObject, error) {
+	return contractapi.RetryFailedUserImportEntries202JSONResponse{Id: uuid.New(), State: contractapi.UserImportStatePending, RetryOf: &request.ImportId}, nil
+}
+
+func (s *apiStub) ListUserImportEntries(context.Context, contractapi.ListUserImportEntriesRequestObject) (contractapi.ListUserImportEntriesResponseObject, error) {
+	return contractapi.ListUserImportEntries200JSONResponse{Items: []contractapi.UserImportEntry{}}, nil
+}
+
+func (s *apiStub) StreamUserImportEvents(context.Context, contractapi.StreamUserImportEventsRequestObject) (contractapi.StreamUserImportEventsResponseObject, error) {
+	reader, writer := io.Pipe()
+	go func() {
+		_, _ = io.WriteString(writer, "event: progress\ndata: {}\n\n")
+		if s.streamDone != nil {
+			<-s.streamDone
+		}
+		_ = writer.Close()
+	}()
+	return contractapi.StreamUserImportEvents200TexteventStreamResponse{Body: reader}, nil
+}
+
+func (s *apiStub) ListUsers(context.Context, contractapi.ListUsersRequestObject) (contractapi.ListUsersResponseObject, error) {
+	return contractapi.ListUsers200JSONResponse{Items: []contractapi.User{}}, nil
+}
+
+func (s *apiStub) GetUserPermissions(context.Context, contractapi.GetUserPermissionsRequestObject) (contractapi.GetUserPermissionsResponseObject, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (s *apiStub) ListAuditEvents(context.Context, contractapi.ListAuditEventsRequestObject) (contractapi.ListAuditEventsResponseObject, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (s *apiStub) UpdateUser(_ context.Context, request contractapi.UpdateUserRequestObject) (contractapi.UpdateUserResponseObject, error) {
+	return contractapi.UpdateUser200JSONResponse{
+		Body:    contractapi.User{Id: request.UserId, Email: request.Body.Email},
+		Headers: contractapi.UpdateUser200ResponseHeaders{ETag: `"2"`},
+	}, nil
+}
+
+func (s *apiStub) DeleteUser(context.Context, contractapi.DeleteUserRequestObject) (contractapi.DeleteUserResponseObject, error) {
+	return contractapi.DeleteUser204Response{}, nil
+}
+
 func (s *apiStub) CreateUser(_ context.Context, request contractapi.CreateUserRequestObject) (contractapi.CreateUserResponseObject, error) {
+	s.createCalls++
+	time.Sleep(s.createDelay)
 	return contractapi.CreateUser201JSONResponse{
-		Id:        uuid.MustParse("8d37b313-f867-47bc-8e3d-0953db9c05c8"),
-		Email:     request.Body.Email,
-		CreatedAt: time.Date(2026, time.July, 11, 12, 0, 0, 0, time.UTC),
+		Body: contractapi.User{
+			Id:        uuid.MustParse("8d37b313-f867-47bc-8e3d-0953db9c05c8"),
+			Email:     request.Body.Email,
+			CreatedAt: time.Date(2026, time.July, 11, 12, 0, 0, 0, time.UTC),
+		},
+		Headers: contractapi.CreateUser201ResponseHeaders{ETag: `"1"`},
 	}, nil
 }
 
 func (s *apiStub) GetUser(ctx context.Context, request contractapi.GetUserRequestObject) (contractapi.GetUserResponseObject, error) {
 	s.receivedSubject, _ = httpserver.Subject(ctx)
+	s.receivedClaims, _ = httpserver.Claims(ctx)
 	return contractapi.GetUser200JSONResponse{
-		Id:        request.UserId,
-		Email:     openapi_types.Email("person@example.com"),
-		CreatedAt: time.Date(2026, time.July, 11, 12, 0, 0, 0, time.UTC),
+		Body: contractapi.User{
+			Id:        request.UserId,
+			Email:     openapi_types.Email("person@example.com"),
+			CreatedAt: time.Date(2026, time.July, 11, 12, 0, 0, 0, time.UTC),
+		},
+		Headers: contractapi.GetUser200ResponseHeaders{ETag: `"1"`},
 	}, nil
 }
 
+type idempotencyStoreStub struct {
+	mu      sync.Mutex
+	records map[httpserver.IdempotencyKey]httpserver.IdempotencyRecord
+	extends int
+}
+
+func newIdempotencyStoreStub() *idempotencyStoreStub {
+	return &idempotencyStoreStub{records: map[httpserver.IdempotencyKey]httpserver.IdempotencyRecord{}}
+}
+
+func (s *idempotencyStoreStub) Claim(_ context.Context, key httpserver.IdempotencyKey) (httpserver.IdempotencyRecord, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if record, ok := s.records[key]; ok {
+		return record, false, nil
+	}
+	s.records[key] = httpserver.IdempotencyRecord{}
+	return httpserver.IdempotencyRecord{}, true, nil
+}
+
+func (s *idempotencyStoreStub) Extend(context.Context, httpserver.IdempotencyKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.extends++
+	return nil
+}
+
+func (s *idempotencyStoreStub) Complete(_ context.Context, key httpserver.IdempotencyKey, fingerprint []byte, response httpserver.IdempotentResponse) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records[key] = httpserver.IdempotencyRecord{Fingerprint: fingerprint, Response: &response}
+	return nil
+}
+
+func (s *idempotencyStoreStub) Release(_ context.Context, key httpserver.IdempotencyKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.records, key)
+	return nil
+}
+
 type pingerStub struct {
 	err error
 }
@@ -230,10 +765,51 @@ func (p pingerStub) Ping(context.Context) error {
 }
 
 type tokenVerifierStub struct {
-	token string
+	token  string
+	scopes []string
 }
 
-func (v *tokenVerifierStub) Verify(_ context.Context, token string) (string, error) {
+func (v *tokenVerifierStub) Verify(_ context.Context, token string) (auth.Claims, error) {
 	v.token = token
-	return "user-123", nil
+	scopes := v.scopes
+	if scopes == nil {
+		scopes = []string{"users:read", "users:write"}
+	}
+	return auth.Claims{Subject: "user-123", Scopes: scopes, Raw: map[string]any{"sub": "user-123"}}, nil
+}
+
+type permissionLoaderStub struct {
+	set httpserver.PermissionSet
+	err error
+}
+
+func (l permissionLoaderStub) LoadPermissions(context.Context, string) (httpserver.PermissionSet, error) {
+	return l.set, l.err
+}
+
+type countingPermissionLoader struct {
+	set   httpserver.PermissionSet
+	calls int
+	// during runs while the set is loaded.
+	during func()
+}
+
+func (l *countingPermissionLoader) LoadPermissions(context.Context, string) (httpserver.PermissionSet, error) {
+	l.calls++
+	if l.during != nil {
+		l.during()
+	}
+	return l.set, nil
+}
+
+type rateLimitStoreStub struct {
+	decision httpserver.RateLimitDecision
+	err      error
+	key      string
+	limit    httpserver.RateLimit
+}
+
+func (s *rateLimitStoreStub) Take(_ context.Context, key string, limit httpserver.RateLimit) (httpserver.RateLimitDecision, error) {
+	s.key, s.limit = key, limit
+	return s.decision, s.err
 }
@@ -7,6 +7,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net"
+	"strconv"
 	"testing"
 	"time"
 
@@ -28,10 +29,10 @@ func TestSNSAndSQSTopologyIntegration(t *testing.T) {
 	awsConfig := newLocalStackAWSConfig(t)
 	snsClient := sns.NewFromConfig(awsConfig)
 	sqsClient := sqs.NewFromConfig(awsConfig)
-	topology := createTestTopology(t, snsClient, sqsClient, "redrive")
+	topology := createTestTopology(t, snsClient, sqsClient, "redrive", false)
 	publisher := NewSNSTopicPublisher(snsClient, topology.topicARN, nil)
 
-	require.NoError(t, publisher.Publish(t.Context(), []byte(`{"type":"unrelated.event"}`)))
+	require.NoError(t, publisher.Publish(t.Context(), Message{Body: []byte(`{"type":"unrelated.event"}`)}))
 	want := Envelope[map[string]any]{
 		ID:        "event-1",
 		Timestamp: time.Date(2026, time.July, 13, 8, 0, 0, 0, time.UTC),
@@ -43,7 +44,7 @@ func TestSNSAndSQSTopologyIntegration(t *testing.T) {
 	}
 	message, err := json.Marshal(want)
 	require.NoError(t, err)
-	require.NoError(t, publisher.Publish(t.Context(), message))
+	require.NoError(t, publisher.Publish(t.Context(), Message{Body: message}))
 
 	firstDelivery := receiveOne(t, sqsClient, topology.queueURL, 0, "first event delivery")
 	got, err := DecodeSNSNotification[map[string]any]([]byte(aws.ToString(firstDelivery.Body)), "permissions.changed")
@@ -65,13 +66,13 @@ func TestSNSAndSQSTopologyIntegration(t *testing.T) {
 	require.NoError(t, err)
 	assert.Empty(t, filtered.Messages, "the unrelated event must be filtered out")
 
-	acknowledgementTopology := createTestTopology(t, snsClient, sqsClient, "ack")
+	acknowledgementTopology := createTestTopology(t, snsClient, sqsClient, "ack", false)
 	acknowledgementPublisher := NewSNSTopicPublisher(snsClient, acknowledgementTopology.topicARN, nil)
-	require.NoError(t, acknowledgementPublisher.Publish(t.Context(), message))
+	require.NoError(t, acknowledgementPublisher.Publish(t.Context(), Message{Body: message}))
 	ctx, cancel := context.WithCancel(t.Context())
 	client := &cancelAfterDeleteClient{Client: sqsClient, cancel: cancel}
 	handler := &capturingHandler{body: make(chan []byte, 1)}
-	require.NoError(t, NewSQSConsumer(client, acknowledgementTopology.queueURL, handler, discardLogger(), nil).Run(ctx))
+	require.NoError(t, NewSQSConsumer(client, acknowledgementTopology.queueURL, handler, discardLogger(), nil, SQSConsumerOptions{}).Run(ctx))
 
 	gotBody := <-handler.body
 	got, err = DecodeSNSNotification[map[string]any](gotBody, "permissions.changed")
@@ -87,13 +88,98 @@ func TestSNSAndSQSTopologyIntegration(t *testing.T) {
 	assert.Empty(t, empty.Messages, "the acknowledged event must not remain")
 }
 
+func TestMessageFormatsIntegration(t *testing.T) {
+	awsConfig := newLocalStackAWSConfig(t)
+	snsClient := sns.NewFromConfig(awsConfig)
+	sqsClient := sqs.NewFromConfig(awsConfig)
+	topology := createTestTopology(t, snsClient, sqsClient, "raw", true)
+	envelope, err := json.Marshal(Envelope[map[string]any]{
+		ID:        "event-1",
+		Timestamp: time.Date(2026, time.July, 13, 8, 0, 0, 0, time.UTC),
+		Type:      "permissions.changed",
+		Payload:   map[string]any{"userId": "0198a1f7-30b7-7df7-8491-c47f6033525b"},
+		Metadata: Metadata{
+			SchemaVersion: "1.0.0", ProducedBy: "permissions", OriginatedFrom: "permissions", CorrelationID: "correlation-1",
+		},
+	})
+	require.NoError(t, err)
+
+	publisher := NewSNSTopicPublisher(snsClient, topology.topicARN, nil)
+	require.NoError(t, publisher.Publish(t.Context(), Message{Body: envelope, Type: "permissions.changed"}))
+	rawDelivery := receiveOne(t, sqsClient, topology.queueURL, 30, "raw delivery")
+	assert.Equal(t, "permissions.changed", aws.ToString(rawDelivery.MessageAttributes[MessageAttributeType].StringValue),
+		"raw delivery turns SNS message attributes into SQS message attributes")
+	_, err = sqsClient.SendMessage(t.Context(), &sqs.SendMessageInput{QueueUrl: aws.String(topology.queueURL), MessageBody: aws.String(string(envelope))})
+	require.NoError(t, err)
+	direct := receiveOne(t, sqsClient, topology.queueURL, 30, "direct message")
+	_, err = sqsClient.SendMessage(t.Context(), &sqs.SendMessageInput{
+		QueueUrl: aws.String(topology.queueURL),
+		MessageBody: aws.String(fmt.Sprintf(
+			`{"version":"0","id":"eventbridge-1","detail-type":"permissions.changed","source":"permissions","time":"2026-07-13T08:00:00Z","detail":%s}`,
+			envelope,
+		)),
+	})
+	require.NoError(t, err)
+	eventBridge := receiveOne(t, sqsClient, topology.queueURL, 30, "EventBridge event")
+
+	for name, test := range map[string]struct {
+		message types.Message
+		format  MessageFormat
+	}{
+		"raw delivery":         {rawDelivery, MessageFormatRaw},
+		"direct message":       {direct, MessageFormatRaw},
+		"EventBridge event":    {eventBridge, MessageFormatEventBridge},
+		"detected raw":         {rawDelivery, MessageFormatAuto},
+		"detected EventBridge": {eventBridge, MessageFormatAuto},
+	} {
+		got, err := DecodeMessage[map[string]any]([]byte(aws.ToString(test.message.Body)), test.format, "permissions.changed")
+		require.NoError(t, err, name)
+		assert.Equal(t, "event-1", got.ID, name)
+	}
+}
+
+func TestDeadLetterQueueIntegration(t *testing.T) {
+	awsConfig := newLocalStackAWSConfig(t)
+	snsClient := sns.NewFromConfig(awsConfig)
+	sqsClient := sqs.NewFromConfig(awsConfig)
+	topology := createTestTopology(t, snsClient, sqsClient, "dlq", false)
+	body := `{"Type":"Notification","Message":"{\"id\":\"event-1\",\"type\":\"permissions.changed\",\"metadata\":{\"correlationId\":\"correlation-1\"}}"}`
+	var sent []string
+	for range 2 {
+		output, err := sqsClient.SendMessage(t.Context(), &sqs.SendMessageInput{
+			QueueUrl: aws.String(topology.deadLetterQueueURL), MessageBody: aws.String(body),
+		})
+		require.NoError(t, err)
+		sent = append(sent, aws.ToString(output.MessageId))
+	}
+
+	queue, err := NewDeadLetterQueue(t.Context(), sqsClient, topology.queueURL, MessageFormatSNS)
+	require.NoError(t, err)
+	assert.Equal(t, topology.deadLetterQueueURL, queue.URL())
+	listed, err := queue.List(t.Context(), 10)
+	require.NoError(t, err)
+	require.Len(t, listed, 2)
+	assert.Equal(t, "event-1", listed[0].Envelope.ID)
+	assert.Equal(t, "correlation-1", listed[0].Envelope.Metadata.CorrelationID)
+
+	moved, err := queue.Redrive(t.Context(), DeadLetterSelection{MessageIDs: sent[:1]})
+	require.NoError(t, err)
+	assert.Equal(t, sent[:1], moved)
+	redriven := receiveOne(t, sqsClient, topology.queueURL, 30, "redriven message")
+	assert.Equal(t, body, aws.ToString(redriven.Body))
+	remaining, err := queue.List(t.Context(), 10)
+	require.NoError(t, err)
+	require.Len(t, remaining, 1)
+	assert.Equal(t, sent[1], remaining[0].MessageID)
+}
+
 type testTopology struct {
 	topicARN           string
 	queueURL           string
 	deadLetterQueueURL string
 }
 
-func createTestTopology(t *testing.T, snsClient *sns.Client, sqsClient *sqs.Client, suffix string) testTopology {
+func createTestTopology(t *testing.T, snsClient *sns.Client, sqsClient *sqs.Client, suffix string, rawMessageDelivery bool) testTopology {
 	t.Helper()
 
 	topic, err := snsClient.CreateTopic(t.Context(), &sns.CreateTopicInput{Name: aws.String("permissions-" + suffix)})
@@ -128,7 +214,7 @@ func createTestTopology(t *testing.T, snsClient *sns.Client, sqsClient *sqs.Clie
 		Protocol: aws.String("sqs"),
 		Endpoint: aws.String(queueARN),
 		Attributes: map[string]string{
-			"RawMessageDelivery": "false",
+			"RawMessageDelivery": strconv.FormatBool(rawMessageDelivery),
 			"FilterPolicyScope":  "MessageBody",
 			"FilterPolicy":       `{"type":["permissions.changed"]}`,
 		},
@@ -156,6 +242,7 @@ func receiveOne(t *testing.T, client *sqs.Client, queueURL string, visibilityTim
 	for range 10 {
 		output, err := client.ReceiveMessage(t.Context(), &sqs.ReceiveMessageInput{
 			QueueUrl: aws.String(queueURL), MaxNumberOfMessages: 1, WaitTimeSeconds: 1, VisibilityTimeout: visibilityTimeout,
+			MessageAttributeNames: []string{"All"},
 		})
 		require.NoError(t, err)
 		if len(output.Messages) == 1 {
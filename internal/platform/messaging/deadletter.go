@@ -0,0 +1,291 @@
+package messaging
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+)
+
+const (
+	// DeadLetterScanLimit bounds how many messages one command looks at, since
+	// SQS can only be read by receiving messages.
+	DeadLetterScanLimit = 1000
+	// deadLetterScanVisibility hides scanned messages so that later receives
+	// return new ones; every message not moved or deleted is released after
+	// the scan.
+	deadLetterScanVisibility = 60
+)
+
+var ErrDeadLetterNotFound = errors.New("dead-letter message not found")
+
+type DeadLetterClient interface {
+	GetQueueAttributes(context.Context, *sqs.GetQueueAttributesInput, ...func(*sqs.Options)) (*sqs.GetQueueAttributesOutput, error)
+	GetQueueUrl(context.Context, *sqs.GetQueueUrlInput, ...func(*sqs.Options)) (*sqs.GetQueueUrlOutput, error)
+	ReceiveMessage(context.Context, *sqs.ReceiveMessageInput, ...func(*sqs.Options)) (*sqs.ReceiveMessageOutput, error)
+	ChangeMessageVisibility(context.Context, *sqs.ChangeMessageVisibilityInput, ...func(*sqs.Options)) (*sqs.ChangeMessageVisibilityOutput, error)
+	SendMessage(context.Context, *sqs.SendMessageInput, ...func(*sqs.Options)) (*sqs.SendMessageOutput, error)
+	DeleteMessage(context.Context, *sqs.DeleteMessageInput, ...func(*sqs.Options)) (*sqs.DeleteMessageOutput, error)
+	PurgeQueue(context.Context, *sqs.PurgeQueueInput, ...func(*sqs.Options)) (*sqs.PurgeQueueOutput, error)
+}
+
+// DeadLetterMessage is a message in a dead-letter queue. Envelope is decoded
+// without validation; DecodeError explains why it could not be decoded.
+type DeadLetterMessage struct {
+	MessageID   string
+	SentAt      time.Time
+	Body        string
+	Attributes  map[string]string
+	Envelope    Envelope[json.RawMessage]
+	DecodeError string
+
+	receiptHandle string
+	groupID       string
+	attributes    map[string]types.MessageAttributeValue
+}
+
+// DeadLetterSelection names the messages to act on: the listed message IDs,
+// or every message when All is set.
+type DeadLetterSelection struct {
+	MessageIDs []string
+	All        bool
+}
+
+// DeadLetterQueue inspects and recovers the dead-letter queue of a source
+// queue.
+type DeadLetterQueue struct {
+	client    DeadLetterClient
+	format    MessageFormat
+	sourceURL string
+	url       string
+}
+
+// NewDeadLetterQueue finds the dead-letter queue named by the redrive policy
+// of the source queue. Messages are decoded as format, as the consumer of the
+// source queue decodes them.
+func NewDeadLetterQueue(ctx context.Context, client DeadLetterClient, sourceURL string, format MessageFormat) (*DeadLetterQueue, error) {
+	attributes, err := client.GetQueueAttributes(ctx, &sqs.GetQueueAttributesInput{
+		QueueUrl: aws.String(sourceURL), AttributeNames: []types.QueueAttributeName{types.QueueAttributeNameRedrivePolicy},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("read source queue redrive policy: %w", err)
+	}
+	var policy struct {
+		DeadLetterTargetARN string `json:"deadLetterTargetArn"`
+	}
+	encoded := attributes.Attributes[string(types.QueueAttributeNameRedrivePolicy)]
+	if encoded == "" {
+		return nil, errors.New("source queue has no dead-letter queue")
+	}
+	if err := json.Unmarshal([]byte(encoded), &policy); err != nil {
+		return nil, fmt.Errorf("decode source queue redrive policy: %w", err)
+	}
+	// An SQS ARN is arn:partition:sqs:region:account:name.
+	parts := strings.Split(policy.DeadLetterTargetARN, ":")
+	if len(parts) != 6 || parts[2] != "sqs" {
+		return nil, fmt.Errorf("invalid dead-letter queue ARN %q", policy.DeadLetterTargetARN)
+	}
+	queue, err := client.GetQueueUrl(ctx, &sqs.GetQueueUrlInput{QueueName: aws.String(parts[5]), QueueOwnerAWSAccountId: aws.String(parts[4])})
+	if err != nil {
+		return nil, fmt.Errorf("resolve dead-letter queue URL: %w", err)
+	}
+	return &DeadLetterQueue{client: client, format: format, sourceURL: sourceURL, url: aws.ToString(queue.QueueUrl)}, nil
+}
+
+func (q *DeadLetterQueue) URL() string {
+	return q.url
+}
+
+// List returns up to limit messages, oldest receive first as SQS returns
+// them. SQS ordering is approximate, so a message can be missed while
+// another command scans the queue.
+func (q *DeadLetterQueue) List(ctx context.Context, limit int) ([]DeadLetterMessage, error) {
+	if limit < 1 || limit > DeadLetterScanLimit {
+		return nil, fmt.Errorf("dead-letter list limit must be between 1 and %d", DeadLetterScanLimit)
+	}
+	var listed []DeadLetterMessage
+	err := q.scan(ctx, func(message DeadLetterMessage) (bool, bool, error) {
+		listed = append(listed, message)
+		return len(listed) < limit, false, nil
+	})
+	return listed, err
+}
+
+func (q *DeadLetterQueue) Show(ctx context.Context, messageID string) (DeadLetterMessage, error) {
+	var found *DeadLetterMessage
+	err := q.scan(ctx, func(message DeadLetterMessage) (bool, bool, error) {
+		if message.MessageID != messageID {
+			return true, false, nil
+		}
+		found = &message
+		return false, false, nil
+	})
+	if err != nil {
+		return DeadLetterMessage{}, err
+	}
+	if found == nil {
+		return DeadLetterMessage{}, fmt.Errorf("%w: %s", ErrDeadLetterNotFound, messageID)
+	}
+	return *found, nil
+}
+
+// Redrive sends the selected messages back to the source queue with their
+// body and attributes and then deletes them from the dead-letter queue. It
+// returns the IDs of the moved messages.
+func (q *DeadLetterQueue) Redrive(ctx context.Context, selection DeadLetterSelection) ([]string, error) {
+	return q.remove(ctx, selection, func(ctx context.Context, message DeadLetterMessage) error {
+		input := &sqs.SendMessageInput{
+			QueueUrl:          aws.String(q.sourceURL),
+			MessageBody:       aws.String(message.Body),
+			MessageAttributes: message.attributes,
+		}
+		if message.groupID != "" {
+			input.MessageGroupId = aws.String(message.groupID)
+			input.MessageDeduplicationId = aws.String(message.MessageID)
+		}
+		if _, err := q.client.SendMessage(ctx, input); err != nil {
+			return fmt.Errorf("send message %s to source queue: %w", message.MessageID, err)
+		}
+		return nil
+	})
+}
+
+// Purge deletes the selected messages. Selecting all purges the queue, which
+// SQS allows once a minute and which also removes messages sent meanwhile.
+func (q *DeadLetterQueue) Purge(ctx context.Context, selection DeadLetterSelection) ([]string, error) {
+	if selection.All {
+		if _, err := q.client.PurgeQueue(ctx, &sqs.PurgeQueueInput{QueueUrl: aws.String(q.url)}); err != nil {
+			return nil, fmt.Errorf("purge dead-letter queue: %w", err)
+		}
+		return nil, nil
+	}
+	return q.remove(ctx, selection, func(context.Context, DeadLetterMessage) error { return nil })
+}
+
+// remove deletes the selected messages after before succeeds for each. It
+// reports selected IDs it did not find after handling the others.
+func (q *DeadLetterQueue) remove(ctx context.Context, selection DeadLetterSelection, before func(context.Context, DeadLetterMessage) error) ([]string, error) {
+	if !selection.All && len(selection.MessageIDs) == 0 {
+		return nil, errors.New("select dead-letter messages by ID or all of them")
+	}
+	pending := slices.Clone(selection.MessageIDs)
+	var removed []string
+	err := q.scan(ctx, func(message DeadLetterMessage) (bool, bool, error) {
+		index := slices.Index(pending, message.MessageID)
+		if !selection.All && index < 0 {
+			return true, false, nil
+		}
+		if err := before(ctx, message); err != nil {
+			return false, false, err
+		}
+		if _, err := q.client.DeleteMessage(ctx, &sqs.DeleteMessageInput{
+			QueueUrl: aws.String(q.url), ReceiptHandle: aws.String(message.receiptHandle),
+		}); err != nil {
+			return false, false, fmt.Errorf("delete dead-letter message %s: %w", message.MessageID, err)
+		}
+		removed = append(removed, message.MessageID)
+		if index >= 0 {
+			pending = slices.Delete(pending, index, index+1)
+		}
+		return selection.All || len(pending) > 0, true, nil
+	})
+	if err != nil {
+		return removed, err
+	}
+	if !selection.All && len(pending) > 0 {
+		return removed, fmt.Errorf("%w: %s", ErrDeadLetterNotFound, strings.Join(pending, ", "))
+	}
+	return removed, nil
+}
+
+// scan receives messages until visit stops it, the queue is exhausted or
+// DeadLetterScanLimit messages were seen. visit reports whether to continue
+// and whether it deleted the message; the others are made visible again.
+func (q *DeadLetterQueue) scan(ctx context.Context, visit func(DeadLetterMessage) (next, removed bool, err error)) error {
+	var hidden []string
+	defer func() {
+		// Release even after ctx ends, or the messages stay hidden for the
+		// whole scan visibility timeout.
+		releaseCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+		defer cancel()
+		for _, receiptHandle := range hidden {
+			_, _ = q.client.ChangeMessageVisibility(releaseCtx, &sqs.ChangeMessageVisibilityInput{
+				QueueUrl: aws.String(q.url), ReceiptHandle: aws.String(receiptHandle), VisibilityTimeout: 0,
+			})
+		}
+	}()
+
+	seen := make(map[string]bool)
+	for len(seen) < DeadLetterScanLimit {
+		output, err := q.client.ReceiveMessage(ctx, &sqs.ReceiveMessageInput{
+			QueueUrl:              aws.String(q.url),
+			MaxNumberOfMessages:   10,
+			WaitTimeSeconds:       1,
+			VisibilityTimeout:     deadLetterScanVisibility,
+			MessageAttributeNames: []string{"All"},
+			MessageSystemAttributeNames: []types.MessageSystemAttributeName{
+				types.MessageSystemAttributeNameSentTimestamp,
+				types.MessageSystemAttributeNameMessageGroupId,
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("receive dead-letter messages: %w", err)
+		}
+		if len(output.Messages) == 0 {
+			return nil
+		}
+		for index, sqsMessage := range output.Messages {
+			message := q.decode(sqsMessage)
+			if seen[message.MessageID] {
+				hidden = append(hidden, message.receiptHandle)
+				continue
+			}
+			seen[message.MessageID] = true
+			next, removed, err := visit(message)
+			if !removed {
+				hidden = append(hidden, message.receiptHandle)
+			}
+			if err != nil || !next {
+				for _, unvisited := range output.Messages[index+1:] {
+					hidden = append(hidden, aws.ToString(unvisited.ReceiptHandle))
+				}
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (q *DeadLetterQueue) decode(received types.Message) DeadLetterMessage {
+	message := DeadLetterMessage{
+		MessageID:     aws.ToString(received.MessageId),
+		Body:          aws.ToString(received.Body),
+		receiptHandle: aws.ToString(received.ReceiptHandle),
+		groupID:       received.Attributes[string(types.MessageSystemAttributeNameMessageGroupId)],
+		attributes:    received.MessageAttributes,
+	}
+	if milliseconds, err := strconv.ParseInt(received.Attributes[string(types.MessageSystemAttributeNameSentTimestamp)], 10, 64); err == nil {
+		message.SentAt = time.UnixMilli(milliseconds).UTC()
+	}
+	if len(received.MessageAttributes) > 0 {
+		message.Attributes = make(map[string]string, len(received.MessageAttributes))
+		for name, value := range received.MessageAttributes {
+			message.Attributes[name] = aws.ToString(value.StringValue)
+		}
+	}
+	envelope, err := InspectMessage([]byte(message.Body), q.format)
+	if err != nil {
+		message.DecodeError = err.Error()
+	} else {
+		message.Envelope = envelope
+	}
+	return message
+}
@@ -0,0 +1,176 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeadLetterQueueListsDecodedMessagesAndReleasesThem(t *testing.T) {
+	t.Parallel()
+
+	client := newDeadLetterClientStub(25)
+	queue, err := NewDeadLetterQueue(t.Context(), client, "source-url", MessageFormatSNS)
+	require.NoError(t, err)
+	assert.Equal(t, "dlq-url", queue.URL())
+	assert.Equal(t, "permissions-dlq", client.queueName)
+	assert.Equal(t, "123456789012", client.queueOwner)
+
+	listed, err := queue.List(t.Context(), 12)
+	require.NoError(t, err)
+	require.Len(t, listed, 12)
+	assert.Equal(t, "message-0", listed[0].MessageID)
+	assert.Equal(t, "permissions.changed", listed[0].Envelope.Type)
+	assert.Equal(t, "event-0", listed[0].Envelope.ID)
+	assert.Equal(t, "correlation-0", listed[0].Envelope.Metadata.CorrelationID)
+	assert.Equal(t, "permissions.changed", listed[0].Attributes["type"])
+	assert.Empty(t, client.hidden, "listed messages become visible again")
+
+	client.messages[0].Body = aws.String("not json")
+	shown, err := queue.Show(t.Context(), "message-0")
+	require.NoError(t, err)
+	assert.Contains(t, shown.DecodeError, "decode SNS notification")
+	_, err = queue.Show(t.Context(), "missing")
+	require.ErrorIs(t, err, ErrDeadLetterNotFound)
+}
+
+func TestDeadLetterQueueRedrivesSelectedMessages(t *testing.T) {
+	t.Parallel()
+
+	client := newDeadLetterClientStub(15)
+	queue, err := NewDeadLetterQueue(t.Context(), client, "source-url", MessageFormatSNS)
+	require.NoError(t, err)
+
+	moved, err := queue.Redrive(t.Context(), DeadLetterSelection{MessageIDs: []string{"message-3", "message-12", "missing"}})
+	require.ErrorIs(t, err, ErrDeadLetterNotFound)
+	assert.ErrorContains(t, err, "missing")
+	assert.Equal(t, []string{"message-3", "message-12"}, moved)
+	require.Len(t, client.sent, 2)
+	assert.Equal(t, "source-url", aws.ToString(client.sent[0].QueueUrl))
+	assert.Equal(t, aws.ToString(deadLetterBody(3)), aws.ToString(client.sent[0].MessageBody))
+	assert.Equal(t, "permissions.changed", aws.ToString(client.sent[0].MessageAttributes["type"].StringValue))
+	assert.Len(t, client.messages, 13)
+	assert.Empty(t, client.hidden)
+
+	client.sendErr = errors.New("access denied")
+	moved, err = queue.Redrive(t.Context(), DeadLetterSelection{All: true})
+	require.ErrorContains(t, err, "access denied")
+	assert.Empty(t, moved)
+	assert.Len(t, client.messages, 13, "messages that were not sent stay in the dead-letter queue")
+}
+
+func TestDeadLetterQueuePurgesSelectedOrAllMessages(t *testing.T) {
+	t.Parallel()
+
+	client := newDeadLetterClientStub(3)
+	queue, err := NewDeadLetterQueue(t.Context(), client, "source-url", MessageFormatSNS)
+	require.NoError(t, err)
+
+	deleted, err := queue.Purge(t.Context(), DeadLetterSelection{MessageIDs: []string{"message-1"}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"message-1"}, deleted)
+	assert.Len(t, client.messages, 2)
+	assert.Empty(t, client.sent)
+
+	_, err = queue.Purge(t.Context(), DeadLetterSelection{All: true})
+	require.NoError(t, err)
+	assert.Empty(t, client.messages)
+
+	_, err = queue.Purge(t.Context(), DeadLetterSelection{})
+	require.Error(t, err)
+}
+
+// deadLetterClientStub keeps the messages of the dead-letter queue and hides
+// received messages until their visibility changes or they are deleted.
+type deadLetterClientStub struct {
+	messages   []types.Message
+	hidden     map[string]bool
+	sent       []*sqs.SendMessageInput
+	sendErr    error
+	queueName  string
+	queueOwner string
+}
+
+func newDeadLetterClientStub(count int) *deadLetterClientStub {
+	client := &deadLetterClientStub{hidden: make(map[string]bool)}
+	for index := range count {
+		client.messages = append(client.messages, types.Message{
+			MessageId:     aws.String(fmt.Sprintf("message-%d", index)),
+			ReceiptHandle: aws.String(fmt.Sprintf("receipt-%d", index)),
+			Body:          deadLetterBody(index),
+			Attributes:    map[string]string{"SentTimestamp": "1783926000000"},
+			MessageAttributes: map[string]types.MessageAttributeValue{
+				"type": {DataType: aws.String("String"), StringValue: aws.String("permissions.changed")},
+			},
+		})
+	}
+	return client
+}
+
+func deadLetterBody(index int) *string {
+	envelope := fmt.Sprintf(`{"id":"event-%d","timestamp":"2026-07-13T07:00:00Z","type":"permissions.changed","payload":{},`+
+		`"metadata":{"schemaVersion":"1.0.0","producedBy":"permissions","originatedFrom":"permissions","correlationId":"correlation-%d"}}`, index, index)
+	return aws.String(fmt.Sprintf(`{"Type":"Notification","Message":%q}`, envelope))
+}
+
+func (c *deadLetterClientStub) GetQueueAttributes(context.Context, *sqs.GetQueueAttributesInput, ...func(*sqs.Options)) (*sqs.GetQueueAttributesOutput, error) {
+	return &sqs.GetQueueAttributesOutput{Attributes: map[string]string{
+		"RedrivePolicy": `{"deadLetterTargetArn":"arn:aws:sqs:eu-west-1:123456789012:permissions-dlq","maxReceiveCount":5}`,
+	}}, nil
+}
+
+func (c *deadLetterClientStub) GetQueueUrl(_ context.Context, input *sqs.GetQueueUrlInput, _ ...func(*sqs.Options)) (*sqs.GetQueueUrlOutput, error) {
+	c.queueName, c.queueOwner = aws.ToString(input.QueueName), aws.ToString(input.QueueOwnerAWSAccountId)
+	return &sqs.GetQueueUrlOutput{QueueUrl: aws.String("dlq-url")}, nil
+}
+
+func (c *deadLetterClientStub) ReceiveMessage(_ context.Context, input *sqs.ReceiveMessageInput, _ ...func(*sqs.Options)) (*sqs.ReceiveMessageOutput, error) {
+	var received []types.Message
+	for _, message := range c.messages {
+		if len(received) == int(input.MaxNumberOfMessages) {
+			break
+		}
+		if !c.hidden[aws.ToString(message.ReceiptHandle)] {
+			c.hidden[aws.ToString(message.ReceiptHandle)] = true
+			received = append(received, message)
+		}
+	}
+	return &sqs.ReceiveMessageOutput{Messages: received}, nil
+}
+
+func (c *deadLetterClientStub) ChangeMessageVisibility(_ context.Context, input *sqs.ChangeMessageVisibilityInput, _ ...func(*sqs.Options)) (*sqs.ChangeMessageVisibilityOutput, error) {
+	if input.VisibilityTimeout == 0 {
+		delete(c.hidden, aws.ToString(input.ReceiptHandle))
+	}
+	return &sqs.ChangeMessageVisibilityOutput{}, nil
+}
+
+func (c *deadLetterClientStub) SendMessage(_ context.Context, input *sqs.SendMessageInput, _ ...func(*sqs.Options)) (*sqs.SendMessageOutput, error) {
+	if c.sendErr != nil {
+		return nil, c.sendErr
+	}
+	c.sent = append(c.sent, input)
+	return &sqs.SendMessageOutput{}, nil
+}
+
+func (c *deadLetterClientStub) DeleteMessage(_ context.Context, input *sqs.DeleteMessageInput, _ ...func(*sqs.Options)) (*sqs.DeleteMessageOutput, error) {
+	receiptHandle := aws.ToString(input.ReceiptHandle)
+	c.messages = slices.DeleteFunc(c.messages, func(message types.Message) bool {
+		return aws.ToString(message.ReceiptHandle) == receiptHandle
+	})
+	delete(c.hidden, receiptHandle)
+	return &sqs.DeleteMessageOutput{}, nil
+}
+
+func (c *deadLetterClientStub) PurgeQueue(context.Context, *sqs.PurgeQueueInput, ...func(*sqs.Options)) (*sqs.PurgeQueueOutput, error) {
+	c.messages, c.hidden = nil, make(map[string]bool)
+	return &sqs.PurgeQueueOutput{}, nil
+}
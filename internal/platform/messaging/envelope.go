@@ -45,8 +45,14 @@ type Envelope[T any] struct {
 }
 
 type snsNotification struct {
-	Type    string `json:"Type"`
-	Message string `json:"Message"`
+	Type              string                         `json:"Type"`
+	Message           string                         `json:"Message"`
+	MessageAttributes map[string]snsMessageAttribute `json:"MessageAttributes"`
+}
+
+type snsMessageAttribute struct {
+	Type  string `json:"Type"`
+	Value string `json:"Value"`
 }
 
 type rawEnvelope struct {
@@ -59,25 +65,41 @@ type rawEnvelope struct {
 	DeduplicationOptions *DeduplicationOptions `json:"deduplicationOptions"`
 }
 
+// MessageFormat is how the SQS message bodies of a queue wrap the envelope.
+type MessageFormat string
+
+const (
+	// MessageFormatSNS is an SNS notification, whose Message field holds the
+	// envelope, delivered with raw message delivery disabled.
+	MessageFormatSNS MessageFormat = "sns"
+	// MessageFormatRaw is the envelope itself, delivered by SNS with raw
+	// message delivery enabled or sent to SQS directly.
+	MessageFormatRaw MessageFormat = "raw"
+	// MessageFormatEventBridge is an EventBridge event, whose detail field
+	// holds the envelope, delivered by an EventBridge rule with an SQS target.
+	MessageFormatEventBridge MessageFormat = "eventbridge"
+	// MessageFormatAuto detects any of the other formats per message, for
+	// queues that several kinds of producer send to.
+	MessageFormatAuto MessageFormat = "auto"
+)
+
+type eventBridgeEvent struct {
+	Detail json.RawMessage `json:"detail"`
+}
+
 func DecodeSNSNotification[T any](body []byte, expectedType string) (Envelope[T], error) {
+	return DecodeMessage[T](body, MessageFormatSNS, expectedType)
+}
+
+// DecodeMessage unwraps the envelope from a message body of the given format
+// and validates it.
+func DecodeMessage[T any](body []byte, format MessageFormat, expectedType string) (Envelope[T], error) {
 	if expectedType == "" {
 		return Envelope[T]{}, errors.New("expected message type is required")
 	}
-
-	var notification snsNotification
-	if err := json.Unmarshal(body, &notification); err != nil {
-		return Envelope[T]{}, fmt.Errorf("decode SNS notification: %w", err)
-	}
-	if notification.Type != "Notification" {
-		return Envelope[T]{}, fmt.Errorf("unexpected SNS message type %q", notification.Type)
-	}
-	if notification.Message == "" {
-		return Envelope[T]{}, errors.New("SNS notification message is required")
-	}
-
-	var raw rawEnvelope
-	if err := json.Unmarshal([]byte(notification.Message), &raw); err != nil {
-		return Envelope[T]{}, fmt.Errorf("decode message envelope: %w", err)
+	raw, err := unwrapEnvelope(body, format)
+	if err != nil {
+		return Envelope[T]{}, err
 	}
 	if err := raw.validate(expectedType); err != nil {
 		return Envelope[T]{}, err
@@ -99,6 +121,88 @@ func DecodeSNSNotification[T any](body []byte, expectedType string) (Envelope[T]
 	}, nil
 }
 
+// InspectMessage unwraps the envelope like DecodeMessage but does not
+// validate it, so that tools can show messages of any type, including ones a
+// handler rejected.
+func InspectMessage(body []byte, format MessageFormat) (Envelope[json.RawMessage], error) {
+	raw, err := unwrapEnvelope(body, format)
+	if err != nil {
+		return Envelope[json.RawMessage]{}, err
+	}
+	return Envelope[json.RawMessage]{
+		ID:                   raw.ID,
+		Timestamp:            raw.Timestamp,
+		Type:                 raw.Type,
+		Payload:              raw.Payload,
+		Metadata:             raw.Metadata,
+		DeduplicationID:      raw.DeduplicationID,
+		DeduplicationOptions: raw.DeduplicationOptions,
+	}, nil
+}
+
+func unwrapEnvelope(body []byte, format MessageFormat) (rawEnvelope, error) {
+	if format == MessageFormatAuto {
+		format = detectMessageFormat(body)
+	}
+
+	var encoded []byte
+	switch format {
+	case MessageFormatSNS:
+		var notification snsNotification
+		if err := json.Unmarshal(body, &notification); err != nil {
+			return rawEnvelope{}, fmt.Errorf("decode SNS notification: %w", err)
+		}
+		if notification.Type != "Notification" {
+			return rawEnvelope{}, fmt.Errorf("unexpected SNS message type %q", notification.Type)
+		}
+		if notification.Message == "" {
+			return rawEnvelope{}, errors.New("SNS notification message is required")
+		}
+		encoded = []byte(notification.Message)
+	case MessageFormatEventBridge:
+		var event eventBridgeEvent
+		if err := json.Unmarshal(body, &event); err != nil {
+			return rawEnvelope{}, fmt.Errorf("decode EventBridge event: %w", err)
+		}
+		if len(event.Detail) == 0 || string(event.Detail) == "null" {
+			return rawEnvelope{}, errors.New("EventBridge event detail is required")
+		}
+		encoded = event.Detail
+	case MessageFormatRaw:
+		encoded = body
+	default:
+		return rawEnvelope{}, fmt.Errorf("unknown message format %q", format)
+	}
+
+	var raw rawEnvelope
+	if err := json.Unmarshal(encoded, &raw); err != nil {
+		return rawEnvelope{}, fmt.Errorf("decode message envelope: %w", err)
+	}
+	return raw, nil
+}
+
+// detectMessageFormat tells the wrappers apart by the fields only they have;
+// anything else is decoded as a raw envelope.
+func detectMessageFormat(body []byte) MessageFormat {
+	var probe struct {
+		Type       string          `json:"Type"`
+		Message    *string         `json:"Message"`
+		DetailType *string         `json:"detail-type"`
+		Detail     json.RawMessage `json:"detail"`
+	}
+	if err := json.Unmarshal(body, &probe); err != nil {
+		return MessageFormatRaw
+	}
+	switch {
+	case probe.Type == "Notification" && probe.Message != nil:
+		return MessageFormatSNS
+	case probe.DetailType != nil && len(probe.Detail) > 0:
+		return MessageFormatEventBridge
+	default:
+		return MessageFormatRaw
+	}
+}
+
 func (e rawEnvelope) validate(expectedType string) error {
 	if strings.TrimSpace(e.ID) == "" {
 		return errors.New("message ID is required")
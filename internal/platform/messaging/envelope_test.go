@@ -82,6 +82,47 @@ func TestDecodeSNSNotificationRejectsInvalidMessages(t *testing.T) {
 	}
 }
 
+func TestDecodeMessageUnwrapsEachFormat(t *testing.T) {
+	t.Parallel()
+
+	envelope := `{"id":"event-id","timestamp":"2026-07-13T07:00:00Z","type":"user.created","payload":{"userId":"user-1"},"metadata":{"schemaVersion":"1.0.0","producedBy":"users","originatedFrom":"users","correlationId":"request-id"}}`
+	eventBridge := `{"version":"0","id":"eventbridge-id","detail-type":"user.created","source":"users","detail":` + envelope + `}`
+	tests := map[string]struct {
+		body   string
+		format messaging.MessageFormat
+	}{
+		"SNS notification":     {notification(envelope), messaging.MessageFormatSNS},
+		"raw envelope":         {envelope, messaging.MessageFormatRaw},
+		"EventBridge event":    {eventBridge, messaging.MessageFormatEventBridge},
+		"detected SNS":         {notification(envelope), messaging.MessageFormatAuto},
+		"detected raw":         {envelope, messaging.MessageFormatAuto},
+		"detected EventBridge": {eventBridge, messaging.MessageFormatAuto},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			event, err := messaging.DecodeMessage[userCreatedPayload]([]byte(test.body), test.format, "user.created")
+			require.NoError(t, err)
+			assert.Equal(t, "event-id", event.ID)
+			assert.Equal(t, "user-1", event.Payload.UserID)
+		})
+	}
+}
+
+func TestDecodeMessageRejectsMismatchedFormats(t *testing.T) {
+	t.Parallel()
+
+	envelope := `{"id":"event-id","timestamp":"2026-07-13T07:00:00Z","type":"user.created","payload":{},"metadata":{"schemaVersion":"1.0.0","producedBy":"users","originatedFrom":"users","correlationId":"request-id"}}`
+	_, err := messaging.DecodeMessage[map[string]any]([]byte(notification(envelope)), messaging.MessageFormatRaw, "user.created")
+	require.ErrorContains(t, err, "message ID is required")
+	_, err = messaging.DecodeMessage[map[string]any]([]byte(envelope), messaging.MessageFormatEventBridge, "user.created")
+	require.ErrorContains(t, err, "EventBridge event detail is required")
+	_, err = messaging.DecodeMessage[map[string]any]([]byte(envelope), "cloudevents", "user.created")
+	require.ErrorContains(t, err, "unknown message format")
+}
+
 func notification(message string) string {
 	return `{"Type":"Notification","Message":` + strconv.Quote(message) + `}`
 }
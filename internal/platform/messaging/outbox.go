@@ -0,0 +1,170 @@
+package messaging
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+)
+
+const (
+	// OutboxBatchSize is the most messages one SNS PublishBatch call accepts.
+	OutboxBatchSize = 10
+	// OutboxMaxAttempts is how many failed attempts park a message, about an
+	// hour of retries. A parked message stays in the outbox but is neither
+	// published nor holds back its group.
+	OutboxMaxAttempts  = 20
+	outboxPollInterval = time.Second
+)
+
+// OutboxMessage is an encoded envelope written to the outbox in the
+// transaction that makes the change it announces. Sequence orders messages
+// by the time they were written. GroupID names the aggregate, such as a
+// user, whose messages FIFO topics deliver in order. TraceContext keeps the
+// trace of the change, so consumers link to it rather than to the relay.
+type OutboxMessage struct {
+	Sequence      int64
+	EventID       string
+	Type          string
+	SchemaVersion string
+	GroupID       string
+	Body          []byte
+	TraceContext  map[string]string
+	Attempts      int
+	CreatedAt     time.Time
+}
+
+// NewOutboxMessage encodes the envelope once, so that every delivery attempt
+// publishes the same bytes and the same event ID. The envelope's
+// deduplication ID defaults to its ID.
+func NewOutboxMessage[T any](groupID string, envelope Envelope[T]) (OutboxMessage, error) {
+	if envelope.DeduplicationID == nil {
+		envelope.DeduplicationID = &envelope.ID
+	}
+	body, err := json.Marshal(envelope)
+	if err != nil {
+		return OutboxMessage{}, fmt.Errorf("encode %s event: %w", envelope.Type, err)
+	}
+	return OutboxMessage{
+		EventID: envelope.ID, Type: envelope.Type, SchemaVersion: envelope.Metadata.SchemaVersion, GroupID: groupID, Body: body,
+	}, nil
+}
+
+// OutboxLag describes the messages still waiting to be published.
+type OutboxLag struct {
+	Pending int64
+	// OldestAge is how long the oldest pending message has waited.
+	OldestAge time.Duration
+	// MaxAttempts is the most failed attempts of any pending message.
+	MaxAttempts int
+	// Parked counts the messages that failed OutboxMaxAttempts times, which
+	// are not pending.
+	Parked int64
+}
+
+type OutboxStore interface {
+	// RelayOutbox claims up to limit due messages, oldest first, passes them
+	// to publish outside any transaction, and then deletes those published
+	// and schedules the failed ones, keyed by Sequence, for another attempt,
+	// parking those that have failed OutboxMaxAttempts times. It returns the
+	// number of messages passed to publish.
+	RelayOutbox(ctx context.Context, limit int, publish func(context.Context, []OutboxMessage) map[int64]error) (int, error)
+	OutboxLag(context.Context) (OutboxLag, error)
+}
+
+type BatchPublisher interface {
+	// PublishBatch returns the errors of the messages that were rejected,
+	// keyed by BatchMessage.ID. An error means none are known to be published.
+	PublishBatch(context.Context, []BatchMessage) (map[string]error, error)
+}
+
+type OutboxObserver interface {
+	RecordOutboxRelay(ctx context.Context, published, failed int)
+	RecordOutboxLag(context.Context, OutboxLag)
+}
+
+// OutboxRelay publishes outbox messages in batches. A message is deleted only
+// after it is published, so it is delivered at least once; a relay that stops
+// or fails to record the results after publishing repeats the batch with the
+// same event IDs once its claim ends.
+type OutboxRelay struct {
+	store     OutboxStore
+	publisher BatchPublisher
+	logger    *slog.Logger
+	observer  OutboxObserver
+	interval  time.Duration
+}
+
+func NewOutboxRelay(store OutboxStore, publisher BatchPublisher, logger *slog.Logger, observer OutboxObserver) *OutboxRelay {
+	return &OutboxRelay{store: store, publisher: publisher, logger: logger, observer: observer, interval: outboxPollInterval}
+}
+
+// Run relays full batches back to back and otherwise polls the outbox once
+// per interval until ctx ends.
+func (r *OutboxRelay) Run(ctx context.Context) {
+	for {
+		relayed, err := r.RelayBatch(ctx)
+		if err != nil && ctx.Err() == nil {
+			r.logger.ErrorContext(ctx, "relay outbox messages", "error", err)
+		}
+		if err == nil && relayed == OutboxBatchSize {
+			continue
+		}
+		r.recordLag(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(r.interval):
+		}
+	}
+}
+
+func (r *OutboxRelay) RelayBatch(ctx context.Context) (int, error) {
+	return r.store.RelayOutbox(ctx, OutboxBatchSize, func(ctx context.Context, messages []OutboxMessage) map[int64]error {
+		batch := make([]BatchMessage, len(messages))
+		for index, message := range messages {
+			batch[index] = BatchMessage{ID: strconv.FormatInt(message.Sequence, 10), Message: Message{
+				Body: message.Body, GroupID: message.GroupID, DeduplicationID: message.EventID,
+				Type: message.Type, SchemaVersion: message.SchemaVersion, TraceContext: message.TraceContext,
+			}}
+		}
+		rejected, publishErr := r.publisher.PublishBatch(ctx, batch)
+
+		failed := make(map[int64]error)
+		for index, message := range messages {
+			err := publishErr
+			if err == nil {
+				err = rejected[batch[index].ID]
+			}
+			if err == nil {
+				continue
+			}
+			failed[message.Sequence] = err
+			if message.Attempts+1 >= OutboxMaxAttempts {
+				r.logger.ErrorContext(ctx, "publish outbox message; parking it",
+					"event_id", message.EventID, "event_type", message.Type, "attempt", message.Attempts+1, "error", err)
+				continue
+			}
+			r.logger.WarnContext(ctx, "publish outbox message; retrying",
+				"event_id", message.EventID, "event_type", message.Type, "attempt", message.Attempts+1, "error", err)
+		}
+		if r.observer != nil {
+			r.observer.RecordOutboxRelay(ctx, len(messages)-len(failed), len(failed))
+		}
+		return failed
+	})
+}
+
+func (r *OutboxRelay) recordLag(ctx context.Context) {
+	if r.observer == nil || ctx.Err() != nil {
+		return
+	}
+	lag, err := r.store.OutboxLag(ctx)
+	if err != nil {
+		r.logger.WarnContext(ctx, "measure outbox lag", "error", err)
+		return
+	}
+	r.observer.RecordOutboxLag(ctx, lag)
+}
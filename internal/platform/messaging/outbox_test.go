@@ -0,0 +1,151 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewOutboxMessageEncodesEnvelopeOnce(t *testing.T) {
+	t.Parallel()
+
+	message, err := NewOutboxMessage("user-1", Envelope[map[string]string]{
+		ID: "event-1", Timestamp: time.Date(2026, time.October, 18, 12, 0, 0, 0, time.UTC), Type: "user.created",
+		Payload: map[string]string{"userId": "user-1"}, Metadata: Metadata{SchemaVersion: "1.0.0"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "event-1", message.EventID)
+	assert.Equal(t, "user.created", message.Type)
+	assert.Equal(t, "user-1", message.GroupID)
+	assert.Equal(t, "1.0.0", message.SchemaVersion)
+	assert.JSONEq(t, `{
+		"id": "event-1", "timestamp": "2026-10-18T12:00:00Z", "type": "user.created", "deduplicationId": "event-1",
+		"payload": {"userId": "user-1"},
+		"metadata": {"schemaVersion": "1.0.0", "producedBy": "", "originatedFrom": "", "correlationId": ""}
+	}`, string(message.Body))
+}
+
+func TestOutboxRelayPublishesBatchesAndRetriesFailures(t *testing.T) {
+	t.Parallel()
+
+	store := &outboxStoreStub{messages: []OutboxMessage{
+		{
+			Sequence: 7, EventID: "event-7", Type: "user.created", SchemaVersion: "1.0.0", GroupID: "user-1", Body: []byte("seven"),
+			TraceContext: map[string]string{"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"},
+		},
+		{Sequence: 8, EventID: "event-8", Body: []byte("eight"), Attempts: 2},
+	}}
+	publisher := &batchPublisherStub{rejected: map[string]error{"8": errors.New("throttled")}}
+	observer := &outboxObserverStub{}
+	relay := NewOutboxRelay(store, publisher, slog.New(slog.DiscardHandler), observer)
+
+	relayed, err := relay.RelayBatch(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, 2, relayed)
+	assert.Equal(t, OutboxBatchSize, store.limit)
+	assert.Equal(t, []BatchMessage{
+		{ID: "7", Message: Message{
+			Body: []byte("seven"), GroupID: "user-1", DeduplicationID: "event-7", Type: "user.created", SchemaVersion: "1.0.0",
+			TraceContext: map[string]string{"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"},
+		}},
+		{ID: "8", Message: Message{Body: []byte("eight"), DeduplicationID: "event-8"}},
+	}, publisher.messages)
+	require.Len(t, store.failed, 1)
+	assert.EqualError(t, store.failed[8], "throttled")
+	assert.Equal(t, [2]int{1, 1}, observer.relayed)
+
+	publisher.err = errors.New("SNS unavailable")
+	_, err = relay.RelayBatch(t.Context())
+	require.NoError(t, err)
+	assert.Len(t, store.failed, 2, "a failed call schedules every message for another attempt")
+}
+
+func TestOutboxRelayRecordsLagWhenIdle(t *testing.T) {
+	t.Parallel()
+
+	store := &outboxStoreStub{lag: OutboxLag{Pending: 4, OldestAge: time.Minute, MaxAttempts: 3}}
+	observer := &outboxObserverStub{}
+	relay := NewOutboxRelay(store, &batchPublisherStub{}, slog.New(slog.DiscardHandler), observer)
+	relay.interval = time.Hour
+
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		relay.Run(ctx)
+	}()
+	require.Eventually(t, func() bool { return observer.lagRecorded() }, time.Second, 5*time.Millisecond)
+	cancel()
+	<-done
+	assert.Equal(t, store.lag, observer.lastLag())
+}
+
+type outboxStoreStub struct {
+	messages []OutboxMessage
+	limit    int
+	failed   map[int64]error
+	lag      OutboxLag
+}
+
+func (s *outboxStoreStub) RelayOutbox(ctx context.Context, limit int, publish func(context.Context, []OutboxMessage) map[int64]error) (int, error) {
+	s.limit = limit
+	if len(s.messages) == 0 {
+		return 0, nil
+	}
+	s.failed = publish(ctx, s.messages)
+	return len(s.messages), nil
+}
+
+func (s *outboxStoreStub) OutboxLag(context.Context) (OutboxLag, error) {
+	return s.lag, nil
+}
+
+type batchPublisherStub struct {
+	messages []BatchMessage
+	rejected map[string]error
+	err      error
+}
+
+func (p *batchPublisherStub) PublishBatch(_ context.Context, messages []BatchMessage) (map[string]error, error) {
+	p.messages = messages
+	if p.err != nil {
+		return nil, p.err
+	}
+	return p.rejected, nil
+}
+
+type outboxObserverStub struct {
+	mu      sync.Mutex
+	relayed [2]int
+	lags    []OutboxLag
+}
+
+func (s *outboxObserverStub) RecordOutboxRelay(_ context.Context, published, failed int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.relayed = [2]int{published, failed}
+}
+
+func (s *outboxObserverStub) RecordOutboxLag(_ context.Context, lag OutboxLag) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lags = append(s.lags, lag)
+}
+
+func (s *outboxObserverStub) lagRecorded() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.lags) > 0
+}
+
+func (s *outboxObserverStub) lastLag() OutboxLag {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lags[len(s.lags)-1]
+}
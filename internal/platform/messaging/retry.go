@@ -0,0 +1,90 @@
+package messaging
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// sqsMaxVisibility is the longest visibility timeout SQS accepts.
+const sqsMaxVisibility = 12 * time.Hour
+
+// RetryPolicy hides a failed message for an exponentially growing delay
+// before its next attempt: BaseDelay after the first receive, doubling with
+// each receive up to MaxDelay. The zero policy leaves a failed message
+// hidden until its visibility timeout expires.
+type RetryPolicy struct {
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+}
+
+// delay returns how long to hide a message after its attempt-th receive
+// failed, and false when the policy does not change visibility.
+func (p RetryPolicy) delay(attempt int) (time.Duration, bool) {
+	if p.BaseDelay <= 0 {
+		return 0, false
+	}
+	maximum := min(max(p.MaxDelay, p.BaseDelay), sqsMaxVisibility)
+	delay := p.BaseDelay
+	for range attempt - 1 {
+		if delay >= maximum {
+			break
+		}
+		delay *= 2
+	}
+	return min(delay, maximum), true
+}
+
+// PermanentError marks a failure that redelivery cannot fix, such as a
+// message that does not decode. SQSConsumer sends the message to its
+// dead-letter queue at once instead of retrying it.
+type PermanentError struct {
+	Err error
+}
+
+func Permanent(err error) error {
+	return &PermanentError{Err: err}
+}
+
+func (e *PermanentError) Error() string {
+	return fmt.Sprintf("permanent failure: %v", e.Err)
+}
+
+func (e *PermanentError) Unwrap() error {
+	return e.Err
+}
+
+// RetryAfterError asks SQSConsumer to attempt the message again after Delay
+// rather than after the retry policy's delay, as when a dependency names how
+// long it is unavailable.
+type RetryAfterError struct {
+	Err   error
+	Delay time.Duration
+}
+
+func RetryAfter(err error, delay time.Duration) error {
+	return &RetryAfterError{Err: err, Delay: delay}
+}
+
+func (e *RetryAfterError) Error() string {
+	return fmt.Sprintf("retry after %s: %v", e.Delay, e.Err)
+}
+
+func (e *RetryAfterError) Unwrap() error {
+	return e.Err
+}
+
+func isPermanent(err error) bool {
+	var permanent *PermanentError
+	return errors.As(err, &permanent)
+}
+
+// retryDelay returns how long to hide a message whose attempt-th receive
+// failed with err, and false when its visibility should not change.
+func retryDelay(policy RetryPolicy, attempt int, err error) (time.Duration, bool) {
+	var retryAfter *RetryAfterError
+	if errors.As(err, &retryAfter) {
+		return min(max(retryAfter.Delay, 0), sqsMaxVisibility), true
+	}
+	return policy.delay(attempt)
+}
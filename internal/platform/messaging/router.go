@@ -0,0 +1,90 @@
+package messaging
+
+import (
+	"context"
+	"fmt"
+)
+
+// UnknownTypePolicy is what a Router does with messages whose type has no
+// handler.
+type UnknownTypePolicy string
+
+const (
+	// UnknownTypeAck deletes the message, for queues that receive types this
+	// service does not care about.
+	UnknownTypeAck UnknownTypePolicy = "ack"
+	// UnknownTypeRetry leaves the message for redelivery, for producers that
+	// may send a type before a deployment adds its handler.
+	UnknownTypeRetry UnknownTypePolicy = "retry"
+	// UnknownTypeDeadLetter rejects the message with a PermanentError.
+	UnknownTypeDeadLetter UnknownTypePolicy = "deadletter"
+)
+
+// unknownMessageType labels the metrics of messages without a routed type,
+// so that arbitrary producer types do not become metric attribute values.
+const unknownMessageType = "unknown"
+
+// Router dispatches messages to the handler registered for their envelope
+// type. It is a MessageHandler, so one queue and consumer serve every type.
+type Router struct {
+	format  MessageFormat
+	unknown UnknownTypePolicy
+	routes  map[string]func(context.Context, []byte) error
+}
+
+// NewRouter decodes message bodies of the given format and applies unknown
+// to messages of types without a handler.
+func NewRouter(format MessageFormat, unknown UnknownTypePolicy) *Router {
+	return &Router{format: format, unknown: unknown, routes: make(map[string]func(context.Context, []byte) error)}
+}
+
+// Route registers handle for messages of messageType, which it decodes and
+// validates as an Envelope[T]; a message that does not decode fails
+// permanently. Routes are registered at startup, so routing a type twice
+// panics.
+func Route[T any](r *Router, messageType string, handle func(context.Context, Envelope[T]) error) {
+	if _, exists := r.routes[messageType]; exists {
+		panic(fmt.Sprintf("message type %q is already routed", messageType))
+	}
+	r.routes[messageType] = func(ctx context.Context, body []byte) error {
+		event, err := DecodeMessage[T](body, r.format, messageType)
+		if err != nil {
+			return Permanent(fmt.Errorf("decode %s: %w", messageType, err))
+		}
+		return handle(ctx, event)
+	}
+}
+
+func (r *Router) Handle(ctx context.Context, body []byte) error {
+	envelope, err := InspectMessage(body, r.format)
+	if err != nil {
+		return Permanent(err)
+	}
+	route, ok := r.routes[envelope.Type]
+	if !ok {
+		switch r.unknown {
+		case UnknownTypeAck:
+			return nil
+		case UnknownTypeRetry:
+			return fmt.Errorf("no handler for message type %q", envelope.Type)
+		default:
+			return Permanent(fmt.Errorf("no handler for message type %q", envelope.Type))
+		}
+	}
+	setMessageType(ctx, envelope.Type)
+	return route(ctx, body)
+}
+
+type messageTypeKey struct{}
+
+// withMessageType lets the handler name the type of the message it handles
+// in *messageType, which SQSConsumer uses to label the message's metrics.
+func withMessageType(ctx context.Context, messageType *string) context.Context {
+	return context.WithValue(ctx, messageTypeKey{}, messageType)
+}
+
+func setMessageType(ctx context.Context, messageType string) {
+	if target, ok := ctx.Value(messageTypeKey{}).(*string); ok {
+		*target = messageType
+	}
+}
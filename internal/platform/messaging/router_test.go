@@ -0,0 +1,69 @@
+package messaging
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRouterDispatchesMessagesByType(t *testing.T) {
+	t.Parallel()
+
+	router := NewRouter(MessageFormatSNS, UnknownTypeDeadLetter)
+	var permissions []string
+	var created []Envelope[map[string]string]
+	Route(router, "permissions.changed", func(_ context.Context, event Envelope[struct{ Permissions []string }]) error {
+		permissions = event.Payload.Permissions
+		return nil
+	})
+	Route(router, "user.created", func(_ context.Context, event Envelope[map[string]string]) error {
+		created = append(created, event)
+		return nil
+	})
+	assert.Panics(t, func() {
+		Route(router, "user.created", func(context.Context, Envelope[map[string]string]) error { return nil })
+	})
+
+	var messageType string
+	ctx := withMessageType(t.Context(), &messageType)
+	require.NoError(t, router.Handle(ctx, routedBody("user.created", `{"userId":"user-1"}`)))
+	require.Len(t, created, 1)
+	assert.Equal(t, "user-1", created[0].Payload["userId"])
+	assert.Equal(t, "user.created", messageType)
+	assert.Nil(t, permissions)
+
+	require.NoError(t, router.Handle(ctx, routedBody("permissions.changed", `{"permissions":["read"]}`)))
+	assert.Equal(t, []string{"read"}, permissions)
+	assert.Equal(t, "permissions.changed", messageType)
+
+	err := router.Handle(ctx, routedBody("user.created", `null`))
+	require.ErrorContains(t, err, "decode user.created: message payload is required")
+	assert.True(t, isPermanent(err))
+	assert.True(t, isPermanent(router.Handle(ctx, []byte("not json"))))
+}
+
+func TestRouterAppliesUnknownTypePolicy(t *testing.T) {
+	t.Parallel()
+
+	body := routedBody("user.archived", `{}`)
+	require.NoError(t, NewRouter(MessageFormatSNS, UnknownTypeAck).Handle(t.Context(), body))
+
+	err := NewRouter(MessageFormatSNS, UnknownTypeRetry).Handle(t.Context(), body)
+	require.ErrorContains(t, err, `no handler for message type "user.archived"`)
+	assert.False(t, isPermanent(err))
+
+	var messageType string
+	err = NewRouter(MessageFormatSNS, UnknownTypeDeadLetter).Handle(withMessageType(t.Context(), &messageType), body)
+	require.Error(t, err)
+	assert.True(t, isPermanent(err))
+	assert.Empty(t, messageType, "unknown types do not label metrics")
+}
+
+func routedBody(messageType, payload string) []byte {
+	envelope := fmt.Sprintf(`{"id":"event-1","timestamp":"2026-10-18T12:00:00Z","type":%q,"payload":%s,`+
+		`"metadata":{"schemaVersion":"1.0.0","producedBy":"users","originatedFrom":"users","correlationId":"correlation-1"}}`, messageType, payload)
+	return fmt.Appendf(nil, `{"Type":"Notification","Message":%q}`, envelope)
+}
@@ -2,37 +2,87 @@ package messaging
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/sns"
+	"github.com/aws/aws-sdk-go-v2/service/sns/types"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Message attribute names published with every message. Subscriptions can
+// filter on them with FilterPolicyScope MessageAttributes.
+const (
+	MessageAttributeType          = "type"
+	MessageAttributeSchemaVersion = "schemaVersion"
 )
 
 type SNSPublishClient interface {
 	Publish(context.Context, *sns.PublishInput, ...func(*sns.Options)) (*sns.PublishOutput, error)
+	PublishBatch(context.Context, *sns.PublishBatchInput, ...func(*sns.Options)) (*sns.PublishBatchOutput, error)
 }
 
 type PublishObserver interface {
 	RecordMessagePublish(context.Context, time.Duration, error)
 }
 
+// Message is a message to publish. GroupID and DeduplicationID are sent only
+// to FIFO topics, which order messages within a group and drop a repeated
+// deduplication ID for five minutes. Type and SchemaVersion are published as
+// message attributes for subscription filter policies. TraceContext holds the
+// propagation fields of the span that produced the message; when it is empty
+// the publish span is propagated instead.
+type Message struct {
+	Body            []byte
+	GroupID         string
+	DeduplicationID string
+	Type            string
+	SchemaVersion   string
+	TraceContext    map[string]string
+}
+
 type SNSTopicPublisher struct {
-	client   SNSPublishClient
-	observer PublishObserver
-	topicARN string
+	client     SNSPublishClient
+	observer   PublishObserver
+	tracer     trace.Tracer
+	propagator propagation.TextMapPropagator
+	topicARN   string
+	fifo       bool
 }
 
+// NewSNSTopicPublisher publishes FIFO messages when the topic ARN ends in
+// ".fifo", which SNS requires of FIFO topics.
 func NewSNSTopicPublisher(client SNSPublishClient, topicARN string, observer PublishObserver) *SNSTopicPublisher {
-	return &SNSTopicPublisher{client: client, topicARN: topicARN, observer: observer}
+	return &SNSTopicPublisher{
+		client: client, topicARN: topicARN, observer: observer, fifo: strings.HasSuffix(topicARN, ".fifo"),
+		tracer: otel.Tracer(instrumentationName), propagator: otel.GetTextMapPropagator(),
+	}
 }
 
-func (p *SNSTopicPublisher) Publish(ctx context.Context, message []byte) error {
+func (p *SNSTopicPublisher) Publish(ctx context.Context, message Message) error {
+	groupID, deduplicationID, err := p.fifoAttributes(message)
+	if err != nil {
+		return err
+	}
+	ctx, span := p.startSpan(ctx, []Message{message})
+	defer span.End()
 	started := time.Now()
 	if _, err := p.client.Publish(ctx, &sns.PublishInput{
-		TopicArn: aws.String(p.topicARN),
-		Message:  aws.String(string(message)),
+		TopicArn:               aws.String(p.topicARN),
+		Message:                aws.String(string(message.Body)),
+		MessageGroupId:         groupID,
+		MessageDeduplicationId: deduplicationID,
+		MessageAttributes:      p.messageAttributes(ctx, message),
 	}); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "publish failed")
 		if p.observer != nil {
 			p.observer.RecordMessagePublish(ctx, time.Since(started), err)
 		}
@@ -43,3 +93,138 @@ func (p *SNSTopicPublisher) Publish(ctx context.Context, message []byte) error {
 	}
 	return nil
 }
+
+// fifoAttributes returns nil attributes for standard topics.
+func (p *SNSTopicPublisher) fifoAttributes(message Message) (*string, *string, error) {
+	if !p.fifo {
+		return nil, nil, nil
+	}
+	if message.GroupID == "" {
+		return nil, nil, errors.New("messages to a FIFO topic need a group ID")
+	}
+	var deduplicationID *string
+	if message.DeduplicationID != "" {
+		deduplicationID = aws.String(message.DeduplicationID)
+	}
+	return aws.String(message.GroupID), deduplicationID, nil
+}
+
+// startSpan starts the producer span, linked to the spans that produced
+// messages carrying their own trace context.
+func (p *SNSTopicPublisher) startSpan(ctx context.Context, messages []Message) (context.Context, trace.Span) {
+	var links []trace.Link
+	for _, message := range messages {
+		if producer := remoteSpanContext(p.propagator, message.TraceContext); producer.IsValid() {
+			links = append(links, trace.Link{SpanContext: producer})
+		}
+	}
+	return p.tracer.Start(ctx, "sns.publish",
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithLinks(links...),
+		trace.WithAttributes(
+			attribute.String("messaging.system", "aws_sns"),
+			attribute.String("messaging.destination.name", p.topicARN),
+			attribute.Int("messaging.batch.message_count", len(messages)),
+		),
+	)
+}
+
+// messageAttributes omits empty values, which SNS rejects.
+func (p *SNSTopicPublisher) messageAttributes(ctx context.Context, message Message) map[string]types.MessageAttributeValue {
+	fields := message.TraceContext
+	if len(fields) == 0 {
+		carrier := propagation.MapCarrier{}
+		p.propagator.Inject(ctx, carrier)
+		fields = carrier
+	}
+	attributes := make(map[string]types.MessageAttributeValue, len(fields)+2)
+	for name, value := range fields {
+		attributes[name] = stringAttribute(value)
+	}
+	if message.Type != "" {
+		attributes[MessageAttributeType] = stringAttribute(message.Type)
+	}
+	if message.SchemaVersion != "" {
+		attributes[MessageAttributeSchemaVersion] = stringAttribute(message.SchemaVersion)
+	}
+	for name, value := range attributes {
+		if aws.ToString(value.StringValue) == "" {
+			delete(attributes, name)
+		}
+	}
+	if len(attributes) == 0 {
+		return nil
+	}
+	return attributes
+}
+
+func stringAttribute(value string) types.MessageAttributeValue {
+	return types.MessageAttributeValue{DataType: aws.String("String"), StringValue: aws.String(value)}
+}
+
+// BatchMessage is one message of a PublishBatch call. IDs must be unique
+// within the batch.
+type BatchMessage struct {
+	ID string
+	Message
+}
+
+// PublishBatch publishes up to OutboxBatchSize messages in one request and
+// returns the errors of the entries SNS rejected, keyed by message ID. A
+// message a FIFO topic would refuse, for lack of a group ID, is rejected on its
+// own without holding up the others.
+func (p *SNSTopicPublisher) PublishBatch(ctx context.Context, messages []BatchMessage) (map[string]error, error) {
+	unwrapped := make([]Message, len(messages))
+	for index, message := range messages {
+		unwrapped[index] = message.Message
+	}
+	ctx, span := p.startSpan(ctx, unwrapped)
+	defer span.End()
+
+	rejected := make(map[string]error)
+	entries := make([]types.PublishBatchRequestEntry, 0, len(messages))
+	for _, message := range messages {
+		groupID, deduplicationID, err := p.fifoAttributes(message.Message)
+		if err != nil {
+			rejected[message.ID] = err
+			continue
+		}
+		entries = append(entries, types.PublishBatchRequestEntry{
+			Id:                     aws.String(message.ID),
+			Message:                aws.String(string(message.Body)),
+			MessageGroupId:         groupID,
+			MessageDeduplicationId: deduplicationID,
+			MessageAttributes:      p.messageAttributes(ctx, message.Message),
+		})
+	}
+	if len(entries) == 0 {
+		span.SetStatus(codes.Error, "no publishable messages")
+		return rejected, nil
+	}
+	started := time.Now()
+	output, err := p.client.PublishBatch(ctx, &sns.PublishBatchInput{
+		TopicArn:                   aws.String(p.topicARN),
+		PublishBatchRequestEntries: entries,
+	})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "publish failed")
+		if p.observer != nil {
+			p.observer.RecordMessagePublish(ctx, time.Since(started), err)
+		}
+		return nil, fmt.Errorf("publish SNS batch: %w", err)
+	}
+
+	for _, entry := range output.Failed {
+		rejected[aws.ToString(entry.Id)] = fmt.Errorf("SNS rejected message: %s: %s", aws.ToString(entry.Code), aws.ToString(entry.Message))
+	}
+	var observed error
+	if len(rejected) > 0 {
+		observed = fmt.Errorf("SNS rejected %d of %d messages", len(rejected), len(messages))
+		span.SetStatus(codes.Error, observed.Error())
+	}
+	if p.observer != nil {
+		p.observer.RecordMessagePublish(ctx, time.Since(started), observed)
+	}
+	return rejected, nil
+}
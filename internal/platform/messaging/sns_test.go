@@ -6,9 +6,14 @@ import (
 	"testing"
 	"time"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/sns"
+	"github.com/aws/aws-sdk-go-v2/service/sns/types"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 )
 
 func TestSNSTopicPublisher(t *testing.T) {
@@ -17,25 +22,127 @@ func TestSNSTopicPublisher(t *testing.T) {
 	client := &snsPublishClientStub{}
 	observer := &messagingObserverStub{}
 	publisher := NewSNSTopicPublisher(client, "arn:aws:sns:eu-west-1:123456789012:user-events", observer)
-	require.NoError(t, publisher.Publish(t.Context(), []byte(`{"type":"user.created"}`)))
+	require.NoError(t, publisher.Publish(t.Context(), Message{Body: []byte(`{"type":"user.created"}`), GroupID: "user-1"}))
 	require.NotNil(t, client.input)
 	assert.Equal(t, "arn:aws:sns:eu-west-1:123456789012:user-events", *client.input.TopicArn)
 	assert.JSONEq(t, `{"type":"user.created"}`, *client.input.Message)
+	assert.Nil(t, client.input.MessageGroupId, "standard topics reject message group IDs")
 	assert.Equal(t, 1, observer.publishCalls)
 }
 
+func TestSNSTopicPublisherSetsFIFOAttributes(t *testing.T) {
+	t.Parallel()
+
+	client := &snsPublishClientStub{}
+	publisher := NewSNSTopicPublisher(client, "arn:aws:sns:eu-west-1:123456789012:user-events.fifo", nil)
+	require.NoError(t, publisher.Publish(t.Context(), Message{Body: []byte("created"), GroupID: "user-1", DeduplicationID: "event-1"}))
+	assert.Equal(t, "user-1", aws.ToString(client.input.MessageGroupId))
+	assert.Equal(t, "event-1", aws.ToString(client.input.MessageDeduplicationId))
+
+	_, err := publisher.PublishBatch(t.Context(), []BatchMessage{
+		{ID: "1", Message: Message{Body: []byte("updated"), GroupID: "user-1", DeduplicationID: "event-2"}},
+	})
+	require.NoError(t, err)
+	entry := client.batchInput.PublishBatchRequestEntries[0]
+	assert.Equal(t, "user-1", aws.ToString(entry.MessageGroupId))
+	assert.Equal(t, "event-2", aws.ToString(entry.MessageDeduplicationId))
+
+	err = publisher.Publish(t.Context(), Message{Body: []byte("ungrouped")})
+	require.ErrorContains(t, err, "group ID")
+
+	rejected, err := publisher.PublishBatch(t.Context(), []BatchMessage{
+		{ID: "1", Message: Message{Body: []byte("ungrouped")}},
+		{ID: "2", Message: Message{Body: []byte("deleted"), GroupID: "user-1"}},
+	})
+	require.NoError(t, err)
+	require.Len(t, rejected, 1)
+	require.ErrorContains(t, rejected["1"], "group ID")
+	require.Len(t, client.batchInput.PublishBatchRequestEntries, 1)
+	assert.Equal(t, "2", aws.ToString(client.batchInput.PublishBatchRequestEntries[0].Id))
+
+	client.batchInput = nil
+	rejected, err = publisher.PublishBatch(t.Context(), []BatchMessage{{ID: "1", Message: Message{Body: []byte("ungrouped")}}})
+	require.NoError(t, err)
+	assert.Len(t, rejected, 1)
+	assert.Nil(t, client.batchInput, "a batch without publishable messages is not sent")
+}
+
+func TestSNSTopicPublisherPublishesFilterAndTraceAttributes(t *testing.T) {
+	t.Parallel()
+
+	recorder := tracetest.NewSpanRecorder()
+	tracer := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test")
+	client := &snsPublishClientStub{}
+	publisher := NewSNSTopicPublisher(client, "topic", nil)
+	publisher.tracer, publisher.propagator = tracer, propagation.TraceContext{}
+	ctx, request := tracer.Start(t.Context(), "request")
+
+	require.NoError(t, publisher.Publish(ctx, Message{Body: []byte("created"), Type: "user.created", SchemaVersion: "1.0.0"}))
+	attributes := client.input.MessageAttributes
+	assert.Equal(t, "user.created", aws.ToString(attributes[MessageAttributeType].StringValue))
+	assert.Equal(t, "1.0.0", aws.ToString(attributes[MessageAttributeSchemaVersion].StringValue))
+	producer := remoteSpanContext(propagation.TraceContext{}, map[string]string{
+		"traceparent": aws.ToString(attributes["traceparent"].StringValue),
+	})
+	assert.Equal(t, request.SpanContext().TraceID(), producer.TraceID())
+	assert.NotEqual(t, request.SpanContext().SpanID(), producer.SpanID(), "consumers link to the publish span")
+
+	const stored = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	_, err := publisher.PublishBatch(ctx, []BatchMessage{
+		{ID: "1", Message: Message{Body: []byte("updated"), TraceContext: map[string]string{"traceparent": stored}}},
+	})
+	require.NoError(t, err)
+	entry := client.batchInput.PublishBatchRequestEntries[0]
+	assert.Equal(t, stored, aws.ToString(entry.MessageAttributes["traceparent"].StringValue))
+	assert.NotContains(t, entry.MessageAttributes, MessageAttributeType, "empty attributes are omitted")
+	request.End()
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 3)
+	assert.Equal(t, "sns.publish", spans[1].Name())
+	require.Len(t, spans[1].Links(), 1)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", spans[1].Links()[0].SpanContext.TraceID().String())
+}
+
 func TestSNSTopicPublisherPreservesErrors(t *testing.T) {
 	t.Parallel()
 
 	want := errors.New("SNS unavailable")
 	publisher := NewSNSTopicPublisher(&snsPublishClientStub{err: want}, "topic", nil)
-	err := publisher.Publish(t.Context(), []byte("message"))
+	err := publisher.Publish(t.Context(), Message{Body: []byte("message")})
+	require.ErrorIs(t, err, want)
+}
+
+func TestSNSTopicPublisherPublishesBatchesAndReportsRejectedEntries(t *testing.T) {
+	t.Parallel()
+
+	client := &snsPublishClientStub{batchFailed: []types.BatchResultErrorEntry{
+		{Id: aws.String("2"), Code: aws.String("InternalError"), Message: aws.String("try again")},
+	}}
+	observer := &messagingObserverStub{}
+	publisher := NewSNSTopicPublisher(client, "topic", observer)
+	rejected, err := publisher.PublishBatch(t.Context(), []BatchMessage{
+		{ID: "1", Message: Message{Body: []byte("first")}}, {ID: "2", Message: Message{Body: []byte("second")}},
+	})
+	require.NoError(t, err)
+	require.NotNil(t, client.batchInput)
+	assert.Equal(t, "topic", *client.batchInput.TopicArn)
+	require.Len(t, client.batchInput.PublishBatchRequestEntries, 2)
+	assert.Equal(t, "first", *client.batchInput.PublishBatchRequestEntries[0].Message)
+	require.Len(t, rejected, 1)
+	assert.ErrorContains(t, rejected["2"], "InternalError")
+	assert.Equal(t, 1, observer.publishCalls)
+
+	want := errors.New("SNS unavailable")
+	_, err = NewSNSTopicPublisher(&snsPublishClientStub{err: want}, "topic", nil).PublishBatch(t.Context(), []BatchMessage{{ID: "1"}})
 	require.ErrorIs(t, err, want)
 }
 
 type snsPublishClientStub struct {
-	input *sns.PublishInput
-	err   error
+	input       *sns.PublishInput
+	batchInput  *sns.PublishBatchInput
+	batchFailed []types.BatchResultErrorEntry
+	err         error
 }
 
 func (c *snsPublishClientStub) Publish(_ context.Context, input *sns.PublishInput, _ ...func(*sns.Options)) (*sns.PublishOutput, error) {
@@ -43,6 +150,14 @@ func (c *snsPublishClientStub) Publish(_ context.Context, input *sns.PublishInpu
 	return &sns.PublishOutput{}, c.err
 }
 
+func (c *snsPublishClientStub) PublishBatch(_ context.Context, input *sns.PublishBatchInput, _ ...func(*sns.Options)) (*sns.PublishBatchOutput, error) {
+	c.batchInput = input
+	if c.err != nil {
+		return nil, c.err
+	}
+	return &sns.PublishBatchOutput{Failed: c.batchFailed}, nil
+}
+
 type messagingObserverStub struct {
 	publishCalls int
 	processes    []MessageProcess
@@ -2,6 +2,7 @@ package messaging
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"log/slog"
@@ -18,28 +19,36 @@ import (
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
 	"go.opentelemetry.io/otel/trace"
 )
 
 const (
 	sqsConcurrency       = 10
-	sqsVisibilitySeconds = 120
-	sqsLeaseRefresh      = 30 * time.Second
+	sqsVisibilityTimeout = 120 * time.Second
+	sqsWaitTime          = 20 * time.Second
+	// sqsMaxWaitTime and sqsMaxReceive are the SQS limits of one receive.
+	sqsMaxWaitTime = 20 * time.Second
+	sqsMaxReceive  = 10
 )
 
 var ErrVisibilityLeaseLost = errors.New("SQS visibility lease lost")
 
 type SQSClient interface {
 	ReceiveMessage(context.Context, *sqs.ReceiveMessageInput, ...func(*sqs.Options)) (*sqs.ReceiveMessageOutput, error)
-	DeleteMessage(context.Context, *sqs.DeleteMessageInput, ...func(*sqs.Options)) (*sqs.DeleteMessageOutput, error)
-	ChangeMessageVisibility(context.Context, *sqs.ChangeMessageVisibilityInput, ...func(*sqs.Options)) (*sqs.ChangeMessageVisibilityOutput, error)
+	DeleteMessageBatch(context.Context, *sqs.DeleteMessageBatchInput, ...func(*sqs.Options)) (*sqs.DeleteMessageBatchOutput, error)
+	ChangeMessageVisibilityBatch(context.Context, *sqs.ChangeMessageVisibilityBatchInput, ...func(*sqs.Options)) (*sqs.ChangeMessageVisibilityBatchOutput, error)
+	SendMessage(context.Context, *sqs.SendMessageInput, ...func(*sqs.Options)) (*sqs.SendMessageOutput, error)
 }
 
 type MessageHandler interface {
 	Handle(context.Context, []byte) error
 }
 
+// MessageProcess describes one handled message. Type is the envelope type a
+// Router dispatched the message on, or "unknown".
 type MessageProcess struct {
+	Type     string
 	Duration time.Duration
 	QueueAge time.Duration
 	Attempt  int
@@ -52,22 +61,66 @@ type ConsumerObserver interface {
 	AddMessagesInFlight(context.Context, int64)
 }
 
+// SQSConsumerOptions tunes a consumer to its queue and handler. Zero fields
+// take the defaults: ten messages at a time, a 120 second visibility timeout,
+// and 20 second long polls.
+type SQSConsumerOptions struct {
+	// Concurrency is how many messages are handled at once.
+	Concurrency int
+	// VisibilityTimeout hides a received message from other receivers. The
+	// consumer extends it every quarter of the timeout while handling.
+	VisibilityTimeout time.Duration
+	// WaitTime is how long a receive waits for messages, at most 20 seconds.
+	WaitTime time.Duration
+	// Retry delays the next attempt of a failed message.
+	Retry RetryPolicy
+	// DeadLetterQueueURL receives messages that fail permanently. Without
+	// it, they are made visible again at once, so that the queue's redrive
+	// policy moves them after its maximum receive count.
+	DeadLetterQueueURL string
+}
+
 type SQSConsumer struct {
 	client       SQSClient
 	handler      MessageHandler
 	logger       *slog.Logger
 	observer     ConsumerObserver
 	queueURL     string
+	concurrency  int
+	visibility   int32
+	waitTime     int32
+	retry        RetryPolicy
+	deadLetter   string
+	deletes      *sqsBatcher
+	changes      *sqsBatcher
+	tracer       trace.Tracer
+	propagator   propagation.TextMapPropagator
 	leaseRefresh time.Duration
 	backoff      func(int) time.Duration
 	inFlight     sync.WaitGroup
 }
 
-func NewSQSConsumer(client SQSClient, queueURL string, handler MessageHandler, logger *slog.Logger, observer ConsumerObserver) *SQSConsumer {
-	return &SQSConsumer{
+func NewSQSConsumer(client SQSClient, queueURL string, handler MessageHandler, logger *slog.Logger, observer ConsumerObserver, options SQSConsumerOptions) *SQSConsumer {
+	if options.Concurrency <= 0 {
+		options.Concurrency = sqsConcurrency
+	}
+	if options.VisibilityTimeout <= 0 {
+		options.VisibilityTimeout = sqsVisibilityTimeout
+	}
+	if options.WaitTime <= 0 {
+		options.WaitTime = sqsWaitTime
+	}
+	consumer := &SQSConsumer{
 		client: client, queueURL: queueURL, handler: handler, logger: logger, observer: observer,
-		leaseRefresh: sqsLeaseRefresh, backoff: receiveBackoff,
+		concurrency: options.Concurrency, retry: options.Retry, deadLetter: options.DeadLetterQueueURL,
+		visibility: int32(options.VisibilityTimeout / time.Second), //nolint:gosec // SQS caps visibility at 12 hours
+		waitTime:   int32(min(options.WaitTime, sqsMaxWaitTime) / time.Second),
+		tracer:     otel.Tracer(instrumentationName), propagator: otel.GetTextMapPropagator(),
+		leaseRefresh: options.VisibilityTimeout / 4, backoff: receiveBackoff,
 	}
+	consumer.deletes = newSQSBatcher(consumer.deleteBatch)
+	consumer.changes = newSQSBatcher(consumer.changeVisibilityBatch)
+	return consumer
 }
 
 func (c *SQSConsumer) Run(ctx context.Context) error {
@@ -77,7 +130,7 @@ func (c *SQSConsumer) Run(ctx context.Context) error {
 }
 
 func (c *SQSConsumer) RunWithHandlerContext(receiveCtx, handlerCtx context.Context) error {
-	semaphore := make(chan struct{}, sqsConcurrency)
+	semaphore := make(chan struct{}, c.concurrency)
 	slotAvailable := make(chan struct{}, 1)
 	consecutiveFailures := 0
 	fatalCode := ""
@@ -94,13 +147,15 @@ func (c *SQSConsumer) RunWithHandlerContext(receiveCtx, handlerCtx context.Conte
 		}
 
 		messages, err := c.client.ReceiveMessage(receiveCtx, &sqs.ReceiveMessageInput{
-			QueueUrl:            aws.String(c.queueURL),
-			MaxNumberOfMessages: int32(available), //nolint:gosec // available is capped at ten
-			WaitTimeSeconds:     20,
-			VisibilityTimeout:   sqsVisibilitySeconds,
+			QueueUrl:              aws.String(c.queueURL),
+			MaxNumberOfMessages:   int32(min(available, sqsMaxReceive)), //nolint:gosec // capped at ten
+			WaitTimeSeconds:       c.waitTime,
+			VisibilityTimeout:     c.visibility,
+			MessageAttributeNames: []string{"All"},
 			MessageSystemAttributeNames: []types.MessageSystemAttributeName{
 				types.MessageSystemAttributeNameApproximateReceiveCount,
 				types.MessageSystemAttributeNameSentTimestamp,
+				types.MessageSystemAttributeNameMessageGroupId,
 			},
 		})
 		if err != nil {
@@ -175,15 +230,19 @@ func (c *SQSConsumer) Wait(ctx context.Context) error {
 }
 
 func (c *SQSConsumer) handle(parent context.Context, message types.Message) {
-	parent, span := otel.Tracer("github.com/your-org/go-service-template/internal/platform/messaging").Start(
-		parent,
-		"sqs.process",
+	options := []trace.SpanStartOption{
 		trace.WithSpanKind(trace.SpanKindConsumer),
 		trace.WithAttributes(attribute.Int("messaging.message.receive_count", receiveCount(message))),
-	)
+	}
+	if producer := remoteSpanContext(c.propagator, messageTraceContext(message)); producer.IsValid() {
+		options = append(options, trace.WithLinks(trace.Link{SpanContext: producer}))
+	}
+	parent, span := c.tracer.Start(parent, "sqs.process", options...)
 	defer span.End()
 	started := time.Now()
-	process := MessageProcess{Attempt: receiveCount(message), QueueAge: queueAge(message), Outcome: "failed"}
+	process := MessageProcess{
+		Type: unknownMessageType, Attempt: receiveCount(message), QueueAge: queueAge(message), Outcome: "failed",
+	}
 	if c.observer != nil {
 		c.observer.AddMessagesInFlight(parent, 1)
 		defer c.observer.AddMessagesInFlight(parent, -1)
@@ -200,17 +259,19 @@ func (c *SQSConsumer) handle(parent context.Context, message types.Message) {
 		return
 	}
 
-	handlerCtx, cancelHandler := context.WithCancelCause(parent)
+	handlerCtx, cancelHandler := context.WithCancelCause(withMessageType(parent, &process.Type))
 	defer cancelHandler(nil)
 	leaseCtx, cancelLease := context.WithCancel(handlerCtx)
 	leaseErrors := make(chan error, 1)
+	var refreshes sync.WaitGroup
 	go func() {
-		leaseErrors <- c.refreshLease(leaseCtx, cancelHandler, message)
+		leaseErrors <- c.refreshLease(leaseCtx, cancelHandler, message, &refreshes)
 	}()
 
 	handlerErr := c.handler.Handle(handlerCtx, []byte(*message.Body))
 	cancelLease()
 	leaseErr := <-leaseErrors
+	span.SetAttributes(attribute.String("messaging.message.type", process.Type))
 	if leaseErr != nil {
 		span.RecordError(leaseErr)
 		span.SetStatus(codes.Error, "visibility lease lost")
@@ -218,22 +279,34 @@ func (c *SQSConsumer) handle(parent context.Context, message types.Message) {
 		c.logger.ErrorContext(parent, "SQS visibility lease lost", "error", leaseErr, "message_id", messageID)
 		return
 	}
+	if handlerErr != nil {
+		// A refresh sent after the change below would overwrite its visibility.
+		refreshes.Wait()
+	}
+	if isPermanent(handlerErr) {
+		span.RecordError(handlerErr)
+		span.SetStatus(codes.Error, "message rejected")
+		process.Outcome = "rejected"
+		c.logger.WarnContext(parent, "SQS message rejected", "error", handlerErr, "message_id", messageID, "receive_count", process.Attempt)
+		c.reject(parent, message)
+		return
+	}
 	if handlerErr != nil {
 		span.RecordError(handlerErr)
 		span.SetStatus(codes.Error, "message processing failed")
-		c.logger.WarnContext(parent, "SQS message processing failed",
-			"error", handlerErr,
-			"message_id", messageID,
-			"receive_count", process.Attempt,
-		)
+		fields := []any{"error", handlerErr, "message_id", messageID, "receive_count", process.Attempt}
+		delay, ok := retryDelay(c.retry, process.Attempt, handlerErr)
+		if ok && parent.Err() == nil {
+			fields = append(fields, "retry_in", delay)
+			c.hide(parent, message, delay)
+		}
+		c.logger.WarnContext(parent, "SQS message processing failed", fields...)
 		return
 	}
 	if parent.Err() != nil {
 		return
 	}
-	if _, err := c.client.DeleteMessage(parent, &sqs.DeleteMessageInput{
-		QueueUrl: aws.String(c.queueURL), ReceiptHandle: message.ReceiptHandle,
-	}); err != nil {
+	if err := c.deletes.do(parent, sqsBatchRequest{receiptHandle: *message.ReceiptHandle}); err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, "message acknowledgement failed")
 		process.Outcome = "ack_failed"
@@ -243,7 +316,46 @@ func (c *SQSConsumer) handle(parent context.Context, message types.Message) {
 	process.Outcome = "success"
 }
 
-func (c *SQSConsumer) refreshLease(ctx context.Context, cancelHandler context.CancelCauseFunc, message types.Message) error {
+// reject sends a permanently failed message to the dead-letter queue with its
+// body and attributes and deletes it. Without a dead-letter queue, or when the
+// send fails, it makes the message visible again at once instead.
+func (c *SQSConsumer) reject(ctx context.Context, message types.Message) {
+	messageID := aws.ToString(message.MessageId)
+	if c.deadLetter == "" {
+		c.hide(ctx, message, 0)
+		return
+	}
+	input := &sqs.SendMessageInput{
+		QueueUrl: aws.String(c.deadLetter), MessageBody: message.Body, MessageAttributes: message.MessageAttributes,
+	}
+	if groupID := message.Attributes[string(types.MessageSystemAttributeNameMessageGroupId)]; groupID != "" {
+		input.MessageGroupId = aws.String(groupID)
+		input.MessageDeduplicationId = message.MessageId
+	}
+	if _, err := c.client.SendMessage(ctx, input); err != nil {
+		c.logger.WarnContext(ctx, "send SQS message to dead-letter queue failed", "error", err, "message_id", messageID)
+		c.hide(ctx, message, 0)
+		return
+	}
+	if err := c.deletes.do(ctx, sqsBatchRequest{receiptHandle: *message.ReceiptHandle}); err != nil {
+		c.logger.WarnContext(ctx, "delete dead-lettered SQS message failed; it will be redelivered",
+			"error", fmt.Errorf("delete message: %w", err), "message_id", messageID)
+	}
+}
+
+// hide sets how long the message stays invisible before its next receive. A
+// failed change leaves the current visibility timeout in place.
+func (c *SQSConsumer) hide(ctx context.Context, message types.Message, delay time.Duration) {
+	visibility := int32(delay.Round(time.Second) / time.Second) //nolint:gosec // delays are capped at 12 hours
+	if err := c.changes.do(ctx, sqsBatchRequest{receiptHandle: *message.ReceiptHandle, visibility: visibility}); err != nil {
+		c.logger.WarnContext(ctx, "change SQS message visibility failed", "error", err, "message_id", aws.ToString(message.MessageId))
+	}
+}
+
+// refreshLease extends the message's visibility until ctx ends. A refresh
+// still queued or in flight when ctx ends is tracked in pending until its
+// batch has been sent.
+func (c *SQSConsumer) refreshLease(ctx context.Context, cancelHandler context.CancelCauseFunc, message types.Message, pending *sync.WaitGroup) error {
 	ticker := time.NewTicker(c.leaseRefresh)
 	defer ticker.Stop()
 	for {
@@ -252,9 +364,14 @@ func (c *SQSConsumer) refreshLease(ctx context.Context, cancelHandler context.Ca
 			return nil
 		case <-ticker.C:
 			requestCtx, cancelRequest := context.WithTimeout(ctx, 10*time.Second)
-			_, err := c.client.ChangeMessageVisibility(requestCtx, &sqs.ChangeMessageVisibilityInput{
-				QueueUrl: aws.String(c.queueURL), ReceiptHandle: message.ReceiptHandle, VisibilityTimeout: sqsVisibilitySeconds,
-			})
+			result := c.changes.enqueue(sqsBatchRequest{receiptHandle: *message.ReceiptHandle, visibility: c.visibility})
+			var err error
+			select {
+			case err = <-result:
+			case <-requestCtx.Done():
+				err = requestCtx.Err()
+				pending.Go(func() { <-result })
+			}
 			cancelRequest()
 			if err != nil {
 				if ctx.Err() != nil {
@@ -268,6 +385,29 @@ func (c *SQSConsumer) refreshLease(ctx context.Context, cancelHandler context.Ca
 	}
 }
 
+// messageTraceContext reads the propagation fields from the SQS message
+// attributes of raw deliveries and from the SNS notification's message
+// attributes otherwise.
+func messageTraceContext(message types.Message) map[string]string {
+	fields := make(map[string]string)
+	for name, value := range message.MessageAttributes {
+		if value.StringValue != nil {
+			fields[name] = *value.StringValue
+		}
+	}
+	if message.Body != nil {
+		var notification snsNotification
+		if json.Unmarshal([]byte(*message.Body), &notification) == nil {
+			for name, value := range notification.MessageAttributes {
+				if value.Type == "String" {
+					fields[name] = value.Value
+				}
+			}
+		}
+	}
+	return fields
+}
+
 func receiveCount(message types.Message) int {
 	value := message.Attributes[string(types.MessageSystemAttributeNameApproximateReceiveCount)]
 	count, err := strconv.Atoi(value)
@@ -3,10 +3,12 @@ package messaging
 import (
 	"context"
 	"errors"
+	"fmt"
 	"io"
 	"log/slog"
 	"sync"
 	"testing"
+	"testing/synctest"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -15,6 +17,9 @@ import (
 	"github.com/aws/smithy-go"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 )
 
 func TestSQSConsumerDeletesMessageAfterSuccessfulHandling(t *testing.T) {
@@ -30,17 +35,79 @@ func TestSQSConsumerDeletesMessageAfterSuccessfulHandling(t *testing.T) {
 	})
 	client.afterDelete = cancel
 
-	err := NewSQSConsumer(client, "queue-url", handler, discardLogger(), nil).Run(ctx)
+	err := NewSQSConsumer(client, "queue-url", handler, discardLogger(), nil, SQSConsumerOptions{}).Run(ctx)
 	require.NoError(t, err)
 	require.NotNil(t, client.receiveInput)
 	assert.Equal(t, int32(10), client.receiveInput.MaxNumberOfMessages)
 	assert.Equal(t, int32(20), client.receiveInput.WaitTimeSeconds)
 	assert.Equal(t, int32(120), client.receiveInput.VisibilityTimeout)
+	assert.Equal(t, []string{"All"}, client.receiveInput.MessageAttributeNames)
 	require.NotNil(t, client.deleteInput)
 	assert.Equal(t, "queue-url", aws.ToString(client.deleteInput.QueueUrl))
 	assert.Equal(t, "receipt-1", aws.ToString(client.deleteInput.ReceiptHandle))
 }
 
+func TestSQSConsumerAppliesQueueOptions(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(t.Context())
+	client := &sqsClientStub{
+		message:       types.Message{Body: aws.String(`{}`), MessageId: aws.String("message-1"), ReceiptHandle: aws.String("receipt-1")},
+		changeStarted: make(chan struct{}),
+		blockChange:   true,
+		afterDelete:   cancel,
+	}
+	handler := messageHandlerFunc(func(context.Context, []byte) error {
+		<-client.changeStarted
+		return nil
+	})
+	consumer := NewSQSConsumer(client, "queue-url", handler, discardLogger(), nil, SQSConsumerOptions{
+		Concurrency: 25, VisibilityTimeout: 8 * time.Second, WaitTime: 5 * time.Second,
+	})
+	assert.Equal(t, 2*time.Second, consumer.leaseRefresh)
+	consumer.leaseRefresh = time.Millisecond
+
+	require.NoError(t, consumer.Run(ctx))
+	require.NotNil(t, client.receiveInput)
+	assert.Equal(t, int32(10), client.receiveInput.MaxNumberOfMessages, "a receive returns at most ten messages")
+	assert.Equal(t, int32(5), client.receiveInput.WaitTimeSeconds)
+	assert.Equal(t, int32(8), client.receiveInput.VisibilityTimeout)
+	require.NotNil(t, client.changeInput)
+	assert.Equal(t, int32(8), client.changeInput.VisibilityTimeout)
+}
+
+func TestSQSConsumerLinksProcessSpanToProducer(t *testing.T) {
+	t.Parallel()
+
+	const traceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	for name, message := range map[string]types.Message{
+		"SNS notification": {Body: aws.String(`{"Type":"Notification","Message":"{}","MessageAttributes":{
+			"traceparent":{"Type":"String","Value":"` + traceparent + `"}}}`)},
+		"raw delivery": {Body: aws.String(`{}`), MessageAttributes: map[string]types.MessageAttributeValue{
+			"traceparent": {DataType: aws.String("String"), StringValue: aws.String(traceparent)},
+		}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			recorder := tracetest.NewSpanRecorder()
+			consumer := NewSQSConsumer(&sqsClientStub{}, "queue-url", messageHandlerFunc(func(context.Context, []byte) error {
+				return nil
+			}), discardLogger(), nil, SQSConsumerOptions{})
+			consumer.tracer = sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test")
+			consumer.propagator = propagation.TraceContext{}
+			message.MessageId, message.ReceiptHandle = aws.String("message-1"), aws.String("receipt-1")
+			consumer.handle(t.Context(), message)
+
+			spans := recorder.Ended()
+			require.Len(t, spans, 1)
+			require.Len(t, spans[0].Links(), 1)
+			assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", spans[0].Links()[0].SpanContext.TraceID().String())
+			assert.Equal(t, "00f067aa0ba902b7", spans[0].Links()[0].SpanContext.SpanID().String())
+		})
+	}
+}
+
 func TestSQSConsumerLeavesFailedMessageForRedelivery(t *testing.T) {
 	t.Parallel()
 
@@ -53,11 +120,102 @@ func TestSQSConsumerLeavesFailedMessageForRedelivery(t *testing.T) {
 		return errors.New("processing failed")
 	})
 
-	err := NewSQSConsumer(client, "queue-url", handler, discardLogger(), nil).Run(ctx)
+	err := NewSQSConsumer(client, "queue-url", handler, discardLogger(), nil, SQSConsumerOptions{}).Run(ctx)
 	require.NoError(t, err)
 	assert.Nil(t, client.deleteInput)
 }
 
+func TestSQSConsumerReleasesRejectedMessage(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(t.Context())
+	client := &sqsClientStub{
+		message: types.Message{
+			Body: aws.String(string(routedBody("user.archived", `{}`))), MessageId: aws.String("message-1"), ReceiptHandle: aws.String("receipt-1"),
+		},
+		changeCalled: make(chan struct{}, 1),
+	}
+	observer := &messagingObserverStub{}
+	consumer := NewSQSConsumer(client, "queue-url", NewRouter(MessageFormatSNS, UnknownTypeDeadLetter), discardLogger(), observer, SQSConsumerOptions{})
+	go func() {
+		<-client.changeCalled
+		cancel()
+	}()
+
+	require.NoError(t, consumer.Run(ctx))
+	assert.Nil(t, client.deleteInput)
+	require.NotNil(t, client.changeInput)
+	assert.Equal(t, "receipt-1", aws.ToString(client.changeInput.ReceiptHandle))
+	assert.Equal(t, int32(0), client.changeInput.VisibilityTimeout)
+	require.Len(t, observer.processes, 1)
+	assert.Equal(t, "rejected", observer.processes[0].Outcome)
+	assert.Equal(t, "unknown", observer.processes[0].Type)
+}
+
+func TestSQSConsumerSendsPermanentFailuresToDeadLetterQueue(t *testing.T) {
+	t.Parallel()
+
+	client := &sqsClientStub{}
+	observer := &messagingObserverStub{}
+	consumer := NewSQSConsumer(client, "queue-url", messageHandlerFunc(func(context.Context, []byte) error {
+		return fmt.Errorf("apply change: %w", Permanent(errors.New("invalid user ID")))
+	}), discardLogger(), observer, SQSConsumerOptions{DeadLetterQueueURL: "dlq-url", Retry: RetryPolicy{BaseDelay: time.Second}})
+	consumer.handle(t.Context(), types.Message{
+		Body: aws.String(`{"id":"event-1"}`), MessageId: aws.String("message-1"), ReceiptHandle: aws.String("receipt-1"),
+		Attributes: map[string]string{
+			string(types.MessageSystemAttributeNameApproximateReceiveCount): "1",
+			string(types.MessageSystemAttributeNameMessageGroupId):          "user-1",
+		},
+		MessageAttributes: map[string]types.MessageAttributeValue{
+			"type": {DataType: aws.String("String"), StringValue: aws.String("permissions.changed")},
+		},
+	})
+
+	require.NotNil(t, client.sendInput)
+	assert.Equal(t, "dlq-url", aws.ToString(client.sendInput.QueueUrl))
+	assert.JSONEq(t, `{"id":"event-1"}`, aws.ToString(client.sendInput.MessageBody))
+	assert.Equal(t, "permissions.changed", aws.ToString(client.sendInput.MessageAttributes["type"].StringValue))
+	assert.Equal(t, "user-1", aws.ToString(client.sendInput.MessageGroupId))
+	assert.Equal(t, "message-1", aws.ToString(client.sendInput.MessageDeduplicationId))
+	require.NotNil(t, client.deleteInput, "the message leaves the source queue after its first receive")
+	assert.Nil(t, client.changeInput)
+	require.Len(t, observer.processes, 1)
+	assert.Equal(t, "rejected", observer.processes[0].Outcome)
+}
+
+func TestSQSConsumerBacksOffFailedMessages(t *testing.T) {
+	t.Parallel()
+
+	for name, test := range map[string]struct {
+		receiveCount string
+		err          error
+		visibility   int32
+	}{
+		"first attempt":     {receiveCount: "1", err: errors.New("database unavailable"), visibility: 5},
+		"third attempt":     {receiveCount: "3", err: errors.New("database unavailable"), visibility: 20},
+		"capped":            {receiveCount: "30", err: errors.New("database unavailable"), visibility: 60},
+		"retry after delay": {receiveCount: "1", err: RetryAfter(errors.New("rate limited"), 90*time.Second), visibility: 90},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			client := &sqsClientStub{}
+			consumer := NewSQSConsumer(client, "queue-url", messageHandlerFunc(func(context.Context, []byte) error {
+				return test.err
+			}), discardLogger(), nil, SQSConsumerOptions{Retry: RetryPolicy{BaseDelay: 5 * time.Second, MaxDelay: time.Minute}})
+			consumer.handle(t.Context(), types.Message{
+				Body: aws.String(`{}`), MessageId: aws.String("message-1"), ReceiptHandle: aws.String("receipt-1"),
+				Attributes: map[string]string{string(types.MessageSystemAttributeNameApproximateReceiveCount): test.receiveCount},
+			})
+
+			require.NotNil(t, client.changeInput)
+			assert.Equal(t, test.visibility, client.changeInput.VisibilityTimeout)
+			assert.Nil(t, client.deleteInput)
+			assert.Nil(t, client.sendInput)
+		})
+	}
+}
+
 func TestSQSConsumerCancelsHandlerWhenVisibilityLeaseIsLost(t *testing.T) {
 	t.Parallel()
 
@@ -74,7 +232,7 @@ func TestSQSConsumerCancelsHandlerWhenVisibilityLeaseIsLost(t *testing.T) {
 		return handlerCtx.Err()
 	})
 	observer := &messagingObserverStub{}
-	consumer := NewSQSConsumer(client, "queue-url", handler, discardLogger(), observer)
+	consumer := NewSQSConsumer(client, "queue-url", handler, discardLogger(), observer, SQSConsumerOptions{})
 	consumer.leaseRefresh = time.Millisecond
 
 	require.NoError(t, consumer.Run(ctx))
@@ -95,7 +253,7 @@ func TestSQSConsumerRecoversAfterTransientReceiveError(t *testing.T) {
 		receiveErrors: []error{errors.New("temporary network failure")},
 		afterDelete:   cancel,
 	}
-	consumer := NewSQSConsumer(client, "queue-url", messageHandlerFunc(func(context.Context, []byte) error { return nil }), discardLogger(), nil)
+	consumer := NewSQSConsumer(client, "queue-url", messageHandlerFunc(func(context.Context, []byte) error { return nil }), discardLogger(), nil, SQSConsumerOptions{})
 	consumer.backoff = func(int) time.Duration { return 0 }
 
 	require.NoError(t, consumer.Run(ctx))
@@ -108,7 +266,7 @@ func TestSQSConsumerStopsAfterRepeatedFatalReceiveError(t *testing.T) {
 
 	fatal := &smithy.GenericAPIError{Code: "AccessDeniedException", Message: "denied", Fault: smithy.FaultClient}
 	client := &sqsClientStub{receiveErrors: []error{fatal, fatal, fatal}}
-	consumer := NewSQSConsumer(client, "queue-url", messageHandlerFunc(func(context.Context, []byte) error { return nil }), discardLogger(), nil)
+	consumer := NewSQSConsumer(client, "queue-url", messageHandlerFunc(func(context.Context, []byte) error { return nil }), discardLogger(), nil, SQSConsumerOptions{})
 	consumer.backoff = func(int) time.Duration { return 0 }
 
 	err := consumer.Run(t.Context())
@@ -130,7 +288,7 @@ func TestSQSConsumerReportsFatalReceiveErrorWithoutWaitingForHandler(t *testing.
 		<-release
 		return nil
 	})
-	consumer := NewSQSConsumer(client, "queue-url", handler, discardLogger(), nil)
+	consumer := NewSQSConsumer(client, "queue-url", handler, discardLogger(), nil, SQSConsumerOptions{})
 	consumer.backoff = func(int) time.Duration { return 0 }
 	errors := make(chan error, 1)
 	go func() { errors <- consumer.RunWithHandlerContext(t.Context(), t.Context()) }()
@@ -158,7 +316,7 @@ func TestSQSConsumerDoesNotReceiveBeyondAvailableCapacity(t *testing.T) {
 	receiveCtx, stopReceiving := context.WithCancel(t.Context())
 	handlerCtx, cancelHandlers := context.WithCancel(t.Context())
 	defer cancelHandlers()
-	consumer := NewSQSConsumer(client, "queue-url", handler, discardLogger(), nil)
+	consumer := NewSQSConsumer(client, "queue-url", handler, discardLogger(), nil, SQSConsumerOptions{})
 	done := make(chan error, 1)
 	go func() {
 		done <- consumer.RunWithHandlerContext(receiveCtx, handlerCtx)
@@ -188,7 +346,7 @@ func TestSQSConsumerKeepsLeaseWhileDraining(t *testing.T) {
 	})
 	receiveCtx, stopReceiving := context.WithCancel(t.Context())
 	handlerCtx, cancelHandlers := context.WithCancel(t.Context())
-	consumer := NewSQSConsumer(client, "queue-url", handler, discardLogger(), nil)
+	consumer := NewSQSConsumer(client, "queue-url", handler, discardLogger(), nil, SQSConsumerOptions{})
 	consumer.leaseRefresh = time.Millisecond
 	done := make(chan error, 1)
 	go func() { done <- consumer.RunWithHandlerContext(receiveCtx, handlerCtx) }()
@@ -216,13 +374,33 @@ func TestSQSConsumerAcknowledgesAfterCompletedHandlerCancelsRenewal(t *testing.T
 		<-client.changeStarted
 		return nil
 	})
-	consumer := NewSQSConsumer(client, "queue-url", handler, discardLogger(), nil)
+	consumer := NewSQSConsumer(client, "queue-url", handler, discardLogger(), nil, SQSConsumerOptions{})
 	consumer.leaseRefresh = time.Millisecond
 
 	require.NoError(t, consumer.Run(ctx))
 	require.NotNil(t, client.deleteInput)
 }
 
+func TestSQSConsumerWaitsForLeaseRefreshBeforeRetryDelay(t *testing.T) {
+	t.Parallel()
+
+	synctest.Test(t, func(t *testing.T) {
+		client := &slowRefreshSQSClient{refreshStarted: make(chan struct{}), overtaken: make(chan struct{})}
+		handler := messageHandlerFunc(func(context.Context, []byte) error {
+			<-client.refreshStarted
+			return errors.New("database unavailable")
+		})
+		consumer := NewSQSConsumer(client, "queue-url", handler, discardLogger(), nil, SQSConsumerOptions{
+			Retry: RetryPolicy{BaseDelay: 5 * time.Second},
+		})
+		consumer.leaseRefresh = time.Millisecond
+
+		consumer.handle(t.Context(), types.Message{Body: aws.String(`{}`), MessageId: aws.String("message-1"), ReceiptHandle: aws.String("receipt-1")})
+
+		assert.Equal(t, []int32{120, 5}, client.applied, "the retry delay is applied after the refresh")
+	})
+}
+
 type sqsClientStub struct {
 	mu            sync.Mutex
 	message       types.Message
@@ -237,6 +415,7 @@ type sqsClientStub struct {
 	changeCalled  chan struct{}
 	changeStarted chan struct{}
 	blockChange   bool
+	sendInput     *sqs.SendMessageInput
 }
 
 func (s *sqsClientStub) ReceiveMessage(ctx context.Context, input *sqs.ReceiveMessageInput, _ ...func(*sqs.Options)) (*sqs.ReceiveMessageOutput, error) {
@@ -259,16 +438,28 @@ func (s *sqsClientStub) ReceiveMessage(ctx context.Context, input *sqs.ReceiveMe
 	return nil, ctx.Err()
 }
 
-func (s *sqsClientStub) DeleteMessage(_ context.Context, input *sqs.DeleteMessageInput, _ ...func(*sqs.Options)) (*sqs.DeleteMessageOutput, error) {
-	s.deleteInput = input
+// DeleteMessageBatch records the last entry as a single delete, since the
+// stub delivers one message.
+func (s *sqsClientStub) DeleteMessageBatch(_ context.Context, input *sqs.DeleteMessageBatchInput, _ ...func(*sqs.Options)) (*sqs.DeleteMessageBatchOutput, error) {
+	output := &sqs.DeleteMessageBatchOutput{}
+	for _, entry := range input.Entries {
+		s.deleteInput = &sqs.DeleteMessageInput{QueueUrl: input.QueueUrl, ReceiptHandle: entry.ReceiptHandle}
+		output.Successful = append(output.Successful, types.DeleteMessageBatchResultEntry{Id: entry.Id})
+	}
 	if s.afterDelete != nil {
 		s.afterDelete()
 	}
-	return &sqs.DeleteMessageOutput{}, nil
+	return output, nil
 }
 
-func (s *sqsClientStub) ChangeMessageVisibility(ctx context.Context, input *sqs.ChangeMessageVisibilityInput, _ ...func(*sqs.Options)) (*sqs.ChangeMessageVisibilityOutput, error) {
-	s.changeInput = input
+func (s *sqsClientStub) ChangeMessageVisibilityBatch(ctx context.Context, input *sqs.ChangeMessageVisibilityBatchInput, _ ...func(*sqs.Options)) (*sqs.ChangeMessageVisibilityBatchOutput, error) {
+	output := &sqs.ChangeMessageVisibilityBatchOutput{}
+	for _, entry := range input.Entries {
+		s.changeInput = &sqs.ChangeMessageVisibilityInput{
+			QueueUrl: input.QueueUrl, ReceiptHandle: entry.ReceiptHandle, VisibilityTimeout: entry.VisibilityTimeout,
+		}
+		output.Successful = append(output.Successful, types.ChangeMessageVisibilityBatchResultEntry{Id: entry.Id})
+	}
 	if s.changeCalled != nil {
 		s.changeCalled <- struct{}{}
 	}
@@ -279,7 +470,15 @@ func (s *sqsClientStub) ChangeMessageVisibility(ctx context.Context, input *sqs.
 		<-ctx.Done()
 		return nil, ctx.Err()
 	}
-	return &sqs.ChangeMessageVisibilityOutput{}, s.changeErr
+	if s.changeErr != nil {
+		return nil, s.changeErr
+	}
+	return output, nil
+}
+
+func (s *sqsClientStub) SendMessage(_ context.Context, input *sqs.SendMessageInput, _ ...func(*sqs.Options)) (*sqs.SendMessageOutput, error) {
+	s.sendInput = input
+	return &sqs.SendMessageOutput{}, nil
 }
 
 type messageHandlerFunc func(context.Context, []byte) error
@@ -311,12 +510,16 @@ func (c *batchSQSClient) ReceiveMessage(ctx context.Context, _ *sqs.ReceiveMessa
 	return nil, ctx.Err()
 }
 
-func (*batchSQSClient) DeleteMessage(context.Context, *sqs.DeleteMessageInput, ...func(*sqs.Options)) (*sqs.DeleteMessageOutput, error) {
-	return &sqs.DeleteMessageOutput{}, nil
+func (*batchSQSClient) DeleteMessageBatch(context.Context, *sqs.DeleteMessageBatchInput, ...func(*sqs.Options)) (*sqs.DeleteMessageBatchOutput, error) {
+	return &sqs.DeleteMessageBatchOutput{}, nil
 }
 
-func (*batchSQSClient) ChangeMessageVisibility(context.Context, *sqs.ChangeMessageVisibilityInput, ...func(*sqs.Options)) (*sqs.ChangeMessageVisibilityOutput, error) {
-	return &sqs.ChangeMessageVisibilityOutput{}, nil
+func (*batchSQSClient) ChangeMessageVisibilityBatch(context.Context, *sqs.ChangeMessageVisibilityBatchInput, ...func(*sqs.Options)) (*sqs.ChangeMessageVisibilityBatchOutput, error) {
+	return &sqs.ChangeMessageVisibilityBatchOutput{}, nil
+}
+
+func (*batchSQSClient) SendMessage(context.Context, *sqs.SendMessageInput, ...func(*sqs.Options)) (*sqs.SendMessageOutput, error) {
+	return &sqs.SendMessageOutput{}, nil
 }
 
 type fatalAfterMessageSQSClient struct {
@@ -337,10 +540,63 @@ func (c *fatalAfterMessageSQSClient) ReceiveMessage(context.Context, *sqs.Receiv
 	return nil, c.fatal
 }
 
-func (*fatalAfterMessageSQSClient) DeleteMessage(context.Context, *sqs.DeleteMessageInput, ...func(*sqs.Options)) (*sqs.DeleteMessageOutput, error) {
-	return &sqs.DeleteMessageOutput{}, nil
+func (*fatalAfterMessageSQSClient) DeleteMessageBatch(context.Context, *sqs.DeleteMessageBatchInput, ...func(*sqs.Options)) (*sqs.DeleteMessageBatchOutput, error) {
+	return &sqs.DeleteMessageBatchOutput{}, nil
+}
+
+func (*fatalAfterMessageSQSClient) ChangeMessageVisibilityBatch(context.Context, *sqs.ChangeMessageVisibilityBatchInput, ...func(*sqs.Options)) (*sqs.ChangeMessageVisibilityBatchOutput, error) {
+	return &sqs.ChangeMessageVisibilityBatchOutput{}, nil
+}
+
+func (*fatalAfterMessageSQSClient) SendMessage(context.Context, *sqs.SendMessageInput, ...func(*sqs.Options)) (*sqs.SendMessageOutput, error) {
+	return &sqs.SendMessageOutput{}, nil
+}
+
+// slowRefreshSQSClient holds the first visibility change for a second, unless
+// another change overtakes it, and records the visibilities in the order the
+// changes complete.
+type slowRefreshSQSClient struct {
+	mu             sync.Mutex
+	calls          int
+	applied        []int32
+	refreshStarted chan struct{}
+	overtaken      chan struct{}
+}
+
+func (*slowRefreshSQSClient) ReceiveMessage(ctx context.Context, _ *sqs.ReceiveMessageInput, _ ...func(*sqs.Options)) (*sqs.ReceiveMessageOutput, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func (*slowRefreshSQSClient) DeleteMessageBatch(context.Context, *sqs.DeleteMessageBatchInput, ...func(*sqs.Options)) (*sqs.DeleteMessageBatchOutput, error) {
+	return &sqs.DeleteMessageBatchOutput{}, nil
+}
+
+func (c *slowRefreshSQSClient) ChangeMessageVisibilityBatch(_ context.Context, input *sqs.ChangeMessageVisibilityBatchInput, _ ...func(*sqs.Options)) (*sqs.ChangeMessageVisibilityBatchOutput, error) {
+	c.mu.Lock()
+	c.calls++
+	first := c.calls == 1
+	c.mu.Unlock()
+	if first {
+		close(c.refreshStarted)
+		select {
+		case <-c.overtaken:
+		case <-time.After(time.Second):
+		}
+	} else {
+		close(c.overtaken)
+	}
+
+	output := &sqs.ChangeMessageVisibilityBatchOutput{}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, entry := range input.Entries {
+		c.applied = append(c.applied, entry.VisibilityTimeout)
+		output.Successful = append(output.Successful, types.ChangeMessageVisibilityBatchResultEntry{Id: entry.Id})
+	}
+	return output, nil
 }
 
-func (*fatalAfterMessageSQSClient) ChangeMessageVisibility(context.Context, *sqs.ChangeMessageVisibilityInput, ...func(*sqs.Options)) (*sqs.ChangeMessageVisibilityOutput, error) {
-	return &sqs.ChangeMessageVisibilityOutput{}, nil
+func (*slowRefreshSQSClient) SendMessage(context.Context, *sqs.SendMessageInput, ...func(*sqs.Options)) (*sqs.SendMessageOutput, error) {
+	return &sqs.SendMessageOutput{}, nil
 }
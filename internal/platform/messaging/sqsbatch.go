@@ -0,0 +1,171 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+)
+
+const (
+	// sqsMaxBatch is the most entries one SQS batch call accepts.
+	sqsMaxBatch = 10
+	// sqsBatchInterval is how long a request waits for others to share its
+	// batch call; it delays each acknowledgement by at most that long.
+	sqsBatchInterval = 20 * time.Millisecond
+	sqsBatchTimeout  = 10 * time.Second
+)
+
+var errMissingBatchResult = errors.New("SQS batch response has no result for the entry")
+
+type sqsBatchRequest struct {
+	receiptHandle string
+	visibility    int32
+	result        chan error
+}
+
+// sqsBatcher coalesces the requests of one SQS batch action from concurrent
+// handlers. A full batch is sent at once and a partial one after interval.
+// Each request gets the result of its own entry, so one failed entry does not
+// fail the others.
+type sqsBatcher struct {
+	send     func(context.Context, []sqsBatchRequest) []error
+	interval time.Duration
+
+	mu      sync.Mutex
+	pending []sqsBatchRequest
+	timer   *time.Timer
+}
+
+func newSQSBatcher(send func(context.Context, []sqsBatchRequest) []error) *sqsBatcher {
+	return &sqsBatcher{send: send, interval: sqsBatchInterval}
+}
+
+// do queues the request and waits for its result or for ctx to end. An entry
+// whose ctx ended may still be sent.
+func (b *sqsBatcher) do(ctx context.Context, request sqsBatchRequest) error {
+	select {
+	case err := <-b.enqueue(request):
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// enqueue queues the request and returns the channel that receives its
+// result once its batch has been sent.
+func (b *sqsBatcher) enqueue(request sqsBatchRequest) <-chan error {
+	request.result = make(chan error, 1)
+	b.mu.Lock()
+	b.pending = append(b.pending, request)
+	var full []sqsBatchRequest
+	if len(b.pending) == sqsMaxBatch {
+		full = b.take()
+	} else if len(b.pending) == 1 {
+		b.timer = time.AfterFunc(b.interval, b.flushPending)
+	}
+	b.mu.Unlock()
+	if full != nil {
+		go b.flush(full)
+	}
+	return request.result
+}
+
+// take must be called with mu held.
+func (b *sqsBatcher) take() []sqsBatchRequest {
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+	batch := b.pending
+	b.pending = nil
+	return batch
+}
+
+func (b *sqsBatcher) flushPending() {
+	b.mu.Lock()
+	batch := b.take()
+	b.mu.Unlock()
+	if len(batch) > 0 {
+		b.flush(batch)
+	}
+}
+
+// flush sends the batch with its own deadline, since its requests come from
+// handlers whose contexts end independently.
+func (b *sqsBatcher) flush(batch []sqsBatchRequest) {
+	ctx, cancel := context.WithTimeout(context.Background(), sqsBatchTimeout)
+	defer cancel()
+	for index, err := range b.send(ctx, batch) {
+		batch[index].result <- err
+	}
+}
+
+func (c *SQSConsumer) deleteBatch(ctx context.Context, batch []sqsBatchRequest) []error {
+	entries := make([]types.DeleteMessageBatchRequestEntry, len(batch))
+	for index, request := range batch {
+		entries[index] = types.DeleteMessageBatchRequestEntry{
+			Id: aws.String(strconv.Itoa(index)), ReceiptHandle: aws.String(request.receiptHandle),
+		}
+	}
+	output, err := c.client.DeleteMessageBatch(ctx, &sqs.DeleteMessageBatchInput{QueueUrl: aws.String(c.queueURL), Entries: entries})
+	if err != nil {
+		return batchCallErrors(len(batch), fmt.Errorf("delete message batch: %w", err))
+	}
+	successful := make([]string, len(output.Successful))
+	for index, entry := range output.Successful {
+		successful[index] = aws.ToString(entry.Id)
+	}
+	return batchEntryErrors(len(batch), successful, output.Failed)
+}
+
+func (c *SQSConsumer) changeVisibilityBatch(ctx context.Context, batch []sqsBatchRequest) []error {
+	entries := make([]types.ChangeMessageVisibilityBatchRequestEntry, len(batch))
+	for index, request := range batch {
+		entries[index] = types.ChangeMessageVisibilityBatchRequestEntry{
+			Id: aws.String(strconv.Itoa(index)), ReceiptHandle: aws.String(request.receiptHandle), VisibilityTimeout: request.visibility,
+		}
+	}
+	output, err := c.client.ChangeMessageVisibilityBatch(ctx, &sqs.ChangeMessageVisibilityBatchInput{
+		QueueUrl: aws.String(c.queueURL), Entries: entries,
+	})
+	if err != nil {
+		return batchCallErrors(len(batch), fmt.Errorf("change message visibility batch: %w", err))
+	}
+	successful := make([]string, len(output.Successful))
+	for index, entry := range output.Successful {
+		successful[index] = aws.ToString(entry.Id)
+	}
+	return batchEntryErrors(len(batch), successful, output.Failed)
+}
+
+func batchCallErrors(count int, err error) []error {
+	errs := make([]error, count)
+	for index := range errs {
+		errs[index] = err
+	}
+	return errs
+}
+
+// batchEntryErrors maps the entry results of a batch response, whose IDs are
+// the request indexes, back to the requests.
+func batchEntryErrors(count int, successful []string, failed []types.BatchResultErrorEntry) []error {
+	errs := batchCallErrors(count, errMissingBatchResult)
+	for _, id := range successful {
+		if index, err := strconv.Atoi(id); err == nil && index >= 0 && index < count {
+			errs[index] = nil
+		}
+	}
+	for _, entry := range failed {
+		if index, err := strconv.Atoi(aws.ToString(entry.Id)); err == nil && index >= 0 && index < count {
+			errs[index] = fmt.Errorf("SQS rejected batch entry: %s: %s", aws.ToString(entry.Code), aws.ToString(entry.Message))
+		}
+	}
+	return errs
+}
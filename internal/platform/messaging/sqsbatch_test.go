@@ -0,0 +1,154 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSQSBatcherCoalescesRequestsAndReportsEntryFailures(t *testing.T) {
+	t.Parallel()
+
+	client := &batchAcknowledgementClient{rejected: "receipt-3"}
+	consumer := NewSQSConsumer(client, "queue-url", messageHandlerFunc(func(context.Context, []byte) error { return nil }),
+		discardLogger(), nil, SQSConsumerOptions{})
+	consumer.deletes.interval = time.Hour
+
+	errs := make([]error, sqsMaxBatch)
+	var wg sync.WaitGroup
+	for index := range sqsMaxBatch {
+		wg.Go(func() {
+			errs[index] = consumer.deletes.do(t.Context(), sqsBatchRequest{receiptHandle: fmt.Sprintf("receipt-%d", index)})
+		})
+	}
+	wg.Wait()
+	require.Len(t, client.deletes, 1, "a full batch is sent at once")
+	assert.Len(t, client.deletes[0].Entries, sqsMaxBatch)
+	for index, err := range errs {
+		if index == 3 {
+			assert.ErrorContains(t, err, "ReceiptHandleIsInvalid")
+			continue
+		}
+		assert.NoError(t, err)
+	}
+
+	consumer.changes.interval = time.Millisecond
+	wg.Go(func() {
+		assert.NoError(t, consumer.changes.do(t.Context(), sqsBatchRequest{receiptHandle: "receipt-a", visibility: 0}))
+	})
+	wg.Go(func() {
+		assert.NoError(t, consumer.changes.do(t.Context(), sqsBatchRequest{receiptHandle: "receipt-b", visibility: 120}))
+	})
+	wg.Wait()
+	entries := 0
+	for _, input := range client.changes {
+		entries += len(input.Entries)
+	}
+	assert.Equal(t, 2, entries)
+
+	client.err = errors.New("throttled")
+	require.ErrorContains(t, consumer.changes.do(t.Context(), sqsBatchRequest{receiptHandle: "receipt-c"}), "throttled")
+}
+
+func TestSQSConsumerReportsAcknowledgementFailurePerMessage(t *testing.T) {
+	t.Parallel()
+
+	client := &batchAcknowledgementClient{rejected: "receipt-2"}
+	observer := &outcomeObserverStub{}
+	consumer := NewSQSConsumer(client, "queue-url", messageHandlerFunc(func(context.Context, []byte) error { return nil }),
+		discardLogger(), observer, SQSConsumerOptions{})
+	consumer.deletes.interval = time.Hour
+
+	var wg sync.WaitGroup
+	for index := range sqsMaxBatch {
+		receiptHandle := fmt.Sprintf("receipt-%d", index)
+		wg.Go(func() {
+			consumer.handle(t.Context(), types.Message{
+				Body: aws.String(`{}`), MessageId: aws.String(receiptHandle), ReceiptHandle: aws.String(receiptHandle),
+			})
+		})
+	}
+	wg.Wait()
+	require.Len(t, client.deletes, 1)
+	assert.Equal(t, map[string]int{"success": sqsMaxBatch - 1, "ack_failed": 1}, observer.outcomes)
+}
+
+type outcomeObserverStub struct {
+	mu       sync.Mutex
+	outcomes map[string]int
+}
+
+func (*outcomeObserverStub) RecordMessageReceiveFailure(context.Context, string) {}
+
+func (s *outcomeObserverStub) RecordMessageProcess(_ context.Context, process MessageProcess) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.outcomes == nil {
+		s.outcomes = make(map[string]int)
+	}
+	s.outcomes[process.Outcome]++
+}
+
+func (*outcomeObserverStub) AddMessagesInFlight(context.Context, int64) {}
+
+// batchAcknowledgementClient accepts every batch entry except the one with the
+// rejected receipt handle.
+type batchAcknowledgementClient struct {
+	mu       sync.Mutex
+	rejected string
+	err      error
+	deletes  []*sqs.DeleteMessageBatchInput
+	changes  []*sqs.ChangeMessageVisibilityBatchInput
+}
+
+func (*batchAcknowledgementClient) ReceiveMessage(ctx context.Context, _ *sqs.ReceiveMessageInput, _ ...func(*sqs.Options)) (*sqs.ReceiveMessageOutput, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func (c *batchAcknowledgementClient) DeleteMessageBatch(_ context.Context, input *sqs.DeleteMessageBatchInput, _ ...func(*sqs.Options)) (*sqs.DeleteMessageBatchOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.deletes = append(c.deletes, input)
+	if c.err != nil {
+		return nil, c.err
+	}
+	output := &sqs.DeleteMessageBatchOutput{}
+	for _, entry := range input.Entries {
+		if aws.ToString(entry.ReceiptHandle) == c.rejected {
+			output.Failed = append(output.Failed, types.BatchResultErrorEntry{
+				Id: entry.Id, Code: aws.String("ReceiptHandleIsInvalid"), Message: aws.String("invalid receipt handle"), SenderFault: true,
+			})
+			continue
+		}
+		output.Successful = append(output.Successful, types.DeleteMessageBatchResultEntry{Id: entry.Id})
+	}
+	return output, nil
+}
+
+func (c *batchAcknowledgementClient) ChangeMessageVisibilityBatch(_ context.Context, input *sqs.ChangeMessageVisibilityBatchInput, _ ...func(*sqs.Options)) (*sqs.ChangeMessageVisibilityBatchOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.changes = append(c.changes, input)
+	if c.err != nil {
+		return nil, c.err
+	}
+	output := &sqs.ChangeMessageVisibilityBatchOutput{}
+	for _, entry := range input.Entries {
+		output.Successful = append(output.Successful, types.ChangeMessageVisibilityBatchResultEntry{Id: entry.Id})
+	}
+	return output, nil
+}
+
+func (*batchAcknowledgementClient) SendMessage(context.Context, *sqs.SendMessageInput, ...func(*sqs.Options)) (*sqs.SendMessageOutput, error) {
+	return &sqs.SendMessageOutput{}, nil
+}
@@ -0,0 +1,28 @@
+package messaging
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/your-org/go-service-template/internal/platform/messaging"
+
+// TraceContext returns the propagation fields of ctx, such as the W3C
+// traceparent, for messages published after ctx ends.
+func TraceContext(ctx context.Context) map[string]string {
+	carrier := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	return carrier
+}
+
+// remoteSpanContext returns the span context carried by the propagation
+// fields, which is invalid when they carry none.
+func remoteSpanContext(propagator propagation.TextMapPropagator, fields map[string]string) trace.SpanContext {
+	if len(fields) == 0 {
+		return trace.SpanContext{}
+	}
+	return trace.SpanContextFromContext(propagator.Extract(context.Background(), propagation.MapCarrier(fields)))
+}
@@ -47,6 +47,11 @@ type messagingMetrics struct {
 	backlog           metric.Int64Gauge
 	awsAvailable      metric.Int64Gauge
 	awsCheck          metric.Float64Histogram
+	outboxRelayed     metric.Int64Counter
+	outboxPending     metric.Int64Gauge
+	outboxLag         metric.Float64Gauge
+	outboxAttempts    metric.Int64Gauge
+	outboxParked      metric.Int64Gauge
 }
 
 func (r Runtime) Shutdown(ctx context.Context) error {
@@ -81,13 +86,14 @@ func (r Runtime) RecordMessageReceiveFailure(ctx context.Context, class string)
 }
 
 func (r Runtime) RecordMessageProcess(ctx context.Context, process messaging.MessageProcess) {
-	attributes := metric.WithAttributes(attribute.String("outcome", process.Outcome))
+	messageType := attribute.String("type", process.Type)
+	attributes := metric.WithAttributes(messageType, attribute.String("outcome", process.Outcome))
 	r.messaging.processDuration.Record(ctx, process.Duration.Seconds(), attributes)
-	r.messaging.queueAge.Record(ctx, process.QueueAge.Seconds())
-	r.messaging.attempts.Record(ctx, int64(process.Attempt))
+	r.messaging.queueAge.Record(ctx, process.QueueAge.Seconds(), metric.WithAttributes(messageType))
+	r.messaging.attempts.Record(ctx, int64(process.Attempt), metric.WithAttributes(messageType))
 	r.messaging.processed.Add(ctx, 1, attributes)
 	if process.Outcome != "success" {
-		r.messaging.failures.Add(ctx, 1, metric.WithAttributes(attribute.String("operation", "process")))
+		r.messaging.failures.Add(ctx, 1, metric.WithAttributes(attribute.String("operation", "process"), messageType))
 	}
 }
 
@@ -99,6 +105,20 @@ func (r Runtime) RecordPermissionOutcome(ctx context.Context, outcome string) {
 	r.messaging.permissionChanges.Add(ctx, 1, metric.WithAttributes(attribute.String("outcome", outcome)))
 }
 
+func (r Runtime) RecordOutboxRelay(ctx context.Context, published, failed int) {
+	r.messaging.outboxRelayed.Add(ctx, int64(published), metric.WithAttributes(attribute.String("outcome", "published")))
+	if failed > 0 {
+		r.messaging.outboxRelayed.Add(ctx, int64(failed), metric.WithAttributes(attribute.String("outcome", "failed")))
+	}
+}
+
+func (r Runtime) RecordOutboxLag(ctx context.Context, lag messaging.OutboxLag) {
+	r.messaging.outboxPending.Record(ctx, lag.Pending)
+	r.messaging.outboxLag.Record(ctx, lag.OldestAge.Seconds())
+	r.messaging.outboxAttempts.Record(ctx, int64(lag.MaxAttempts))
+	r.messaging.outboxParked.Record(ctx, lag.Parked)
+}
+
 func (r Runtime) RecordAWSCheck(ctx context.Context, dependency string, duration time.Duration, checkError error) {
 	available := int64(1)
 	if checkError != nil {
@@ -109,9 +129,10 @@ func (r Runtime) RecordAWSCheck(ctx context.Context, dependency string, duration
 	r.messaging.awsCheck.Record(ctx, duration.Seconds(), attributes)
 }
 
-func (r Runtime) RecordSQSBacklog(ctx context.Context, visible, inFlight int64) {
-	r.messaging.backlog.Record(ctx, visible, metric.WithAttributes(attribute.String("state", "visible")))
-	r.messaging.backlog.Record(ctx, inFlight, metric.WithAttributes(attribute.String("state", "in_flight")))
+func (r Runtime) RecordSQSBacklog(ctx context.Context, queue string, visible, inFlight int64) {
+	queueName := attribute.String("queue", queue)
+	r.messaging.backlog.Record(ctx, visible, metric.WithAttributes(queueName, attribute.String("state", "visible")))
+	r.messaging.backlog.Record(ctx, inFlight, metric.WithAttributes(queueName, attribute.String("state", "in_flight")))
 }
 
 func Setup(ctx context.Context, serviceName, version, endpoint string) (Runtime, error) {
@@ -245,5 +266,28 @@ func newMessagingMetrics(meter metric.Meter) (messagingMetrics, error) {
 	if err != nil {
 		return messagingMetrics{}, fmt.Errorf("create AWS check duration metric: %w", err)
 	}
+	metrics.outboxRelayed, err = meter.Int64Counter("service.outbox.relayed")
+	if err != nil {
+		return messagingMetrics{}, fmt.Errorf("create relayed outbox messages metric: %w", err)
+	}
+	metrics.outboxPending, err = meter.Int64Gauge("service.outbox.pending")
+	if err != nil {
+		return messagingMetrics{}, fmt.Errorf("create pending outbox messages metric: %w", err)
+	}
+	metrics.outboxLag, err = meter.Float64Gauge("service.outbox.lag", metric.WithUnit("s"),
+		metric.WithDescription("Age of the oldest outbox message not yet published"))
+	if err != nil {
+		return messagingMetrics{}, fmt.Errorf("create outbox lag metric: %w", err)
+	}
+	metrics.outboxAttempts, err = meter.Int64Gauge("service.outbox.attempts",
+		metric.WithDescription("Most failed publication attempts of any pending outbox message"))
+	if err != nil {
+		return messagingMetrics{}, fmt.Errorf("create outbox attempts metric: %w", err)
+	}
+	metrics.outboxParked, err = meter.Int64Gauge("service.outbox.parked",
+		metric.WithDescription("Outbox messages parked after too many failed publication attempts"))
+	if err != nil {
+		return messagingMetrics{}, fmt.Errorf("create parked outbox messages metric: %w", err)
+	}
 	return metrics, nil
 }
@@ -39,12 +39,14 @@ func TestRuntimeExposesHTTPAndProcessMetrics(t *testing.T) {
 	runtime.RecordMessagePublish(t.Context(), 10*time.Millisecond, errors.New("publish failed"))
 	runtime.RecordMessageReceiveFailure(t.Context(), "transient")
 	runtime.RecordMessageProcess(t.Context(), messaging.MessageProcess{
-		Duration: 20 * time.Millisecond, QueueAge: time.Second, Attempt: 2, Outcome: "lease_lost",
+		Type: "permissions.changed", Duration: 20 * time.Millisecond, QueueAge: time.Second, Attempt: 2, Outcome: "lease_lost",
 	})
 	runtime.AddMessagesInFlight(t.Context(), 1)
 	runtime.RecordPermissionOutcome(t.Context(), "duplicate")
 	runtime.RecordAWSCheck(t.Context(), "sqs", 5*time.Millisecond, nil)
-	runtime.RecordSQSBacklog(t.Context(), 4, 2)
+	runtime.RecordSQSBacklog(t.Context(), "permissions", 4, 2)
+	runtime.RecordOutboxRelay(t.Context(), 9, 1)
+	runtime.RecordOutboxLag(t.Context(), messaging.OutboxLag{Pending: 3, OldestAge: 1500 * time.Millisecond, MaxAttempts: 2, Parked: 1})
 
 	metricsRequest := httptest.NewRequestWithContext(t.Context(), http.MethodGet, "/metrics", nil)
 	metricsResponse := httptest.NewRecorder()
@@ -64,10 +66,15 @@ func TestRuntimeExposesHTTPAndProcessMetrics(t *testing.T) {
 	assert.Contains(t, string(body), "service_messaging_publish_duration_seconds")
 	assert.Regexp(t, `service_messaging_failures_total\{[^}]*operation="publish"[^}]*\} 1`, string(body))
 	assert.Contains(t, string(body), `class="transient",operation="receive"`)
-	assert.Contains(t, string(body), `outcome="lease_lost"`)
+	assert.Regexp(t, `service_messaging_processed_total\{[^}]*outcome="lease_lost"[^}]*type="permissions.changed"[^}]*\} 1`, string(body))
 	assert.Contains(t, string(body), "service_messaging_queue_age_seconds")
 	assert.Regexp(t, `service_permissions_changes_total\{[^}]*outcome="duplicate"[^}]*\} 1`, string(body))
 	assert.Regexp(t, `service_messaging_in_flight\{[^}]*\} 1`, string(body))
-	assert.Regexp(t, `service_messaging_backlog\{[^}]*state="visible"[^}]*\} 4`, string(body))
+	assert.Regexp(t, `service_messaging_backlog\{[^}]*queue="permissions",state="visible"[^}]*\} 4`, string(body))
 	assert.Regexp(t, `service_aws_available\{[^}]*dependency="sqs"[^}]*\} 1`, string(body))
+	assert.Regexp(t, `service_outbox_relayed_total\{[^}]*outcome="published"[^}]*\} 9`, string(body))
+	assert.Regexp(t, `service_outbox_pending\{[^}]*\} 3`, string(body))
+	assert.Regexp(t, `service_outbox_lag_seconds\{[^}]*\} 1.5`, string(body))
+	assert.Regexp(t, `service_outbox_attempts\{[^}]*\} 2`, string(body))
+	assert.Regexp(t, `service_outbox_parked\{[^}]*\} 1`, string(body))
 }
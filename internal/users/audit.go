@@ -0,0 +1,133 @@
+package users
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type AuditAction string
+
+const (
+	AuditActionCreateUser       AuditAction = "user.create"
+	AuditActionUpdateUser       AuditAction = "user.update"
+	AuditActionDeleteUser       AuditAction = "user.delete"
+	AuditActionCreateUserImport AuditAction = "user_import.create"
+	AuditActionCancelUserImport AuditAction = "user_import.cancel"
+	AuditActionApplyPermissions AuditAction = "user_permissions.apply"
+)
+
+const (
+	AuditOutcomeSucceeded = "succeeded"
+	AuditOutcomeRejected  = "rejected"
+
+	auditActorUnknown = "anonymous"
+)
+
+// AuditEvent records who changed what. Repositories write it in the same
+// transaction as the change, so a change that rolls back is not audited as
+// made. Outcome is AuditOutcomeSucceeded for committed API calls,
+// AuditOutcomeRejected for API calls refused by a conflict or a failed
+// precondition, which are recorded after their transaction rolls back, and
+// the PermissionChangeResult for consumed permission events.
+type AuditEvent struct {
+	ID            uuid.UUID
+	Actor         string
+	Action        AuditAction
+	TargetID      string
+	CorrelationID string
+	Outcome       string
+	OccurredAt    time.Time
+}
+
+type auditActorKey struct{}
+
+// WithAuditActor names who performs the changes made with ctx: the token
+// subject for API calls and the producing service for consumed events.
+func WithAuditActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, auditActorKey{}, actor)
+}
+
+// AuditActor returns the actor set by WithAuditActor, or "anonymous" for
+// changes made without one.
+func AuditActor(ctx context.Context) string {
+	if actor, _ := ctx.Value(auditActorKey{}).(string); actor != "" {
+		return actor
+	}
+	return auditActorUnknown
+}
+
+// AuditFilter selects one page of audit events ordered by ID, which orders
+// them by time. Cursor is the opaque value returned as
+// AuditPage.NextCursor by the previous page.
+type AuditFilter struct {
+	Cursor         string
+	Limit          int
+	Actor          string
+	TargetID       string
+	OccurredAfter  *time.Time
+	OccurredBefore *time.Time
+}
+
+type AuditPage struct {
+	Events     []AuditEvent
+	NextCursor string
+}
+
+// AuditQuery is the decoded keyset query passed to the repository. A nil
+// AfterID starts from the oldest event.
+type AuditQuery struct {
+	AfterID        uuid.UUID
+	Limit          int
+	Actor          string
+	TargetID       string
+	OccurredAfter  *time.Time
+	OccurredBefore *time.Time
+}
+
+type AuditRepository interface {
+	ListAuditEvents(context.Context, AuditQuery) ([]AuditEvent, error)
+}
+
+type AuditService struct {
+	repository AuditRepository
+}
+
+func NewAuditService(repository AuditRepository) *AuditService {
+	return &AuditService{repository: repository}
+}
+
+func (s *AuditService) List(ctx context.Context, filter AuditFilter) (AuditPage, error) {
+	if filter.Limit == 0 {
+		filter.Limit = DefaultListLimit
+	}
+	if filter.Limit < 1 || filter.Limit > MaxListLimit {
+		return AuditPage{}, ErrInvalidListFilter
+	}
+	afterID, err := decodeCursor(filter.Cursor)
+	if err != nil {
+		return AuditPage{}, err
+	}
+
+	listed, err := s.repository.ListAuditEvents(ctx, AuditQuery{
+		AfterID:        afterID,
+		Limit:          filter.Limit + 1,
+		Actor:          strings.TrimSpace(filter.Actor),
+		TargetID:       strings.TrimSpace(filter.TargetID),
+		OccurredAfter:  filter.OccurredAfter,
+		OccurredBefore: filter.OccurredBefore,
+	})
+	if err != nil {
+		return AuditPage{}, fmt.Errorf("list audit events: %w", err)
+	}
+
+	page := AuditPage{Events: listed}
+	if len(listed) > filter.Limit {
+		page.Events = listed[:filter.Limit]
+		page.NextCursor = encodeCursor(page.Events[filter.Limit-1].ID)
+	}
+	return page, nil
+}
@@ -0,0 +1,54 @@
+package users
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuditServiceListPaginatesWithFilters(t *testing.T) {
+	t.Parallel()
+
+	listed := []AuditEvent{
+		{ID: uuid.MustParse("0198a1f7-30b7-7df1-8491-c47f6033525b")},
+		{ID: uuid.MustParse("0198a1f7-30b7-7df2-8491-c47f6033525b")},
+	}
+	repository := &auditRepositoryStub{listed: listed}
+	after := time.Date(2026, time.October, 1, 0, 0, 0, 0, time.UTC)
+
+	page, err := NewAuditService(repository).List(t.Context(), AuditFilter{
+		Limit: 1, Actor: " user-123 ", TargetID: " target ", OccurredAfter: &after,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, listed[:1], page.Events)
+	assert.Equal(t, AuditQuery{Limit: 2, Actor: "user-123", TargetID: "target", OccurredAfter: &after}, repository.query)
+
+	_, err = NewAuditService(repository).List(t.Context(), AuditFilter{Cursor: page.NextCursor})
+	require.NoError(t, err)
+	assert.Equal(t, listed[0].ID, repository.query.AfterID)
+	assert.Equal(t, DefaultListLimit+1, repository.query.Limit)
+
+	_, err = NewAuditService(repository).List(t.Context(), AuditFilter{Limit: MaxListLimit + 1})
+	require.ErrorIs(t, err, ErrInvalidListFilter)
+}
+
+func TestAuditActorDefaultsToAnonymous(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "anonymous", AuditActor(t.Context()))
+	assert.Equal(t, "user-123", AuditActor(WithAuditActor(t.Context(), "user-123")))
+}
+
+type auditRepositoryStub struct {
+	listed []AuditEvent
+	query  AuditQuery
+}
+
+func (r *auditRepositoryStub) ListAuditEvents(_ context.Context, query AuditQuery) ([]AuditEvent, error) {
+	r.query = query
+	return r.listed, nil
+}
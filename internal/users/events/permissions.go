@@ -2,6 +2,7 @@ package usersevents
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"github.com/google/uuid"
@@ -14,6 +15,8 @@ type PermissionApplier interface {
 	Apply(context.Context, users.PermissionChange) (users.PermissionChangeResult, error)
 }
 
+const permissionsChangedType = "permissions.changed"
+
 type PermissionHandler struct {
 	permissions PermissionApplier
 	observe     func(context.Context, users.PermissionChangeResult)
@@ -29,18 +32,23 @@ type permissionsChangedPayload struct {
 	Permissions []string  `json:"permissions"`
 }
 
-func (h *PermissionHandler) Handle(ctx context.Context, body []byte) error {
-	event, err := messaging.DecodeSNSNotification[permissionsChangedPayload](body, "permissions.changed")
-	if err != nil {
-		return fmt.Errorf("decode permissions.changed: %w", err)
-	}
+// Register routes permissions.changed messages to the handler.
+func (h *PermissionHandler) Register(router *messaging.Router) {
+	messaging.Route(router, permissionsChangedType, h.handlePermissionsChanged)
+}
+
+func (h *PermissionHandler) handlePermissionsChanged(ctx context.Context, event messaging.Envelope[permissionsChangedPayload]) error {
 	ctx = messaging.WithCorrelationID(ctx, event.Metadata.CorrelationID)
+	ctx = users.WithAuditActor(ctx, event.Metadata.ProducedBy)
 	result, err := h.permissions.Apply(ctx, users.PermissionChange{
 		EventID: event.ID, UserID: event.Payload.UserID,
 		Revision: event.Payload.Revision, Permissions: event.Payload.Permissions,
 	})
+	if errors.Is(err, users.ErrInvalidPermissionsEvent) {
+		return messaging.Permanent(fmt.Errorf("handle %s: %w", permissionsChangedType, err))
+	}
 	if err != nil {
-		return fmt.Errorf("handle permissions.changed: %w", err)
+		return fmt.Errorf("handle %s: %w", permissionsChangedType, err)
 	}
 	if h.observe != nil {
 		h.observe(ctx, result)
@@ -28,24 +28,61 @@ func TestPermissionHandlerDecodesStandardSNSMessage(t *testing.T) {
 	body := []byte(fmt.Sprintf(`{"Type":"Notification","Message":%q}`, envelope))
 	applier := &permissionApplierStub{}
 	var observed users.PermissionChangeResult
-	require.NoError(t, NewPermissionHandler(applier, func(_ context.Context, result users.PermissionChangeResult) {
+	router := messaging.NewRouter(messaging.MessageFormatSNS, messaging.UnknownTypeDeadLetter)
+	NewPermissionHandler(applier, func(_ context.Context, result users.PermissionChangeResult) {
 		observed = result
-	}).Handle(t.Context(), body))
+	}).Register(router)
+	require.NoError(t, router.Handle(t.Context(), body))
 	assert.Equal(t, "event-1", applier.change.EventID)
 	assert.Equal(t, userID, applier.change.UserID)
 	assert.Equal(t, int64(3), applier.change.Revision)
 	assert.Equal(t, []string{"read"}, applier.change.Permissions)
 	assert.Equal(t, "correlation-1", applier.correlationID)
+	assert.Equal(t, "permissions", applier.actor)
 	assert.Equal(t, users.PermissionChangeApplied, observed)
 }
 
+func TestPermissionHandlerFailsPermanentlyOnInvalidMessages(t *testing.T) {
+	t.Parallel()
+
+	invalidEvent, err := json.Marshal(messaging.Envelope[permissionsChangedPayload]{
+		ID: "event-1", Timestamp: time.Now().UTC(), Type: "permissions.changed",
+		Payload:  permissionsChangedPayload{UserID: uuid.New(), Revision: 0},
+		Metadata: messaging.Metadata{SchemaVersion: "1.0.0", ProducedBy: "permissions", OriginatedFrom: "permissions", CorrelationID: "correlation-1"},
+	})
+	require.NoError(t, err)
+	for name, test := range map[string]struct {
+		body    []byte
+		applier *permissionApplierStub
+	}{
+		"undecodable body": {body: []byte(`{"Type":"Notification","Message":"not json"}`), applier: &permissionApplierStub{}},
+		"invalid event":    {body: invalidEvent, applier: &permissionApplierStub{err: users.ErrInvalidPermissionsEvent}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			router := messaging.NewRouter(messaging.MessageFormatAuto, messaging.UnknownTypeRetry)
+			NewPermissionHandler(test.applier, nil).Register(router)
+			err := router.Handle(t.Context(), test.body)
+			var permanent *messaging.PermanentError
+			require.ErrorAs(t, err, &permanent)
+		})
+	}
+}
+
 type permissionApplierStub struct {
 	change        users.PermissionChange
 	correlationID string
+	actor         string
+	err           error
 }
 
 func (s *permissionApplierStub) Apply(ctx context.Context, change users.PermissionChange) (users.PermissionChangeResult, error) {
 	s.change = change
 	s.correlationID = messaging.CorrelationID(ctx)
+	s.actor = users.AuditActor(ctx)
+	if s.err != nil {
+		return "", s.err
+	}
 	return users.PermissionChangeApplied, nil
 }
@@ -0,0 +1,87 @@
+package usershttp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	contractapi "github.com/your-org/go-service-template/internal/api"
+	"github.com/your-org/go-service-template/internal/platform/httpserver"
+	"github.com/your-org/go-service-template/internal/users"
+)
+
+type AuditService interface {
+	List(context.Context, users.AuditFilter) (users.AuditPage, error)
+}
+
+// RecordAuditActor makes the verified token subject the actor of the audit
+// events written while handling the request.
+func RecordAuditActor(next contractapi.StrictHandlerFunc, _ string) contractapi.StrictHandlerFunc {
+	return func(ctx context.Context, w http.ResponseWriter, r *http.Request, request any) (any, error) {
+		if subject, ok := httpserver.Subject(ctx); ok {
+			ctx = users.WithAuditActor(ctx, subject)
+		}
+		return next(ctx, w, r, request)
+	}
+}
+
+func (h *Handler) ListAuditEvents(ctx context.Context, request contractapi.ListAuditEventsRequestObject) (contractapi.ListAuditEventsResponseObject, error) {
+	filter := users.AuditFilter{
+		OccurredAfter:  request.Params.OccurredAfter,
+		OccurredBefore: request.Params.OccurredBefore,
+	}
+	if request.Params.Cursor != nil {
+		filter.Cursor = *request.Params.Cursor
+	}
+	if request.Params.Limit != nil {
+		filter.Limit = *request.Params.Limit
+	}
+	if request.Params.Actor != nil {
+		filter.Actor = *request.Params.Actor
+	}
+	if request.Params.TargetId != nil {
+		filter.TargetID = *request.Params.TargetId
+	}
+
+	page, err := h.audit.List(ctx, filter)
+	if err != nil {
+		switch {
+		case errors.Is(err, users.ErrInvalidCursor):
+			return contractapi.ListAuditEvents400ApplicationProblemPlusJSONResponse{
+				BadRequestApplicationProblemPlusJSONResponse: contractapi.BadRequestApplicationProblemPlusJSONResponse(
+					httpserver.NewProblem(httpserver.RequestID(ctx), 400, "Bad Request", "cursor is malformed"),
+				),
+			}, nil
+		case errors.Is(err, users.ErrInvalidListFilter):
+			return contractapi.ListAuditEvents400ApplicationProblemPlusJSONResponse{
+				BadRequestApplicationProblemPlusJSONResponse: contractapi.BadRequestApplicationProblemPlusJSONResponse(
+					httpserver.NewProblem(httpserver.RequestID(ctx), 400, "Bad Request", "limit must be between 1 and 100"),
+				),
+			}, nil
+		default:
+			h.logUnexpected(ctx, err)
+			return contractapi.ListAuditEvents500ApplicationProblemPlusJSONResponse{
+				InternalErrorApplicationProblemPlusJSONResponse: contractapi.InternalErrorApplicationProblemPlusJSONResponse(
+					httpserver.NewProblem(httpserver.RequestID(ctx), 500, "Internal Server Error", ""),
+				),
+			}, nil
+		}
+	}
+
+	response := contractapi.ListAuditEvents200JSONResponse{Items: make([]contractapi.AuditEvent, 0, len(page.Events))}
+	for _, event := range page.Events {
+		response.Items = append(response.Items, contractapi.AuditEvent{
+			Id:            event.ID,
+			Actor:         event.Actor,
+			Action:        contractapi.AuditEventAction(event.Action),
+			TargetId:      event.TargetID,
+			CorrelationId: event.CorrelationID,
+			Outcome:       contractapi.AuditEventOutcome(event.Outcome),
+			OccurredAt:    event.OccurredAt,
+		})
+	}
+	if page.NextCursor != "" {
+		response.NextCursor = &page.NextCursor
+	}
+	return response, nil
+}
@@ -0,0 +1,118 @@
+package usershttp
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"syscall"
+	"time"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+
+	"github.com/your-org/go-service-template/internal/users"
+)
+
+const callbackTimeout = 10 * time.Second
+
+var errCallbackAddressNotPublic = errors.New("callback address is not public")
+
+// CallbackSender posts finished imports to their callback URLs. Each request
+// carries Webhook-Id, Webhook-Timestamp and Webhook-Signature headers, where
+// the signature is "v1=" followed by the hex HMAC-SHA256 of
+// "<id>.<timestamp>.<body>" under the shared secret.
+type CallbackSender struct {
+	client *http.Client
+	secret []byte
+	now    func() time.Time
+}
+
+// NewCallbackSender returns a sender that connects only to public addresses.
+// Callback URLs name any host, so the address each resolves to is checked
+// when connecting, which also defeats DNS answers that change after a check.
+func NewCallbackSender(secret []byte) *CallbackSender {
+	return newCallbackSender(secret, publicAddress)
+}
+
+func newCallbackSender(secret []byte, allowed func(netip.Addr) bool) *CallbackSender {
+	dialer := &net.Dialer{
+		Timeout:   callbackTimeout,
+		KeepAlive: 30 * time.Second,
+		Control: func(_, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return fmt.Errorf("parse callback address: %w", err)
+			}
+			if !allowed(addrPort.Addr().Unmap()) {
+				return fmt.Errorf("%w: %s", errCallbackAddressNotPublic, addrPort.Addr())
+			}
+			return nil
+		},
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// A proxy would be dialled instead of the callback host, bypassing the
+	// address check.
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &CallbackSender{
+		client: &http.Client{
+			Transport: otelhttp.NewTransport(transport),
+			Timeout:   callbackTimeout,
+			// A redirect would resend the signed body to a URL nobody validated.
+			CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		},
+		secret: secret,
+		now:    time.Now,
+	}
+}
+
+func (s *CallbackSender) Send(ctx context.Context, callbackURL string, userImport users.Import) (int, error) {
+	body, err := json.Marshal(toAPIImport(userImport))
+	if err != nil {
+		return 0, fmt.Errorf("encode user import: %w", err)
+	}
+	id := userImport.ID.String()
+	timestamp := strconv.FormatInt(s.now().Unix(), 10)
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, callbackURL, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("create callback request: %w", err)
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("Webhook-Id", id)
+	request.Header.Set("Webhook-Timestamp", timestamp)
+	request.Header.Set("Webhook-Signature", "v1="+signCallback(s.secret, id, timestamp, body))
+
+	response, err := s.client.Do(request)
+	if err != nil {
+		return 0, err
+	}
+	_, readErr := io.Copy(io.Discard, io.LimitReader(response.Body, 64<<10))
+	closeErr := response.Body.Close()
+	if err := errors.Join(readErr, closeErr); err != nil {
+		return response.StatusCode, fmt.Errorf("read callback response: %w", err)
+	}
+	return response.StatusCode, nil
+}
+
+// publicAddress rejects loopback, private, link-local, multicast, and
+// unspecified addresses, which reach the service's own network.
+func publicAddress(addr netip.Addr) bool {
+	return addr.IsGlobalUnicast() && !addr.IsPrivate()
+}
+
+func signCallback(secret []byte, id, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(id + "." + timestamp + "."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,112 @@
+package usershttp
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	contractapi "github.com/your-org/go-service-template/internal/api"
+	"github.com/your-org/go-service-template/internal/users"
+)
+
+func TestCallbackSenderSignsImportBody(t *testing.T) {
+	t.Parallel()
+
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	userImport := users.Import{
+		ID:          uuid.MustParse("0198a1f7-30b7-7df0-8491-c47f6033525b"),
+		State:       users.ImportStateCompleted,
+		TotalCount:  1,
+		CallbackURL: "https://hooks.example.com/imports",
+	}
+	var received *http.Request
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		received = request
+		body, _ = io.ReadAll(request.Body)
+		writer.WriteHeader(http.StatusAccepted)
+	}))
+	t.Cleanup(server.Close)
+
+	sender := newCallbackSender(secret, anyAddress)
+	sender.now = func() time.Time { return time.Unix(1_784_000_000, 0) }
+	status, err := sender.Send(t.Context(), server.URL, userImport)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusAccepted, status)
+
+	require.NotNil(t, received)
+	assert.Equal(t, http.MethodPost, received.Method)
+	assert.Equal(t, "application/json", received.Header.Get("Content-Type"))
+	assert.Equal(t, userImport.ID.String(), received.Header.Get("Webhook-Id"))
+	assert.Equal(t, "1784000000", received.Header.Get("Webhook-Timestamp"))
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(userImport.ID.String() + ".1784000000."))
+	mac.Write(body)
+	assert.Equal(t, "v1="+hex.EncodeToString(mac.Sum(nil)), received.Header.Get("Webhook-Signature"))
+
+	var delivered contractapi.UserImport
+	require.NoError(t, json.Unmarshal(body, &delivered))
+	assert.Equal(t, userImport.ID, delivered.Id)
+	assert.Equal(t, contractapi.UserImportStateCompleted, delivered.State)
+}
+
+func TestCallbackSenderDoesNotFollowRedirects(t *testing.T) {
+	t.Parallel()
+
+	redirected := false
+	target := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) { redirected = true }))
+	t.Cleanup(target.Close)
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		http.Redirect(writer, request, target.URL, http.StatusTemporaryRedirect)
+	}))
+	t.Cleanup(server.Close)
+
+	status, err := newCallbackSender([]byte("secret"), anyAddress).Send(t.Context(), server.URL, users.Import{ID: uuid.New()})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusTemporaryRedirect, status)
+	assert.False(t, redirected)
+}
+
+func TestCallbackSenderRefusesInternalAddresses(t *testing.T) {
+	t.Parallel()
+
+	received := false
+	server := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) { received = true }))
+	t.Cleanup(server.Close)
+
+	_, err := NewCallbackSender([]byte("secret")).Send(t.Context(), server.URL, users.Import{ID: uuid.New()})
+	require.ErrorIs(t, err, errCallbackAddressNotPublic)
+	assert.False(t, received)
+
+	for address, public := range map[string]bool{
+		"93.184.215.14":   true,
+		"2606:2800:21f::": true,
+		"127.0.0.1":       false,
+		"::1":             false,
+		"10.1.2.3":        false,
+		"172.16.0.1":      false,
+		"192.168.1.1":     false,
+		"169.254.169.254": false,
+		"fe80::1":         false,
+		"fd00::1":         false,
+		"0.0.0.0":         false,
+		"224.0.0.1":       false,
+	} {
+		assert.Equal(t, public, publicAddress(netip.MustParseAddr(address)), address)
+	}
+}
+
+func anyAddress(netip.Addr) bool {
+	return true
+}
@@ -3,7 +3,10 @@ package usershttp
 import (
 	"context"
 	"errors"
+	"io"
 	"log/slog"
+	"net/http"
+	"strconv"
 
 	"github.com/google/uuid"
 	openapi_types "github.com/oapi-codegen/runtime/types"
@@ -16,44 +19,101 @@ import (
 type UserService interface {
 	Create(context.Context, string) (users.User, error)
 	Get(context.Context, uuid.UUID) (users.User, error)
+	List(context.Context, users.ListFilter) (users.Page, error)
+	Update(context.Context, uuid.UUID, string, users.Precondition) (users.User, error)
+	Delete(context.Context, uuid.UUID, users.Precondition) error
 }
 
 type ImportService interface {
-	Create(context.Context, []string) (users.Import, error)
+	Create(context.Context, []string, string) (users.Import, error)
+	CreateStreamed(context.Context, users.ImportFormat, io.Reader) (users.Import, error)
 	Get(context.Context, uuid.UUID) (users.Import, error)
+	Cancel(context.Context, uuid.UUID) (users.Import, error)
+	RetryFailed(context.Context, uuid.UUID) (users.Import, error)
+	ListEntries(context.Context, uuid.UUID, users.EntryFilter) (users.EntryPage, error)
+}
+
+type PermissionService interface {
+	Get(context.Context, uuid.UUID) (users.Permissions, error)
 }
 
 type Handler struct {
-	logger  *slog.Logger
-	users   UserService
-	imports ImportService
+	logger      *slog.Logger
+	users       UserService
+	imports     ImportService
+	permissions PermissionService
+	audit       AuditService
+	progress    ImportProgress
 }
 
-func NewHandler(logger *slog.Logger, userService UserService, importService ImportService) *Handler {
-	return &Handler{logger: logger, users: userService, imports: importService}
+func NewHandler(logger *slog.Logger, userService UserService, importService ImportService, permissionService PermissionService, auditService AuditService, importProgress ImportProgress) *Handler {
+	return &Handler{logger: logger, users: userService, imports: importService, permissions: permissionService, audit: auditService, progress: importProgress}
 }
 
 func (h *Handler) CreateUserImport(ctx context.Context, request contractapi.CreateUserImportRequestObject) (contractapi.CreateUserImportResponseObject, error) {
-	emails := make([]string, len(request.Body.Emails))
-	for index, email := range request.Body.Emails {
-		emails[index] = string(email)
+	var userImport users.Import
+	var err error
+	if request.JSONBody != nil {
+		emails := make([]string, len(request.JSONBody.Emails))
+		for index, email := range request.JSONBody.Emails {
+			emails[index] = string(email)
+		}
+		var callbackURL string
+		if request.JSONBody.CallbackUrl != nil {
+			callbackURL = *request.JSONBody.CallbackUrl
+		}
+		userImport, err = h.imports.Create(ctx, emails, callbackURL)
+	} else {
+		body, ok := request.Body.(streamedImportBody)
+		if !ok {
+			return contractapi.CreateUserImport400ApplicationProblemPlusJSONResponse{
+				BadRequestApplicationProblemPlusJSONResponse: contractapi.BadRequestApplicationProblemPlusJSONResponse(
+					httpserver.NewProblem(httpserver.RequestID(ctx), 400, "Bad Request", "unsupported content type"),
+				),
+			}, nil
+		}
+		userImport, err = h.imports.CreateStreamed(ctx, body.format, body)
 	}
-
-	userImport, err := h.imports.Create(ctx, emails)
 	if err != nil {
-		if errors.Is(err, users.ErrInvalidImport) {
+		var lineError *users.ImportLineError
+		var maxBytesError *http.MaxBytesError
+		switch {
+		case errors.As(err, &lineError):
 			return contractapi.CreateUserImport400ApplicationProblemPlusJSONResponse{
 				BadRequestApplicationProblemPlusJSONResponse: contractapi.BadRequestApplicationProblemPlusJSONResponse(
-					httpserver.NewProblem(httpserver.RequestID(ctx), 400, "Bad Request", "emails must contain 1 to 100 unique valid addresses"),
+					httpserver.NewProblem(httpserver.RequestID(ctx), 400, "Bad Request", lineError.Error()),
+				),
+			}, nil
+		case errors.Is(err, users.ErrInvalidCallbackURL):
+			return contractapi.CreateUserImport400ApplicationProblemPlusJSONResponse{
+				BadRequestApplicationProblemPlusJSONResponse: contractapi.BadRequestApplicationProblemPlusJSONResponse(
+					httpserver.NewProblem(httpserver.RequestID(ctx), 400, "Bad Request", "callbackUrl must be an absolute https URL without credentials"),
+				),
+			}, nil
+		case errors.Is(err, users.ErrInvalidImport):
+			detail := "emails must contain 1 to 100 unique valid addresses"
+			if request.JSONBody == nil {
+				detail = "import must contain 1 to 100000 unique valid addresses"
+			}
+			return contractapi.CreateUserImport400ApplicationProblemPlusJSONResponse{
+				BadRequestApplicationProblemPlusJSONResponse: contractapi.BadRequestApplicationProblemPlusJSONResponse(
+					httpserver.NewProblem(httpserver.RequestID(ctx), 400, "Bad Request", detail),
+				),
+			}, nil
+		case errors.As(err, &maxBytesError):
+			return contractapi.CreateUserImport413ApplicationProblemPlusJSONResponse{
+				PayloadTooLargeApplicationProblemPlusJSONResponse: contractapi.PayloadTooLargeApplicationProblemPlusJSONResponse(
+					httpserver.NewProblem(httpserver.RequestID(ctx), 413, "Request Entity Too Large", ""),
+				),
+			}, nil
+		default:
+			h.logUnexpected(ctx, err)
+			return contractapi.CreateUserImport500ApplicationProblemPlusJSONResponse{
+				InternalErrorApplicationProblemPlusJSONResponse: contractapi.InternalErrorApplicationProblemPlusJSONResponse(
+					httpserver.NewProblem(httpserver.RequestID(ctx), 500, "Internal Server Error", ""),
 				),
 			}, nil
 		}
-		h.logUnexpected(ctx, err)
-		return contractapi.CreateUserImport500ApplicationProblemPlusJSONResponse{
-			InternalErrorApplicationProblemPlusJSONResponse: contractapi.InternalErrorApplicationProblemPlusJSONResponse(
-				httpserver.NewProblem(httpserver.RequestID(ctx), 500, "Internal Server Error", ""),
-			),
-		}, nil
 	}
 
 	return contractapi.CreateUserImport202JSONResponse(toAPIImport(userImport)), nil
@@ -80,6 +140,117 @@ func (h *Handler) GetUserImport(ctx context.Context, request contractapi.GetUser
 	return contractapi.GetUserImport200JSONResponse(toAPIImport(userImport)), nil
 }
 
+func (h *Handler) CancelUserImport(ctx context.Context, request contractapi.CancelUserImportRequestObject) (contractapi.CancelUserImportResponseObject, error) {
+	userImport, err := h.imports.Cancel(ctx, request.ImportId)
+	if err != nil {
+		switch {
+		case errors.Is(err, users.ErrNotFound):
+			return contractapi.CancelUserImport404ApplicationProblemPlusJSONResponse{
+				NotFoundApplicationProblemPlusJSONResponse: contractapi.NotFoundApplicationProblemPlusJSONResponse(
+					httpserver.NewProblem(httpserver.RequestID(ctx), 404, "Not Found", "user import not found"),
+				),
+			}, nil
+		case errors.Is(err, users.ErrImportFinished):
+			return contractapi.CancelUserImport409ApplicationProblemPlusJSONResponse{
+				ConflictApplicationProblemPlusJSONResponse: contractapi.ConflictApplicationProblemPlusJSONResponse(
+					httpserver.NewProblem(httpserver.RequestID(ctx), 409, "Conflict", "user import already finished"),
+				),
+			}, nil
+		default:
+			h.logUnexpected(ctx, err)
+			return contractapi.CancelUserImport500ApplicationProblemPlusJSONResponse{
+				InternalErrorApplicationProblemPlusJSONResponse: contractapi.InternalErrorApplicationProblemPlusJSONResponse(
+					httpserver.NewProblem(httpserver.RequestID(ctx), 500, "Internal Server Error", ""),
+				),
+			}, nil
+		}
+	}
+
+	return contractapi.CancelUserImport200JSONResponse(toAPIImport(userImport)), nil
+}
+
+func (h *Handler) RetryFailedUserImportEntries(ctx context.Context, request contractapi.RetryFailedUserImportEntriesRequestObject) (contractapi.RetryFailedUserImportEntriesResponseObject, error) {
+	userImport, err := h.imports.RetryFailed(ctx, request.ImportId)
+	if err != nil {
+		switch {
+		case errors.Is(err, users.ErrNotFound):
+			return contractapi.RetryFailedUserImportEntries404ApplicationProblemPlusJSONResponse{
+				NotFoundApplicationProblemPlusJSONResponse: contractapi.NotFoundApplicationProblemPlusJSONResponse(
+					httpserver.NewProblem(httpserver.RequestID(ctx), 404, "Not Found", "user import not found"),
+				),
+			}, nil
+		case errors.Is(err, users.ErrImportNotRetryable):
+			return contractapi.RetryFailedUserImportEntries409ApplicationProblemPlusJSONResponse{
+				ConflictApplicationProblemPlusJSONResponse: contractapi.ConflictApplicationProblemPlusJSONResponse(
+					httpserver.NewProblem(httpserver.RequestID(ctx), 409, "Conflict", "only a finished import with failed entries can be retried"),
+				),
+			}, nil
+		default:
+			h.logUnexpected(ctx, err)
+			return contractapi.RetryFailedUserImportEntries500ApplicationProblemPlusJSONResponse{
+				InternalErrorApplicationProblemPlusJSONResponse: contractapi.InternalErrorApplicationProblemPlusJSONResponse(
+					httpserver.NewProblem(httpserver.RequestID(ctx), 500, "Internal Server Error", ""),
+				),
+			}, nil
+		}
+	}
+
+	return contractapi.RetryFailedUserImportEntries202JSONResponse(toAPIImport(userImport)), nil
+}
+
+func (h *Handler) ListUserImportEntries(ctx context.Context, request contractapi.ListUserImportEntriesRequestObject) (contractapi.ListUserImportEntriesResponseObject, error) {
+	var filter users.EntryFilter
+	if request.Params.Cursor != nil {
+		filter.Cursor = *request.Params.Cursor
+	}
+	if request.Params.Limit != nil {
+		filter.Limit = *request.Params.Limit
+	}
+	if request.Params.State != nil {
+		filter.State = users.ImportEntryState(*request.Params.State)
+	}
+
+	page, err := h.imports.ListEntries(ctx, request.ImportId, filter)
+	if err != nil {
+		switch {
+		case errors.Is(err, users.ErrInvalidCursor):
+			return contractapi.ListUserImportEntries400ApplicationProblemPlusJSONResponse{
+				BadRequestApplicationProblemPlusJSONResponse: contractapi.BadRequestApplicationProblemPlusJSONResponse(
+					httpserver.NewProblem(httpserver.RequestID(ctx), 400, "Bad Request", "cursor is malformed"),
+				),
+			}, nil
+		case errors.Is(err, users.ErrInvalidListFilter):
+			return contractapi.ListUserImportEntries400ApplicationProblemPlusJSONResponse{
+				BadRequestApplicationProblemPlusJSONResponse: contractapi.BadRequestApplicationProblemPlusJSONResponse(
+					httpserver.NewProblem(httpserver.RequestID(ctx), 400, "Bad Request", "limit must be between 1 and 100 and state must be pending, completed, or failed"),
+				),
+			}, nil
+		case errors.Is(err, users.ErrNotFound):
+			return contractapi.ListUserImportEntries404ApplicationProblemPlusJSONResponse{
+				NotFoundApplicationProblemPlusJSONResponse: contractapi.NotFoundApplicationProblemPlusJSONResponse(
+					httpserver.NewProblem(httpserver.RequestID(ctx), 404, "Not Found", "user import not found"),
+				),
+			}, nil
+		default:
+			h.logUnexpected(ctx, err)
+			return contractapi.ListUserImportEntries500ApplicationProblemPlusJSONResponse{
+				InternalErrorApplicationProblemPlusJSONResponse: contractapi.InternalErrorApplicationProblemPlusJSONResponse(
+					httpserver.NewProblem(httpserver.RequestID(ctx), 500, "Internal Server Error", ""),
+				),
+			}, nil
+		}
+	}
+
+	response := contractapi.ListUserImportEntries200JSONResponse{Items: make([]contractapi.UserImportEntry, 0, len(page.Entries))}
+	for _, entry := range page.Entries {
+		response.Items = append(response.Items, toAPIImportEntry(entry))
+	}
+	if page.NextCursor != "" {
+		response.NextCursor = &page.NextCursor
+	}
+	return response, nil
+}
+
 func (h *Handler) CreateUser(ctx context.Context, request contractapi.CreateUserRequestObject) (contractapi.CreateUserResponseObject, error) {
 	user, err := h.users.Create(ctx, string(request.Body.Email))
 	if err != nil {
@@ -106,7 +277,10 @@ func (h *Handler) CreateUser(ctx context.Context, request contractapi.CreateUser
 		}
 	}
 
-	return contractapi.CreateUser201JSONResponse(toAPIUser(user)), nil
+	return contractapi.CreateUser201JSONResponse{
+		Body:    toAPIUser(user),
+		Headers: contractapi.CreateUser201ResponseHeaders{ETag: userETag(user)},
+	}, nil
 }
 
 func (h *Handler) GetUser(ctx context.Context, request contractapi.GetUserRequestObject) (contractapi.GetUserResponseObject, error) {
@@ -128,7 +302,170 @@ func (h *Handler) GetUser(ctx context.Context, request contractapi.GetUserReques
 		}, nil
 	}
 
-	return contractapi.GetUser200JSONResponse(toAPIUser(user)), nil
+	etag := userETag(user)
+	if request.Params.IfNoneMatch != nil && httpserver.IfNoneMatch(*request.Params.IfNoneMatch, etag) {
+		return contractapi.GetUser304Response{Headers: contractapi.GetUser304ResponseHeaders{ETag: etag}}, nil
+	}
+	return contractapi.GetUser200JSONResponse{
+		Body:    toAPIUser(user),
+		Headers: contractapi.GetUser200ResponseHeaders{ETag: etag},
+	}, nil
+}
+
+func (h *Handler) GetUserPermissions(ctx context.Context, request contractapi.GetUserPermissionsRequestObject) (contractapi.GetUserPermissionsResponseObject, error) {
+	permissions, err := h.permissions.Get(ctx, request.UserId)
+	if err != nil {
+		if errors.Is(err, users.ErrNotFound) {
+			return contractapi.GetUserPermissions404ApplicationProblemPlusJSONResponse{
+				NotFoundApplicationProblemPlusJSONResponse: contractapi.NotFoundApplicationProblemPlusJSONResponse(
+					httpserver.NewProblem(httpserver.RequestID(ctx), 404, "Not Found", "no permissions received for user"),
+				),
+			}, nil
+		}
+
+		h.logUnexpected(ctx, err)
+		return contractapi.GetUserPermissions500ApplicationProblemPlusJSONResponse{
+			InternalErrorApplicationProblemPlusJSONResponse: contractapi.InternalErrorApplicationProblemPlusJSONResponse(
+				httpserver.NewProblem(httpserver.RequestID(ctx), 500, "Internal Server Error", ""),
+			),
+		}, nil
+	}
+
+	etag := httpserver.StrongETag(strconv.FormatInt(permissions.Revision, 10))
+	if request.Params.IfNoneMatch != nil && httpserver.IfNoneMatch(*request.Params.IfNoneMatch, etag) {
+		return contractapi.GetUserPermissions304Response{Headers: contractapi.GetUserPermissions304ResponseHeaders{ETag: etag}}, nil
+	}
+	return contractapi.GetUserPermissions200JSONResponse{
+		Body: contractapi.UserPermissions{
+			UserId:      permissions.UserID,
+			Revision:    permissions.Revision,
+			Permissions: permissions.Permissions,
+			UpdatedAt:   permissions.UpdatedAt,
+		},
+		Headers: contractapi.GetUserPermissions200ResponseHeaders{ETag: etag},
+	}, nil
+}
+
+func (h *Handler) UpdateUser(ctx context.Context, request contractapi.UpdateUserRequestObject) (contractapi.UpdateUserResponseObject, error) {
+	user, err := h.users.Update(ctx, request.UserId, string(request.Body.Email), ifMatch(request.Params.IfMatch))
+	if err != nil {
+		switch {
+		case errors.Is(err, users.ErrInvalidEmail):
+			return contractapi.UpdateUser400ApplicationProblemPlusJSONResponse{
+				BadRequestApplicationProblemPlusJSONResponse: contractapi.BadRequestApplicationProblemPlusJSONResponse(
+					httpserver.NewProblem(httpserver.RequestID(ctx), 400, "Bad Request", "invalid email"),
+				),
+			}, nil
+		case errors.Is(err, users.ErrNotFound):
+			return contractapi.UpdateUser404ApplicationProblemPlusJSONResponse{
+				NotFoundApplicationProblemPlusJSONResponse: contractapi.NotFoundApplicationProblemPlusJSONResponse(
+					httpserver.NewProblem(httpserver.RequestID(ctx), 404, "Not Found", "user not found"),
+				),
+			}, nil
+		case errors.Is(err, users.ErrConflict):
+			return contractapi.UpdateUser409ApplicationProblemPlusJSONResponse{
+				ConflictApplicationProblemPlusJSONResponse: contractapi.ConflictApplicationProblemPlusJSONResponse(
+					httpserver.NewProblem(httpserver.RequestID(ctx), 409, "Conflict", "a user with this email already exists"),
+				),
+			}, nil
+		case errors.Is(err, users.ErrPreconditionFailed):
+			return contractapi.UpdateUser412ApplicationProblemPlusJSONResponse{
+				PreconditionFailedApplicationProblemPlusJSONResponse: contractapi.PreconditionFailedApplicationProblemPlusJSONResponse(
+					httpserver.NewProblem(httpserver.RequestID(ctx), 412, "Precondition Failed", "user has been modified"),
+				),
+			}, nil
+		default:
+			h.logUnexpected(ctx, err)
+			return contractapi.UpdateUser500ApplicationProblemPlusJSONResponse{
+				InternalErrorApplicationProblemPlusJSONResponse: contractapi.InternalErrorApplicationProblemPlusJSONResponse(
+					httpserver.NewProblem(httpserver.RequestID(ctx), 500, "Internal Server Error", ""),
+				),
+			}, nil
+		}
+	}
+
+	return contractapi.UpdateUser200JSONResponse{
+		Body:    toAPIUser(user),
+		Headers: contractapi.UpdateUser200ResponseHeaders{ETag: userETag(user)},
+	}, nil
+}
+
+func (h *Handler) DeleteUser(ctx context.Context, request contractapi.DeleteUserRequestObject) (contractapi.DeleteUserResponseObject, error) {
+	if err := h.users.Delete(ctx, request.UserId, ifMatch(request.Params.IfMatch)); err != nil {
+		switch {
+		case errors.Is(err, users.ErrNotFound):
+			return contractapi.DeleteUser404ApplicationProblemPlusJSONResponse{
+				NotFoundApplicationProblemPlusJSONResponse: contractapi.NotFoundApplicationProblemPlusJSONResponse(
+					httpserver.NewProblem(httpserver.RequestID(ctx), 404, "Not Found", "user not found"),
+				),
+			}, nil
+		case errors.Is(err, users.ErrPreconditionFailed):
+			return contractapi.DeleteUser412ApplicationProblemPlusJSONResponse{
+				PreconditionFailedApplicationProblemPlusJSONResponse: contractapi.PreconditionFailedApplicationProblemPlusJSONResponse(
+					httpserver.NewProblem(httpserver.RequestID(ctx), 412, "Precondition Failed", "user has been modified"),
+				),
+			}, nil
+		default:
+			h.logUnexpected(ctx, err)
+			return contractapi.DeleteUser500ApplicationProblemPlusJSONResponse{
+				InternalErrorApplicationProblemPlusJSONResponse: contractapi.InternalErrorApplicationProblemPlusJSONResponse(
+					httpserver.NewProblem(httpserver.RequestID(ctx), 500, "Internal Server Error", ""),
+				),
+			}, nil
+		}
+	}
+
+	return contractapi.DeleteUser204Response{}, nil
+}
+
+func (h *Handler) ListUsers(ctx context.Context, request contractapi.ListUsersRequestObject) (contractapi.ListUsersResponseObject, error) {
+	filter := users.ListFilter{
+		CreatedAfter:  request.Params.CreatedAfter,
+		CreatedBefore: request.Params.CreatedBefore,
+	}
+	if request.Params.Cursor != nil {
+		filter.Cursor = *request.Params.Cursor
+	}
+	if request.Params.Limit != nil {
+		filter.Limit = *request.Params.Limit
+	}
+	if request.Params.EmailPrefix != nil {
+		filter.EmailPrefix = *request.Params.EmailPrefix
+	}
+
+	page, err := h.users.List(ctx, filter)
+	if err != nil {
+		switch {
+		case errors.Is(err, users.ErrInvalidCursor):
+			return contractapi.ListUsers400ApplicationProblemPlusJSONResponse{
+				BadRequestApplicationProblemPlusJSONResponse: contractapi.BadRequestApplicationProblemPlusJSONResponse(
+					httpserver.NewProblem(httpserver.RequestID(ctx), 400, "Bad Request", "cursor is malformed"),
+				),
+			}, nil
+		case errors.Is(err, users.ErrInvalidListFilter):
+			return contractapi.ListUsers400ApplicationProblemPlusJSONResponse{
+				BadRequestApplicationProblemPlusJSONResponse: contractapi.BadRequestApplicationProblemPlusJSONResponse(
+					httpserver.NewProblem(httpserver.RequestID(ctx), 400, "Bad Request", "limit must be between 1 and 100"),
+				),
+			}, nil
+		default:
+			h.logUnexpected(ctx, err)
+			return contractapi.ListUsers500ApplicationProblemPlusJSONResponse{
+				InternalErrorApplicationProblemPlusJSONResponse: contractapi.InternalErrorApplicationProblemPlusJSONResponse(
+					httpserver.NewProblem(httpserver.RequestID(ctx), 500, "Internal Server Error", ""),
+				),
+			}, nil
+		}
+	}
+
+	response := contractapi.ListUsers200JSONResponse{Items: make([]contractapi.User, 0, len(page.Users))}
+	for _, user := range page.Users {
+		response.Items = append(response.Items, toAPIUser(user))
+	}
+	if page.NextCursor != "" {
+		response.NextCursor = &page.NextCursor
+	}
+	return response, nil
 }
 
 func (h *Handler) logUnexpected(ctx context.Context, err error) {
@@ -138,6 +475,21 @@ func (h *Handler) logUnexpected(ctx context.Context, err error) {
 	)
 }
 
+// ifMatch turns an optional If-Match header into a precondition evaluated
+// against the locked user, so the comparison and the write are atomic.
+func ifMatch(header *string) users.Precondition {
+	if header == nil {
+		return nil
+	}
+	return func(user users.User) bool {
+		return httpserver.IfMatch(*header, userETag(user))
+	}
+}
+
+func userETag(user users.User) string {
+	return httpserver.StrongETag(strconv.FormatInt(user.Version, 10))
+}
+
 func toAPIUser(user users.User) contractapi.User {
 	return contractapi.User{
 		Id:        user.ID,
@@ -156,5 +508,40 @@ func toAPIImport(userImport users.Import) contractapi.UserImport {
 		CreatedAt:      userImport.CreatedAt,
 		StartedAt:      userImport.StartedAt,
 		FinishedAt:     userImport.FinishedAt,
+		RetryOf:        userImport.RetryOf,
+		Callback:       toAPIImportCallback(userImport),
+	}
+}
+
+func toAPIImportCallback(userImport users.Import) *contractapi.UserImportCallback {
+	if userImport.CallbackURL == "" {
+		return nil
+	}
+	deliveries := make([]contractapi.UserImportCallbackDelivery, len(userImport.CallbackAttempts))
+	for index, attempt := range userImport.CallbackAttempts {
+		deliveries[index] = contractapi.UserImportCallbackDelivery{
+			Attempt:     attempt.Attempt,
+			AttemptedAt: attempt.AttemptedAt,
+		}
+		if attempt.StatusCode != 0 {
+			deliveries[index].StatusCode = &attempt.StatusCode
+		}
+		if attempt.Error != "" {
+			deliveries[index].Error = &attempt.Error
+		}
+	}
+	return &contractapi.UserImportCallback{Url: userImport.CallbackURL, Deliveries: deliveries}
+}
+
+func toAPIImportEntry(entry users.ImportEntry) contractapi.UserImportEntry {
+	apiEntry := contractapi.UserImportEntry{
+		UserId: entry.UserID,
+		Email:  openapi_types.Email(entry.Email),
+		State:  contractapi.UserImportEntryState(entry.State),
+	}
+	if entry.FailureReason != "" {
+		reason := contractapi.UserImportEntryFailureReason(entry.FailureReason)
+		apiEntry.FailureReason = &reason
 	}
+	return apiEntry
 }
@@ -2,8 +2,14 @@ package usershttp
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
@@ -23,9 +29,10 @@ func TestHandlerCreateUser(t *testing.T) {
 		ID:        uuid.MustParse("8d37b313-f867-47bc-8e3d-0953db9c05c8"),
 		Email:     "person@example.com",
 		CreatedAt: time.Date(2026, time.July, 11, 12, 0, 0, 0, time.UTC),
+		Version:   1,
 	}
 	service := &userServiceStub{createUser: want}
-	handler := NewHandler(discardLogger(), service, &importServiceStub{})
+	handler := NewHandler(discardLogger(), service, &importServiceStub{}, nil, nil, nil)
 
 	response, err := handler.CreateUser(t.Context(), contractapi.CreateUserRequestObject{
 		Body: &contractapi.CreateUserJSONRequestBody{Email: openapi_types.Email(want.Email)},
@@ -34,12 +41,55 @@ func TestHandlerCreateUser(t *testing.T) {
 
 	created, ok := response.(contractapi.CreateUser201JSONResponse)
 	require.True(t, ok)
-	assert.Equal(t, want.ID, created.Id)
-	assert.Equal(t, want.Email, string(created.Email))
-	assert.Equal(t, want.CreatedAt, created.CreatedAt)
+	assert.Equal(t, want.ID, created.Body.Id)
+	assert.Equal(t, want.Email, string(created.Body.Email))
+	assert.Equal(t, want.CreatedAt, created.Body.CreatedAt)
+	assert.Equal(t, `"1"`, created.Headers.ETag)
 	assert.Equal(t, want.Email, service.receivedEmail)
 }
 
+func TestHandlerHonorsEntityTags(t *testing.T) {
+	t.Parallel()
+
+	userID := uuid.MustParse("0198a1f7-30b7-7df3-8491-c47f6033525b")
+	service := &userServiceStub{current: users.User{ID: userID, Email: "person@example.com", Version: 3}}
+	handler := NewHandler(discardLogger(), service, &importServiceStub{}, nil, nil, nil)
+	current, stale := `"3"`, `"2"`
+
+	response, err := handler.GetUser(t.Context(), contractapi.GetUserRequestObject{UserId: userID})
+	require.NoError(t, err)
+	found, ok := response.(contractapi.GetUser200JSONResponse)
+	require.True(t, ok)
+	assert.Equal(t, current, found.Headers.ETag)
+
+	response, err = handler.GetUser(t.Context(), contractapi.GetUserRequestObject{
+		UserId: userID, Params: contractapi.GetUserParams{IfNoneMatch: &current},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, contractapi.GetUser304Response{Headers: contractapi.GetUser304ResponseHeaders{ETag: current}}, response)
+
+	update := &contractapi.UpdateUserJSONRequestBody{Email: openapi_types.Email("new@example.com")}
+	updateResponse, err := handler.UpdateUser(t.Context(), contractapi.UpdateUserRequestObject{
+		UserId: userID, Params: contractapi.UpdateUserParams{IfMatch: &stale}, Body: update,
+	})
+	require.NoError(t, err)
+	assert.IsType(t, contractapi.UpdateUser412ApplicationProblemPlusJSONResponse{}, updateResponse)
+
+	updateResponse, err = handler.UpdateUser(t.Context(), contractapi.UpdateUserRequestObject{
+		UserId: userID, Params: contractapi.UpdateUserParams{IfMatch: &current}, Body: update,
+	})
+	require.NoError(t, err)
+	updated, ok := updateResponse.(contractapi.UpdateUser200JSONResponse)
+	require.True(t, ok)
+	assert.Equal(t, `"4"`, updated.Headers.ETag)
+
+	deleteResponse, err := handler.DeleteUser(t.Context(), contractapi.DeleteUserRequestObject{
+		UserId: userID, Params: contractapi.DeleteUserParams{IfMatch: &stale},
+	})
+	require.NoError(t, err)
+	assert.IsType(t, contractapi.DeleteUser412ApplicationProblemPlusJSONResponse{}, deleteResponse)
+}
+
 func TestHandlerMapsUserErrors(t *testing.T) {
 	t.Parallel()
 
@@ -64,7 +114,7 @@ func TestHandlerMapsUserErrors(t *testing.T) {
 	} {
 		t.Run(name, func(t *testing.T) {
 			t.Parallel()
-			handler := NewHandler(discardLogger(), &userServiceStub{createErr: testCase.serviceError}, &importServiceStub{})
+			handler := NewHandler(discardLogger(), &userServiceStub{createErr: testCase.serviceError}, &importServiceStub{}, nil, nil, nil)
 			response, err := handler.CreateUser(t.Context(), contractapi.CreateUserRequestObject{
 				Body: &contractapi.CreateUserJSONRequestBody{Email: openapi_types.Email("person@example.com")},
 			})
@@ -74,19 +124,170 @@ func TestHandlerMapsUserErrors(t *testing.T) {
 	}
 }
 
+func TestHandlerMapsUpdateAndDeleteErrors(t *testing.T) {
+	t.Parallel()
+
+	userID := uuid.MustParse("0198a1f7-30b7-7df1-8491-c47f6033525b")
+	for name, testCase := range map[string]struct {
+		serviceError error
+		wantUpdate   contractapi.UpdateUserResponseObject
+	}{
+		"invalid email": {serviceError: users.ErrInvalidEmail, wantUpdate: contractapi.UpdateUser400ApplicationProblemPlusJSONResponse{}},
+		"not found":     {serviceError: users.ErrNotFound, wantUpdate: contractapi.UpdateUser404ApplicationProblemPlusJSONResponse{}},
+		"conflict":      {serviceError: users.ErrConflict, wantUpdate: contractapi.UpdateUser409ApplicationProblemPlusJSONResponse{}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			handler := NewHandler(discardLogger(), &userServiceStub{updateErr: testCase.serviceError}, &importServiceStub{}, nil, nil, nil)
+			response, err := handler.UpdateUser(t.Context(), contractapi.UpdateUserRequestObject{
+				UserId: userID,
+				Body:   &contractapi.UpdateUserJSONRequestBody{Email: openapi_types.Email("person@example.com")},
+			})
+			require.NoError(t, err)
+			assert.IsType(t, testCase.wantUpdate, response)
+		})
+	}
+
+	handler := NewHandler(discardLogger(), &userServiceStub{deleteErr: users.ErrNotFound}, &importServiceStub{}, nil, nil, nil)
+	response, err := handler.DeleteUser(t.Context(), contractapi.DeleteUserRequestObject{UserId: userID})
+	require.NoError(t, err)
+	assert.IsType(t, contractapi.DeleteUser404ApplicationProblemPlusJSONResponse{}, response)
+
+	handler = NewHandler(discardLogger(), &userServiceStub{}, &importServiceStub{}, nil, nil, nil)
+	response, err = handler.DeleteUser(t.Context(), contractapi.DeleteUserRequestObject{UserId: userID})
+	require.NoError(t, err)
+	assert.IsType(t, contractapi.DeleteUser204Response{}, response)
+}
+
+func TestHandlerGetsUserPermissionsWithRevisionETag(t *testing.T) {
+	t.Parallel()
+
+	userID := uuid.MustParse("0198a1f7-30b7-7dfd-8491-c47f6033525b")
+	updatedAt := time.Date(2026, time.October, 1, 9, 0, 0, 0, time.UTC)
+	permissions := &permissionServiceStub{permissions: users.Permissions{
+		UserID: userID, Revision: 7, Permissions: []string{"users.read"}, UpdatedAt: updatedAt,
+	}}
+	handler := NewHandler(discardLogger(), &userServiceStub{}, &importServiceStub{}, permissions, nil, nil)
+
+	response, err := handler.GetUserPermissions(t.Context(), contractapi.GetUserPermissionsRequestObject{UserId: userID})
+	require.NoError(t, err)
+	assert.Equal(t, contractapi.GetUserPermissions200JSONResponse{
+		Body: contractapi.UserPermissions{
+			UserId: userID, Revision: 7, Permissions: []string{"users.read"}, UpdatedAt: updatedAt,
+		},
+		Headers: contractapi.GetUserPermissions200ResponseHeaders{ETag: `"7"`},
+	}, response)
+
+	current := `"6", "7"`
+	response, err = handler.GetUserPermissions(t.Context(), contractapi.GetUserPermissionsRequestObject{
+		UserId: userID, Params: contractapi.GetUserPermissionsParams{IfNoneMatch: &current},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, contractapi.GetUserPermissions304Response{Headers: contractapi.GetUserPermissions304ResponseHeaders{ETag: `"7"`}}, response)
+
+	permissions.err = users.ErrNotFound
+	response, err = handler.GetUserPermissions(t.Context(), contractapi.GetUserPermissionsRequestObject{UserId: userID})
+	require.NoError(t, err)
+	problem, ok := response.(contractapi.GetUserPermissions404ApplicationProblemPlusJSONResponse)
+	require.True(t, ok)
+	assert.Equal(t, "no permissions received for user", *problem.Detail)
+}
+
+func TestHandlerListsUsers(t *testing.T) {
+	t.Parallel()
+
+	listed := users.User{
+		ID:        uuid.MustParse("0198a1f7-30b7-7df2-8491-c47f6033525b"),
+		Email:     "person@example.com",
+		CreatedAt: time.Date(2026, time.July, 11, 12, 0, 0, 0, time.UTC),
+	}
+	service := &userServiceStub{page: users.Page{Users: []users.User{listed}, NextCursor: "next"}}
+	handler := NewHandler(discardLogger(), service, &importServiceStub{}, nil, nil, nil)
+	cursor, limit, prefix := "current", 10, "Person"
+	response, err := handler.ListUsers(t.Context(), contractapi.ListUsersRequestObject{
+		Params: contractapi.ListUsersParams{Cursor: &cursor, Limit: &limit, EmailPrefix: &prefix},
+	})
+	require.NoError(t, err)
+
+	page, ok := response.(contractapi.ListUsers200JSONResponse)
+	require.True(t, ok)
+	require.Len(t, page.Items, 1)
+	assert.Equal(t, listed.ID, page.Items[0].Id)
+	require.NotNil(t, page.NextCursor)
+	assert.Equal(t, "next", *page.NextCursor)
+	assert.Equal(t, users.ListFilter{Cursor: cursor, Limit: limit, EmailPrefix: prefix}, service.receivedFilter)
+}
+
+func TestHandlerListsAuditEvents(t *testing.T) {
+	t.Parallel()
+
+	event := users.AuditEvent{
+		ID:            uuid.MustParse("0198a1f7-30b7-7df4-8491-c47f6033525b"),
+		Actor:         "user-123",
+		Action:        users.AuditActionCreateUser,
+		TargetID:      "0198a1f7-30b7-7df2-8491-c47f6033525b",
+		CorrelationID: "request-1",
+		Outcome:       users.AuditOutcomeSucceeded,
+		OccurredAt:    time.Date(2026, time.October, 1, 9, 0, 0, 0, time.UTC),
+	}
+	audit := &auditServiceStub{page: users.AuditPage{Events: []users.AuditEvent{event}, NextCursor: "next"}}
+	handler := NewHandler(discardLogger(), &userServiceStub{}, &importServiceStub{}, nil, audit, nil)
+	actor, target := "user-123", event.TargetID
+	response, err := handler.ListAuditEvents(t.Context(), contractapi.ListAuditEventsRequestObject{
+		Params: contractapi.ListAuditEventsParams{Actor: &actor, TargetId: &target, OccurredBefore: &event.OccurredAt},
+	})
+	require.NoError(t, err)
+
+	next := "next"
+	assert.Equal(t, contractapi.ListAuditEvents200JSONResponse{
+		Items: []contractapi.AuditEvent{{
+			Id: event.ID, Actor: "user-123", Action: "user.create", TargetId: event.TargetID,
+			CorrelationId: "request-1", Outcome: contractapi.Succeeded, OccurredAt: event.OccurredAt,
+		}},
+		NextCursor: &next,
+	}, response)
+	assert.Equal(t, users.AuditFilter{Actor: actor, TargetID: target, OccurredBefore: &event.OccurredAt}, audit.filter)
+
+	audit.err = users.ErrInvalidListFilter
+	response, err = handler.ListAuditEvents(t.Context(), contractapi.ListAuditEventsRequestObject{})
+	require.NoError(t, err)
+	_, ok := response.(contractapi.ListAuditEvents400ApplicationProblemPlusJSONResponse)
+	assert.True(t, ok)
+}
+
+func TestHandlerRejectsMalformedCursor(t *testing.T) {
+	t.Parallel()
+
+	handler := NewHandler(discardLogger(), &userServiceStub{listErr: users.ErrInvalidCursor}, &importServiceStub{}, nil, nil, nil)
+	cursor := "not-a-cursor"
+	response, err := handler.ListUsers(t.Context(), contractapi.ListUsersRequestObject{
+		Params: contractapi.ListUsersParams{Cursor: &cursor},
+	})
+	require.NoError(t, err)
+
+	problem, ok := response.(contractapi.ListUsers400ApplicationProblemPlusJSONResponse)
+	require.True(t, ok)
+	assert.Equal(t, 400, problem.Status)
+}
+
 func TestHandlerCreatesUserImport(t *testing.T) {
 	t.Parallel()
 
 	want := users.Import{
-		ID:         uuid.MustParse("0198a1f7-30b7-7df1-8491-c47f6033525b"),
-		State:      users.ImportStatePending,
-		TotalCount: 2,
-		CreatedAt:  time.Date(2026, time.July, 13, 2, 0, 0, 0, time.UTC),
+		ID:          uuid.MustParse("0198a1f7-30b7-7df1-8491-c47f6033525b"),
+		State:       users.ImportStatePending,
+		TotalCount:  2,
+		CreatedAt:   time.Date(2026, time.July, 13, 2, 0, 0, 0, time.UTC),
+		CallbackURL: "https://hooks.example.com/imports",
 	}
 	imports := &importServiceStub{created: want}
-	handler := NewHandler(discardLogger(), &userServiceStub{}, imports)
+	handler := NewHandler(discardLogger(), &userServiceStub{}, imports, nil, nil, nil)
+	callbackURL := "https://hooks.example.com/imports"
 	response, err := handler.CreateUserImport(t.Context(), contractapi.CreateUserImportRequestObject{
-		Body: &contractapi.CreateUserImportJSONRequestBody{Emails: []openapi_types.Email{"one@example.com", "two@example.com"}},
+		JSONBody: &contractapi.CreateUserImportJSONRequestBody{
+			Emails:      []openapi_types.Email{"one@example.com", "two@example.com"},
+			CallbackUrl: &callbackURL,
+		},
 	})
 	require.NoError(t, err)
 
@@ -94,17 +295,233 @@ func TestHandlerCreatesUserImport(t *testing.T) {
 	require.True(t, ok)
 	assert.Equal(t, want.ID, created.Id)
 	assert.Equal(t, want.TotalCount, created.TotalCount)
+	assert.Equal(t, &contractapi.UserImportCallback{Url: callbackURL, Deliveries: []contractapi.UserImportCallbackDelivery{}}, created.Callback)
 	assert.Equal(t, []string{"one@example.com", "two@example.com"}, imports.receivedEmails)
+	assert.Equal(t, callbackURL, imports.receivedCallbackURL)
+
+	imports.createErr = fmt.Errorf("wrapped: %w", users.ErrInvalidCallbackURL)
+	response, err = handler.CreateUserImport(t.Context(), contractapi.CreateUserImportRequestObject{
+		JSONBody: &contractapi.CreateUserImportJSONRequestBody{Emails: []openapi_types.Email{"one@example.com"}},
+	})
+	require.NoError(t, err)
+	_, ok = response.(contractapi.CreateUserImport400ApplicationProblemPlusJSONResponse)
+	assert.True(t, ok)
+}
+
+func TestToAPIImportReportsCallbackDeliveries(t *testing.T) {
+	t.Parallel()
+
+	attemptedAt := time.Date(2026, time.July, 13, 2, 0, 0, 0, time.UTC)
+	apiImport := toAPIImport(users.Import{
+		CallbackURL: "https://hooks.example.com/imports",
+		CallbackAttempts: []users.CallbackAttempt{
+			{Attempt: 1, AttemptedAt: attemptedAt, Error: "connection refused"},
+			{Attempt: 2, AttemptedAt: attemptedAt.Add(time.Minute), StatusCode: http.StatusNoContent},
+		},
+	})
+	require.NotNil(t, apiImport.Callback)
+	require.Len(t, apiImport.Callback.Deliveries, 2)
+	assert.Nil(t, apiImport.Callback.Deliveries[0].StatusCode)
+	assert.Equal(t, "connection refused", *apiImport.Callback.Deliveries[0].Error)
+	assert.Equal(t, http.StatusNoContent, *apiImport.Callback.Deliveries[1].StatusCode)
+	assert.Nil(t, apiImport.Callback.Deliveries[1].Error)
+
+	assert.Nil(t, toAPIImport(users.Import{}).Callback)
+}
+
+func TestHandlerStreamsUserImportEvents(t *testing.T) {
+	t.Parallel()
+
+	importID := uuid.MustParse("0198a1f7-30b7-7df1-8491-c47f6033525b")
+	createdAt := time.Date(2026, time.July, 13, 2, 0, 0, 0, time.UTC)
+	progress := importProgressStub{updates: []users.Import{
+		{ID: importID, State: users.ImportStateRunning, TotalCount: 2, CompletedCount: 1, CreatedAt: createdAt},
+		{ID: importID, State: users.ImportStateCompleted, TotalCount: 2, CompletedCount: 2, CreatedAt: createdAt},
+	}}
+	handler := NewHandler(discardLogger(), &userServiceStub{}, &importServiceStub{}, nil, nil, progress)
+	response, err := handler.StreamUserImportEvents(t.Context(), contractapi.StreamUserImportEventsRequestObject{ImportId: importID})
+	require.NoError(t, err)
+
+	recorder := httptest.NewRecorder()
+	require.NoError(t, response.VisitStreamUserImportEventsResponse(recorder))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "text/event-stream", recorder.Header().Get("Content-Type"))
+	assert.Equal(t, "no-cache", recorder.Header().Get("Cache-Control"))
+	events := strings.Split(strings.TrimSuffix(recorder.Body.String(), "\n\n"), "\n\n")
+	require.Len(t, events, 2)
+	for index, event := range events {
+		name, data, found := strings.Cut(event, "\n")
+		require.True(t, found)
+		assert.Equal(t, "event: progress", name)
+		var userImport contractapi.UserImport
+		require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(data, "data: ")), &userImport))
+		assert.Equal(t, progress.updates[index].CompletedCount, userImport.CompletedCount)
+	}
+
+	handler = NewHandler(discardLogger(), &userServiceStub{}, &importServiceStub{getErr: users.ErrNotFound}, nil, nil, progress)
+	response, err = handler.StreamUserImportEvents(t.Context(), contractapi.StreamUserImportEventsRequestObject{ImportId: importID})
+	require.NoError(t, err)
+	_, ok := response.(contractapi.StreamUserImportEvents404ApplicationProblemPlusJSONResponse)
+	assert.True(t, ok)
+}
+
+func TestHandlerCreatesStreamedUserImport(t *testing.T) {
+	t.Parallel()
+
+	for name, testCase := range map[string]struct {
+		contentType  string
+		serviceError error
+		wantStatus   int
+		wantDetail   string
+		wantFormat   users.ImportFormat
+	}{
+		"csv":                {contentType: "text/csv; charset=utf-8", wantStatus: http.StatusAccepted, wantFormat: users.ImportFormatCSV},
+		"ndjson":             {contentType: "application/x-ndjson", wantStatus: http.StatusAccepted, wantFormat: users.ImportFormatNDJSON},
+		"unsupported":        {contentType: "text/plain", wantStatus: http.StatusBadRequest, wantDetail: "unsupported content type"},
+		"invalid line":       {contentType: "text/csv", serviceError: fmt.Errorf("wrapped: %w", &users.ImportLineError{Line: 7, Reason: "invalid email address"}), wantStatus: http.StatusBadRequest, wantDetail: "line 7: invalid email address"},
+		"body too large":     {contentType: "text/csv", serviceError: &http.MaxBytesError{Limit: 1}, wantStatus: http.StatusRequestEntityTooLarge},
+		"unexpected failure": {contentType: "text/csv", serviceError: errors.New("database unavailable"), wantStatus: http.StatusInternalServerError},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			imports := &importServiceStub{created: users.Import{ID: uuid.New(), TotalCount: 1}, createErr: testCase.serviceError}
+			handler := NewHandler(discardLogger(), &userServiceStub{}, imports, nil, nil, nil)
+			strict := BindStreamedImport(func(ctx context.Context, _ http.ResponseWriter, _ *http.Request, request any) (any, error) {
+				return handler.CreateUserImport(ctx, request.(contractapi.CreateUserImportRequestObject))
+			}, "CreateUserImport")
+
+			request := httptest.NewRequest(http.MethodPost, "/v1/user-imports", strings.NewReader("email\nperson@example.com\n"))
+			request.Header.Set("Content-Type", testCase.contentType)
+			writer := &deadlineRecorder{ResponseRecorder: httptest.NewRecorder()}
+			response, err := strict(t.Context(), writer, request, contractapi.CreateUserImportRequestObject{Body: request.Body})
+			require.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+			require.NoError(t, response.(contractapi.CreateUserImportResponseObject).VisitCreateUserImportResponse(recorder))
+			assert.Equal(t, testCase.wantStatus, recorder.Code)
+			if testCase.wantDetail != "" {
+				assert.Contains(t, recorder.Body.String(), testCase.wantDetail)
+			}
+			if testCase.wantStatus == http.StatusAccepted {
+				assert.Equal(t, testCase.wantFormat, imports.receivedFormat)
+				assert.Equal(t, "email\nperson@example.com\n", imports.receivedBody)
+				assert.WithinDuration(t, time.Now().Add(streamedImportWriteTimeout), writer.writeDeadline, time.Minute)
+			}
+		})
+	}
+}
+
+// deadlineRecorder records the write deadline set through an
+// http.ResponseController.
+type deadlineRecorder struct {
+	*httptest.ResponseRecorder
+	writeDeadline time.Time
+}
+
+func (r *deadlineRecorder) SetWriteDeadline(deadline time.Time) error {
+	r.writeDeadline = deadline
+	return nil
+}
+
+func TestHandlerListsUserImportEntries(t *testing.T) {
+	t.Parallel()
+
+	failed := users.ImportEntry{
+		UserID:        uuid.MustParse("0198a1f7-30b7-7df1-8491-c47f6033525b"),
+		Email:         "taken@example.com",
+		State:         users.ImportEntryStateFailed,
+		FailureReason: users.ImportFailureEmailTaken,
+	}
+	completed := users.ImportEntry{
+		UserID: uuid.MustParse("0198a1f7-30b7-7df2-8491-c47f6033525b"),
+		Email:  "new@example.com",
+		State:  users.ImportEntryStateCompleted,
+	}
+	imports := &importServiceStub{entryPage: users.EntryPage{Entries: []users.ImportEntry{failed, completed}, NextCursor: "next"}}
+	handler := NewHandler(discardLogger(), &userServiceStub{}, imports, nil, nil, nil)
+	state, limit := contractapi.UserImportEntryStateFailed, 2
+	response, err := handler.ListUserImportEntries(t.Context(), contractapi.ListUserImportEntriesRequestObject{
+		ImportId: uuid.New(),
+		Params:   contractapi.ListUserImportEntriesParams{State: &state, Limit: &limit},
+	})
+	require.NoError(t, err)
+
+	page, ok := response.(contractapi.ListUserImportEntries200JSONResponse)
+	require.True(t, ok)
+	require.Len(t, page.Items, 2)
+	require.NotNil(t, page.Items[0].FailureReason)
+	assert.Equal(t, contractapi.EmailTaken, *page.Items[0].FailureReason)
+	assert.Nil(t, page.Items[1].FailureReason)
+	assert.Equal(t, "next", *page.NextCursor)
+	assert.Equal(t, users.EntryFilter{Limit: 2, State: users.ImportEntryStateFailed}, imports.receivedFilter)
+
+	imports.entryErr = fmt.Errorf("list: %w", users.ErrNotFound)
+	response, err = handler.ListUserImportEntries(t.Context(), contractapi.ListUserImportEntriesRequestObject{ImportId: uuid.New()})
+	require.NoError(t, err)
+	assert.IsType(t, contractapi.ListUserImportEntries404ApplicationProblemPlusJSONResponse{}, response)
+}
+
+func TestHandlerCancelsAndRetriesUserImports(t *testing.T) {
+	t.Parallel()
+
+	importID := uuid.MustParse("0198a1f7-30b7-7df1-8491-c47f6033525b")
+	imports := &importServiceStub{}
+	handler := NewHandler(discardLogger(), &userServiceStub{}, imports, nil, nil, nil)
+
+	cancelled, err := handler.CancelUserImport(t.Context(), contractapi.CancelUserImportRequestObject{ImportId: importID})
+	require.NoError(t, err)
+	require.IsType(t, contractapi.CancelUserImport200JSONResponse{}, cancelled)
+	assert.Equal(t, contractapi.UserImportStateCancelled, cancelled.(contractapi.CancelUserImport200JSONResponse).State)
+
+	retried, err := handler.RetryFailedUserImportEntries(t.Context(), contractapi.RetryFailedUserImportEntriesRequestObject{ImportId: importID})
+	require.NoError(t, err)
+	require.IsType(t, contractapi.RetryFailedUserImportEntries202JSONResponse{}, retried)
+	assert.Equal(t, &importID, retried.(contractapi.RetryFailedUserImportEntries202JSONResponse).RetryOf)
+
+	imports.cancelErr = fmt.Errorf("cancel: %w", users.ErrImportFinished)
+	imports.retryErr = fmt.Errorf("retry: %w", users.ErrImportNotRetryable)
+	cancelled, err = handler.CancelUserImport(t.Context(), contractapi.CancelUserImportRequestObject{ImportId: importID})
+	require.NoError(t, err)
+	assert.IsType(t, contractapi.CancelUserImport409ApplicationProblemPlusJSONResponse{}, cancelled)
+	retried, err = handler.RetryFailedUserImportEntries(t.Context(), contractapi.RetryFailedUserImportEntriesRequestObject{ImportId: importID})
+	require.NoError(t, err)
+	assert.IsType(t, contractapi.RetryFailedUserImportEntries409ApplicationProblemPlusJSONResponse{}, retried)
 }
 
 func discardLogger() *slog.Logger {
 	return slog.New(slog.NewTextHandler(io.Discard, nil))
 }
 
+type permissionServiceStub struct {
+	permissions users.Permissions
+	err         error
+}
+
+func (s *permissionServiceStub) Get(context.Context, uuid.UUID) (users.Permissions, error) {
+	return s.permissions, s.err
+}
+
+type auditServiceStub struct {
+	page   users.AuditPage
+	err    error
+	filter users.AuditFilter
+}
+
+func (s *auditServiceStub) List(_ context.Context, filter users.AuditFilter) (users.AuditPage, error) {
+	s.filter = filter
+	return s.page, s.err
+}
+
 type userServiceStub struct {
-	createUser    users.User
-	createErr     error
-	receivedEmail string
+	createUser     users.User
+	createErr      error
+	receivedEmail  string
+	page           users.Page
+	listErr        error
+	receivedFilter users.ListFilter
+	current        users.User
+	updateErr      error
+	deleteErr      error
 }
 
 func (s *userServiceStub) Create(_ context.Context, email string) (users.User, error) {
@@ -113,20 +530,83 @@ func (s *userServiceStub) Create(_ context.Context, email string) (users.User, e
 }
 
 func (s *userServiceStub) Get(context.Context, uuid.UUID) (users.User, error) {
-	return users.User{}, nil
+	return s.current, nil
+}
+
+func (s *userServiceStub) Update(_ context.Context, id uuid.UUID, email string, precondition users.Precondition) (users.User, error) {
+	if precondition != nil && !precondition(s.current) {
+		return users.User{}, users.ErrPreconditionFailed
+	}
+	return users.User{ID: id, Email: email, Version: s.current.Version + 1}, s.updateErr
+}
+
+func (s *userServiceStub) Delete(_ context.Context, _ uuid.UUID, precondition users.Precondition) error {
+	if precondition != nil && !precondition(s.current) {
+		return users.ErrPreconditionFailed
+	}
+	return s.deleteErr
+}
+
+func (s *userServiceStub) List(_ context.Context, filter users.ListFilter) (users.Page, error) {
+	s.receivedFilter = filter
+	return s.page, s.listErr
 }
 
 type importServiceStub struct {
-	created        users.Import
-	createErr      error
-	receivedEmails []string
+	created             users.Import
+	createErr           error
+	receivedEmails      []string
+	receivedCallbackURL string
+	receivedFormat      users.ImportFormat
+	receivedBody        string
+	entryPage           users.EntryPage
+	entryErr            error
+	receivedFilter      users.EntryFilter
+	getErr              error
+	cancelErr           error
+	retryErr            error
 }
 
-func (s *importServiceStub) Create(_ context.Context, emails []string) (users.Import, error) {
-	s.receivedEmails = emails
+func (s *importServiceStub) Create(_ context.Context, emails []string, callbackURL string) (users.Import, error) {
+	s.receivedEmails, s.receivedCallbackURL = emails, callbackURL
+	return s.created, s.createErr
+}
+
+func (s *importServiceStub) CreateStreamed(_ context.Context, format users.ImportFormat, body io.Reader) (users.Import, error) {
+	content, err := io.ReadAll(body)
+	if err != nil {
+		return users.Import{}, err
+	}
+	s.receivedFormat, s.receivedBody = format, string(content)
 	return s.created, s.createErr
 }
 
 func (s *importServiceStub) Get(context.Context, uuid.UUID) (users.Import, error) {
-	return users.Import{}, nil
+	return users.Import{}, s.getErr
+}
+
+func (s *importServiceStub) Cancel(_ context.Context, id uuid.UUID) (users.Import, error) {
+	return users.Import{ID: id, State: users.ImportStateCancelled}, s.cancelErr
+}
+
+func (s *importServiceStub) RetryFailed(_ context.Context, id uuid.UUID) (users.Import, error) {
+	return users.Import{ID: uuid.New(), State: users.ImportStatePending, RetryOf: &id}, s.retryErr
+}
+
+func (s *importServiceStub) ListEntries(_ context.Context, _ uuid.UUID, filter users.EntryFilter) (users.EntryPage, error) {
+	s.receivedFilter = filter
+	return s.entryPage, s.entryErr
+}
+
+type importProgressStub struct {
+	updates []users.Import
+}
+
+func (p importProgressStub) Watch(_ context.Context, _ uuid.UUID, emit func(users.Import) error) error {
+	for _, update := range p.updates {
+		if err := emit(update); err != nil {
+			return err
+		}
+	}
+	return nil
 }
@@ -0,0 +1,108 @@
+package usershttp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	contractapi "github.com/your-org/go-service-template/internal/api"
+	"github.com/your-org/go-service-template/internal/platform/httpserver"
+	"github.com/your-org/go-service-template/internal/users"
+)
+
+const importEventsHeartbeat = 15 * time.Second
+
+type ImportProgress interface {
+	Watch(context.Context, uuid.UUID, func(users.Import) error) error
+}
+
+// ClearEventStreamDeadline removes the server's write timeout for import event
+// streams, which stay open until the import finishes. Heartbeats detect
+// clients that have gone away.
+func ClearEventStreamDeadline(next contractapi.StrictHandlerFunc, operationID string) contractapi.StrictHandlerFunc {
+	if operationID != "StreamUserImportEvents" {
+		return next
+	}
+	return func(ctx context.Context, w http.ResponseWriter, r *http.Request, request any) (any, error) {
+		if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+			return nil, fmt.Errorf("clear event stream write deadline: %w", err)
+		}
+		return next(ctx, w, r, request)
+	}
+}
+
+func (h *Handler) StreamUserImportEvents(ctx context.Context, request contractapi.StreamUserImportEventsRequestObject) (contractapi.StreamUserImportEventsResponseObject, error) {
+	if _, err := h.imports.Get(ctx, request.ImportId); err != nil {
+		if errors.Is(err, users.ErrNotFound) {
+			return contractapi.StreamUserImportEvents404ApplicationProblemPlusJSONResponse{
+				NotFoundApplicationProblemPlusJSONResponse: contractapi.NotFoundApplicationProblemPlusJSONResponse(
+					httpserver.NewProblem(httpserver.RequestID(ctx), 404, "Not Found", "user import not found"),
+				),
+			}, nil
+		}
+		h.logUnexpected(ctx, err)
+		return contractapi.StreamUserImportEvents500ApplicationProblemPlusJSONResponse{
+			InternalErrorApplicationProblemPlusJSONResponse: contractapi.InternalErrorApplicationProblemPlusJSONResponse(
+				httpserver.NewProblem(httpserver.RequestID(ctx), 500, "Internal Server Error", ""),
+			),
+		}, nil
+	}
+
+	// The generated response flushes each chunk read from the body, so events
+	// are written to a pipe as they happen. Failures after the status line has
+	// been sent can only end the stream, so they are logged here instead.
+	reader, writer := io.Pipe()
+	go func() {
+		if err := h.streamImportEvents(ctx, request.ImportId, writer); err != nil && !errors.Is(err, context.Canceled) && !errors.Is(err, io.ErrClosedPipe) {
+			h.logUnexpected(ctx, err)
+		}
+		_ = writer.Close()
+	}()
+	cacheControl := "no-cache"
+	return contractapi.StreamUserImportEvents200TexteventStreamResponse{
+		Body:    reader,
+		Headers: contractapi.StreamUserImportEvents200ResponseHeaders{CacheControl: &cacheControl},
+	}, nil
+}
+
+func (h *Handler) streamImportEvents(ctx context.Context, id uuid.UUID, w io.Writer) error {
+	var mu sync.Mutex
+	write := func(event []byte) error {
+		mu.Lock()
+		defer mu.Unlock()
+		_, err := w.Write(event)
+		return err
+	}
+
+	heartbeatCtx, stopHeartbeat := context.WithCancel(ctx)
+	defer stopHeartbeat()
+	go func() {
+		ticker := time.NewTicker(importEventsHeartbeat)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-heartbeatCtx.Done():
+				return
+			case <-ticker.C:
+				if write([]byte(": heartbeat\n\n")) != nil {
+					stopHeartbeat()
+				}
+			}
+		}
+	}()
+
+	return h.progress.Watch(heartbeatCtx, id, func(userImport users.Import) error {
+		data, err := json.Marshal(toAPIImport(userImport))
+		if err != nil {
+			return fmt.Errorf("encode user import: %w", err)
+		}
+		return write(fmt.Appendf(nil, "event: progress\ndata: %s\n\n", data))
+	})
+}
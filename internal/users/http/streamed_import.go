@@ -0,0 +1,74 @@
+package usershttp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"mime"
+	"net/http"
+	"slices"
+	"time"
+
+	contractapi "github.com/your-org/go-service-template/internal/api"
+	"github.com/your-org/go-service-template/internal/platform/httpserver"
+	"github.com/your-org/go-service-template/internal/users"
+)
+
+const (
+	maxStreamedImportBytes = 32 << 20
+	// streamedImportWriteTimeout replaces the server's write timeout for
+	// streamed imports, which covers reading the whole upload and copying it
+	// into PostgreSQL before the response is written.
+	streamedImportWriteTimeout = 10 * time.Minute
+)
+
+var streamedImportFormats = map[string]users.ImportFormat{
+	"text/csv":             users.ImportFormatCSV,
+	"application/x-ndjson": users.ImportFormatNDJSON,
+}
+
+// StreamedRoutes lets CSV and NDJSON import bodies bypass buffered request
+// validation and the default body limit.
+func StreamedRoutes() []httpserver.StreamedRoute {
+	return []httpserver.StreamedRoute{{
+		Method:       http.MethodPost,
+		Path:         "/v1/user-imports",
+		MediaTypes:   slices.Sorted(maps.Keys(streamedImportFormats)),
+		MaxBodyBytes: maxStreamedImportBytes,
+	}}
+}
+
+type streamedImportBody struct {
+	io.Reader
+	format users.ImportFormat
+}
+
+// BindStreamedImport passes CSV and NDJSON bodies and their format to
+// CreateUserImport. The generated server binds only one of the two media
+// types, so the body is taken from the request here instead. It also extends
+// the write deadline, so that the response to an import committed after a
+// long upload still reaches the client instead of prompting a retry.
+func BindStreamedImport(next contractapi.StrictHandlerFunc, operationID string) contractapi.StrictHandlerFunc {
+	if operationID != "CreateUserImport" {
+		return next
+	}
+	return func(ctx context.Context, w http.ResponseWriter, r *http.Request, request any) (any, error) {
+		importRequest, ok := request.(contractapi.CreateUserImportRequestObject)
+		if !ok || importRequest.JSONBody != nil {
+			return next(ctx, w, r, request)
+		}
+		importRequest.Body = nil
+		if mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err == nil {
+			if format, ok := streamedImportFormats[mediaType]; ok {
+				importRequest.Body = streamedImportBody{Reader: r.Body, format: format}
+				deadline := time.Now().Add(streamedImportWriteTimeout)
+				if err := http.NewResponseController(w).SetWriteDeadline(deadline); err != nil && !errors.Is(err, http.ErrNotSupported) {
+					return nil, fmt.Errorf("extend streamed import write deadline: %w", err)
+				}
+			}
+		}
+		return next(ctx, w, r, importRequest)
+	}
+}
@@ -0,0 +1,89 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var ErrInvalidCallbackURL = errors.New("invalid import callback URL")
+
+const maxCallbackURLBytes = 2048
+
+// CallbackAttempt records one delivery of an import's completion callback.
+// StatusCode is zero and Error is set when no response was received.
+type CallbackAttempt struct {
+	Attempt     int
+	AttemptedAt time.Time
+	StatusCode  int
+	Error       string
+}
+
+type CallbackSender interface {
+	// Send posts the import to the callback URL and returns the response
+	// status code, which is zero when no response was received.
+	Send(context.Context, string, Import) (int, error)
+}
+
+type CallbackRepository interface {
+	GetImport(context.Context, uuid.UUID) (Import, error)
+	RecordCallbackAttempt(context.Context, uuid.UUID, CallbackAttempt) error
+}
+
+type CallbackService struct {
+	repository CallbackRepository
+	sender     CallbackSender
+}
+
+func NewCallbackService(repository CallbackRepository, sender CallbackSender) *CallbackService {
+	return &CallbackService{repository: repository, sender: sender}
+}
+
+// Deliver posts the finished import to its callback URL and records the
+// attempt. A response outside 2xx is an error, so the job retries it.
+func (s *CallbackService) Deliver(ctx context.Context, id uuid.UUID) error {
+	userImport, err := s.repository.GetImport(ctx, id)
+	if err != nil {
+		return fmt.Errorf("get user import: %w", err)
+	}
+	if userImport.CallbackURL == "" {
+		return nil
+	}
+	userImport.CallbackAttempts = nil
+
+	status, sendErr := s.sender.Send(ctx, userImport.CallbackURL, userImport)
+	attempt := CallbackAttempt{StatusCode: status}
+	if sendErr != nil {
+		attempt.Error = sendErr.Error()
+	}
+	if err := s.repository.RecordCallbackAttempt(ctx, id, attempt); err != nil {
+		return fmt.Errorf("record import callback attempt: %w", err)
+	}
+	if sendErr != nil {
+		return fmt.Errorf("send import callback: %w", sendErr)
+	}
+	if status < 200 || status > 299 {
+		return fmt.Errorf("send import callback: endpoint returned status %d", status)
+	}
+	return nil
+}
+
+// normalizeCallbackURL accepts only absolute HTTPS URLs without credentials,
+// so that callbacks neither travel in clear text nor leak secrets into logs.
+func normalizeCallbackURL(raw string) (string, error) {
+	if raw == "" {
+		return "", nil
+	}
+	if len(raw) > maxCallbackURLBytes {
+		return "", ErrInvalidCallbackURL
+	}
+	parsed, err := url.Parse(raw)
+	if err != nil || parsed.Scheme != "https" || parsed.Host == "" || parsed.User != nil || parsed.Fragment != "" {
+		return "", ErrInvalidCallbackURL
+	}
+	return parsed.String(), nil
+}
@@ -0,0 +1,71 @@
+package users
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+type ImportNotifications interface {
+	// SubscribeImport returns a channel that receives a value after changes to
+	// the import are committed. Signals may be coalesced, and the channel is
+	// closed when notifications stop. The returned function unsubscribes.
+	SubscribeImport(uuid.UUID) (<-chan struct{}, func())
+}
+
+type ImportProgress struct {
+	repository    ImportRepository
+	notifications ImportNotifications
+}
+
+func NewImportProgress(repository ImportRepository, notifications ImportNotifications) *ImportProgress {
+	return &ImportProgress{repository: repository, notifications: notifications}
+}
+
+// Watch calls emit with the import's current state and again after each
+// committed change to its state or counters. It returns nil once an import in
+// a final state has been emitted or notifications stop, and ctx.Err() when
+// the context ends first.
+func (p *ImportProgress) Watch(ctx context.Context, id uuid.UUID, emit func(Import) error) error {
+	// Subscribing before the first read guarantees that no change between the
+	// read and the subscription is missed.
+	changes, unsubscribe := p.notifications.SubscribeImport(id)
+	defer unsubscribe()
+
+	var last Import
+	for first := true; ; first = false {
+		current, err := p.repository.GetImport(ctx, id)
+		if err != nil {
+			return fmt.Errorf("get user import: %w", err)
+		}
+		if first || progressChanged(last, current) {
+			if err := emit(current); err != nil {
+				return err
+			}
+			last = current
+		}
+		if current.finished() {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case _, open := <-changes:
+			if !open {
+				return nil
+			}
+		}
+	}
+}
+
+func progressChanged(previous, current Import) bool {
+	return previous.State != current.State ||
+		previous.CompletedCount != current.CompletedCount ||
+		previous.FailedCount != current.FailedCount
+}
+
+func (i Import) finished() bool {
+	return i.State == ImportStateCompleted || i.State == ImportStateFailed || i.State == ImportStateCancelled
+}
@@ -0,0 +1,77 @@
+package users
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImportProgressWatchEmitsChangesUntilFinished(t *testing.T) {
+	t.Parallel()
+
+	states := []Import{
+		{State: ImportStateRunning, CompletedCount: 1},
+		{State: ImportStateRunning, CompletedCount: 1},
+		{State: ImportStateRunning, CompletedCount: 2},
+		{State: ImportStateCompleted, CompletedCount: 3},
+	}
+	repository := &progressRepositoryStub{states: states}
+	notifications := &importNotificationsStub{changes: make(chan struct{}, len(states))}
+	for range len(states) - 1 {
+		notifications.changes <- struct{}{}
+	}
+
+	var emitted []Import
+	err := NewImportProgress(repository, notifications).Watch(t.Context(), uuid.New(), func(userImport Import) error {
+		emitted = append(emitted, userImport)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []Import{states[0], states[2], states[3]}, emitted)
+	assert.True(t, notifications.unsubscribed)
+}
+
+func TestImportProgressWatchStopsWithNotifications(t *testing.T) {
+	t.Parallel()
+
+	notifications := &importNotificationsStub{changes: make(chan struct{})}
+	close(notifications.changes)
+	repository := &progressRepositoryStub{states: []Import{{State: ImportStatePending}}}
+	emitted := 0
+	require.NoError(t, NewImportProgress(repository, notifications).Watch(t.Context(), uuid.New(), func(Import) error {
+		emitted++
+		return nil
+	}))
+	assert.Equal(t, 1, emitted)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	notifications = &importNotificationsStub{changes: make(chan struct{})}
+	err := NewImportProgress(repository, notifications).Watch(ctx, uuid.New(), func(Import) error { return nil })
+	require.ErrorIs(t, err, context.Canceled)
+}
+
+type progressRepositoryStub struct {
+	importRepositoryStub
+	states []Import
+}
+
+func (r *progressRepositoryStub) GetImport(context.Context, uuid.UUID) (Import, error) {
+	current := r.states[0]
+	if len(r.states) > 1 {
+		r.states = r.states[1:]
+	}
+	return current, nil
+}
+
+type importNotificationsStub struct {
+	changes      chan struct{}
+	unsubscribed bool
+}
+
+func (n *importNotificationsStub) SubscribeImport(uuid.UUID) (<-chan struct{}, func()) {
+	return n.changes, func() { n.unsubscribed = true }
+}
@@ -0,0 +1,186 @@
+package users
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+const (
+	MaxStreamedImportEntries = 100_000
+
+	importChunkSize     = 1000
+	maxImportEmailBytes = 320
+	maxNDJSONLineBytes  = 64 << 10
+)
+
+type ImportFormat string
+
+const (
+	ImportFormatCSV    ImportFormat = "csv"
+	ImportFormatNDJSON ImportFormat = "ndjson"
+)
+
+// ImportLineError reports the first record that makes a streamed import
+// invalid. It matches ErrInvalidImport.
+type ImportLineError struct {
+	Line   int
+	Reason string
+}
+
+func (e *ImportLineError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Reason)
+}
+
+func (e *ImportLineError) Unwrap() error {
+	return ErrInvalidImport
+}
+
+// ImportChunks yields the entries of an import in order, one chunk per call,
+// and returns io.EOF after the last chunk.
+type ImportChunks func() ([]ImportEntry, error)
+
+type importRecords interface {
+	// next returns the next email and its line number, or io.EOF.
+	next() (string, int, error)
+}
+
+func newImportRecords(format ImportFormat, body io.Reader) (importRecords, error) {
+	switch format {
+	case ImportFormatCSV:
+		return newCSVImportRecords(body)
+	case ImportFormatNDJSON:
+		scanner := bufio.NewScanner(body)
+		scanner.Buffer(make([]byte, 0, 4<<10), maxNDJSONLineBytes)
+		return &ndjsonImportRecords{scanner: scanner}, nil
+	default:
+		return nil, fmt.Errorf("%w: unsupported format %q", ErrInvalidImport, format)
+	}
+}
+
+type csvImportRecords struct {
+	reader *csv.Reader
+	column int
+}
+
+// newCSVImportRecords reads the header row and locates its email column.
+// Other columns are ignored so that exports from other systems can be used
+// unchanged.
+func newCSVImportRecords(body io.Reader) (*csvImportRecords, error) {
+	reader := csv.NewReader(body)
+	reader.ReuseRecord = true
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, &ImportLineError{Line: 1, Reason: "missing header row"}
+	}
+	if err != nil {
+		return nil, csvImportError(err)
+	}
+	column := slices.IndexFunc(header, func(name string) bool {
+		return strings.EqualFold(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")), "email")
+	})
+	if column < 0 {
+		return nil, &ImportLineError{Line: 1, Reason: `header row has no "email" column`}
+	}
+	return &csvImportRecords{reader: reader, column: column}, nil
+}
+
+func (r *csvImportRecords) next() (string, int, error) {
+	record, err := r.reader.Read()
+	if err != nil {
+		return "", 0, csvImportError(err)
+	}
+	line, _ := r.reader.FieldPos(r.column)
+	return record[r.column], line, nil
+}
+
+func csvImportError(err error) error {
+	var parseError *csv.ParseError
+	if errors.As(err, &parseError) {
+		return &ImportLineError{Line: parseError.Line, Reason: parseError.Err.Error()}
+	}
+	return err
+}
+
+type ndjsonImportRecords struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+func (r *ndjsonImportRecords) next() (string, int, error) {
+	for r.scanner.Scan() {
+		r.line++
+		text := bytes.TrimSpace(r.scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+		var record struct {
+			Email *string `json:"email"`
+		}
+		if err := json.Unmarshal(text, &record); err != nil || record.Email == nil {
+			return "", r.line, &ImportLineError{Line: r.line, Reason: `expected a JSON object with a string "email" field`}
+		}
+		return *record.Email, r.line, nil
+	}
+	if err := r.scanner.Err(); err != nil {
+		if errors.Is(err, bufio.ErrTooLong) {
+			return "", 0, &ImportLineError{Line: r.line + 1, Reason: "line is too long"}
+		}
+		return "", 0, err
+	}
+	return "", 0, io.EOF
+}
+
+// importChunker validates, normalizes, and deduplicates records as they are
+// read and assigns each entry its user ID.
+type importChunker struct {
+	records importRecords
+	seen    map[string]struct{}
+	count   int
+}
+
+func (c *importChunker) next() ([]ImportEntry, error) {
+	entries := make([]ImportEntry, 0, importChunkSize)
+	for len(entries) < importChunkSize {
+		email, line, err := c.records.next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if c.count == MaxStreamedImportEntries {
+			return nil, &ImportLineError{Line: line, Reason: fmt.Sprintf("import exceeds %d entries", MaxStreamedImportEntries)}
+		}
+		normalized, err := normalizeEmail(email)
+		if err != nil || len(normalized) > maxImportEmailBytes {
+			return nil, &ImportLineError{Line: line, Reason: "invalid email address"}
+		}
+		if _, exists := c.seen[normalized]; exists {
+			return nil, &ImportLineError{Line: line, Reason: "duplicate email address"}
+		}
+		c.seen[normalized] = struct{}{}
+
+		userID, err := uuid.NewV7()
+		if err != nil {
+			return nil, fmt.Errorf("generate imported user ID: %w", err)
+		}
+		entries = append(entries, ImportEntry{UserID: userID, Email: normalized})
+		c.count++
+	}
+	if len(entries) == 0 {
+		if c.count == 0 {
+			return nil, fmt.Errorf("%w: no entries", ErrInvalidImport)
+		}
+		return nil, io.EOF
+	}
+	return entries, nil
+}
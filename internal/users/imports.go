@@ -4,12 +4,17 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"time"
 
 	"github.com/google/uuid"
 )
 
-var ErrInvalidImport = errors.New("invalid user import")
+var (
+	ErrInvalidImport      = errors.New("invalid user import")
+	ErrImportFinished     = errors.New("user import already finished")
+	ErrImportNotRetryable = errors.New("user import has no failed entries to retry")
+)
 
 type ImportState string
 
@@ -18,6 +23,7 @@ const (
 	ImportStateRunning   ImportState = "running"
 	ImportStateCompleted ImportState = "completed"
 	ImportStateFailed    ImportState = "failed"
+	ImportStateCancelled ImportState = "cancelled"
 )
 
 type Import struct {
@@ -29,17 +35,73 @@ type Import struct {
 	CreatedAt      time.Time
 	StartedAt      *time.Time
 	FinishedAt     *time.Time
-	Entries        []ImportEntry
+	// RetryOf is the import whose failed entries this import retries.
+	RetryOf *uuid.UUID
+	// CallbackURL receives the import once it finishes; CallbackAttempts
+	// records each delivery.
+	CallbackURL      string
+	CallbackAttempts []CallbackAttempt
+	Entries          []ImportEntry
 }
 
+type ImportEntryState string
+
+const (
+	ImportEntryStatePending   ImportEntryState = "pending"
+	ImportEntryStateCompleted ImportEntryState = "completed"
+	ImportEntryStateFailed    ImportEntryState = "failed"
+)
+
+// ImportFailureReason records why a failed entry did not create its user.
+type ImportFailureReason string
+
+const (
+	// ImportFailureEmailTaken means another active user already has the email.
+	ImportFailureEmailTaken ImportFailureReason = "email_taken"
+	// ImportFailureInvalid means the user could not be created for any other
+	// reason, such as a conflicting user ID.
+	ImportFailureInvalid ImportFailureReason = "invalid"
+)
+
 type ImportEntry struct {
-	UserID uuid.UUID
-	Email  string
+	UserID        uuid.UUID
+	Email         string
+	State         ImportEntryState
+	FailureReason ImportFailureReason
+}
+
+// EntryFilter selects one page of an import's entries ordered by user ID. An
+// empty State selects entries in every state.
+type EntryFilter struct {
+	Cursor string
+	Limit  int
+	State  ImportEntryState
+}
+
+type EntryPage struct {
+	Entries    []ImportEntry
+	NextCursor string
+}
+
+// EntryQuery is the decoded keyset query passed to the repository. A nil
+// AfterUserID starts from the first entry.
+type EntryQuery struct {
+	AfterUserID uuid.UUID
+	Limit       int
+	State       ImportEntryState
 }
 
 type ImportRepository interface {
 	CreateImport(context.Context, Import) (Import, error)
+	// CreateStreamedImport stores the import and every chunk in one
+	// transaction and sets TotalCount from the stored entries.
+	CreateStreamedImport(context.Context, Import, ImportChunks) (Import, error)
 	GetImport(context.Context, uuid.UUID) (Import, error)
+	// CancelImport moves a pending or running import to ImportStateCancelled
+	// and returns ErrImportFinished for any other import.
+	CancelImport(context.Context, uuid.UUID) (Import, error)
+	// ListImportEntries returns ErrNotFound when the import does not exist.
+	ListImportEntries(context.Context, uuid.UUID, EntryQuery) ([]ImportEntry, error)
 	ProcessImport(context.Context, uuid.UUID) error
 	DeleteFinishedImportsBefore(context.Context, time.Time) (int64, error)
 }
@@ -52,10 +114,14 @@ func NewImportService(repository ImportRepository) *ImportService {
 	return &ImportService{repository: repository}
 }
 
-func (s *ImportService) Create(ctx context.Context, emails []string) (Import, error) {
+func (s *ImportService) Create(ctx context.Context, emails []string, callbackURL string) (Import, error) {
 	if len(emails) < 1 || len(emails) > 100 {
 		return Import{}, ErrInvalidImport
 	}
+	callbackURL, err := normalizeCallbackURL(callbackURL)
+	if err != nil {
+		return Import{}, err
+	}
 
 	entries := make([]ImportEntry, 0, len(emails))
 	seen := make(map[string]struct{}, len(emails))
@@ -82,10 +148,11 @@ func (s *ImportService) Create(ctx context.Context, emails []string) (Import, er
 	}
 
 	created, err := s.repository.CreateImport(ctx, Import{
-		ID:         importID,
-		State:      ImportStatePending,
-		TotalCount: len(entries),
-		Entries:    entries,
+		ID:          importID,
+		State:       ImportStatePending,
+		TotalCount:  len(entries),
+		CallbackURL: callbackURL,
+		Entries:     entries,
 	})
 	if err != nil {
 		return Import{}, fmt.Errorf("create user import: %w", err)
@@ -93,6 +160,27 @@ func (s *ImportService) Create(ctx context.Context, emails []string) (Import, er
 	return created, nil
 }
 
+// CreateStreamed creates an import from CSV or NDJSON records. Records are
+// validated and stored in chunks as they are read, so the body is never
+// buffered, and an invalid record aborts the whole import.
+func (s *ImportService) CreateStreamed(ctx context.Context, format ImportFormat, body io.Reader) (Import, error) {
+	records, err := newImportRecords(format, body)
+	if err != nil {
+		return Import{}, err
+	}
+	importID, err := uuid.NewV7()
+	if err != nil {
+		return Import{}, fmt.Errorf("generate user import ID: %w", err)
+	}
+
+	chunker := &importChunker{records: records, seen: make(map[string]struct{})}
+	created, err := s.repository.CreateStreamedImport(ctx, Import{ID: importID, State: ImportStatePending}, chunker.next)
+	if err != nil {
+		return Import{}, fmt.Errorf("create streamed user import: %w", err)
+	}
+	return created, nil
+}
+
 func (s *ImportService) Get(ctx context.Context, id uuid.UUID) (Import, error) {
 	userImport, err := s.repository.GetImport(ctx, id)
 	if err != nil {
@@ -101,6 +189,97 @@ func (s *ImportService) Get(ctx context.Context, id uuid.UUID) (Import, error) {
 	return userImport, nil
 }
 
+func (s *ImportService) Cancel(ctx context.Context, id uuid.UUID) (Import, error) {
+	cancelled, err := s.repository.CancelImport(ctx, id)
+	if err != nil {
+		return Import{}, fmt.Errorf("cancel user import: %w", err)
+	}
+	return cancelled, nil
+}
+
+// RetryFailed creates an import from the failed entries of a finished import.
+// Each entry gets a new user ID, because the failed ID may be the conflict. The
+// new import keeps the original callback URL.
+func (s *ImportService) RetryFailed(ctx context.Context, id uuid.UUID) (Import, error) {
+	source, err := s.repository.GetImport(ctx, id)
+	if err != nil {
+		return Import{}, fmt.Errorf("get user import: %w", err)
+	}
+	if source.State == ImportStatePending || source.State == ImportStateRunning || source.FailedCount == 0 {
+		return Import{}, ErrImportNotRetryable
+	}
+	importID, err := uuid.NewV7()
+	if err != nil {
+		return Import{}, fmt.Errorf("generate user import ID: %w", err)
+	}
+
+	var afterUserID uuid.UUID
+	created, err := s.repository.CreateStreamedImport(ctx, Import{
+		ID: importID, State: ImportStatePending, RetryOf: &source.ID, CallbackURL: source.CallbackURL,
+	}, func() ([]ImportEntry, error) {
+		failed, err := s.repository.ListImportEntries(ctx, source.ID, EntryQuery{
+			AfterUserID: afterUserID,
+			Limit:       importChunkSize,
+			State:       ImportEntryStateFailed,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if len(failed) == 0 {
+			return nil, io.EOF
+		}
+		afterUserID = failed[len(failed)-1].UserID
+
+		entries := make([]ImportEntry, 0, len(failed))
+		for _, entry := range failed {
+			userID, err := uuid.NewV7()
+			if err != nil {
+				return nil, fmt.Errorf("generate imported user ID: %w", err)
+			}
+			entries = append(entries, ImportEntry{UserID: userID, Email: entry.Email})
+		}
+		return entries, nil
+	})
+	if err != nil {
+		return Import{}, fmt.Errorf("create retry user import: %w", err)
+	}
+	return created, nil
+}
+
+func (s *ImportService) ListEntries(ctx context.Context, id uuid.UUID, filter EntryFilter) (EntryPage, error) {
+	if filter.Limit == 0 {
+		filter.Limit = DefaultListLimit
+	}
+	if filter.Limit < 1 || filter.Limit > MaxListLimit {
+		return EntryPage{}, ErrInvalidListFilter
+	}
+	switch filter.State {
+	case "", ImportEntryStatePending, ImportEntryStateCompleted, ImportEntryStateFailed:
+	default:
+		return EntryPage{}, ErrInvalidListFilter
+	}
+	afterUserID, err := decodeCursor(filter.Cursor)
+	if err != nil {
+		return EntryPage{}, err
+	}
+
+	listed, err := s.repository.ListImportEntries(ctx, id, EntryQuery{
+		AfterUserID: afterUserID,
+		Limit:       filter.Limit + 1,
+		State:       filter.State,
+	})
+	if err != nil {
+		return EntryPage{}, fmt.Errorf("list user import entries: %w", err)
+	}
+
+	page := EntryPage{Entries: listed}
+	if len(listed) > filter.Limit {
+		page.Entries = listed[:filter.Limit]
+		page.NextCursor = encodeCursor(page.Entries[filter.Limit-1].UserID)
+	}
+	return page, nil
+}
+
 func (s *ImportService) Process(ctx context.Context, id uuid.UUID) error {
 	if err := s.repository.ProcessImport(ctx, id); err != nil {
 		return fmt.Errorf("process user import: %w", err)
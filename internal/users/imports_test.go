@@ -2,6 +2,11 @@ package users
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
 	"testing"
 	"time"
 
@@ -15,7 +20,7 @@ func TestImportServiceCreateNormalizesAndAssignsIDs(t *testing.T) {
 
 	repository := &importRepositoryStub{}
 	service := NewImportService(repository)
-	created, err := service.Create(t.Context(), []string{" One@Example.COM ", "two@example.com"})
+	created, err := service.Create(t.Context(), []string{" One@Example.COM ", "two@example.com"}, "")
 	require.NoError(t, err)
 	assert.Equal(t, uuid.Version(7), created.ID.Version())
 	require.Len(t, created.Entries, 2)
@@ -36,14 +41,184 @@ func TestImportServiceCreateRejectsInvalidLists(t *testing.T) {
 	} {
 		t.Run(name, func(t *testing.T) {
 			t.Parallel()
-			_, err := service.Create(t.Context(), emails)
+			_, err := service.Create(t.Context(), emails, "")
 			require.ErrorIs(t, err, ErrInvalidImport)
 		})
 	}
 }
 
+func TestImportServiceCreateStreamedParsesFormats(t *testing.T) {
+	t.Parallel()
+
+	for name, testCase := range map[string]struct {
+		format ImportFormat
+		body   string
+	}{
+		"csv": {
+			format: ImportFormatCSV,
+			body:   "\ufeffname,Email\nOne,\" One@Example.COM \"\n\nTwo,two@example.com\n",
+		},
+		"ndjson": {
+			format: ImportFormatNDJSON,
+			body:   "{\"email\":\" One@Example.COM \",\"name\":\"One\"}\n\n{\"email\":\"two@example.com\"}",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			repository := &importRepositoryStub{}
+			created, err := NewImportService(repository).CreateStreamed(t.Context(), testCase.format, strings.NewReader(testCase.body))
+			require.NoError(t, err)
+			assert.Equal(t, 2, created.TotalCount)
+			require.Len(t, repository.streamed, 2)
+			assert.Equal(t, "one@example.com", repository.streamed[0].Email)
+			assert.Equal(t, "two@example.com", repository.streamed[1].Email)
+			assert.Equal(t, uuid.Version(7), repository.streamed[0].UserID.Version())
+		})
+	}
+}
+
+func TestImportServiceCreateStreamedStoresChunks(t *testing.T) {
+	t.Parallel()
+
+	var body strings.Builder
+	body.WriteString("email\n")
+	for index := range importChunkSize + 1 {
+		fmt.Fprintf(&body, "person-%d@example.com\n", index)
+	}
+	repository := &importRepositoryStub{}
+	created, err := NewImportService(repository).CreateStreamed(t.Context(), ImportFormatCSV, strings.NewReader(body.String()))
+	require.NoError(t, err)
+	assert.Equal(t, importChunkSize+1, created.TotalCount)
+	assert.Equal(t, []int{importChunkSize, 1}, repository.chunkSizes)
+}
+
+func TestImportServiceCreateStreamedReportsInvalidLines(t *testing.T) {
+	t.Parallel()
+
+	for name, testCase := range map[string]struct {
+		format ImportFormat
+		body   string
+		want   string
+	}{
+		"missing email column": {ImportFormatCSV, "name\nOne\n", `line 1: header row has no "email" column`},
+		"invalid csv email":    {ImportFormatCSV, "email\none@example.com\nnot-an-email\n", "line 3: invalid email address"},
+		"ragged csv row":       {ImportFormatCSV, "email,name\none@example.com\n", "line 2: wrong number of fields"},
+		"duplicate ndjson":     {ImportFormatNDJSON, "{\"email\":\"a@example.com\"}\n{\"email\":\"A@example.com\"}\n", "line 2: duplicate email address"},
+		"malformed ndjson":     {ImportFormatNDJSON, "{\"email\":\"a@example.com\"}\n[\"b@example.com\"]\n", `line 2: expected a JSON object with a string "email" field`},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			_, err := NewImportService(&importRepositoryStub{}).CreateStreamed(t.Context(), testCase.format, strings.NewReader(testCase.body))
+			require.ErrorIs(t, err, ErrInvalidImport)
+			var lineError *ImportLineError
+			require.ErrorAs(t, err, &lineError)
+			assert.Equal(t, testCase.want, lineError.Error())
+		})
+	}
+
+	_, err := NewImportService(&importRepositoryStub{}).CreateStreamed(t.Context(), ImportFormatNDJSON, strings.NewReader("\n"))
+	require.ErrorIs(t, err, ErrInvalidImport)
+}
+
+func TestImportServiceListEntriesPaginatesWithOpaqueCursor(t *testing.T) {
+	t.Parallel()
+
+	importID := uuid.MustParse("0198a1f7-30b7-7df0-8491-c47f6033525b")
+	listed := []ImportEntry{
+		{UserID: uuid.MustParse("0198a1f7-30b7-7df1-8491-c47f6033525b"), State: ImportEntryStateFailed, FailureReason: ImportFailureEmailTaken},
+		{UserID: uuid.MustParse("0198a1f7-30b7-7df2-8491-c47f6033525b"), State: ImportEntryStateFailed, FailureReason: ImportFailureInvalid},
+		{UserID: uuid.MustParse("0198a1f7-30b7-7df3-8491-c47f6033525b"), State: ImportEntryStateFailed, FailureReason: ImportFailureEmailTaken},
+	}
+	repository := &importRepositoryStub{listed: listed}
+	service := NewImportService(repository)
+
+	page, err := service.ListEntries(t.Context(), importID, EntryFilter{Limit: 2, State: ImportEntryStateFailed})
+	require.NoError(t, err)
+	assert.Equal(t, listed[:2], page.Entries)
+	require.NotEmpty(t, page.NextCursor)
+	assert.Equal(t, importID, repository.listedImportID)
+	assert.Equal(t, EntryQuery{Limit: 3, State: ImportEntryStateFailed}, repository.entryQuery)
+
+	repository.listed = listed[2:]
+	page, err = service.ListEntries(t.Context(), importID, EntryFilter{Cursor: page.NextCursor})
+	require.NoError(t, err)
+	assert.Equal(t, EntryQuery{AfterUserID: listed[1].UserID, Limit: DefaultListLimit + 1}, repository.entryQuery)
+	assert.Equal(t, listed[2:], page.Entries)
+	assert.Empty(t, page.NextCursor)
+}
+
+func TestImportServiceListEntriesRejectsInvalidInput(t *testing.T) {
+	t.Parallel()
+
+	service := NewImportService(&importRepositoryStub{})
+	for name, testCase := range map[string]struct {
+		filter EntryFilter
+		want   error
+	}{
+		"malformed cursor":  {filter: EntryFilter{Cursor: "%%%"}, want: ErrInvalidCursor},
+		"limit above range": {filter: EntryFilter{Limit: MaxListLimit + 1}, want: ErrInvalidListFilter},
+		"unknown state":     {filter: EntryFilter{State: "running"}, want: ErrInvalidListFilter},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			_, err := service.ListEntries(t.Context(), uuid.New(), testCase.filter)
+			require.ErrorIs(t, err, testCase.want)
+		})
+	}
+}
+
+func TestImportServiceRetryFailedCopiesFailedEntries(t *testing.T) {
+	t.Parallel()
+
+	source := Import{
+		ID:          uuid.MustParse("0198a1f7-30b7-7df0-8491-c47f6033525b"),
+		State:       ImportStateFailed,
+		FailedCount: 2,
+		CallbackURL: "https://hooks.example.com/imports",
+	}
+	failed := []ImportEntry{
+		{UserID: uuid.MustParse("0198a1f7-30b7-7df1-8491-c47f6033525b"), Email: "one@example.com", State: ImportEntryStateFailed},
+		{UserID: uuid.MustParse("0198a1f7-30b7-7df2-8491-c47f6033525b"), Email: "two@example.com", State: ImportEntryStateFailed},
+	}
+	repository := &importRepositoryStub{current: source, listed: failed}
+
+	created, err := NewImportService(repository).RetryFailed(t.Context(), source.ID)
+	require.NoError(t, err)
+	assert.Equal(t, &source.ID, created.RetryOf)
+	assert.Equal(t, source.CallbackURL, created.CallbackURL)
+	assert.NotEqual(t, source.ID, created.ID)
+	assert.Equal(t, 2, created.TotalCount)
+	require.Len(t, repository.streamed, 2)
+	for index, entry := range repository.streamed {
+		assert.Equal(t, failed[index].Email, entry.Email)
+		assert.NotEqual(t, failed[index].UserID, entry.UserID)
+	}
+	assert.Equal(t, ImportEntryStateFailed, repository.entryQuery.State)
+}
+
+func TestImportServiceRetryFailedRejectsUnretryableImports(t *testing.T) {
+	t.Parallel()
+
+	for name, source := range map[string]Import{
+		"running":           {State: ImportStateRunning, FailedCount: 1},
+		"no failed entries": {State: ImportStateCompleted},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			_, err := NewImportService(&importRepositoryStub{current: source}).RetryFailed(t.Context(), uuid.New())
+			require.ErrorIs(t, err, ErrImportNotRetryable)
+		})
+	}
+}
+
 type importRepositoryStub struct {
-	created Import
+	created        Import
+	streamed       []ImportEntry
+	chunkSizes     []int
+	listed         []ImportEntry
+	listedImportID uuid.UUID
+	entryQuery     EntryQuery
+	current        Import
 }
 
 func (r *importRepositoryStub) CreateImport(_ context.Context, userImport Import) (Import, error) {
@@ -51,7 +226,36 @@ func (r *importRepositoryStub) CreateImport(_ context.Context, userImport Import
 	return userImport, nil
 }
 
+func (r *importRepositoryStub) CreateStreamedImport(_ context.Context, userImport Import, chunks ImportChunks) (Import, error) {
+	for {
+		entries, err := chunks()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return Import{}, err
+		}
+		r.streamed = append(r.streamed, entries...)
+		r.chunkSizes = append(r.chunkSizes, len(entries))
+	}
+	userImport.TotalCount = len(r.streamed)
+	return userImport, nil
+}
+
+func (r *importRepositoryStub) ListImportEntries(_ context.Context, importID uuid.UUID, query EntryQuery) ([]ImportEntry, error) {
+	r.listedImportID, r.entryQuery = importID, query
+	listed := r.listed
+	if query.AfterUserID != uuid.Nil {
+		listed = listed[slices.IndexFunc(listed, func(entry ImportEntry) bool { return entry.UserID == query.AfterUserID })+1:]
+	}
+	return listed, nil
+}
+
 func (r *importRepositoryStub) GetImport(context.Context, uuid.UUID) (Import, error) {
+	return r.current, nil
+}
+
+func (r *importRepositoryStub) CancelImport(context.Context, uuid.UUID) (Import, error) {
 	return Import{}, nil
 }
 
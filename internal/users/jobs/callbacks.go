@@ -0,0 +1,51 @@
+package usersjobs
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/riverqueue/river"
+)
+
+const QueueCallbacks = "callbacks"
+
+type DeliverImportCallbackArgs struct {
+	ImportID uuid.UUID `json:"importId"`
+}
+
+func (DeliverImportCallbackArgs) Kind() string { return "users.deliver-import-callback" }
+
+type ImportCallbackDeliverer interface {
+	Deliver(context.Context, uuid.UUID) error
+}
+
+// DeliverImportCallbackWorker relies on River's exponential backoff between
+// attempts, so an unavailable endpoint is retried for about a day.
+type DeliverImportCallbackWorker struct {
+	river.WorkerDefaults[DeliverImportCallbackArgs]
+	callbacks ImportCallbackDeliverer
+}
+
+func NewDeliverImportCallbackWorker(callbacks ImportCallbackDeliverer) *DeliverImportCallbackWorker {
+	return &DeliverImportCallbackWorker{callbacks: callbacks}
+}
+
+func (w *DeliverImportCallbackWorker) Work(ctx context.Context, job *river.Job[DeliverImportCallbackArgs]) error {
+	if err := w.callbacks.Deliver(ctx, job.Args.ImportID); err != nil {
+		return fmt.Errorf("deliver user import callback: %w", err)
+	}
+	return nil
+}
+
+func (e *Enqueuer) EnqueueImportCallback(ctx context.Context, tx pgx.Tx, importID uuid.UUID) error {
+	_, err := e.client.InsertTx(ctx, tx, DeliverImportCallbackArgs{ImportID: importID}, &river.InsertOpts{
+		Queue:       QueueCallbacks,
+		MaxAttempts: 12,
+	})
+	if err != nil {
+		return fmt.Errorf("insert users.deliver-import-callback job: %w", err)
+	}
+	return nil
+}
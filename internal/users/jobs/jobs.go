@@ -2,6 +2,7 @@ package usersjobs
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"time"
@@ -9,6 +10,8 @@ import (
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 	"github.com/riverqueue/river"
+
+	"github.com/your-org/go-service-template/internal/platform/httpserver"
 )
 
 const QueueUsers = "users"
@@ -23,6 +26,14 @@ type CleanupImportsArgs struct{}
 
 func (CleanupImportsArgs) Kind() string { return "users.cleanup-imports" }
 
+type CleanupIdempotencyKeysArgs struct{}
+
+func (CleanupIdempotencyKeysArgs) Kind() string { return "users.cleanup-idempotency-keys" }
+
+type CleanupRateLimitBucketsArgs struct{}
+
+func (CleanupRateLimitBucketsArgs) Kind() string { return "users.cleanup-rate-limit-buckets" }
+
 type ImportProcessor interface {
 	Process(context.Context, uuid.UUID) error
 }
@@ -40,6 +51,12 @@ func NewImportWorker(imports ImportProcessor) *ImportWorker {
 	return &ImportWorker{imports: imports}
 }
 
+// Timeout allows a streamed import to finish in one attempt. Each batch
+// commits on its own, so a timed-out attempt resumes where it stopped.
+func (w *ImportWorker) Timeout(*river.Job[ImportArgs]) time.Duration {
+	return 30 * time.Minute
+}
+
 func (w *ImportWorker) Work(ctx context.Context, job *river.Job[ImportArgs]) error {
 	if err := w.imports.Process(ctx, job.Args.ImportID); err != nil {
 		return fmt.Errorf("process user import: %w", err)
@@ -66,21 +83,83 @@ func (w *CleanupImportsWorker) Work(ctx context.Context, _ *river.Job[CleanupImp
 	return nil
 }
 
+type IdempotencyKeyCleaner interface {
+	Cleanup(context.Context, time.Time) (int64, error)
+}
+
+type CleanupIdempotencyKeysWorker struct {
+	river.WorkerDefaults[CleanupIdempotencyKeysArgs]
+	keys   IdempotencyKeyCleaner
+	logger *slog.Logger
+}
+
+func NewCleanupIdempotencyKeysWorker(logger *slog.Logger, keys IdempotencyKeyCleaner) *CleanupIdempotencyKeysWorker {
+	return &CleanupIdempotencyKeysWorker{keys: keys, logger: logger}
+}
+
+func (w *CleanupIdempotencyKeysWorker) Work(ctx context.Context, _ *river.Job[CleanupIdempotencyKeysArgs]) error {
+	deleted, err := w.keys.Cleanup(ctx, time.Now().UTC().Add(-24*time.Hour))
+	if err != nil {
+		return fmt.Errorf("cleanup expired idempotency keys: %w", err)
+	}
+	w.logger.InfoContext(ctx, "cleaned up idempotency keys", "deleted_count", deleted)
+	return nil
+}
+
+type RateLimitBucketCleaner interface {
+	Cleanup(context.Context, time.Time) (int64, error)
+}
+
+type CleanupRateLimitBucketsWorker struct {
+	river.WorkerDefaults[CleanupRateLimitBucketsArgs]
+	buckets RateLimitBucketCleaner
+	logger  *slog.Logger
+}
+
+func NewCleanupRateLimitBucketsWorker(logger *slog.Logger, buckets RateLimitBucketCleaner) *CleanupRateLimitBucketsWorker {
+	return &CleanupRateLimitBucketsWorker{buckets: buckets, logger: logger}
+}
+
+// Work deletes buckets idle long enough to have refilled, which behave like
+// missing ones.
+func (w *CleanupRateLimitBucketsWorker) Work(ctx context.Context, _ *river.Job[CleanupRateLimitBucketsArgs]) error {
+	deleted, err := w.buckets.Cleanup(ctx, time.Now().UTC().Add(-httpserver.MaxRateLimitRefill))
+	if err != nil {
+		return fmt.Errorf("cleanup idle rate limit buckets: %w", err)
+	}
+	w.logger.InfoContext(ctx, "cleaned up rate limit buckets", "deleted_count", deleted)
+	return nil
+}
+
 type Enqueuer struct {
-	client *river.Client[pgx.Tx]
+	client      *river.Client[pgx.Tx]
+	outbox      OutboxWriter
+	serviceName string
 }
 
-func NewEnqueuer(client *river.Client[pgx.Tx]) *Enqueuer {
-	return &Enqueuer{client: client}
+// NewEnqueuer inserts jobs through client and writes user events, produced
+// by serviceName, to outbox.
+func NewEnqueuer(client *river.Client[pgx.Tx], outbox OutboxWriter, serviceName string) *Enqueuer {
+	return &Enqueuer{client: client, outbox: outbox, serviceName: serviceName}
 }
 
-func (e *Enqueuer) EnqueueImport(ctx context.Context, tx pgx.Tx, importID uuid.UUID) error {
-	_, err := e.client.InsertTx(ctx, tx, ImportArgs{ImportID: importID}, &river.InsertOpts{
+// EnqueueImport returns the River job ID so that the import can cancel it.
+func (e *Enqueuer) EnqueueImport(ctx context.Context, tx pgx.Tx, importID uuid.UUID) (int64, error) {
+	result, err := e.client.InsertTx(ctx, tx, ImportArgs{ImportID: importID}, &river.InsertOpts{
 		Queue:       QueueUsers,
 		MaxAttempts: 5,
 	})
 	if err != nil {
-		return fmt.Errorf("insert users.import job: %w", err)
+		return 0, fmt.Errorf("insert users.import job: %w", err)
+	}
+	return result.Job.ID, nil
+}
+
+// CancelImport cancels a queued import job, or signals a running one through
+// its context. Jobs that have finished or were already removed are ignored.
+func (e *Enqueuer) CancelImport(ctx context.Context, tx pgx.Tx, jobID int64) error {
+	if _, err := e.client.JobCancelTx(ctx, tx, jobID); err != nil && !errors.Is(err, river.ErrNotFound) {
+		return fmt.Errorf("cancel users.import job: %w", err)
 	}
 	return nil
 }
@@ -91,6 +170,18 @@ func PeriodicCleanup() *river.PeriodicJob {
 	}, &river.PeriodicJobOpts{ID: "users.cleanup-imports"})
 }
 
+func PeriodicIdempotencyCleanup() *river.PeriodicJob {
+	return river.NewPeriodicJob(river.PeriodicInterval(time.Hour), func() (river.JobArgs, *river.InsertOpts) {
+		return CleanupIdempotencyKeysArgs{}, &river.InsertOpts{Queue: QueueUsers, MaxAttempts: 5}
+	}, &river.PeriodicJobOpts{ID: "users.cleanup-idempotency-keys"})
+}
+
+func PeriodicRateLimitCleanup() *river.PeriodicJob {
+	return river.NewPeriodicJob(river.PeriodicInterval(time.Hour), func() (river.JobArgs, *river.InsertOpts) {
+		return CleanupRateLimitBucketsArgs{}, &river.InsertOpts{Queue: QueueUsers, MaxAttempts: 5}
+	}, &river.PeriodicJobOpts{ID: "users.cleanup-rate-limit-buckets"})
+}
+
 type dailyAtUTC struct {
 	hour int
 }
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/riverqueue/river"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -42,8 +43,14 @@ func TestPublishCreatedWorkerUsesCompatibleEnvelope(t *testing.T) {
 	require.NoError(t, worker.Work(t.Context(), &river.Job[PublishCreatedArgs]{Args: args}))
 
 	var envelope messaging.Envelope[CreatedPayload]
-	require.NoError(t, json.Unmarshal(publisher.message, &envelope))
+	require.NoError(t, json.Unmarshal(publisher.message.Body, &envelope))
+	assert.Equal(t, args.UserID.String(), publisher.message.GroupID)
+	assert.Equal(t, args.EventID.String(), publisher.message.DeduplicationID)
+	assert.Equal(t, "user.created", publisher.message.Type)
+	assert.Equal(t, "1.0.0", publisher.message.SchemaVersion)
 	assert.Equal(t, args.EventID.String(), envelope.ID)
+	require.NotNil(t, envelope.DeduplicationID)
+	assert.Equal(t, args.EventID.String(), *envelope.DeduplicationID)
 	assert.Equal(t, args.Timestamp, envelope.Timestamp)
 	assert.Equal(t, "user.created", envelope.Type)
 	assert.Equal(t, args.UserID, envelope.Payload.UserID)
@@ -53,11 +60,78 @@ func TestPublishCreatedWorkerUsesCompatibleEnvelope(t *testing.T) {
 	assert.Equal(t, args.CorrelationID, envelope.Metadata.CorrelationID)
 }
 
+func TestUserLifecycleWorkersPublishTypedEnvelopes(t *testing.T) {
+	t.Parallel()
+
+	eventID := uuid.MustParse("0198a1f7-30b7-7df1-8491-c47f6033525b")
+	userID := uuid.MustParse("0198a1f7-30b7-7df2-8491-c47f6033525b")
+	timestamp := time.Date(2026, time.July, 13, 8, 0, 0, 0, time.UTC)
+
+	updated := &publisherStub{}
+	require.NoError(t, NewPublishUpdatedWorker(updated, "go-service-template").Work(t.Context(), &river.Job[PublishUpdatedArgs]{
+		Args: PublishUpdatedArgs{EventID: eventID, UserID: userID, Timestamp: timestamp, CorrelationID: "request-123"},
+	}))
+	deleted := &publisherStub{}
+	require.NoError(t, NewPublishDeletedWorker(deleted, "go-service-template").Work(t.Context(), &river.Job[PublishDeletedArgs]{
+		Args: PublishDeletedArgs{EventID: eventID, UserID: userID, Timestamp: timestamp, CorrelationID: "request-123"},
+	}))
+
+	for eventType, message := range map[string]messaging.Message{"user.updated": updated.message, "user.deleted": deleted.message} {
+		var envelope messaging.Envelope[UpdatedPayload]
+		require.NoError(t, json.Unmarshal(message.Body, &envelope))
+		assert.Equal(t, userID.String(), message.GroupID, eventType)
+		assert.Equal(t, eventID.String(), envelope.ID, eventType)
+		assert.Equal(t, eventType, envelope.Type)
+		assert.Equal(t, userID, envelope.Payload.UserID, eventType)
+		assert.Equal(t, "request-123", envelope.Metadata.CorrelationID, eventType)
+	}
+}
+
+func TestEnqueuerWritesUserEventsToOutbox(t *testing.T) {
+	t.Parallel()
+
+	outbox := &outboxWriterStub{}
+	enqueuer := NewEnqueuer(nil, outbox, "go-service-template")
+	userID := uuid.MustParse("0198a1f7-30b7-7df2-8491-c47f6033525b")
+	ctx := messaging.WithCorrelationID(t.Context(), "request-123")
+	require.NoError(t, enqueuer.EnqueueUserCreated(ctx, nil, userID, "import-1"))
+	require.NoError(t, enqueuer.EnqueueUserUpdated(ctx, nil, userID))
+	require.NoError(t, enqueuer.EnqueueUserDeleted(t.Context(), nil, userID))
+
+	require.Len(t, outbox.messages, 3)
+	for index, want := range []struct{ eventType, correlationID string }{
+		{"user.created", "import-1"}, {"user.updated", "request-123"}, {"user.deleted", outbox.messages[2].EventID},
+	} {
+		message := outbox.messages[index]
+		var envelope messaging.Envelope[CreatedPayload]
+		require.NoError(t, json.Unmarshal(message.Body, &envelope))
+		assert.Equal(t, want.eventType, message.Type)
+		assert.Equal(t, userID.String(), message.GroupID, "events of one user share a FIFO message group")
+		assert.Equal(t, message.EventID, envelope.ID)
+		require.NotNil(t, envelope.DeduplicationID)
+		assert.Equal(t, envelope.ID, *envelope.DeduplicationID)
+		assert.Equal(t, uuid.Version(7), uuid.MustParse(envelope.ID).Version())
+		assert.Equal(t, want.eventType, envelope.Type)
+		assert.Equal(t, userID, envelope.Payload.UserID)
+		assert.Equal(t, "go-service-template", envelope.Metadata.ProducedBy)
+		assert.Equal(t, want.correlationID, envelope.Metadata.CorrelationID)
+	}
+}
+
+type outboxWriterStub struct {
+	messages []messaging.OutboxMessage
+}
+
+func (o *outboxWriterStub) AddToOutbox(_ context.Context, _ pgx.Tx, message messaging.OutboxMessage) error {
+	o.messages = append(o.messages, message)
+	return nil
+}
+
 type publisherStub struct {
-	message []byte
+	message messaging.Message
 }
 
-func (p *publisherStub) Publish(_ context.Context, message []byte) error {
+func (p *publisherStub) Publish(_ context.Context, message messaging.Message) error {
 	p.message = message
 	return nil
 }
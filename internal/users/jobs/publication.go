@@ -13,6 +13,8 @@ import (
 	"github.com/your-org/go-service-template/internal/platform/messaging"
 )
 
+// QueueEvents runs the publication jobs enqueued before user events moved to
+// the outbox, so that they drain after an upgrade.
 const QueueEvents = "events"
 
 type PublishCreatedArgs struct {
@@ -24,12 +26,42 @@ type PublishCreatedArgs struct {
 
 func (PublishCreatedArgs) Kind() string { return "users.publish-created" }
 
+type PublishUpdatedArgs struct {
+	EventID       uuid.UUID `json:"eventId"`
+	UserID        uuid.UUID `json:"userId"`
+	Timestamp     time.Time `json:"timestamp"`
+	CorrelationID string    `json:"correlationId"`
+}
+
+func (PublishUpdatedArgs) Kind() string { return "users.publish-updated" }
+
+type PublishDeletedArgs struct {
+	EventID       uuid.UUID `json:"eventId"`
+	UserID        uuid.UUID `json:"userId"`
+	Timestamp     time.Time `json:"timestamp"`
+	CorrelationID string    `json:"correlationId"`
+}
+
+func (PublishDeletedArgs) Kind() string { return "users.publish-deleted" }
+
 type CreatedPayload struct {
 	UserID uuid.UUID `json:"userId"`
 }
 
+type UpdatedPayload struct {
+	UserID uuid.UUID `json:"userId"`
+}
+
+type DeletedPayload struct {
+	UserID uuid.UUID `json:"userId"`
+}
+
+type OutboxWriter interface {
+	AddToOutbox(context.Context, pgx.Tx, messaging.OutboxMessage) error
+}
+
 type EventPublisher interface {
-	Publish(context.Context, []byte) error
+	Publish(context.Context, messaging.Message) error
 }
 
 type PublishCreatedWorker struct {
@@ -43,32 +75,123 @@ func NewPublishCreatedWorker(publisher EventPublisher, serviceName string) *Publ
 }
 
 func (w *PublishCreatedWorker) Work(ctx context.Context, job *river.Job[PublishCreatedArgs]) error {
-	envelope := messaging.Envelope[CreatedPayload]{
+	return publishUserEvent(ctx, w.publisher, job.Args.UserID, messaging.Envelope[CreatedPayload]{
 		ID:        job.Args.EventID.String(),
 		Timestamp: job.Args.Timestamp,
 		Type:      "user.created",
 		Payload:   CreatedPayload{UserID: job.Args.UserID},
-		Metadata: messaging.Metadata{
-			SchemaVersion:  "1.0.0",
-			ProducedBy:     w.serviceName,
-			OriginatedFrom: w.serviceName,
-			CorrelationID:  job.Args.CorrelationID,
-		},
+		Metadata:  eventMetadata(w.serviceName, job.Args.CorrelationID),
+	})
+}
+
+type PublishUpdatedWorker struct {
+	river.WorkerDefaults[PublishUpdatedArgs]
+	publisher   EventPublisher
+	serviceName string
+}
+
+func NewPublishUpdatedWorker(publisher EventPublisher, serviceName string) *PublishUpdatedWorker {
+	return &PublishUpdatedWorker{publisher: publisher, serviceName: serviceName}
+}
+
+func (w *PublishUpdatedWorker) Work(ctx context.Context, job *river.Job[PublishUpdatedArgs]) error {
+	return publishUserEvent(ctx, w.publisher, job.Args.UserID, messaging.Envelope[UpdatedPayload]{
+		ID:        job.Args.EventID.String(),
+		Timestamp: job.Args.Timestamp,
+		Type:      "user.updated",
+		Payload:   UpdatedPayload{UserID: job.Args.UserID},
+		Metadata:  eventMetadata(w.serviceName, job.Args.CorrelationID),
+	})
+}
+
+type PublishDeletedWorker struct {
+	river.WorkerDefaults[PublishDeletedArgs]
+	publisher   EventPublisher
+	serviceName string
+}
+
+func NewPublishDeletedWorker(publisher EventPublisher, serviceName string) *PublishDeletedWorker {
+	return &PublishDeletedWorker{publisher: publisher, serviceName: serviceName}
+}
+
+func (w *PublishDeletedWorker) Work(ctx context.Context, job *river.Job[PublishDeletedArgs]) error {
+	return publishUserEvent(ctx, w.publisher, job.Args.UserID, messaging.Envelope[DeletedPayload]{
+		ID:        job.Args.EventID.String(),
+		Timestamp: job.Args.Timestamp,
+		Type:      "user.deleted",
+		Payload:   DeletedPayload{UserID: job.Args.UserID},
+		Metadata:  eventMetadata(w.serviceName, job.Args.CorrelationID),
+	})
+}
+
+func eventMetadata(serviceName, correlationID string) messaging.Metadata {
+	return messaging.Metadata{
+		SchemaVersion:  "1.0.0",
+		ProducedBy:     serviceName,
+		OriginatedFrom: serviceName,
+		CorrelationID:  correlationID,
 	}
-	message, err := json.Marshal(envelope)
+}
+
+// publishUserEvent groups events by user, so FIFO topics deliver each user's
+// lifecycle events in order.
+func publishUserEvent[T any](ctx context.Context, publisher EventPublisher, userID uuid.UUID, envelope messaging.Envelope[T]) error {
+	envelope.DeduplicationID = &envelope.ID
+	body, err := json.Marshal(envelope)
 	if err != nil {
-		return fmt.Errorf("encode user.created event: %w", err)
+		return fmt.Errorf("encode %s event: %w", envelope.Type, err)
+	}
+	message := messaging.Message{
+		Body:            body,
+		GroupID:         userID.String(),
+		DeduplicationID: envelope.ID,
+		Type:            envelope.Type,
+		SchemaVersion:   envelope.Metadata.SchemaVersion,
 	}
-	if err := w.publisher.Publish(ctx, message); err != nil {
-		return fmt.Errorf("publish user.created event: %w", err)
+	if err := publisher.Publish(ctx, message); err != nil {
+		return fmt.Errorf("publish %s event: %w", envelope.Type, err)
 	}
 	return nil
 }
 
 func (e *Enqueuer) EnqueueUserCreated(ctx context.Context, tx pgx.Tx, userID uuid.UUID, correlationID string) error {
+	return addUserEvent(ctx, e, tx, "user.created", userID, CreatedPayload{UserID: userID}, correlationID)
+}
+
+func (e *Enqueuer) EnqueueUserUpdated(ctx context.Context, tx pgx.Tx, userID uuid.UUID) error {
+	return addUserEvent(ctx, e, tx, "user.updated", userID, UpdatedPayload{UserID: userID}, "")
+}
+
+func (e *Enqueuer) EnqueueUserDeleted(ctx context.Context, tx pgx.Tx, userID uuid.UUID) error {
+	return addUserEvent(ctx, e, tx, "user.deleted", userID, DeletedPayload{UserID: userID}, "")
+}
+
+// addUserEvent writes the event to the outbox in tx, so it is published only
+// if the change commits, grouped by user like publishUserEvent.
+func addUserEvent[T any](ctx context.Context, e *Enqueuer, tx pgx.Tx, eventType string, userID uuid.UUID, payload T, correlationID string) error {
+	eventID, correlationID, err := newEventIdentity(ctx, correlationID)
+	if err != nil {
+		return fmt.Errorf("generate %s event ID: %w", eventType, err)
+	}
+	message, err := messaging.NewOutboxMessage(userID.String(), messaging.Envelope[T]{
+		ID:        eventID.String(),
+		Timestamp: time.Now().UTC(),
+		Type:      eventType,
+		Payload:   payload,
+		Metadata:  eventMetadata(e.serviceName, correlationID),
+	})
+	if err != nil {
+		return err
+	}
+	return e.outbox.AddToOutbox(ctx, tx, message)
+}
+
+// newEventIdentity generates the stable event ID and resolves the correlation
+// ID from the argument, the request context, or the event ID, in that order.
+func newEventIdentity(ctx context.Context, correlationID string) (uuid.UUID, string, error) {
 	eventID, err := uuid.NewV7()
 	if err != nil {
-		return fmt.Errorf("generate user.created event ID: %w", err)
+		return uuid.Nil, "", err
 	}
 	if correlationID == "" {
 		correlationID = messaging.CorrelationID(ctx)
@@ -76,11 +199,5 @@ func (e *Enqueuer) EnqueueUserCreated(ctx context.Context, tx pgx.Tx, userID uui
 			correlationID = eventID.String()
 		}
 	}
-	_, err = e.client.InsertTx(ctx, tx, PublishCreatedArgs{
-		EventID: eventID, UserID: userID, Timestamp: time.Now().UTC(), CorrelationID: correlationID,
-	}, &river.InsertOpts{Queue: QueueEvents, MaxAttempts: 10})
-	if err != nil {
-		return fmt.Errorf("insert users.publish-created job: %w", err)
-	}
-	return nil
+	return eventID, correlationID, nil
 }
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"slices"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
 )
@@ -19,6 +20,14 @@ type PermissionChange struct {
 	Permissions []string
 }
 
+// Permissions is the latest permission set received for a user.
+type Permissions struct {
+	UserID      uuid.UUID
+	Revision    int64
+	Permissions []string
+	UpdatedAt   time.Time
+}
+
 type PermissionChangeResult string
 
 const (
@@ -29,6 +38,7 @@ const (
 
 type PermissionRepository interface {
 	ApplyPermissionChange(context.Context, PermissionChange) (PermissionChangeResult, error)
+	GetPermissions(context.Context, uuid.UUID) (Permissions, error)
 }
 
 type PermissionService struct {
@@ -67,3 +77,12 @@ func (s *PermissionService) Apply(ctx context.Context, change PermissionChange)
 	}
 	return result, nil
 }
+
+// Get returns ErrNotFound until a revision has been received for the user.
+func (s *PermissionService) Get(ctx context.Context, userID uuid.UUID) (Permissions, error) {
+	permissions, err := s.repository.GetPermissions(ctx, userID)
+	if err != nil {
+		return Permissions{}, fmt.Errorf("get user permissions: %w", err)
+	}
+	return permissions, nil
+}
@@ -53,3 +53,7 @@ func (r *permissionRepositoryStub) ApplyPermissionChange(_ context.Context, chan
 	r.change = change
 	return r.result, nil
 }
+
+func (r *permissionRepositoryStub) GetPermissions(context.Context, uuid.UUID) (Permissions, error) {
+	return Permissions{}, ErrNotFound
+}
@@ -0,0 +1,110 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/your-org/go-service-template/internal/platform/auth"
+)
+
+type APIKeyRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewAPIKeyRepository(pool *pgxpool.Pool) *APIKeyRepository {
+	return &APIKeyRepository{pool: pool}
+}
+
+// Create stores the key under its hash. An active key with the same name
+// yields auth.ErrAPIKeyNameTaken, and a subject that is not the ID of an
+// active user yields auth.ErrAPIKeySubjectNotUser. The user stays locked
+// until the key is stored, so it cannot be deleted in between.
+func (r *APIKeyRepository) Create(ctx context.Context, key auth.APIKey, hash []byte) (auth.APIKey, error) {
+	userID, err := uuid.Parse(key.Subject)
+	if err != nil {
+		return auth.APIKey{}, auth.ErrAPIKeySubjectNotUser
+	}
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return auth.APIKey{}, fmt.Errorf("begin API key transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	queries := New(tx)
+	if _, err := queries.GetUserForUpdate(ctx, userID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return auth.APIKey{}, auth.ErrAPIKeySubjectNotUser
+		}
+		return auth.APIKey{}, fmt.Errorf("select API key subject: %w", err)
+	}
+	created, err := queries.CreateAPIKey(ctx, CreateAPIKeyParams{
+		ID:        key.ID,
+		Name:      key.Name,
+		KeyHash:   hash,
+		Subject:   key.Subject,
+		Scopes:    key.Scopes,
+		ExpiresAt: optionalTimestamptz(key.ExpiresAt),
+	})
+	if err != nil {
+		if uniqueViolation(err) {
+			return auth.APIKey{}, auth.ErrAPIKeyNameTaken
+		}
+		return auth.APIKey{}, fmt.Errorf("insert API key: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return auth.APIKey{}, fmt.Errorf("commit API key: %w", err)
+	}
+	return toAuthAPIKey(created), nil
+}
+
+func (r *APIKeyRepository) FindAPIKey(ctx context.Context, hash []byte) (auth.APIKey, error) {
+	key, err := New(r.pool).GetAPIKeyByHash(ctx, hash)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return auth.APIKey{}, auth.ErrAPIKeyNotFound
+		}
+		return auth.APIKey{}, fmt.Errorf("select API key: %w", err)
+	}
+	return toAuthAPIKey(key), nil
+}
+
+func (r *APIKeyRepository) List(ctx context.Context) ([]auth.APIKey, error) {
+	rows, err := New(r.pool).ListAPIKeys(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list API keys: %w", err)
+	}
+	keys := make([]auth.APIKey, 0, len(rows))
+	for _, row := range rows {
+		keys = append(keys, toAuthAPIKey(row))
+	}
+	return keys, nil
+}
+
+// Revoke returns auth.ErrAPIKeyNotFound for unknown and already revoked keys.
+func (r *APIKeyRepository) Revoke(ctx context.Context, id uuid.UUID) (auth.APIKey, error) {
+	revoked, err := New(r.pool).RevokeAPIKey(ctx, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return auth.APIKey{}, auth.ErrAPIKeyNotFound
+		}
+		return auth.APIKey{}, fmt.Errorf("revoke API key: %w", err)
+	}
+	return toAuthAPIKey(revoked), nil
+}
+
+func toAuthAPIKey(key ApiKey) auth.APIKey {
+	return auth.APIKey{
+		ID:        key.ID,
+		Name:      key.Name,
+		Subject:   key.Subject,
+		Scopes:    key.Scopes,
+		CreatedAt: key.CreatedAt,
+		ExpiresAt: optionalTime(key.ExpiresAt),
+		RevokedAt: optionalTime(key.RevokedAt),
+	}
+}
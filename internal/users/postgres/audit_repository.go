@@ -0,0 +1,83 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/your-org/go-service-template/internal/users"
+)
+
+type AuditRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewAuditRepository(pool *pgxpool.Pool) *AuditRepository {
+	return &AuditRepository{pool: pool}
+}
+
+func (r *AuditRepository) ListAuditEvents(ctx context.Context, query users.AuditQuery) ([]users.AuditEvent, error) {
+	listed, err := New(r.pool).ListAuditEvents(ctx, ListAuditEventsParams{
+		AfterID:        pgtype.UUID{Bytes: query.AfterID, Valid: query.AfterID != uuid.Nil},
+		Actor:          pgtype.Text{String: query.Actor, Valid: query.Actor != ""},
+		TargetID:       pgtype.Text{String: query.TargetID, Valid: query.TargetID != ""},
+		OccurredAfter:  optionalTimestamptz(query.OccurredAfter),
+		OccurredBefore: optionalTimestamptz(query.OccurredBefore),
+		RowLimit:       int32(query.Limit),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("select audit events: %w", err)
+	}
+
+	result := make([]users.AuditEvent, 0, len(listed))
+	for _, event := range listed {
+		result = append(result, users.AuditEvent{
+			ID:            event.ID,
+			Actor:         event.Actor,
+			Action:        users.AuditAction(event.Action),
+			TargetID:      event.TargetID,
+			CorrelationID: event.CorrelationID,
+			Outcome:       event.Outcome,
+			OccurredAt:    event.OccurredAt,
+		})
+	}
+	return result, nil
+}
+
+// recordAuditEvent must run in the transaction that makes the change, except
+// for rejections, which have none. The actor and correlation ID are taken
+// from ctx.
+func recordAuditEvent(ctx context.Context, queries *Queries, action users.AuditAction, targetID uuid.UUID, outcome string) error {
+	id, err := uuid.NewV7()
+	if err != nil {
+		return fmt.Errorf("generate audit event ID: %w", err)
+	}
+	if err := queries.CreateAuditEvent(ctx, CreateAuditEventParams{
+		ID:            id,
+		Actor:         users.AuditActor(ctx),
+		Action:        string(action),
+		TargetID:      targetID.String(),
+		CorrelationID: correlationID(ctx, ""),
+		Outcome:       outcome,
+	}); err != nil {
+		return fmt.Errorf("insert %s audit event: %w", action, err)
+	}
+	return nil
+}
+
+// auditRejection records an API call refused with err by a conflict or a
+// failed precondition, which rolled back its own transaction, and returns
+// err. Other errors are returned without an audit event.
+func auditRejection(ctx context.Context, pool *pgxpool.Pool, action users.AuditAction, targetID uuid.UUID, err error) error {
+	if !errors.Is(err, users.ErrConflict) && !errors.Is(err, users.ErrPreconditionFailed) && !errors.Is(err, users.ErrImportFinished) {
+		return err
+	}
+	if auditErr := recordAuditEvent(ctx, New(pool), action, targetID, users.AuditOutcomeRejected); auditErr != nil {
+		return auditErr
+	}
+	return err
+}
@@ -0,0 +1,44 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.31.1
+// source: copyfrom.go
+
+package postgres
+
+import (
+	"context"
+)
+
+// iteratorForCreateUserImportEntries implements pgx.CopyFromSource.
+type iteratorForCreateUserImportEntries struct {
+	rows                 []CreateUserImportEntriesParams
+	skippedFirstNextCall bool
+}
+
+func (r *iteratorForCreateUserImportEntries) Next() bool {
+	if len(r.rows) == 0 {
+		return false
+	}
+	if !r.skippedFirstNextCall {
+		r.skippedFirstNextCall = true
+		return true
+	}
+	r.rows = r.rows[1:]
+	return len(r.rows) > 0
+}
+
+func (r iteratorForCreateUserImportEntries) Values() ([]interface{}, error) {
+	return []interface{}{
+		r.rows[0].ImportID,
+		r.rows[0].UserID,
+		r.rows[0].Email,
+	}, nil
+}
+
+func (r iteratorForCreateUserImportEntries) Err() error {
+	return nil
+}
+
+func (q *Queries) CreateUserImportEntries(ctx context.Context, arg []CreateUserImportEntriesParams) (int64, error) {
+	return q.db.CopyFrom(ctx, []string{"user_import_entries"}, []string{"import_id", "user_id", "email"}, &iteratorForCreateUserImportEntries{rows: arg})
+}
@@ -0,0 +1,109 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/your-org/go-service-template/internal/platform/httpserver"
+)
+
+// idempotencyLease bounds how long an unfinished request holds its key
+// without extending it. Running requests extend their claims several times
+// within it, so only a crashed request loses its claim.
+const idempotencyLease = 4 * httpserver.IdempotencyExtendInterval
+
+type IdempotencyRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewIdempotencyRepository(pool *pgxpool.Pool) *IdempotencyRepository {
+	return &IdempotencyRepository{pool: pool}
+}
+
+func (r *IdempotencyRepository) Claim(ctx context.Context, key httpserver.IdempotencyKey) (httpserver.IdempotencyRecord, bool, error) {
+	queries := New(r.pool)
+	// A key released between the claim and the read is claimed again.
+	for range 2 {
+		_, err := queries.ClaimIdempotencyKey(ctx, ClaimIdempotencyKeyParams{
+			Subject:     key.Subject,
+			Key:         key.Key,
+			StaleBefore: time.Now().Add(-idempotencyLease),
+		})
+		if err == nil {
+			return httpserver.IdempotencyRecord{}, true, nil
+		}
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return httpserver.IdempotencyRecord{}, false, fmt.Errorf("claim idempotency key: %w", err)
+		}
+
+		stored, err := queries.GetIdempotencyKey(ctx, GetIdempotencyKeyParams{Subject: key.Subject, Key: key.Key})
+		if errors.Is(err, pgx.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return httpserver.IdempotencyRecord{}, false, fmt.Errorf("select idempotency key: %w", err)
+		}
+		record := httpserver.IdempotencyRecord{Fingerprint: stored.Fingerprint}
+		if stored.StatusCode.Valid {
+			header := http.Header{}
+			if err := json.Unmarshal(stored.ResponseHeaders, &header); err != nil {
+				return httpserver.IdempotencyRecord{}, false, fmt.Errorf("decode idempotent response headers: %w", err)
+			}
+			record.Response = &httpserver.IdempotentResponse{
+				StatusCode: int(stored.StatusCode.Int32),
+				Header:     header,
+				Body:       stored.ResponseBody,
+			}
+		}
+		return record, false, nil
+	}
+	return httpserver.IdempotencyRecord{}, false, errors.New("claim idempotency key: key changed concurrently")
+}
+
+func (r *IdempotencyRepository) Extend(ctx context.Context, key httpserver.IdempotencyKey) error {
+	if err := New(r.pool).ExtendIdempotencyKey(ctx, ExtendIdempotencyKeyParams{Subject: key.Subject, Key: key.Key}); err != nil {
+		return fmt.Errorf("extend idempotency key: %w", err)
+	}
+	return nil
+}
+
+func (r *IdempotencyRepository) Complete(ctx context.Context, key httpserver.IdempotencyKey, fingerprint []byte, response httpserver.IdempotentResponse) error {
+	header, err := json.Marshal(response.Header)
+	if err != nil {
+		return fmt.Errorf("encode idempotent response headers: %w", err)
+	}
+	if err := New(r.pool).CompleteIdempotencyKey(ctx, CompleteIdempotencyKeyParams{
+		Subject:         key.Subject,
+		Key:             key.Key,
+		Fingerprint:     fingerprint,
+		StatusCode:      pgtype.Int4{Int32: int32(response.StatusCode), Valid: true},
+		ResponseHeaders: header,
+		ResponseBody:    response.Body,
+	}); err != nil {
+		return fmt.Errorf("record idempotent response: %w", err)
+	}
+	return nil
+}
+
+func (r *IdempotencyRepository) Release(ctx context.Context, key httpserver.IdempotencyKey) error {
+	if err := New(r.pool).ReleaseIdempotencyKey(ctx, ReleaseIdempotencyKeyParams{Subject: key.Subject, Key: key.Key}); err != nil {
+		return fmt.Errorf("release idempotency key: %w", err)
+	}
+	return nil
+}
+
+func (r *IdempotencyRepository) Cleanup(ctx context.Context, cutoff time.Time) (int64, error) {
+	deleted, err := New(r.pool).DeleteIdempotencyKeysBefore(ctx, cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("delete expired idempotency keys: %w", err)
+	}
+	return deleted, nil
+}
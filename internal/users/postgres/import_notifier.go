@@ -0,0 +1,112 @@
+package postgres
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// importProgressChannel is notified by the user_imports_notify_progress
+// trigger with the import ID as payload.
+const importProgressChannel = "user_import_progress"
+
+// ImportNotifier shares one listening connection between every subscriber,
+// so that open event streams do not each hold a pooled connection.
+type ImportNotifier struct {
+	pool   *pgxpool.Pool
+	logger *slog.Logger
+
+	mu          sync.Mutex
+	subscribers map[uuid.UUID]map[chan struct{}]struct{}
+	stopped     bool
+}
+
+func NewImportNotifier(pool *pgxpool.Pool, logger *slog.Logger) *ImportNotifier {
+	return &ImportNotifier{pool: pool, logger: logger, subscribers: make(map[uuid.UUID]map[chan struct{}]struct{})}
+}
+
+func (n *ImportNotifier) SubscribeImport(id uuid.UUID) (<-chan struct{}, func()) {
+	changes := make(chan struct{}, 1)
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.stopped {
+		close(changes)
+		return changes, func() {}
+	}
+	if n.subscribers[id] == nil {
+		n.subscribers[id] = make(map[chan struct{}]struct{})
+	}
+	n.subscribers[id][changes] = struct{}{}
+
+	return changes, func() {
+		n.mu.Lock()
+		defer n.mu.Unlock()
+		if _, subscribed := n.subscribers[id][changes]; !subscribed {
+			return
+		}
+		delete(n.subscribers[id], changes)
+		if len(n.subscribers[id]) == 0 {
+			delete(n.subscribers, id)
+		}
+		close(changes)
+	}
+}
+
+// Run listens until ctx ends and then closes every subscription. All
+// subscribers are signalled after each reconnect.
+func (n *ImportNotifier) Run(ctx context.Context) {
+	defer n.stop()
+	listener{
+		pool: n.pool, logger: n.logger, channel: importProgressChannel,
+		onListen: n.signalAll,
+		onNotify: func(ctx context.Context, payload string) {
+			id, err := uuid.Parse(payload)
+			if err != nil {
+				n.logger.WarnContext(ctx, "ignore malformed user import notification", "payload", payload)
+				return
+			}
+			n.signal(id)
+		},
+	}.run(ctx)
+}
+
+func (n *ImportNotifier) signal(id uuid.UUID) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	for changes := range n.subscribers[id] {
+		notify(changes)
+	}
+}
+
+func (n *ImportNotifier) signalAll() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	for _, subscribers := range n.subscribers {
+		for changes := range subscribers {
+			notify(changes)
+		}
+	}
+}
+
+func (n *ImportNotifier) stop() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.stopped = true
+	for id, subscribers := range n.subscribers {
+		for changes := range subscribers {
+			close(changes)
+		}
+		delete(n.subscribers, id)
+	}
+}
+
+// notify never blocks; a pending signal already covers the new change.
+func notify(changes chan struct{}) {
+	select {
+	case changes <- struct{}{}:
+	default:
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"time"
 
 	"github.com/google/uuid"
@@ -16,10 +17,16 @@ import (
 )
 
 type ImportJobEnqueuer interface {
-	EnqueueImport(context.Context, pgx.Tx, uuid.UUID) error
+	EnqueueImport(context.Context, pgx.Tx, uuid.UUID) (int64, error)
+	CancelImport(context.Context, pgx.Tx, int64) error
+	EnqueueImportCallback(context.Context, pgx.Tx, uuid.UUID) error
 	EnqueueUserCreated(context.Context, pgx.Tx, uuid.UUID, string) error
 }
 
+// errImportCancelled stops processing when an import is cancelled between
+// batches.
+var errImportCancelled = errors.New("user import cancelled")
+
 type ImportRepository struct {
 	pool     *pgxpool.Pool
 	enqueuer ImportJobEnqueuer
@@ -29,7 +36,23 @@ func NewImportRepository(pool *pgxpool.Pool, enqueuer ImportJobEnqueuer) *Import
 	return &ImportRepository{pool: pool, enqueuer: enqueuer}
 }
 
+// importBatchSize bounds the entries processed in one transaction, so that
+// progress becomes visible and a retry repeats at most one batch.
+const importBatchSize = 500
+
 func (r *ImportRepository) CreateImport(ctx context.Context, userImport users.Import) (users.Import, error) {
+	entries := userImport.Entries
+	return r.CreateStreamedImport(ctx, userImport, func() ([]users.ImportEntry, error) {
+		if len(entries) == 0 {
+			return nil, io.EOF
+		}
+		chunk := entries
+		entries = nil
+		return chunk, nil
+	})
+}
+
+func (r *ImportRepository) CreateStreamedImport(ctx context.Context, userImport users.Import, chunks users.ImportChunks) (users.Import, error) {
 	tx, err := r.pool.Begin(ctx)
 	if err != nil {
 		return users.Import{}, fmt.Errorf("begin user import transaction: %w", err)
@@ -37,26 +60,52 @@ func (r *ImportRepository) CreateImport(ctx context.Context, userImport users.Im
 	defer func() { _ = tx.Rollback(ctx) }()
 
 	queries := New(tx)
-	created, err := queries.CreateUserImport(ctx, CreateUserImportParams{
+	params := CreateUserImportParams{
 		ID:            userImport.ID,
-		TotalCount:    int32(userImport.TotalCount),
 		CorrelationID: correlationID(ctx, userImport.ID.String()),
-	})
-	if err != nil {
+	}
+	if userImport.RetryOf != nil {
+		params.RetryOf = pgtype.UUID{Bytes: *userImport.RetryOf, Valid: true}
+	}
+	if userImport.CallbackURL != "" {
+		params.CallbackUrl = pgtype.Text{String: userImport.CallbackURL, Valid: true}
+	}
+	if _, err := queries.CreateUserImport(ctx, params); err != nil {
 		return users.Import{}, fmt.Errorf("insert user import: %w", err)
 	}
-	for _, entry := range userImport.Entries {
-		if err := queries.CreateUserImportEntry(ctx, CreateUserImportEntryParams{
-			ImportID: userImport.ID,
-			UserID:   entry.UserID,
-			Email:    entry.Email,
-		}); err != nil {
-			return users.Import{}, fmt.Errorf("insert user import entry: %w", err)
+	total := 0
+	for {
+		entries, err := chunks()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return users.Import{}, fmt.Errorf("read user import entries: %w", err)
 		}
+		rows := make([]CreateUserImportEntriesParams, len(entries))
+		for index, entry := range entries {
+			rows[index] = CreateUserImportEntriesParams{ImportID: userImport.ID, UserID: entry.UserID, Email: entry.Email}
+		}
+		if _, err := queries.CreateUserImportEntries(ctx, rows); err != nil {
+			return users.Import{}, fmt.Errorf("copy user import entries: %w", err)
+		}
+		total += len(entries)
 	}
-	if err := r.enqueuer.EnqueueImport(ctx, tx, userImport.ID); err != nil {
+	jobID, err := r.enqueuer.EnqueueImport(ctx, tx, userImport.ID)
+	if err != nil {
 		return users.Import{}, fmt.Errorf("enqueue user import: %w", err)
 	}
+	created, err := queries.ScheduleUserImport(ctx, ScheduleUserImportParams{
+		ID:         userImport.ID,
+		TotalCount: int32(total),
+		JobID:      pgtype.Int8{Int64: jobID, Valid: true},
+	})
+	if err != nil {
+		return users.Import{}, fmt.Errorf("schedule user import: %w", err)
+	}
+	if err := recordAuditEvent(ctx, queries, users.AuditActionCreateUserImport, created.ID, users.AuditOutcomeSucceeded); err != nil {
+		return users.Import{}, err
+	}
 	if err := tx.Commit(ctx); err != nil {
 		return users.Import{}, fmt.Errorf("commit user import: %w", err)
 	}
@@ -65,14 +114,125 @@ func (r *ImportRepository) CreateImport(ctx context.Context, userImport users.Im
 }
 
 func (r *ImportRepository) GetImport(ctx context.Context, id uuid.UUID) (users.Import, error) {
-	userImport, err := New(r.pool).GetUserImport(ctx, id)
+	queries := New(r.pool)
+	userImport, err := queries.GetUserImport(ctx, id)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return users.Import{}, users.ErrNotFound
 		}
 		return users.Import{}, fmt.Errorf("select user import: %w", err)
 	}
-	return toDomainImport(userImport), nil
+	result := toDomainImport(userImport)
+	if !userImport.CallbackUrl.Valid {
+		return result, nil
+	}
+
+	attempts, err := queries.ListUserImportCallbackAttempts(ctx, id)
+	if err != nil {
+		return users.Import{}, fmt.Errorf("select user import callback attempts: %w", err)
+	}
+	result.CallbackAttempts = make([]users.CallbackAttempt, 0, len(attempts))
+	for _, attempt := range attempts {
+		result.CallbackAttempts = append(result.CallbackAttempts, users.CallbackAttempt{
+			Attempt:     int(attempt.Attempt),
+			AttemptedAt: attempt.AttemptedAt,
+			StatusCode:  int(attempt.StatusCode.Int32),
+			Error:       attempt.Error.String,
+		})
+	}
+	return result, nil
+}
+
+func (r *ImportRepository) RecordCallbackAttempt(ctx context.Context, id uuid.UUID, attempt users.CallbackAttempt) error {
+	if err := New(r.pool).RecordUserImportCallbackAttempt(ctx, RecordUserImportCallbackAttemptParams{
+		ImportID:   id,
+		StatusCode: pgtype.Int4{Int32: int32(attempt.StatusCode), Valid: attempt.StatusCode != 0},
+		Error:      pgtype.Text{String: attempt.Error, Valid: attempt.Error != ""},
+	}); err != nil {
+		return fmt.Errorf("insert user import callback attempt: %w", err)
+	}
+	return nil
+}
+
+func (r *ImportRepository) CancelImport(ctx context.Context, id uuid.UUID) (users.Import, error) {
+	cancelled, err := r.cancelImport(ctx, id)
+	if err != nil {
+		return users.Import{}, auditRejection(ctx, r.pool, users.AuditActionCancelUserImport, id, err)
+	}
+	return cancelled, nil
+}
+
+func (r *ImportRepository) cancelImport(ctx context.Context, id uuid.UUID) (users.Import, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return users.Import{}, fmt.Errorf("begin cancel user import transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	queries := New(tx)
+	cancelled, err := queries.CancelUserImport(ctx, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		if _, err := queries.GetUserImport(ctx, id); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return users.Import{}, users.ErrNotFound
+			}
+			return users.Import{}, fmt.Errorf("select user import: %w", err)
+		}
+		return users.Import{}, users.ErrImportFinished
+	}
+	if err != nil {
+		return users.Import{}, fmt.Errorf("mark user import cancelled: %w", err)
+	}
+	if cancelled.JobID.Valid {
+		if err := r.enqueuer.CancelImport(ctx, tx, cancelled.JobID.Int64); err != nil {
+			return users.Import{}, err
+		}
+	}
+	if cancelled.CallbackUrl.Valid {
+		if err := r.enqueuer.EnqueueImportCallback(ctx, tx, id); err != nil {
+			return users.Import{}, fmt.Errorf("enqueue user import callback: %w", err)
+		}
+	}
+	if err := recordAuditEvent(ctx, queries, users.AuditActionCancelUserImport, id, users.AuditOutcomeSucceeded); err != nil {
+		return users.Import{}, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return users.Import{}, fmt.Errorf("commit cancelled user import: %w", err)
+	}
+	return toDomainImport(cancelled), nil
+}
+
+func (r *ImportRepository) ListImportEntries(ctx context.Context, importID uuid.UUID, query users.EntryQuery) ([]users.ImportEntry, error) {
+	queries := New(r.pool)
+	listed, err := queries.ListUserImportEntries(ctx, ListUserImportEntriesParams{
+		ImportID:    importID,
+		State:       NullUserImportEntryState{UserImportEntryState: UserImportEntryState(query.State), Valid: query.State != ""},
+		AfterUserID: pgtype.UUID{Bytes: query.AfterUserID, Valid: query.AfterUserID != uuid.Nil},
+		RowLimit:    int32(query.Limit),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("select user import entries: %w", err)
+	}
+	// An empty page is ambiguous, so only then check that the import exists.
+	if len(listed) == 0 {
+		if _, err := queries.GetUserImport(ctx, importID); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return nil, users.ErrNotFound
+			}
+			return nil, fmt.Errorf("select user import: %w", err)
+		}
+	}
+
+	entries := make([]users.ImportEntry, 0, len(listed))
+	for _, entry := range listed {
+		entries = append(entries, users.ImportEntry{
+			UserID:        entry.UserID,
+			Email:         entry.Email,
+			State:         users.ImportEntryState(entry.State),
+			FailureReason: users.ImportFailureReason(entry.FailureReason.UserImportEntryFailureReason),
+		})
+	}
+	return entries, nil
 }
 
 func (r *ImportRepository) ProcessImport(ctx context.Context, id uuid.UUID) error {
@@ -88,65 +248,132 @@ func (r *ImportRepository) ProcessImport(ctx context.Context, id uuid.UUID) erro
 		if getErr != nil {
 			return fmt.Errorf("select user import state: %w", getErr)
 		}
-		if userImport.State == UserImportStateCompleted || userImport.State == UserImportStateFailed {
+		if userImport.State != UserImportStatePending && userImport.State != UserImportStateRunning {
 			return nil
 		}
 	}
 
+	for {
+		processed, err := r.processImportBatch(ctx, id)
+		if errors.Is(err, errImportCancelled) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if processed < importBatchSize {
+			break
+		}
+	}
+	return r.finishImport(ctx, id)
+}
+
+// finishImport records the final state and enqueues the completion callback
+// in one transaction. FinishUserImport matches only a running import, so an
+// import cancelled after the last batch stays cancelled.
+func (r *ImportRepository) finishImport(ctx context.Context, id uuid.UUID) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin finish user import transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	finished, err := New(tx).FinishUserImport(ctx, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("finish user import: %w", err)
+	}
+	if finished.CallbackUrl.Valid {
+		if err := r.enqueuer.EnqueueImportCallback(ctx, tx, id); err != nil {
+			return fmt.Errorf("enqueue user import callback: %w", err)
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit finished user import: %w", err)
+	}
+	return nil
+}
+
+// processImportBatch commits one batch of pending entries together with their
+// user.created jobs and the import's progress counters. It returns the number
+// of entries processed. The import row stays locked for the batch, so a
+// cancellation waits for the batch to commit and is seen by the next one,
+// which returns errImportCancelled without processing entries.
+func (r *ImportRepository) processImportBatch(ctx context.Context, id uuid.UUID) (int, error) {
 	tx, err := r.pool.Begin(ctx)
 	if err != nil {
-		return fmt.Errorf("begin import processing transaction: %w", err)
+		return 0, fmt.Errorf("begin import batch transaction: %w", err)
 	}
 	defer func() { _ = tx.Rollback(ctx) }()
 
-	transactionQueries := New(tx)
-	entries, err := transactionQueries.ListPendingUserImportEntries(ctx, id)
+	queries := New(tx)
+	state, err := queries.LockUserImportState(ctx, id)
+	if err != nil {
+		return 0, fmt.Errorf("lock user import: %w", err)
+	}
+	if state == UserImportStateCancelled {
+		return 0, errImportCancelled
+	}
+	entries, err := queries.ListPendingUserImportEntries(ctx, ListPendingUserImportEntriesParams{ImportID: id, Limit: importBatchSize})
 	if err != nil {
-		return fmt.Errorf("list user import entries: %w", err)
+		return 0, fmt.Errorf("list user import entries: %w", err)
 	}
+	var completed, failed int32
 	for _, entry := range entries {
-		createdID, createErr := transactionQueries.CreateImportedUser(ctx, CreateImportedUserParams{
+		createdID, createErr := queries.CreateImportedUser(ctx, CreateImportedUserParams{
 			ID:    entry.UserID,
 			Email: entry.Email,
 		})
 		if createErr == nil {
 			if createdID != entry.UserID {
-				return errors.New("insert imported user returned unexpected ID")
+				return 0, errors.New("insert imported user returned unexpected ID")
 			}
-			if err := transactionQueries.CompleteUserImportEntry(ctx, CompleteUserImportEntryParams{ImportID: id, UserID: entry.UserID}); err != nil {
-				return fmt.Errorf("complete user import entry: %w", err)
+			if err := queries.CompleteUserImportEntry(ctx, CompleteUserImportEntryParams{ImportID: id, UserID: entry.UserID}); err != nil {
+				return 0, fmt.Errorf("complete user import entry: %w", err)
 			}
 			if err := r.enqueuer.EnqueueUserCreated(ctx, tx, entry.UserID, entry.CorrelationID); err != nil {
-				return fmt.Errorf("enqueue imported user.created: %w", err)
+				return 0, fmt.Errorf("enqueue imported user.created: %w", err)
 			}
+			completed++
 			continue
 		}
 		if !errors.Is(createErr, pgx.ErrNoRows) {
-			return fmt.Errorf("insert imported user: %w", createErr)
+			return 0, fmt.Errorf("insert imported user: %w", createErr)
 		}
 
-		existing, lookupErr := transactionQueries.GetUserByEmail(ctx, entry.Email)
+		existing, lookupErr := queries.GetUserByEmail(ctx, entry.Email)
 		if lookupErr == nil && existing.ID == entry.UserID {
-			if err := transactionQueries.CompleteUserImportEntry(ctx, CompleteUserImportEntryParams{ImportID: id, UserID: entry.UserID}); err != nil {
-				return fmt.Errorf("complete retried user import entry: %w", err)
+			if err := queries.CompleteUserImportEntry(ctx, CompleteUserImportEntryParams{ImportID: id, UserID: entry.UserID}); err != nil {
+				return 0, fmt.Errorf("complete retried user import entry: %w", err)
 			}
+			completed++
 			continue
 		}
-		if lookupErr != nil && !errors.Is(lookupErr, pgx.ErrNoRows) {
-			return fmt.Errorf("select imported user conflict: %w", lookupErr)
+		reason := UserImportEntryFailureReasonEmailTaken
+		if errors.Is(lookupErr, pgx.ErrNoRows) {
+			reason = UserImportEntryFailureReasonInvalid
+		} else if lookupErr != nil {
+			return 0, fmt.Errorf("select imported user conflict: %w", lookupErr)
 		}
-		if err := transactionQueries.FailUserImportEntry(ctx, FailUserImportEntryParams{ImportID: id, UserID: entry.UserID}); err != nil {
-			return fmt.Errorf("fail user import entry: %w", err)
+		if err := queries.FailUserImportEntry(ctx, FailUserImportEntryParams{ImportID: id, UserID: entry.UserID, FailureReason: reason}); err != nil {
+			return 0, fmt.Errorf("fail user import entry: %w", err)
 		}
+		failed++
 	}
 
-	if _, err := transactionQueries.FinishUserImport(ctx, id); err != nil {
-		return fmt.Errorf("finish user import: %w", err)
+	if completed+failed > 0 {
+		if err := queries.RecordUserImportProgress(ctx, RecordUserImportProgressParams{
+			ID: id, CompletedCount: completed, FailedCount: failed,
+		}); err != nil {
+			return 0, fmt.Errorf("record user import progress: %w", err)
+		}
 	}
 	if err := tx.Commit(ctx); err != nil {
-		return fmt.Errorf("commit import processing: %w", err)
+		return 0, fmt.Errorf("commit import batch: %w", err)
 	}
-	return nil
+	return len(entries), nil
 }
 
 func correlationID(ctx context.Context, fallback string) string {
@@ -174,6 +401,8 @@ func toDomainImport(userImport UserImport) users.Import {
 		CreatedAt:      userImport.CreatedAt,
 		StartedAt:      optionalTime(userImport.StartedAt),
 		FinishedAt:     optionalTime(userImport.FinishedAt),
+		RetryOf:        optionalUUID(userImport.RetryOf),
+		CallbackURL:    userImport.CallbackUrl.String,
 	}
 }
 
@@ -183,3 +412,11 @@ func optionalTime(value pgtype.Timestamptz) *time.Time {
 	}
 	return &value.Time
 }
+
+func optionalUUID(value pgtype.UUID) *uuid.UUID {
+	if !value.Valid {
+		return nil
+	}
+	id := uuid.UUID(value.Bytes)
+	return &id
+}
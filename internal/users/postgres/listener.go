@@ -0,0 +1,67 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const listenRetryDelay = time.Second
+
+// listener holds one connection listening on a PostgreSQL notification
+// channel.
+type listener struct {
+	pool    *pgxpool.Pool
+	logger  *slog.Logger
+	channel string
+	// onListen runs after every (re)connect, because notifications sent while
+	// disconnected are lost.
+	onListen func()
+	onNotify func(ctx context.Context, payload string)
+}
+
+// run listens until ctx ends and re-establishes a lost connection after a
+// delay.
+func (l listener) run(ctx context.Context) {
+	for {
+		err := l.listen(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		l.logger.WarnContext(ctx, "listen for notifications", "channel", l.channel, "error", err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(listenRetryDelay):
+		}
+	}
+}
+
+func (l listener) listen(ctx context.Context) error {
+	pooled, err := l.pool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("acquire listening connection: %w", err)
+	}
+	// The connection leaves the pool, so that its LISTEN cannot leak into
+	// other queries.
+	conn := pooled.Hijack()
+	defer func() { _ = conn.Close(context.WithoutCancel(ctx)) }()
+
+	if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{l.channel}.Sanitize()); err != nil {
+		return fmt.Errorf("listen on %s: %w", l.channel, err)
+	}
+	l.onListen()
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return fmt.Errorf("wait for notification: %w", err)
+		}
+		l.onNotify(ctx, notification.Payload)
+	}
+}
@@ -13,6 +13,48 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+type UserImportEntryFailureReason string
+
+const (
+	UserImportEntryFailureReasonEmailTaken UserImportEntryFailureReason = "email_taken"
+	UserImportEntryFailureReasonInvalid    UserImportEntryFailureReason = "invalid"
+)
+
+func (e *UserImportEntryFailureReason) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = UserImportEntryFailureReason(s)
+	case string:
+		*e = UserImportEntryFailureReason(s)
+	default:
+		return fmt.Errorf("unsupported scan type for UserImportEntryFailureReason: %T", src)
+	}
+	return nil
+}
+
+type NullUserImportEntryFailureReason struct {
+	UserImportEntryFailureReason UserImportEntryFailureReason `json:"user_import_entry_failure_reason"`
+	Valid                        bool                         `json:"valid"` // Valid is true if UserImportEntryFailureReason is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullUserImportEntryFailureReason) Scan(value interface{}) error {
+	if value == nil {
+		ns.UserImportEntryFailureReason, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.UserImportEntryFailureReason.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullUserImportEntryFailureReason) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.UserImportEntryFailureReason), nil
+}
+
 type UserImportEntryState string
 
 const (
@@ -63,6 +105,7 @@ const (
 	UserImportStateRunning   UserImportState = "running"
 	UserImportStateCompleted UserImportState = "completed"
 	UserImportStateFailed    UserImportState = "failed"
+	UserImportStateCancelled UserImportState = "cancelled"
 )
 
 func (e *UserImportState) Scan(src interface{}) error {
@@ -100,16 +143,72 @@ func (ns NullUserImportState) Value() (driver.Value, error) {
 	return string(ns.UserImportState), nil
 }
 
+type ApiKey struct {
+	ID        uuid.UUID          `json:"id"`
+	Name      string             `json:"name"`
+	KeyHash   []byte             `json:"key_hash"`
+	Subject   string             `json:"subject"`
+	Scopes    []string           `json:"scopes"`
+	CreatedAt time.Time          `json:"created_at"`
+	ExpiresAt pgtype.Timestamptz `json:"expires_at"`
+	RevokedAt pgtype.Timestamptz `json:"revoked_at"`
+}
+
+type AuditEvent struct {
+	ID            uuid.UUID `json:"id"`
+	Actor         string    `json:"actor"`
+	Action        string    `json:"action"`
+	TargetID      string    `json:"target_id"`
+	CorrelationID string    `json:"correlation_id"`
+	Outcome       string    `json:"outcome"`
+	OccurredAt    time.Time `json:"occurred_at"`
+}
+
+type IdempotencyKey struct {
+	Subject         string      `json:"subject"`
+	Key             string      `json:"key"`
+	Fingerprint     []byte      `json:"fingerprint"`
+	StatusCode      pgtype.Int4 `json:"status_code"`
+	ResponseHeaders []byte      `json:"response_headers"`
+	ResponseBody    []byte      `json:"response_body"`
+	LockedAt        time.Time   `json:"locked_at"`
+	CreatedAt       time.Time   `json:"created_at"`
+}
+
+type OutboxMessage struct {
+	Sequence      int64              `json:"sequence"`
+	EventID       string             `json:"event_id"`
+	EventType     string             `json:"event_type"`
+	Body          string             `json:"body"`
+	Attempts      int32              `json:"attempts"`
+	LastError     pgtype.Text        `json:"last_error"`
+	CreatedAt     time.Time          `json:"created_at"`
+	AvailableAt   time.Time          `json:"available_at"`
+	GroupID       string             `json:"group_id"`
+	SchemaVersion string             `json:"schema_version"`
+	TraceContext  []byte             `json:"trace_context"`
+	ParkedAt      pgtype.Timestamptz `json:"parked_at"`
+}
+
 type ProcessedEvent struct {
 	EventID     string    `json:"event_id"`
 	EventType   string    `json:"event_type"`
 	ProcessedAt time.Time `json:"processed_at"`
 }
 
+type RateLimitBucket struct {
+	Key         string    `json:"key"`
+	Tokens      float64   `json:"tokens"`
+	LastAllowed bool      `json:"last_allowed"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
 type User struct {
-	ID        uuid.UUID `json:"id"`
-	Email     string    `json:"email"`
-	CreatedAt time.Time `json:"created_at"`
+	ID        uuid.UUID          `json:"id"`
+	Email     string             `json:"email"`
+	CreatedAt time.Time          `json:"created_at"`
+	DeletedAt pgtype.Timestamptz `json:"deleted_at"`
+	Version   int64              `json:"version"`
 }
 
 type UserImport struct {
@@ -122,13 +221,25 @@ type UserImport struct {
 	StartedAt      pgtype.Timestamptz `json:"started_at"`
 	FinishedAt     pgtype.Timestamptz `json:"finished_at"`
 	CorrelationID  string             `json:"correlation_id"`
+	JobID          pgtype.Int8        `json:"job_id"`
+	RetryOf        pgtype.UUID        `json:"retry_of"`
+	CallbackUrl    pgtype.Text        `json:"callback_url"`
+}
+
+type UserImportCallbackAttempt struct {
+	ImportID    uuid.UUID   `json:"import_id"`
+	Attempt     int32       `json:"attempt"`
+	AttemptedAt time.Time   `json:"attempted_at"`
+	StatusCode  pgtype.Int4 `json:"status_code"`
+	Error       pgtype.Text `json:"error"`
 }
 
 type UserImportEntry struct {
-	ImportID uuid.UUID            `json:"import_id"`
-	UserID   uuid.UUID            `json:"user_id"`
-	Email    string               `json:"email"`
-	State    UserImportEntryState `json:"state"`
+	ImportID      uuid.UUID                        `json:"import_id"`
+	UserID        uuid.UUID                        `json:"user_id"`
+	Email         string                           `json:"email"`
+	State         UserImportEntryState             `json:"state"`
+	FailureReason NullUserImportEntryFailureReason `json:"failure_reason"`
 }
 
 type UserPermission struct {
@@ -0,0 +1,168 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/your-org/go-service-template/internal/platform/messaging"
+)
+
+type OutboxRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewOutboxRepository(pool *pgxpool.Pool) *OutboxRepository {
+	return &OutboxRepository{pool: pool}
+}
+
+// AddToOutbox must run in the transaction that makes the change the message
+// announces. A message without a trace context takes the one of ctx.
+func (r *OutboxRepository) AddToOutbox(ctx context.Context, tx pgx.Tx, message messaging.OutboxMessage) error {
+	if message.TraceContext == nil {
+		message.TraceContext = messaging.TraceContext(ctx)
+	}
+	traceContext, err := json.Marshal(message.TraceContext)
+	if err != nil {
+		return fmt.Errorf("encode %s outbox trace context: %w", message.Type, err)
+	}
+	if err := New(tx).AddOutboxMessage(ctx, AddOutboxMessageParams{
+		EventID:       message.EventID,
+		EventType:     message.Type,
+		SchemaVersion: message.SchemaVersion,
+		GroupID:       message.GroupID,
+		Body:          string(message.Body),
+		TraceContext:  traceContext,
+	}); err != nil {
+		return fmt.Errorf("insert %s outbox message: %w", message.Type, err)
+	}
+	return nil
+}
+
+const (
+	// outboxPublishTimeout bounds the publish call of one batch.
+	outboxPublishTimeout = 30 * time.Second
+	// outboxClaimLease outlasts the publish call, so that claimed messages
+	// become due again only after their relay has given up on them.
+	outboxClaimLease = 2 * outboxPublishTimeout
+)
+
+// RelayOutbox claims a batch, publishes it with no transaction open, and
+// records the results in a second transaction. It returns without relaying
+// while another worker holds the relay lock. A batch holds only the earliest
+// message of each group, and later messages of the group wait while it is
+// claimed or scheduled for a retry, so a failed message holds back the rest
+// of its group until it is published or parked.
+func (r *OutboxRepository) RelayOutbox(ctx context.Context, limit int, publish func(context.Context, []messaging.OutboxMessage) map[int64]error) (int, error) {
+	messages, err := r.claimOutbox(ctx, limit)
+	if err != nil || len(messages) == 0 {
+		return 0, err
+	}
+
+	publishCtx, cancel := context.WithTimeout(ctx, outboxPublishTimeout)
+	failed := publish(publishCtx, messages)
+	cancel()
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("begin outbox result transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	queries := New(tx)
+	published := make([]int64, 0, len(messages))
+	for _, message := range messages {
+		publishErr, ok := failed[message.Sequence]
+		if !ok {
+			published = append(published, message.Sequence)
+			continue
+		}
+		if err := queries.RetryOutboxMessage(ctx, RetryOutboxMessageParams{
+			Sequence: message.Sequence, LastError: pgtype.Text{String: publishErr.Error(), Valid: true},
+			MaxAttempts: messaging.OutboxMaxAttempts,
+		}); err != nil {
+			return 0, fmt.Errorf("schedule outbox message retry: %w", err)
+		}
+	}
+	if err := queries.DeleteOutboxMessages(ctx, published); err != nil {
+		return 0, fmt.Errorf("delete published outbox messages: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("commit outbox relay results: %w", err)
+	}
+	return len(messages), nil
+}
+
+// claimOutbox returns up to limit due messages in sequence order and leases
+// them to this relay for outboxClaimLease.
+func (r *OutboxRepository) claimOutbox(ctx context.Context, limit int) ([]messaging.OutboxMessage, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("begin outbox claim transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	queries := New(tx)
+	locked, err := queries.TryLockOutboxRelay(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("lock outbox relay: %w", err)
+	}
+	if !locked {
+		return nil, nil
+	}
+	due, err := queries.ListDueOutboxMessages(ctx, int32(limit))
+	if err != nil {
+		return nil, fmt.Errorf("select due outbox messages: %w", err)
+	}
+	if len(due) == 0 {
+		return nil, nil
+	}
+
+	messages := make([]messaging.OutboxMessage, len(due))
+	sequences := make([]int64, len(due))
+	for index, message := range due {
+		var traceContext map[string]string
+		if err := json.Unmarshal(message.TraceContext, &traceContext); err != nil {
+			return nil, fmt.Errorf("decode outbox trace context: %w", err)
+		}
+		messages[index] = messaging.OutboxMessage{
+			Sequence:      message.Sequence,
+			EventID:       message.EventID,
+			Type:          message.EventType,
+			SchemaVersion: message.SchemaVersion,
+			GroupID:       message.GroupID,
+			Body:          []byte(message.Body),
+			TraceContext:  traceContext,
+			Attempts:      int(message.Attempts),
+			CreatedAt:     message.CreatedAt,
+		}
+		sequences[index] = message.Sequence
+	}
+	if err := queries.ClaimOutboxMessages(ctx, ClaimOutboxMessagesParams{
+		LeaseSeconds: int32(outboxClaimLease / time.Second), Sequences: sequences,
+	}); err != nil {
+		return nil, fmt.Errorf("claim outbox messages: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("commit outbox claim: %w", err)
+	}
+	return messages, nil
+}
+
+func (r *OutboxRepository) OutboxLag(ctx context.Context) (messaging.OutboxLag, error) {
+	lag, err := New(r.pool).GetOutboxLag(ctx)
+	if err != nil {
+		return messaging.OutboxLag{}, fmt.Errorf("select outbox lag: %w", err)
+	}
+	return messaging.OutboxLag{
+		Pending:     lag.Pending,
+		OldestAge:   time.Duration(lag.OldestAgeSeconds * float64(time.Second)),
+		MaxAttempts: int(lag.MaxAttempts),
+		Parked:      lag.Parked,
+	}, nil
+}
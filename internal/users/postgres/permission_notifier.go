@@ -0,0 +1,69 @@
+package postgres
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// permissionChangesChannel is notified by the user_permissions_notify_change
+// trigger with "<user ID>:<revision>" as payload, and by the
+// users_notify_deletion trigger with "<user ID>" alone.
+const permissionChangesChannel = "user_permissions_changed"
+
+type PermissionInvalidator interface {
+	Invalidate(userID string, revision int64)
+	Clear()
+}
+
+// PermissionNotifier invalidates cached permission sets when the worker
+// commits a new revision or a user is deleted.
+type PermissionNotifier struct {
+	pool        *pgxpool.Pool
+	logger      *slog.Logger
+	invalidator PermissionInvalidator
+}
+
+func NewPermissionNotifier(pool *pgxpool.Pool, logger *slog.Logger, invalidator PermissionInvalidator) *PermissionNotifier {
+	return &PermissionNotifier{pool: pool, logger: logger, invalidator: invalidator}
+}
+
+// Run listens until ctx ends. The whole cache is cleared after each
+// reconnect, since revisions committed meanwhile were not announced.
+func (n *PermissionNotifier) Run(ctx context.Context) {
+	listener{
+		pool: n.pool, logger: n.logger, channel: permissionChangesChannel,
+		onListen: n.invalidator.Clear,
+		onNotify: func(ctx context.Context, payload string) {
+			userID, revision, err := parsePermissionChange(payload)
+			if err != nil {
+				n.logger.WarnContext(ctx, "ignore malformed permission notification", "payload", payload)
+				return
+			}
+			n.invalidator.Invalidate(userID, revision)
+		},
+	}.run(ctx)
+}
+
+// parsePermissionChange returns math.MaxInt64 as the revision of a deleted
+// user, so that every cached set of the user is older.
+func parsePermissionChange(payload string) (string, int64, error) {
+	rawID, rawRevision, hasRevision := strings.Cut(payload, ":")
+	userID, err := uuid.Parse(rawID)
+	if err != nil {
+		return "", 0, err
+	}
+	if !hasRevision {
+		return userID.String(), math.MaxInt64, nil
+	}
+	revision, err := strconv.ParseInt(rawRevision, 10, 64)
+	if err != nil {
+		return "", 0, err
+	}
+	return userID.String(), revision, nil
+}
@@ -5,9 +5,11 @@ import (
 	"errors"
 	"fmt"
 
+	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 
+	"github.com/your-org/go-service-template/internal/platform/httpserver"
 	"github.com/your-org/go-service-template/internal/users"
 )
 
@@ -45,8 +47,54 @@ func (r *PermissionRepository) ApplyPermissionChange(ctx context.Context, change
 		}
 		result = users.PermissionChangeStale
 	}
+	if err := recordAuditEvent(ctx, queries, users.AuditActionApplyPermissions, change.UserID, string(result)); err != nil {
+		return "", err
+	}
 	if err := tx.Commit(ctx); err != nil {
 		return "", fmt.Errorf("commit permission change: %w", err)
 	}
 	return result, nil
 }
+
+func (r *PermissionRepository) GetPermissions(ctx context.Context, userID uuid.UUID) (users.Permissions, error) {
+	permissions, err := New(r.pool).GetUserPermissions(ctx, userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return users.Permissions{}, users.ErrNotFound
+		}
+		return users.Permissions{}, fmt.Errorf("select user permissions: %w", err)
+	}
+	return users.Permissions{
+		UserID:      permissions.UserID,
+		Revision:    permissions.Revision,
+		Permissions: permissions.Permissions,
+		UpdatedAt:   permissions.UpdatedAt,
+	}, nil
+}
+
+// LoadPermissions maps a token subject to the active user with that ID. Any
+// other subject, or a user without a permission set, has no permissions.
+func (r *PermissionRepository) LoadPermissions(ctx context.Context, subject string) (httpserver.PermissionSet, error) {
+	userID, err := uuid.Parse(subject)
+	if err != nil {
+		return httpserver.PermissionSet{}, nil
+	}
+	set := httpserver.PermissionSet{UserID: userID.String()}
+
+	queries := New(r.pool)
+	if _, err := queries.GetUser(ctx, userID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return set, nil
+		}
+		return httpserver.PermissionSet{}, fmt.Errorf("select permission subject: %w", err)
+	}
+	permissions, err := queries.GetUserPermissions(ctx, userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return set, nil
+		}
+		return httpserver.PermissionSet{}, fmt.Errorf("select user permissions: %w", err)
+	}
+	set.Revision, set.Permissions = permissions.Revision, permissions.Permissions
+	return set, nil
+}
@@ -1,27 +1,63 @@
 -- name: CreateUser :one
 INSERT INTO users (id, email)
 VALUES ($1, $2)
-RETURNING id, email, created_at;
+RETURNING *;
 
 -- name: GetUser :one
-SELECT id, email, created_at
+SELECT *
 FROM users
-WHERE id = $1;
+WHERE id = $1 AND deleted_at IS NULL;
+
+-- name: GetUserForUpdate :one
+SELECT *
+FROM users
+WHERE id = $1 AND deleted_at IS NULL
+FOR UPDATE;
+
+-- name: ListUsers :many
+SELECT *
+FROM users
+WHERE
+    deleted_at IS NULL
+    AND (sqlc.narg('after_id')::uuid IS NULL OR id > sqlc.narg('after_id')::uuid)
+    AND (sqlc.narg('email_prefix')::text IS NULL OR starts_with(email, sqlc.narg('email_prefix')::text))
+    AND (sqlc.narg('created_after')::timestamptz IS NULL OR created_at >= sqlc.narg('created_after')::timestamptz)
+    AND (sqlc.narg('created_before')::timestamptz IS NULL OR created_at < sqlc.narg('created_before')::timestamptz)
+ORDER BY id
+LIMIT sqlc.arg('row_limit');
 
 -- name: GetUserByEmail :one
-SELECT id, email, created_at
+SELECT *
 FROM users
-WHERE email = $1;
+WHERE email = $1 AND deleted_at IS NULL;
+
+-- name: UpdateUserEmail :one
+UPDATE users
+SET email = $2, version = version + 1
+WHERE id = $1 AND deleted_at IS NULL
+RETURNING *;
+
+-- name: SoftDeleteUser :one
+UPDATE users
+SET deleted_at = now(), version = version + 1
+WHERE id = $1 AND deleted_at IS NULL
+RETURNING id;
 
 -- name: CreateUserImport :one
-INSERT INTO user_imports (id, total_count, correlation_id)
-VALUES ($1, $2, $3)
+INSERT INTO user_imports (id, total_count, correlation_id, retry_of, callback_url)
+VALUES ($1, $2, $3, $4, $5)
 RETURNING *;
 
--- name: CreateUserImportEntry :exec
+-- name: CreateUserImportEntries :copyfrom
 INSERT INTO user_import_entries (import_id, user_id, email)
 VALUES ($1, $2, $3);
 
+-- name: ScheduleUserImport :one
+UPDATE user_imports
+SET total_count = $2, job_id = $3
+WHERE id = $1
+RETURNING *;
+
 -- name: GetUserImport :one
 SELECT *
 FROM user_imports
@@ -33,12 +69,37 @@ SET state = 'running', started_at = COALESCE(started_at, now())
 WHERE id = $1 AND state IN ('pending', 'running')
 RETURNING id;
 
+-- name: RecordUserImportCallbackAttempt :exec
+INSERT INTO user_import_callback_attempts (import_id, attempt, status_code, error)
+SELECT $1, COALESCE(max(attempt), 0) + 1, $2, $3
+FROM user_import_callback_attempts
+WHERE import_id = $1;
+
+-- name: ListUserImportCallbackAttempts :many
+SELECT *
+FROM user_import_callback_attempts
+WHERE import_id = $1
+ORDER BY attempt;
+
+-- name: LockUserImportState :one
+SELECT state
+FROM user_imports
+WHERE id = $1
+FOR UPDATE;
+
+-- name: CancelUserImport :one
+UPDATE user_imports
+SET state = 'cancelled', finished_at = now()
+WHERE id = $1 AND state IN ('pending', 'running')
+RETURNING *;
+
 -- name: ListPendingUserImportEntries :many
 SELECT entries.import_id, entries.user_id, entries.email, entries.state, imports.correlation_id
 FROM user_import_entries AS entries
 JOIN user_imports AS imports ON imports.id = entries.import_id
 WHERE entries.import_id = $1 AND entries.state = 'pending'
-ORDER BY email;
+ORDER BY entries.user_id
+LIMIT $2;
 
 -- name: CreateImportedUser :one
 INSERT INTO users (id, email)
@@ -53,9 +114,24 @@ WHERE import_id = $1 AND user_id = $2 AND state = 'pending';
 
 -- name: FailUserImportEntry :exec
 UPDATE user_import_entries
-SET state = 'failed'
+SET state = 'failed', failure_reason = sqlc.arg('failure_reason')::user_import_entry_failure_reason
 WHERE import_id = $1 AND user_id = $2 AND state = 'pending';
 
+-- name: ListUserImportEntries :many
+SELECT user_id, email, state, failure_reason
+FROM user_import_entries
+WHERE
+    import_id = sqlc.arg('import_id')
+    AND (sqlc.narg('state')::user_import_entry_state IS NULL OR state = sqlc.narg('state')::user_import_entry_state)
+    AND (sqlc.narg('after_user_id')::uuid IS NULL OR user_id > sqlc.narg('after_user_id')::uuid)
+ORDER BY user_id
+LIMIT sqlc.arg('row_limit');
+
+-- name: RecordUserImportProgress :exec
+UPDATE user_imports
+SET completed_count = completed_count + $2, failed_count = failed_count + $3
+WHERE id = $1;
+
 -- name: FinishUserImport :one
 WITH counts AS (
     SELECT
@@ -71,12 +147,12 @@ SET
     failed_count = counts.failed_count,
     finished_at = now()
 FROM counts
-WHERE id = $1
+WHERE id = $1 AND state = 'running'
 RETURNING user_imports.*;
 
 -- name: DeleteFinishedUserImportsBefore :execrows
 DELETE FROM user_imports
-WHERE state IN ('completed', 'failed') AND finished_at < $1;
+WHERE finished_at < $1;
 
 -- name: RecordProcessedEvent :one
 INSERT INTO processed_events (event_id, event_type)
@@ -99,3 +175,148 @@ RETURNING user_id;
 SELECT user_id, revision, permissions, updated_at
 FROM user_permissions
 WHERE user_id = $1;
+
+-- name: ClaimIdempotencyKey :one
+INSERT INTO idempotency_keys (subject, key)
+VALUES (sqlc.arg('subject'), sqlc.arg('key'))
+ON CONFLICT (subject, key) DO UPDATE
+SET locked_at = now()
+WHERE
+    idempotency_keys.status_code IS NULL
+    AND idempotency_keys.locked_at < sqlc.arg('stale_before')
+RETURNING key;
+
+-- name: GetIdempotencyKey :one
+SELECT *
+FROM idempotency_keys
+WHERE subject = $1 AND key = $2;
+
+-- name: ExtendIdempotencyKey :exec
+UPDATE idempotency_keys
+SET locked_at = now()
+WHERE subject = $1 AND key = $2 AND status_code IS NULL;
+
+-- name: CompleteIdempotencyKey :exec
+UPDATE idempotency_keys
+SET fingerprint = $3, status_code = $4, response_headers = $5, response_body = $6
+WHERE subject = $1 AND key = $2 AND status_code IS NULL;
+
+-- name: ReleaseIdempotencyKey :exec
+DELETE FROM idempotency_keys
+WHERE subject = $1 AND key = $2 AND status_code IS NULL;
+
+-- name: DeleteIdempotencyKeysBefore :execrows
+DELETE FROM idempotency_keys
+WHERE created_at < $1;
+
+-- name: CreateAPIKey :one
+INSERT INTO api_keys (id, name, key_hash, subject, scopes, expires_at)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING *;
+
+-- name: GetAPIKeyByHash :one
+SELECT *
+FROM api_keys
+WHERE key_hash = $1;
+
+-- name: ListAPIKeys :many
+SELECT *
+FROM api_keys
+ORDER BY created_at, id;
+
+-- name: RevokeAPIKey :one
+UPDATE api_keys
+SET revoked_at = now()
+WHERE id = $1 AND revoked_at IS NULL
+RETURNING *;
+
+-- name: TakeRateLimitToken :one
+-- The SET expressions all see the bucket before the update, so the refilled
+-- token count is repeated in each of them.
+INSERT INTO rate_limit_buckets (key, tokens, last_allowed, updated_at)
+VALUES (sqlc.arg('key'), sqlc.arg('burst')::double precision - 1, true, now())
+ON CONFLICT (key) DO UPDATE
+SET
+    tokens = CASE
+        WHEN LEAST(sqlc.arg('burst')::double precision, rate_limit_buckets.tokens + EXTRACT(EPOCH FROM now() - rate_limit_buckets.updated_at)::double precision * sqlc.arg('refill_per_second')::double precision) >= 1
+        THEN LEAST(sqlc.arg('burst')::double precision, rate_limit_buckets.tokens + EXTRACT(EPOCH FROM now() - rate_limit_buckets.updated_at)::double precision * sqlc.arg('refill_per_second')::double precision) - 1
+        ELSE LEAST(sqlc.arg('burst')::double precision, rate_limit_buckets.tokens + EXTRACT(EPOCH FROM now() - rate_limit_buckets.updated_at)::double precision * sqlc.arg('refill_per_second')::double precision)
+    END,
+    last_allowed = LEAST(sqlc.arg('burst')::double precision, rate_limit_buckets.tokens + EXTRACT(EPOCH FROM now() - rate_limit_buckets.updated_at)::double precision * sqlc.arg('refill_per_second')::double precision) >= 1,
+    updated_at = now()
+RETURNING tokens, last_allowed;
+
+-- name: DeleteRateLimitBucketsBefore :execrows
+DELETE FROM rate_limit_buckets
+WHERE updated_at < $1;
+
+-- name: CreateAuditEvent :exec
+INSERT INTO audit_events (id, actor, action, target_id, correlation_id, outcome)
+VALUES ($1, $2, $3, $4, $5, $6);
+
+-- name: ListAuditEvents :many
+SELECT *
+FROM audit_events
+WHERE
+    (sqlc.narg('after_id')::uuid IS NULL OR id > sqlc.narg('after_id')::uuid)
+    AND (sqlc.narg('actor')::text IS NULL OR actor = sqlc.narg('actor')::text)
+    AND (sqlc.narg('target_id')::text IS NULL OR target_id = sqlc.narg('target_id')::text)
+    AND (sqlc.narg('occurred_after')::timestamptz IS NULL OR occurred_at >= sqlc.narg('occurred_after')::timestamptz)
+    AND (sqlc.narg('occurred_before')::timestamptz IS NULL OR occurred_at < sqlc.narg('occurred_before')::timestamptz)
+ORDER BY id
+LIMIT sqlc.arg('row_limit');
+
+-- name: AddOutboxMessage :exec
+INSERT INTO outbox_messages (event_id, event_type, schema_version, group_id, body, trace_context)
+VALUES ($1, $2, $3, $4, $5, $6);
+
+-- name: TryLockOutboxRelay :one
+-- Only one relay claims messages at a time, so no message is claimed twice.
+SELECT pg_try_advisory_xact_lock(hashtext('outbox_messages'));
+
+-- name: ListDueOutboxMessages :many
+-- Only the earliest message of a group is due, so a batch holds at most one
+-- message per group and nothing after a failed message is published before
+-- it. Parked messages no longer hold back their group.
+SELECT *
+FROM outbox_messages AS message
+WHERE message.parked_at IS NULL
+  AND message.available_at <= now()
+  AND NOT EXISTS (
+      SELECT 1
+      FROM outbox_messages AS earlier
+      WHERE message.group_id <> ''
+        AND earlier.group_id = message.group_id
+        AND earlier.sequence < message.sequence
+        AND earlier.parked_at IS NULL
+  )
+ORDER BY message.sequence
+LIMIT $1;
+
+-- name: ClaimOutboxMessages :exec
+-- A claimed message is not due again until the lease ends, so a relay that
+-- stops while publishing leaves it to be published again.
+UPDATE outbox_messages
+SET available_at = now() + interval '1 second' * sqlc.arg('lease_seconds')::integer
+WHERE sequence = ANY(sqlc.arg('sequences')::bigint[]);
+
+-- name: DeleteOutboxMessages :exec
+DELETE FROM outbox_messages
+WHERE sequence = ANY(sqlc.arg('sequences')::bigint[]);
+
+-- name: RetryOutboxMessage :exec
+UPDATE outbox_messages
+SET
+    attempts = attempts + 1,
+    last_error = sqlc.arg('last_error'),
+    available_at = now() + LEAST(interval '5 minutes', interval '1 second' * power(2, LEAST(attempts, 10))),
+    parked_at = CASE WHEN attempts + 1 >= sqlc.arg('max_attempts')::integer THEN now() END
+WHERE sequence = sqlc.arg('sequence');
+
+-- name: GetOutboxLag :one
+SELECT
+    count(*) FILTER (WHERE parked_at IS NULL) AS pending,
+    COALESCE(EXTRACT(EPOCH FROM now() - min(created_at) FILTER (WHERE parked_at IS NULL)), 0)::double precision AS oldest_age_seconds,
+    COALESCE(max(attempts) FILTER (WHERE parked_at IS NULL), 0)::integer AS max_attempts,
+    count(*) FILTER (WHERE parked_at IS NOT NULL) AS parked
+FROM outbox_messages;
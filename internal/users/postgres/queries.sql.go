@@ -7,11 +7,38 @@ package postgres
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const addOutboxMessage = `-- name: AddOutboxMessage :exec
+INSERT INTO outbox_messages (event_id, event_type, schema_version, group_id, body, trace_context)
+VALUES ($1, $2, $3, $4, $5, $6)
+`
+
+type AddOutboxMessageParams struct {
+	EventID       string `json:"event_id"`
+	EventType     string `json:"event_type"`
+	SchemaVersion string `json:"schema_version"`
+	GroupID       string `json:"group_id"`
+	Body          string `json:"body"`
+	TraceContext  []byte `json:"trace_context"`
+}
+
+func (q *Queries) AddOutboxMessage(ctx context.Context, arg AddOutboxMessageParams) error {
+	_, err := q.db.Exec(ctx, addOutboxMessage,
+		arg.EventID,
+		arg.EventType,
+		arg.SchemaVersion,
+		arg.GroupID,
+		arg.Body,
+		arg.TraceContext,
+	)
+	return err
+}
+
 const applyUserPermissions = `-- name: ApplyUserPermissions :one
 INSERT INTO user_permissions (user_id, revision, permissions)
 VALUES ($1, $2, $3)
@@ -37,6 +64,102 @@ func (q *Queries) ApplyUserPermissions(ctx context.Context, arg ApplyUserPermiss
 	return user_id, err
 }
 
+const cancelUserImport = `-- name: CancelUserImport :one
+UPDATE user_imports
+SET state = 'cancelled', finished_at = now()
+WHERE id = $1 AND state IN ('pending', 'running')
+RETURNING id, state, total_count, completed_count, failed_count, created_at, started_at, finished_at, correlation_id, job_id, retry_of, callback_url
+`
+
+func (q *Queries) CancelUserImport(ctx context.Context, id uuid.UUID) (UserImport, error) {
+	row := q.db.QueryRow(ctx, cancelUserImport, id)
+	var i UserImport
+	err := row.Scan(
+		&i.ID,
+		&i.State,
+		&i.TotalCount,
+		&i.CompletedCount,
+		&i.FailedCount,
+		&i.CreatedAt,
+		&i.StartedAt,
+		&i.FinishedAt,
+		&i.CorrelationID,
+		&i.JobID,
+		&i.RetryOf,
+		&i.CallbackUrl,
+	)
+	return i, err
+}
+
+const claimIdempotencyKey = `-- name: ClaimIdempotencyKey :one
+INSERT INTO idempotency_keys (subject, key)
+VALUES ($1, $2)
+ON CONFLICT (subject, key) DO UPDATE
+SET locked_at = now()
+WHERE
+    idempotency_keys.status_code IS NULL
+    AND idempotency_keys.locked_at < $3
+RETURNING key
+`
+
+type ClaimIdempotencyKeyParams struct {
+	Subject     string    `json:"subject"`
+	Key         string    `json:"key"`
+	StaleBefore time.Time `json:"stale_before"`
+}
+
+func (q *Queries) ClaimIdempotencyKey(ctx context.Context, arg ClaimIdempotencyKeyParams) (string, error) {
+	row := q.db.QueryRow(ctx, claimIdempotencyKey, arg.Subject, arg.Key, arg.StaleBefore)
+	var key string
+	err := row.Scan(&key)
+	return key, err
+}
+
+const claimOutboxMessages = `-- name: ClaimOutboxMessages :exec
+UPDATE outbox_messages
+SET available_at = now() + interval '1 second' * $1::integer
+WHERE sequence = ANY($2::bigint[])
+`
+
+type ClaimOutboxMessagesParams struct {
+	LeaseSeconds int32   `json:"lease_seconds"`
+	Sequences    []int64 `json:"sequences"`
+}
+
+// A claimed message is not due again until the lease ends, so a relay that
+// stops while publishing leaves it to be published again.
+func (q *Queries) ClaimOutboxMessages(ctx context.Context, arg ClaimOutboxMessagesParams) error {
+	_, err := q.db.Exec(ctx, claimOutboxMessages, arg.LeaseSeconds, arg.Sequences)
+	return err
+}
+
+const completeIdempotencyKey = `-- name: CompleteIdempotencyKey :exec
+UPDATE idempotency_keys
+SET fingerprint = $3, status_code = $4, response_headers = $5, response_body = $6
+WHERE subject = $1 AND key = $2 AND status_code IS NULL
+`
+
+type CompleteIdempotencyKeyParams struct {
+	Subject         string      `json:"subject"`
+	Key             string      `json:"key"`
+	Fingerprint     []byte      `json:"fingerprint"`
+	StatusCode      pgtype.Int4 `json:"status_code"`
+	ResponseHeaders []byte      `json:"response_headers"`
+	ResponseBody    []byte      `json:"response_body"`
+}
+
+func (q *Queries) CompleteIdempotencyKey(ctx context.Context, arg CompleteIdempotencyKeyParams) error {
+	_, err := q.db.Exec(ctx, completeIdempotencyKey,
+		arg.Subject,
+		arg.Key,
+		arg.Fingerprint,
+		arg.StatusCode,
+		arg.ResponseHeaders,
+		arg.ResponseBody,
+	)
+	return err
+}
+
 const completeUserImportEntry = `-- name: CompleteUserImportEntry :exec
 UPDATE user_import_entries
 SET state = 'completed'
@@ -53,6 +176,70 @@ func (q *Queries) CompleteUserImportEntry(ctx context.Context, arg CompleteUserI
 	return err
 }
 
+const createAPIKey = `-- name: CreateAPIKey :one
+INSERT INTO api_keys (id, name, key_hash, subject, scopes, expires_at)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING id, name, key_hash, subject, scopes, created_at, expires_at, revoked_at
+`
+
+type CreateAPIKeyParams struct {
+	ID        uuid.UUID          `json:"id"`
+	Name      string             `json:"name"`
+	KeyHash   []byte             `json:"key_hash"`
+	Subject   string             `json:"subject"`
+	Scopes    []string           `json:"scopes"`
+	ExpiresAt pgtype.Timestamptz `json:"expires_at"`
+}
+
+func (q *Queries) CreateAPIKey(ctx context.Context, arg CreateAPIKeyParams) (ApiKey, error) {
+	row := q.db.QueryRow(ctx, createAPIKey,
+		arg.ID,
+		arg.Name,
+		arg.KeyHash,
+		arg.Subject,
+		arg.Scopes,
+		arg.ExpiresAt,
+	)
+	var i ApiKey
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.KeyHash,
+		&i.Subject,
+		&i.Scopes,
+		&i.CreatedAt,
+		&i.ExpiresAt,
+		&i.RevokedAt,
+	)
+	return i, err
+}
+
+const createAuditEvent = `-- name: CreateAuditEvent :exec
+INSERT INTO audit_events (id, actor, action, target_id, correlation_id, outcome)
+VALUES ($1, $2, $3, $4, $5, $6)
+`
+
+type CreateAuditEventParams struct {
+	ID            uuid.UUID `json:"id"`
+	Actor         string    `json:"actor"`
+	Action        string    `json:"action"`
+	TargetID      string    `json:"target_id"`
+	CorrelationID string    `json:"correlation_id"`
+	Outcome       string    `json:"outcome"`
+}
+
+func (q *Queries) CreateAuditEvent(ctx context.Context, arg CreateAuditEventParams) error {
+	_, err := q.db.Exec(ctx, createAuditEvent,
+		arg.ID,
+		arg.Actor,
+		arg.Action,
+		arg.TargetID,
+		arg.CorrelationID,
+		arg.Outcome,
+	)
+	return err
+}
+
 const createImportedUser = `-- name: CreateImportedUser :one
 INSERT INTO users (id, email)
 VALUES ($1, $2)
@@ -75,7 +262,7 @@ func (q *Queries) CreateImportedUser(ctx context.Context, arg CreateImportedUser
 const createUser = `-- name: CreateUser :one
 INSERT INTO users (id, email)
 VALUES ($1, $2)
-RETURNING id, email, created_at
+RETURNING id, email, created_at, deleted_at, version
 `
 
 type CreateUserParams struct {
@@ -86,24 +273,38 @@ type CreateUserParams struct {
 func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, error) {
 	row := q.db.QueryRow(ctx, createUser, arg.ID, arg.Email)
 	var i User
-	err := row.Scan(&i.ID, &i.Email, &i.CreatedAt)
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.CreatedAt,
+		&i.DeletedAt,
+		&i.Version,
+	)
 	return i, err
 }
 
 const createUserImport = `-- name: CreateUserImport :one
-INSERT INTO user_imports (id, total_count, correlation_id)
-VALUES ($1, $2, $3)
-RETURNING id, state, total_count, completed_count, failed_count, created_at, started_at, finished_at, correlation_id
+INSERT INTO user_imports (id, total_count, correlation_id, retry_of, callback_url)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING id, state, total_count, completed_count, failed_count, created_at, started_at, finished_at, correlation_id, job_id, retry_of, callback_url
 `
 
 type CreateUserImportParams struct {
-	ID            uuid.UUID `json:"id"`
-	TotalCount    int32     `json:"total_count"`
-	CorrelationID string    `json:"correlation_id"`
+	ID            uuid.UUID   `json:"id"`
+	TotalCount    int32       `json:"total_count"`
+	CorrelationID string      `json:"correlation_id"`
+	RetryOf       pgtype.UUID `json:"retry_of"`
+	CallbackUrl   pgtype.Text `json:"callback_url"`
 }
 
 func (q *Queries) CreateUserImport(ctx context.Context, arg CreateUserImportParams) (UserImport, error) {
-	row := q.db.QueryRow(ctx, createUserImport, arg.ID, arg.TotalCount, arg.CorrelationID)
+	row := q.db.QueryRow(ctx, createUserImport,
+		arg.ID,
+		arg.TotalCount,
+		arg.CorrelationID,
+		arg.RetryOf,
+		arg.CallbackUrl,
+	)
 	var i UserImport
 	err := row.Scan(
 		&i.ID,
@@ -115,29 +316,22 @@ func (q *Queries) CreateUserImport(ctx context.Context, arg CreateUserImportPara
 		&i.StartedAt,
 		&i.FinishedAt,
 		&i.CorrelationID,
+		&i.JobID,
+		&i.RetryOf,
+		&i.CallbackUrl,
 	)
 	return i, err
 }
 
-const createUserImportEntry = `-- name: CreateUserImportEntry :exec
-INSERT INTO user_import_entries (import_id, user_id, email)
-VALUES ($1, $2, $3)
-`
-
-type CreateUserImportEntryParams struct {
+type CreateUserImportEntriesParams struct {
 	ImportID uuid.UUID `json:"import_id"`
 	UserID   uuid.UUID `json:"user_id"`
 	Email    string    `json:"email"`
 }
 
-func (q *Queries) CreateUserImportEntry(ctx context.Context, arg CreateUserImportEntryParams) error {
-	_, err := q.db.Exec(ctx, createUserImportEntry, arg.ImportID, arg.UserID, arg.Email)
-	return err
-}
-
 const deleteFinishedUserImportsBefore = `-- name: DeleteFinishedUserImportsBefore :execrows
 DELETE FROM user_imports
-WHERE state IN ('completed', 'failed') AND finished_at < $1
+WHERE finished_at < $1
 `
 
 func (q *Queries) DeleteFinishedUserImportsBefore(ctx context.Context, finishedAt pgtype.Timestamptz) (int64, error) {
@@ -148,19 +342,72 @@ func (q *Queries) DeleteFinishedUserImportsBefore(ctx context.Context, finishedA
 	return result.RowsAffected(), nil
 }
 
+const deleteIdempotencyKeysBefore = `-- name: DeleteIdempotencyKeysBefore :execrows
+DELETE FROM idempotency_keys
+WHERE created_at < $1
+`
+
+func (q *Queries) DeleteIdempotencyKeysBefore(ctx context.Context, createdAt time.Time) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteIdempotencyKeysBefore, createdAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteOutboxMessages = `-- name: DeleteOutboxMessages :exec
+DELETE FROM outbox_messages
+WHERE sequence = ANY($1::bigint[])
+`
+
+func (q *Queries) DeleteOutboxMessages(ctx context.Context, sequences []int64) error {
+	_, err := q.db.Exec(ctx, deleteOutboxMessages, sequences)
+	return err
+}
+
+const deleteRateLimitBucketsBefore = `-- name: DeleteRateLimitBucketsBefore :execrows
+DELETE FROM rate_limit_buckets
+WHERE updated_at < $1
+`
+
+func (q *Queries) DeleteRateLimitBucketsBefore(ctx context.Context, updatedAt time.Time) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteRateLimitBucketsBefore, updatedAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const extendIdempotencyKey = `-- name: ExtendIdempotencyKey :exec
+UPDATE idempotency_keys
+SET locked_at = now()
+WHERE subject = $1 AND key = $2 AND status_code IS NULL
+`
+
+type ExtendIdempotencyKeyParams struct {
+	Subject string `json:"subject"`
+	Key     string `json:"key"`
+}
+
+func (q *Queries) ExtendIdempotencyKey(ctx context.Context, arg ExtendIdempotencyKeyParams) error {
+	_, err := q.db.Exec(ctx, extendIdempotencyKey, arg.Subject, arg.Key)
+	return err
+}
+
 const failUserImportEntry = `-- name: FailUserImportEntry :exec
 UPDATE user_import_entries
-SET state = 'failed'
+SET state = 'failed', failure_reason = $3::user_import_entry_failure_reason
 WHERE import_id = $1 AND user_id = $2 AND state = 'pending'
 `
 
 type FailUserImportEntryParams struct {
-	ImportID uuid.UUID `json:"import_id"`
-	UserID   uuid.UUID `json:"user_id"`
+	ImportID      uuid.UUID                    `json:"import_id"`
+	UserID        uuid.UUID                    `json:"user_id"`
+	FailureReason UserImportEntryFailureReason `json:"failure_reason"`
 }
 
 func (q *Queries) FailUserImportEntry(ctx context.Context, arg FailUserImportEntryParams) error {
-	_, err := q.db.Exec(ctx, failUserImportEntry, arg.ImportID, arg.UserID)
+	_, err := q.db.Exec(ctx, failUserImportEntry, arg.ImportID, arg.UserID, arg.FailureReason)
 	return err
 }
 
@@ -179,8 +426,8 @@ SET
     failed_count = counts.failed_count,
     finished_at = now()
 FROM counts
-WHERE id = $1
-RETURNING user_imports.id, user_imports.state, user_imports.total_count, user_imports.completed_count, user_imports.failed_count, user_imports.created_at, user_imports.started_at, user_imports.finished_at, user_imports.correlation_id
+WHERE id = $1 AND state = 'running'
+RETURNING user_imports.id, user_imports.state, user_imports.total_count, user_imports.completed_count, user_imports.failed_count, user_imports.created_at, user_imports.started_at, user_imports.finished_at, user_imports.correlation_id, user_imports.job_id, user_imports.retry_of, user_imports.callback_url
 `
 
 func (q *Queries) FinishUserImport(ctx context.Context, id uuid.UUID) (UserImport, error) {
@@ -196,38 +443,150 @@ func (q *Queries) FinishUserImport(ctx context.Context, id uuid.UUID) (UserImpor
 		&i.StartedAt,
 		&i.FinishedAt,
 		&i.CorrelationID,
+		&i.JobID,
+		&i.RetryOf,
+		&i.CallbackUrl,
+	)
+	return i, err
+}
+
+const getAPIKeyByHash = `-- name: GetAPIKeyByHash :one
+SELECT id, name, key_hash, subject, scopes, created_at, expires_at, revoked_at
+FROM api_keys
+WHERE key_hash = $1
+`
+
+func (q *Queries) GetAPIKeyByHash(ctx context.Context, keyHash []byte) (ApiKey, error) {
+	row := q.db.QueryRow(ctx, getAPIKeyByHash, keyHash)
+	var i ApiKey
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.KeyHash,
+		&i.Subject,
+		&i.Scopes,
+		&i.CreatedAt,
+		&i.ExpiresAt,
+		&i.RevokedAt,
+	)
+	return i, err
+}
+
+const getIdempotencyKey = `-- name: GetIdempotencyKey :one
+SELECT subject, key, fingerprint, status_code, response_headers, response_body, locked_at, created_at
+FROM idempotency_keys
+WHERE subject = $1 AND key = $2
+`
+
+type GetIdempotencyKeyParams struct {
+	Subject string `json:"subject"`
+	Key     string `json:"key"`
+}
+
+func (q *Queries) GetIdempotencyKey(ctx context.Context, arg GetIdempotencyKeyParams) (IdempotencyKey, error) {
+	row := q.db.QueryRow(ctx, getIdempotencyKey, arg.Subject, arg.Key)
+	var i IdempotencyKey
+	err := row.Scan(
+		&i.Subject,
+		&i.Key,
+		&i.Fingerprint,
+		&i.StatusCode,
+		&i.ResponseHeaders,
+		&i.ResponseBody,
+		&i.LockedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getOutboxLag = `-- name: GetOutboxLag :one
+SELECT
+    count(*) FILTER (WHERE parked_at IS NULL) AS pending,
+    COALESCE(EXTRACT(EPOCH FROM now() - min(created_at) FILTER (WHERE parked_at IS NULL)), 0)::double precision AS oldest_age_seconds,
+    COALESCE(max(attempts) FILTER (WHERE parked_at IS NULL), 0)::integer AS max_attempts,
+    count(*) FILTER (WHERE parked_at IS NOT NULL) AS parked
+FROM outbox_messages
+`
+
+type GetOutboxLagRow struct {
+	Pending          int64   `json:"pending"`
+	OldestAgeSeconds float64 `json:"oldest_age_seconds"`
+	MaxAttempts      int32   `json:"max_attempts"`
+	Parked           int64   `json:"parked"`
+}
+
+func (q *Queries) GetOutboxLag(ctx context.Context) (GetOutboxLagRow, error) {
+	row := q.db.QueryRow(ctx, getOutboxLag)
+	var i GetOutboxLagRow
+	err := row.Scan(
+		&i.Pending,
+		&i.OldestAgeSeconds,
+		&i.MaxAttempts,
+		&i.Parked,
 	)
 	return i, err
 }
 
 const getUser = `-- name: GetUser :one
-SELECT id, email, created_at
+SELECT id, email, created_at, deleted_at, version
 FROM users
-WHERE id = $1
+WHERE id = $1 AND deleted_at IS NULL
 `
 
 func (q *Queries) GetUser(ctx context.Context, id uuid.UUID) (User, error) {
 	row := q.db.QueryRow(ctx, getUser, id)
 	var i User
-	err := row.Scan(&i.ID, &i.Email, &i.CreatedAt)
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.CreatedAt,
+		&i.DeletedAt,
+		&i.Version,
+	)
 	return i, err
 }
 
 const getUserByEmail = `-- name: GetUserByEmail :one
-SELECT id, email, created_at
+SELECT id, email, created_at, deleted_at, version
 FROM users
-WHERE email = $1
+WHERE email = $1 AND deleted_at IS NULL
 `
 
 func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error) {
 	row := q.db.QueryRow(ctx, getUserByEmail, email)
 	var i User
-	err := row.Scan(&i.ID, &i.Email, &i.CreatedAt)
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.CreatedAt,
+		&i.DeletedAt,
+		&i.Version,
+	)
+	return i, err
+}
+
+const getUserForUpdate = `-- name: GetUserForUpdate :one
+SELECT id, email, created_at, deleted_at, version
+FROM users
+WHERE id = $1 AND deleted_at IS NULL
+FOR UPDATE
+`
+
+func (q *Queries) GetUserForUpdate(ctx context.Context, id uuid.UUID) (User, error) {
+	row := q.db.QueryRow(ctx, getUserForUpdate, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.CreatedAt,
+		&i.DeletedAt,
+		&i.Version,
+	)
 	return i, err
 }
 
 const getUserImport = `-- name: GetUserImport :one
-SELECT id, state, total_count, completed_count, failed_count, created_at, started_at, finished_at, correlation_id
+SELECT id, state, total_count, completed_count, failed_count, created_at, started_at, finished_at, correlation_id, job_id, retry_of, callback_url
 FROM user_imports
 WHERE id = $1
 `
@@ -245,6 +604,9 @@ func (q *Queries) GetUserImport(ctx context.Context, id uuid.UUID) (UserImport,
 		&i.StartedAt,
 		&i.FinishedAt,
 		&i.CorrelationID,
+		&i.JobID,
+		&i.RetryOf,
+		&i.CallbackUrl,
 	)
 	return i, err
 }
@@ -267,14 +629,165 @@ func (q *Queries) GetUserPermissions(ctx context.Context, userID uuid.UUID) (Use
 	return i, err
 }
 
+const listAPIKeys = `-- name: ListAPIKeys :many
+SELECT id, name, key_hash, subject, scopes, created_at, expires_at, revoked_at
+FROM api_keys
+ORDER BY created_at, id
+`
+
+func (q *Queries) ListAPIKeys(ctx context.Context) ([]ApiKey, error) {
+	rows, err := q.db.Query(ctx, listAPIKeys)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ApiKey{}
+	for rows.Next() {
+		var i ApiKey
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.KeyHash,
+			&i.Subject,
+			&i.Scopes,
+			&i.CreatedAt,
+			&i.ExpiresAt,
+			&i.RevokedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listAuditEvents = `-- name: ListAuditEvents :many
+SELECT id, actor, action, target_id, correlation_id, outcome, occurred_at
+FROM audit_events
+WHERE
+    ($1::uuid IS NULL OR id > $1::uuid)
+    AND ($2::text IS NULL OR actor = $2::text)
+    AND ($3::text IS NULL OR target_id = $3::text)
+    AND ($4::timestamptz IS NULL OR occurred_at >= $4::timestamptz)
+    AND ($5::timestamptz IS NULL OR occurred_at < $5::timestamptz)
+ORDER BY id
+LIMIT $6
+`
+
+type ListAuditEventsParams struct {
+	AfterID        pgtype.UUID        `json:"after_id"`
+	Actor          pgtype.Text        `json:"actor"`
+	TargetID       pgtype.Text        `json:"target_id"`
+	OccurredAfter  pgtype.Timestamptz `json:"occurred_after"`
+	OccurredBefore pgtype.Timestamptz `json:"occurred_before"`
+	RowLimit       int32              `json:"row_limit"`
+}
+
+func (q *Queries) ListAuditEvents(ctx context.Context, arg ListAuditEventsParams) ([]AuditEvent, error) {
+	rows, err := q.db.Query(ctx, listAuditEvents,
+		arg.AfterID,
+		arg.Actor,
+		arg.TargetID,
+		arg.OccurredAfter,
+		arg.OccurredBefore,
+		arg.RowLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []AuditEvent{}
+	for rows.Next() {
+		var i AuditEvent
+		if err := rows.Scan(
+			&i.ID,
+			&i.Actor,
+			&i.Action,
+			&i.TargetID,
+			&i.CorrelationID,
+			&i.Outcome,
+			&i.OccurredAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listDueOutboxMessages = `-- name: ListDueOutboxMessages :many
+SELECT sequence, event_id, event_type, body, attempts, last_error, created_at, available_at, group_id, schema_version, trace_context, parked_at
+FROM outbox_messages AS message
+WHERE message.parked_at IS NULL
+  AND message.available_at <= now()
+  AND NOT EXISTS (
+      SELECT 1
+      FROM outbox_messages AS earlier
+      WHERE message.group_id <> ''
+        AND earlier.group_id = message.group_id
+        AND earlier.sequence < message.sequence
+        AND earlier.parked_at IS NULL
+  )
+ORDER BY message.sequence
+LIMIT $1
+`
+
+// Only the earliest message of a group is due, so a batch holds at most one
+// message per group and nothing after a failed message is published before
+// it. Parked messages no longer hold back their group.
+func (q *Queries) ListDueOutboxMessages(ctx context.Context, limit int32) ([]OutboxMessage, error) {
+	rows, err := q.db.Query(ctx, listDueOutboxMessages, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []OutboxMessage{}
+	for rows.Next() {
+		var i OutboxMessage
+		if err := rows.Scan(
+			&i.Sequence,
+			&i.EventID,
+			&i.EventType,
+			&i.Body,
+			&i.Attempts,
+			&i.LastError,
+			&i.CreatedAt,
+			&i.AvailableAt,
+			&i.GroupID,
+			&i.SchemaVersion,
+			&i.TraceContext,
+			&i.ParkedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listPendingUserImportEntries = `-- name: ListPendingUserImportEntries :many
 SELECT entries.import_id, entries.user_id, entries.email, entries.state, imports.correlation_id
 FROM user_import_entries AS entries
 JOIN user_imports AS imports ON imports.id = entries.import_id
 WHERE entries.import_id = $1 AND entries.state = 'pending'
-ORDER BY email
+ORDER BY entries.user_id
+LIMIT $2
 `
 
+type ListPendingUserImportEntriesParams struct {
+	ImportID uuid.UUID `json:"import_id"`
+	Limit    int32     `json:"limit"`
+}
+
 type ListPendingUserImportEntriesRow struct {
 	ImportID      uuid.UUID            `json:"import_id"`
 	UserID        uuid.UUID            `json:"user_id"`
@@ -283,8 +796,8 @@ type ListPendingUserImportEntriesRow struct {
 	CorrelationID string               `json:"correlation_id"`
 }
 
-func (q *Queries) ListPendingUserImportEntries(ctx context.Context, importID uuid.UUID) ([]ListPendingUserImportEntriesRow, error) {
-	rows, err := q.db.Query(ctx, listPendingUserImportEntries, importID)
+func (q *Queries) ListPendingUserImportEntries(ctx context.Context, arg ListPendingUserImportEntriesParams) ([]ListPendingUserImportEntriesRow, error) {
+	rows, err := q.db.Query(ctx, listPendingUserImportEntries, arg.ImportID, arg.Limit)
 	if err != nil {
 		return nil, err
 	}
@@ -309,6 +822,161 @@ func (q *Queries) ListPendingUserImportEntries(ctx context.Context, importID uui
 	return items, nil
 }
 
+const listUserImportCallbackAttempts = `-- name: ListUserImportCallbackAttempts :many
+SELECT import_id, attempt, attempted_at, status_code, error
+FROM user_import_callback_attempts
+WHERE import_id = $1
+ORDER BY attempt
+`
+
+func (q *Queries) ListUserImportCallbackAttempts(ctx context.Context, importID uuid.UUID) ([]UserImportCallbackAttempt, error) {
+	rows, err := q.db.Query(ctx, listUserImportCallbackAttempts, importID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []UserImportCallbackAttempt{}
+	for rows.Next() {
+		var i UserImportCallbackAttempt
+		if err := rows.Scan(
+			&i.ImportID,
+			&i.Attempt,
+			&i.AttemptedAt,
+			&i.StatusCode,
+			&i.Error,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUserImportEntries = `-- name: ListUserImportEntries :many
+SELECT user_id, email, state, failure_reason
+FROM user_import_entries
+WHERE
+    import_id = $1
+    AND ($2::user_import_entry_state IS NULL OR state = $2::user_import_entry_state)
+    AND ($3::uuid IS NULL OR user_id > $3::uuid)
+ORDER BY user_id
+LIMIT $4
+`
+
+type ListUserImportEntriesParams struct {
+	ImportID    uuid.UUID                `json:"import_id"`
+	State       NullUserImportEntryState `json:"state"`
+	AfterUserID pgtype.UUID              `json:"after_user_id"`
+	RowLimit    int32                    `json:"row_limit"`
+}
+
+type ListUserImportEntriesRow struct {
+	UserID        uuid.UUID                        `json:"user_id"`
+	Email         string                           `json:"email"`
+	State         UserImportEntryState             `json:"state"`
+	FailureReason NullUserImportEntryFailureReason `json:"failure_reason"`
+}
+
+func (q *Queries) ListUserImportEntries(ctx context.Context, arg ListUserImportEntriesParams) ([]ListUserImportEntriesRow, error) {
+	rows, err := q.db.Query(ctx, listUserImportEntries,
+		arg.ImportID,
+		arg.State,
+		arg.AfterUserID,
+		arg.RowLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListUserImportEntriesRow{}
+	for rows.Next() {
+		var i ListUserImportEntriesRow
+		if err := rows.Scan(
+			&i.UserID,
+			&i.Email,
+			&i.State,
+			&i.FailureReason,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUsers = `-- name: ListUsers :many
+SELECT id, email, created_at, deleted_at, version
+FROM users
+WHERE
+    deleted_at IS NULL
+    AND ($1::uuid IS NULL OR id > $1::uuid)
+    AND ($2::text IS NULL OR starts_with(email, $2::text))
+    AND ($3::timestamptz IS NULL OR created_at >= $3::timestamptz)
+    AND ($4::timestamptz IS NULL OR created_at < $4::timestamptz)
+ORDER BY id
+LIMIT $5
+`
+
+type ListUsersParams struct {
+	AfterID       pgtype.UUID        `json:"after_id"`
+	EmailPrefix   pgtype.Text        `json:"email_prefix"`
+	CreatedAfter  pgtype.Timestamptz `json:"created_after"`
+	CreatedBefore pgtype.Timestamptz `json:"created_before"`
+	RowLimit      int32              `json:"row_limit"`
+}
+
+func (q *Queries) ListUsers(ctx context.Context, arg ListUsersParams) ([]User, error) {
+	rows, err := q.db.Query(ctx, listUsers,
+		arg.AfterID,
+		arg.EmailPrefix,
+		arg.CreatedAfter,
+		arg.CreatedBefore,
+		arg.RowLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []User{}
+	for rows.Next() {
+		var i User
+		if err := rows.Scan(
+			&i.ID,
+			&i.Email,
+			&i.CreatedAt,
+			&i.DeletedAt,
+			&i.Version,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const lockUserImportState = `-- name: LockUserImportState :one
+SELECT state
+FROM user_imports
+WHERE id = $1
+FOR UPDATE
+`
+
+func (q *Queries) LockUserImportState(ctx context.Context, id uuid.UUID) (UserImportState, error) {
+	row := q.db.QueryRow(ctx, lockUserImportState, id)
+	var state UserImportState
+	err := row.Scan(&state)
+	return state, err
+}
+
 const recordProcessedEvent = `-- name: RecordProcessedEvent :one
 INSERT INTO processed_events (event_id, event_type)
 VALUES ($1, $2)
@@ -328,6 +996,147 @@ func (q *Queries) RecordProcessedEvent(ctx context.Context, arg RecordProcessedE
 	return event_id, err
 }
 
+const recordUserImportCallbackAttempt = `-- name: RecordUserImportCallbackAttempt :exec
+INSERT INTO user_import_callback_attempts (import_id, attempt, status_code, error)
+SELECT $1, COALESCE(max(attempt), 0) + 1, $2, $3
+FROM user_import_callback_attempts
+WHERE import_id = $1
+`
+
+type RecordUserImportCallbackAttemptParams struct {
+	ImportID   uuid.UUID   `json:"import_id"`
+	StatusCode pgtype.Int4 `json:"status_code"`
+	Error      pgtype.Text `json:"error"`
+}
+
+func (q *Queries) RecordUserImportCallbackAttempt(ctx context.Context, arg RecordUserImportCallbackAttemptParams) error {
+	_, err := q.db.Exec(ctx, recordUserImportCallbackAttempt, arg.ImportID, arg.StatusCode, arg.Error)
+	return err
+}
+
+const recordUserImportProgress = `-- name: RecordUserImportProgress :exec
+UPDATE user_imports
+SET completed_count = completed_count + $2, failed_count = failed_count + $3
+WHERE id = $1
+`
+
+type RecordUserImportProgressParams struct {
+	ID             uuid.UUID `json:"id"`
+	CompletedCount int32     `json:"completed_count"`
+	FailedCount    int32     `json:"failed_count"`
+}
+
+func (q *Queries) RecordUserImportProgress(ctx context.Context, arg RecordUserImportProgressParams) error {
+	_, err := q.db.Exec(ctx, recordUserImportProgress, arg.ID, arg.CompletedCount, arg.FailedCount)
+	return err
+}
+
+const releaseIdempotencyKey = `-- name: ReleaseIdempotencyKey :exec
+DELETE FROM idempotency_keys
+WHERE subject = $1 AND key = $2 AND status_code IS NULL
+`
+
+type ReleaseIdempotencyKeyParams struct {
+	Subject string `json:"subject"`
+	Key     string `json:"key"`
+}
+
+func (q *Queries) ReleaseIdempotencyKey(ctx context.Context, arg ReleaseIdempotencyKeyParams) error {
+	_, err := q.db.Exec(ctx, releaseIdempotencyKey, arg.Subject, arg.Key)
+	return err
+}
+
+const retryOutboxMessage = `-- name: RetryOutboxMessage :exec
+UPDATE outbox_messages
+SET
+    attempts = attempts + 1,
+    last_error = $1,
+    available_at = now() + LEAST(interval '5 minutes', interval '1 second' * power(2, LEAST(attempts, 10))),
+    parked_at = CASE WHEN attempts + 1 >= $2::integer THEN now() END
+WHERE sequence = $3
+`
+
+type RetryOutboxMessageParams struct {
+	LastError   pgtype.Text `json:"last_error"`
+	MaxAttempts int32       `json:"max_attempts"`
+	Sequence    int64       `json:"sequence"`
+}
+
+func (q *Queries) RetryOutboxMessage(ctx context.Context, arg RetryOutboxMessageParams) error {
+	_, err := q.db.Exec(ctx, retryOutboxMessage, arg.LastError, arg.MaxAttempts, arg.Sequence)
+	return err
+}
+
+const revokeAPIKey = `-- name: RevokeAPIKey :one
+UPDATE api_keys
+SET revoked_at = now()
+WHERE id = $1 AND revoked_at IS NULL
+RETURNING id, name, key_hash, subject, scopes, created_at, expires_at, revoked_at
+`
+
+func (q *Queries) RevokeAPIKey(ctx context.Context, id uuid.UUID) (ApiKey, error) {
+	row := q.db.QueryRow(ctx, revokeAPIKey, id)
+	var i ApiKey
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.KeyHash,
+		&i.Subject,
+		&i.Scopes,
+		&i.CreatedAt,
+		&i.ExpiresAt,
+		&i.RevokedAt,
+	)
+	return i, err
+}
+
+const scheduleUserImport = `-- name: ScheduleUserImport :one
+UPDATE user_imports
+SET total_count = $2, job_id = $3
+WHERE id = $1
+RETURNING id, state, total_count, completed_count, failed_count, created_at, started_at, finished_at, correlation_id, job_id, retry_of, callback_url
+`
+
+type ScheduleUserImportParams struct {
+	ID         uuid.UUID   `json:"id"`
+	TotalCount int32       `json:"total_count"`
+	JobID      pgtype.Int8 `json:"job_id"`
+}
+
+func (q *Queries) ScheduleUserImport(ctx context.Context, arg ScheduleUserImportParams) (UserImport, error) {
+	row := q.db.QueryRow(ctx, scheduleUserImport, arg.ID, arg.TotalCount, arg.JobID)
+	var i UserImport
+	err := row.Scan(
+		&i.ID,
+		&i.State,
+		&i.TotalCount,
+		&i.CompletedCount,
+		&i.FailedCount,
+		&i.CreatedAt,
+		&i.StartedAt,
+		&i.FinishedAt,
+		&i.CorrelationID,
+		&i.JobID,
+		&i.RetryOf,
+		&i.CallbackUrl,
+	)
+	return i, err
+}
+
+const softDeleteUser = `-- name: SoftDeleteUser :one
+UPDATE users
+SET deleted_at = now(), version = version + 1
+WHERE id = $1 AND deleted_at IS NULL
+RETURNING id
+`
+
+func (q *Queries) SoftDeleteUser(ctx context.Context, id uuid.UUID) (uuid.UUID, error) {
+	row := q.db.QueryRow(ctx, softDeleteUser, id)
+	var id_2 uuid.UUID
+	err := row.Scan(&id_2)
+	return id_2, err
+}
+
 const startUserImport = `-- name: StartUserImport :one
 UPDATE user_imports
 SET state = 'running', started_at = COALESCE(started_at, now())
@@ -341,3 +1150,75 @@ func (q *Queries) StartUserImport(ctx context.Context, id uuid.UUID) (uuid.UUID,
 	err := row.Scan(&id_2)
 	return id_2, err
 }
+
+const takeRateLimitToken = `-- name: TakeRateLimitToken :one
+INSERT INTO rate_limit_buckets (key, tokens, last_allowed, updated_at)
+VALUES ($1, $2::double precision - 1, true, now())
+ON CONFLICT (key) DO UPDATE
+SET
+    tokens = CASE
+        WHEN LEAST($2::double precision, rate_limit_buckets.tokens + EXTRACT(EPOCH FROM now() - rate_limit_buckets.updated_at)::double precision * $3::double precision) >= 1
+        THEN LEAST($2::double precision, rate_limit_buckets.tokens + EXTRACT(EPOCH FROM now() - rate_limit_buckets.updated_at)::double precision * $3::double precision) - 1
+        ELSE LEAST($2::double precision, rate_limit_buckets.tokens + EXTRACT(EPOCH FROM now() - rate_limit_buckets.updated_at)::double precision * $3::double precision)
+    END,
+    last_allowed = LEAST($2::double precision, rate_limit_buckets.tokens + EXTRACT(EPOCH FROM now() - rate_limit_buckets.updated_at)::double precision * $3::double precision) >= 1,
+    updated_at = now()
+RETURNING tokens, last_allowed
+`
+
+type TakeRateLimitTokenParams struct {
+	Key             string  `json:"key"`
+	Burst           float64 `json:"burst"`
+	RefillPerSecond float64 `json:"refill_per_second"`
+}
+
+type TakeRateLimitTokenRow struct {
+	Tokens      float64 `json:"tokens"`
+	LastAllowed bool    `json:"last_allowed"`
+}
+
+// The SET expressions all see the bucket before the update, so the refilled
+// token count is repeated in each of them.
+func (q *Queries) TakeRateLimitToken(ctx context.Context, arg TakeRateLimitTokenParams) (TakeRateLimitTokenRow, error) {
+	row := q.db.QueryRow(ctx, takeRateLimitToken, arg.Key, arg.Burst, arg.RefillPerSecond)
+	var i TakeRateLimitTokenRow
+	err := row.Scan(&i.Tokens, &i.LastAllowed)
+	return i, err
+}
+
+const tryLockOutboxRelay = `-- name: TryLockOutboxRelay :one
+SELECT pg_try_advisory_xact_lock(hashtext('outbox_messages'))
+`
+
+// Only one relay claims messages at a time, so no message is claimed twice.
+func (q *Queries) TryLockOutboxRelay(ctx context.Context) (bool, error) {
+	row := q.db.QueryRow(ctx, tryLockOutboxRelay)
+	var pg_try_advisory_xact_lock bool
+	err := row.Scan(&pg_try_advisory_xact_lock)
+	return pg_try_advisory_xact_lock, err
+}
+
+const updateUserEmail = `-- name: UpdateUserEmail :one
+UPDATE users
+SET email = $2, version = version + 1
+WHERE id = $1 AND deleted_at IS NULL
+RETURNING id, email, created_at, deleted_at, version
+`
+
+type UpdateUserEmailParams struct {
+	ID    uuid.UUID `json:"id"`
+	Email string    `json:"email"`
+}
+
+func (q *Queries) UpdateUserEmail(ctx context.Context, arg UpdateUserEmailParams) (User, error) {
+	row := q.db.QueryRow(ctx, updateUserEmail, arg.ID, arg.Email)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.CreatedAt,
+		&i.DeletedAt,
+		&i.Version,
+	)
+	return i, err
+}
@@ -0,0 +1,43 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/your-org/go-service-template/internal/platform/httpserver"
+)
+
+// RateLimitRepository shares token buckets between API replicas. The
+// database clock refills them, so replica clock skew does not matter.
+type RateLimitRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewRateLimitRepository(pool *pgxpool.Pool) *RateLimitRepository {
+	return &RateLimitRepository{pool: pool}
+}
+
+func (r *RateLimitRepository) Take(ctx context.Context, key string, limit httpserver.RateLimit) (httpserver.RateLimitDecision, error) {
+	bucket, err := New(r.pool).TakeRateLimitToken(ctx, TakeRateLimitTokenParams{
+		Key:             key,
+		Burst:           float64(limit.Burst),
+		RefillPerSecond: float64(limit.PerMinute) / 60,
+	})
+	if err != nil {
+		return httpserver.RateLimitDecision{}, fmt.Errorf("take rate limit token: %w", err)
+	}
+	return httpserver.RateLimitDecision{Allowed: bucket.LastAllowed, Tokens: bucket.Tokens}, nil
+}
+
+// Cleanup deletes buckets untouched since cutoff, which have refilled when
+// cutoff is at least httpserver.MaxRateLimitRefill ago.
+func (r *RateLimitRepository) Cleanup(ctx context.Context, cutoff time.Time) (int64, error) {
+	deleted, err := New(r.pool).DeleteRateLimitBucketsBefore(ctx, cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("delete idle rate limit buckets: %w", err)
+	}
+	return deleted, nil
+}
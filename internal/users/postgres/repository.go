@@ -4,10 +4,12 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jackc/pgx/v5/pgxpool"
 
 	"github.com/your-org/go-service-template/internal/users"
@@ -15,31 +17,41 @@ import (
 
 type UserRepository struct {
 	pool     *pgxpool.Pool
-	enqueuer UserCreatedEnqueuer
+	enqueuer UserEventEnqueuer
 }
 
-type UserCreatedEnqueuer interface {
+type UserEventEnqueuer interface {
 	EnqueueUserCreated(context.Context, pgx.Tx, uuid.UUID, string) error
+	EnqueueUserUpdated(context.Context, pgx.Tx, uuid.UUID) error
+	EnqueueUserDeleted(context.Context, pgx.Tx, uuid.UUID) error
 }
 
-func NewUserRepository(pool *pgxpool.Pool, enqueuer UserCreatedEnqueuer) *UserRepository {
+func NewUserRepository(pool *pgxpool.Pool, enqueuer UserEventEnqueuer) *UserRepository {
 	return &UserRepository{pool: pool, enqueuer: enqueuer}
 }
 
 func (r *UserRepository) Create(ctx context.Context, user users.User) (users.User, error) {
+	created, err := r.create(ctx, user)
+	if err != nil {
+		return users.User{}, auditRejection(ctx, r.pool, users.AuditActionCreateUser, user.ID, err)
+	}
+	return created, nil
+}
+
+func (r *UserRepository) create(ctx context.Context, user users.User) (users.User, error) {
 	tx, err := r.pool.Begin(ctx)
 	if err != nil {
 		return users.User{}, fmt.Errorf("begin user transaction: %w", err)
 	}
 	defer func() { _ = tx.Rollback(ctx) }()
 
-	created, err := New(tx).CreateUser(ctx, CreateUserParams{
+	queries := New(tx)
+	created, err := queries.CreateUser(ctx, CreateUserParams{
 		ID:    user.ID,
 		Email: user.Email,
 	})
 	if err != nil {
-		var postgresError *pgconn.PgError
-		if errors.As(err, &postgresError) && postgresError.Code == "23505" {
+		if uniqueViolation(err) {
 			return users.User{}, users.ErrConflict
 		}
 		return users.User{}, fmt.Errorf("insert user: %w", err)
@@ -47,6 +59,9 @@ func (r *UserRepository) Create(ctx context.Context, user users.User) (users.Use
 	if err := r.enqueuer.EnqueueUserCreated(ctx, tx, created.ID, ""); err != nil {
 		return users.User{}, fmt.Errorf("enqueue user.created: %w", err)
 	}
+	if err := recordAuditEvent(ctx, queries, users.AuditActionCreateUser, created.ID, users.AuditOutcomeSucceeded); err != nil {
+		return users.User{}, err
+	}
 	if err := tx.Commit(ctx); err != nil {
 		return users.User{}, fmt.Errorf("commit user: %w", err)
 	}
@@ -66,10 +81,137 @@ func (r *UserRepository) Get(ctx context.Context, id uuid.UUID) (users.User, err
 	return toDomainUser(user), nil
 }
 
+func (r *UserRepository) Update(ctx context.Context, user users.User, precondition users.Precondition) (users.User, error) {
+	updated, err := r.update(ctx, user, precondition)
+	if err != nil {
+		return users.User{}, auditRejection(ctx, r.pool, users.AuditActionUpdateUser, user.ID, err)
+	}
+	return updated, nil
+}
+
+// update leaves an unchanged email alone without an event or audit record.
+func (r *UserRepository) update(ctx context.Context, user users.User, precondition users.Precondition) (users.User, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return users.User{}, fmt.Errorf("begin user update transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	queries := New(tx)
+	current, err := queries.GetUserForUpdate(ctx, user.ID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return users.User{}, users.ErrNotFound
+		}
+		return users.User{}, fmt.Errorf("lock user: %w", err)
+	}
+	if precondition != nil && !precondition(toDomainUser(current)) {
+		return users.User{}, users.ErrPreconditionFailed
+	}
+	if current.Email == user.Email {
+		return toDomainUser(current), nil
+	}
+
+	updated, err := queries.UpdateUserEmail(ctx, UpdateUserEmailParams{ID: user.ID, Email: user.Email})
+	if err != nil {
+		if uniqueViolation(err) {
+			return users.User{}, users.ErrConflict
+		}
+		return users.User{}, fmt.Errorf("update user email: %w", err)
+	}
+	if err := r.enqueuer.EnqueueUserUpdated(ctx, tx, updated.ID); err != nil {
+		return users.User{}, fmt.Errorf("enqueue user.updated: %w", err)
+	}
+	if err := recordAuditEvent(ctx, queries, users.AuditActionUpdateUser, updated.ID, users.AuditOutcomeSucceeded); err != nil {
+		return users.User{}, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return users.User{}, fmt.Errorf("commit user update: %w", err)
+	}
+
+	return toDomainUser(updated), nil
+}
+
+func (r *UserRepository) Delete(ctx context.Context, id uuid.UUID, precondition users.Precondition) error {
+	if err := r.softDelete(ctx, id, precondition); err != nil {
+		return auditRejection(ctx, r.pool, users.AuditActionDeleteUser, id, err)
+	}
+	return nil
+}
+
+func (r *UserRepository) softDelete(ctx context.Context, id uuid.UUID, precondition users.Precondition) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin user deletion transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	queries := New(tx)
+	current, err := queries.GetUserForUpdate(ctx, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return users.ErrNotFound
+		}
+		return fmt.Errorf("lock user: %w", err)
+	}
+	if precondition != nil && !precondition(toDomainUser(current)) {
+		return users.ErrPreconditionFailed
+	}
+	if _, err := queries.SoftDeleteUser(ctx, id); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return users.ErrNotFound
+		}
+		return fmt.Errorf("soft-delete user: %w", err)
+	}
+	if err := r.enqueuer.EnqueueUserDeleted(ctx, tx, id); err != nil {
+		return fmt.Errorf("enqueue user.deleted: %w", err)
+	}
+	if err := recordAuditEvent(ctx, queries, users.AuditActionDeleteUser, id, users.AuditOutcomeSucceeded); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit user deletion: %w", err)
+	}
+	return nil
+}
+
+func (r *UserRepository) List(ctx context.Context, query users.ListQuery) ([]users.User, error) {
+	params := ListUsersParams{
+		AfterID:       pgtype.UUID{Bytes: query.AfterID, Valid: query.AfterID != uuid.Nil},
+		EmailPrefix:   pgtype.Text{String: query.EmailPrefix, Valid: query.EmailPrefix != ""},
+		CreatedAfter:  optionalTimestamptz(query.CreatedAfter),
+		CreatedBefore: optionalTimestamptz(query.CreatedBefore),
+		RowLimit:      int32(query.Limit),
+	}
+	listed, err := New(r.pool).ListUsers(ctx, params)
+	if err != nil {
+		return nil, fmt.Errorf("select users: %w", err)
+	}
+
+	result := make([]users.User, 0, len(listed))
+	for _, user := range listed {
+		result = append(result, toDomainUser(user))
+	}
+	return result, nil
+}
+
+func optionalTimestamptz(value *time.Time) pgtype.Timestamptz {
+	if value == nil {
+		return pgtype.Timestamptz{}
+	}
+	return pgtype.Timestamptz{Time: *value, Valid: true}
+}
+
+func uniqueViolation(err error) bool {
+	var postgresError *pgconn.PgError
+	return errors.As(err, &postgresError) && postgresError.Code == "23505"
+}
+
 func toDomainUser(user User) users.User {
 	return users.User{
 		ID:        user.ID,
 		Email:     user.Email,
 		CreatedAt: user.CreatedAt,
+		Version:   user.Version,
 	}
 }
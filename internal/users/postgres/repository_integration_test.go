@@ -5,6 +5,11 @@ package postgres
 import (
 	"context"
 	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"net/http"
 	"testing"
 	"time"
 
@@ -19,7 +24,9 @@ import (
 	"github.com/testcontainers/testcontainers-go"
 	tcpostgres "github.com/testcontainers/testcontainers-go/modules/postgres"
 
+	"github.com/your-org/go-service-template/internal/platform/auth"
 	"github.com/your-org/go-service-template/internal/platform/database"
+	"github.com/your-org/go-service-template/internal/platform/httpserver"
 	"github.com/your-org/go-service-template/internal/platform/messaging"
 	"github.com/your-org/go-service-template/internal/users"
 	usersjobs "github.com/your-org/go-service-template/internal/users/jobs"
@@ -29,7 +36,7 @@ func TestUserRepositoryIntegration(t *testing.T) {
 	pool := newTestPool(t)
 	jobClient, err := river.NewClient(riverpgxv5.New(pool), &river.Config{})
 	require.NoError(t, err)
-	repository := NewUserRepository(pool, usersjobs.NewEnqueuer(jobClient))
+	repository := NewUserRepository(pool, newTestEnqueuer(pool, jobClient))
 	want := users.User{
 		ID:    uuid.MustParse("8d37b313-f867-47bc-8e3d-0953db9c05c8"),
 		Email: "person@example.com",
@@ -50,11 +57,79 @@ func TestUserRepositoryIntegration(t *testing.T) {
 	_, err = repository.Get(t.Context(), uuid.New())
 	require.ErrorIs(t, err, users.ErrNotFound)
 
-	jobs, err := jobClient.JobList(t.Context(), river.NewJobListParams().Kinds("users.publish-created"))
+	messages := outboxMessages(t, pool, "user.created")
+	require.Len(t, messages, 1)
+	assert.NotContains(t, messages[0].Body, want.Email)
+	assert.Contains(t, messages[0].Body, "request-123")
+}
+
+func TestUserRepositoryUpdatesAndSoftDeletes(t *testing.T) {
+	pool := newTestPool(t)
+	jobClient, err := river.NewClient(riverpgxv5.New(pool), &river.Config{})
 	require.NoError(t, err)
-	require.Len(t, jobs.Jobs, 1)
-	assert.NotContains(t, string(jobs.Jobs[0].EncodedArgs), want.Email)
-	assert.Contains(t, string(jobs.Jobs[0].EncodedArgs), "request-123")
+	repository := NewUserRepository(pool, newTestEnqueuer(pool, jobClient))
+	userID := uuid.MustParse("0198a1f7-30b7-7df8-8491-c47f6033525b")
+	_, err = repository.Create(t.Context(), users.User{ID: userID, Email: "before@example.com"})
+	require.NoError(t, err)
+	_, err = repository.Create(t.Context(), users.User{ID: uuid.New(), Email: "taken@example.com"})
+	require.NoError(t, err)
+
+	atVersion := func(version int64) users.Precondition {
+		return func(user users.User) bool { return user.Version == version }
+	}
+	updated, err := repository.Update(t.Context(), users.User{ID: userID, Email: "after@example.com"}, atVersion(1))
+	require.NoError(t, err)
+	assert.Equal(t, "after@example.com", updated.Email)
+	assert.Equal(t, int64(2), updated.Version)
+	_, err = repository.Update(t.Context(), users.User{ID: userID, Email: "stale@example.com"}, atVersion(1))
+	require.ErrorIs(t, err, users.ErrPreconditionFailed)
+	_, err = repository.Update(t.Context(), users.User{ID: userID, Email: "after@example.com"}, nil)
+	require.NoError(t, err)
+	_, err = repository.Update(t.Context(), users.User{ID: userID, Email: "taken@example.com"}, nil)
+	require.ErrorIs(t, err, users.ErrConflict)
+
+	require.ErrorIs(t, repository.Delete(t.Context(), userID, atVersion(1)), users.ErrPreconditionFailed)
+	require.NoError(t, repository.Delete(t.Context(), userID, atVersion(2)))
+	require.ErrorIs(t, repository.Delete(t.Context(), userID, nil), users.ErrNotFound)
+	_, err = repository.Get(t.Context(), userID)
+	require.ErrorIs(t, err, users.ErrNotFound)
+	_, err = repository.Create(t.Context(), users.User{ID: uuid.New(), Email: "after@example.com"})
+	require.NoError(t, err)
+
+	for eventType, want := range map[string]int{"user.updated": 1, "user.deleted": 1} {
+		assert.Len(t, outboxMessages(t, pool, eventType), want, eventType)
+	}
+}
+
+func TestUserRepositoryListsByKeyset(t *testing.T) {
+	pool := newTestPool(t)
+	jobClient, err := river.NewClient(riverpgxv5.New(pool), &river.Config{})
+	require.NoError(t, err)
+	repository := NewUserRepository(pool, newTestEnqueuer(pool, jobClient))
+	for _, user := range []users.User{
+		{ID: uuid.MustParse("0198a1f7-30b7-7df1-8491-c47f6033525b"), Email: "alpha@example.com"},
+		{ID: uuid.MustParse("0198a1f7-30b7-7df2-8491-c47f6033525b"), Email: "beta@example.com"},
+		{ID: uuid.MustParse("0198a1f7-30b7-7df3-8491-c47f6033525b"), Email: "alpine@example.com"},
+	} {
+		_, err := repository.Create(t.Context(), user)
+		require.NoError(t, err)
+	}
+
+	listed, err := repository.List(t.Context(), users.ListQuery{Limit: 10, EmailPrefix: "al"})
+	require.NoError(t, err)
+	require.Len(t, listed, 2)
+	assert.Equal(t, "alpha@example.com", listed[0].Email)
+	assert.Equal(t, "alpine@example.com", listed[1].Email)
+
+	listed, err = repository.List(t.Context(), users.ListQuery{AfterID: listed[0].ID, Limit: 1})
+	require.NoError(t, err)
+	require.Len(t, listed, 1)
+	assert.Equal(t, "beta@example.com", listed[0].Email)
+
+	future := time.Now().Add(time.Hour)
+	listed, err = repository.List(t.Context(), users.ListQuery{Limit: 10, CreatedAfter: &future})
+	require.NoError(t, err)
+	assert.Empty(t, listed)
 }
 
 func TestPermissionRepositoryAppliesOnlyNewEventsAndRevisions(t *testing.T) {
@@ -86,17 +161,157 @@ func TestPermissionRepositoryAppliesOnlyNewEventsAndRevisions(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, users.PermissionChangeApplied, result)
 
-	permissions, err := New(pool).GetUserPermissions(t.Context(), userID)
+	permissions, err := repository.GetPermissions(t.Context(), userID)
 	require.NoError(t, err)
 	assert.Equal(t, int64(3), permissions.Revision)
 	assert.Equal(t, []string{"admin"}, permissions.Permissions)
+	assert.False(t, permissions.UpdatedAt.IsZero())
+
+	_, err = repository.GetPermissions(t.Context(), uuid.New())
+	require.ErrorIs(t, err, users.ErrNotFound)
+}
+
+func TestPermissionRepositoryLoadsSubjectPermissionsAndNotifiesChanges(t *testing.T) {
+	pool := newTestPool(t)
+	jobClient, err := river.NewClient(riverpgxv5.New(pool), &river.Config{})
+	require.NoError(t, err)
+	userID := uuid.MustParse("0198a1f7-30b7-7dfc-8491-c47f6033525b")
+	_, err = NewUserRepository(pool, newTestEnqueuer(pool, jobClient)).Create(t.Context(), users.User{ID: userID, Email: "subject@example.com"})
+	require.NoError(t, err)
+	repository := NewPermissionRepository(pool)
+
+	set, err := repository.LoadPermissions(t.Context(), userID.String())
+	require.NoError(t, err)
+	assert.Equal(t, httpserver.PermissionSet{UserID: userID.String()}, set)
+	set, err = repository.LoadPermissions(t.Context(), "not-a-user")
+	require.NoError(t, err)
+	assert.Empty(t, set.Permissions)
+
+	invalidations := make(chan string, 1)
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+	cleared := make(chan struct{}, 1)
+	go NewPermissionNotifier(pool, slog.New(slog.DiscardHandler), permissionInvalidatorFunc{
+		invalidate: func(userID string, revision int64) { invalidations <- fmt.Sprintf("%s:%d", userID, revision) },
+		clear: func() {
+			select {
+			case cleared <- struct{}{}:
+			default:
+			}
+		},
+	}).Run(ctx)
+	select {
+	case <-cleared:
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for permission notifier")
+	}
+
+	_, err = repository.ApplyPermissionChange(t.Context(), users.PermissionChange{
+		EventID: "event-1", UserID: userID, Revision: 4, Permissions: []string{"users.read"},
+	})
+	require.NoError(t, err)
+	select {
+	case got := <-invalidations:
+		assert.Equal(t, userID.String()+":4", got)
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for permission notification")
+	}
+
+	set, err = repository.LoadPermissions(t.Context(), userID.String())
+	require.NoError(t, err)
+	assert.Equal(t, httpserver.PermissionSet{UserID: userID.String(), Revision: 4, Permissions: []string{"users.read"}}, set)
+
+	require.NoError(t, NewUserRepository(pool, newTestEnqueuer(pool, jobClient)).Delete(t.Context(), userID, nil))
+	select {
+	case got := <-invalidations:
+		assert.Equal(t, fmt.Sprintf("%s:%d", userID, int64(math.MaxInt64)), got)
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for user deletion notification")
+	}
+	set, err = repository.LoadPermissions(t.Context(), userID.String())
+	require.NoError(t, err)
+	assert.Equal(t, httpserver.PermissionSet{UserID: userID.String()}, set)
+}
+
+func TestAPIKeyRepositoryFindsListsAndRevokesKeys(t *testing.T) {
+	pool := newTestPool(t)
+	jobClient, err := river.NewClient(riverpgxv5.New(pool), &river.Config{})
+	require.NoError(t, err)
+	userID := uuid.MustParse("0198a1f7-30b7-7e00-8491-c47f6033525b")
+	_, err = NewUserRepository(pool, newTestEnqueuer(pool, jobClient)).Create(t.Context(), users.User{ID: userID, Email: "sync@example.com"})
+	require.NoError(t, err)
+	repository := NewAPIKeyRepository(pool)
+	rawKey, hash, err := auth.NewAPIKey()
+	require.NoError(t, err)
+	expiresAt := time.Now().Add(time.Hour).UTC().Truncate(time.Microsecond)
+	key := auth.APIKey{
+		ID: uuid.MustParse("0198a1f7-30b7-7dff-8491-c47f6033525b"), Name: "nightly-sync",
+		Subject: userID.String(), Scopes: []string{"users:read"}, ExpiresAt: &expiresAt,
+	}
+	created, err := repository.Create(t.Context(), key, hash)
+	require.NoError(t, err)
+	assert.False(t, created.CreatedAt.IsZero())
+
+	for _, subject := range []string{"nightly-sync-service", uuid.NewString()} {
+		_, err = repository.Create(t.Context(), auth.APIKey{ID: uuid.New(), Name: "other", Subject: subject, Scopes: key.Scopes}, auth.HashAPIKey("sk_other"))
+		require.ErrorIs(t, err, auth.ErrAPIKeySubjectNotUser, subject)
+	}
+
+	_, err = repository.Create(t.Context(), auth.APIKey{ID: uuid.New(), Name: key.Name, Subject: key.Subject, Scopes: key.Scopes}, auth.HashAPIKey("sk_other"))
+	require.ErrorIs(t, err, auth.ErrAPIKeyNameTaken)
+
+	claims, err := auth.NewAPIKeyVerifier(repository).Verify(t.Context(), rawKey)
+	require.NoError(t, err)
+	assert.Equal(t, key.Subject, claims.Subject)
+	assert.Equal(t, key.Scopes, claims.Scopes)
+
+	listed, err := repository.List(t.Context())
+	require.NoError(t, err)
+	require.Len(t, listed, 1)
+	assert.True(t, expiresAt.Equal(*listed[0].ExpiresAt))
+
+	revoked, err := repository.Revoke(t.Context(), key.ID)
+	require.NoError(t, err)
+	require.NotNil(t, revoked.RevokedAt)
+	_, err = repository.Revoke(t.Context(), key.ID)
+	require.ErrorIs(t, err, auth.ErrAPIKeyNotFound)
+	_, err = auth.NewAPIKeyVerifier(repository).Verify(t.Context(), rawKey)
+	require.Error(t, err)
+
+	// A revoked key's name can be reused.
+	_, err = repository.Create(t.Context(), auth.APIKey{ID: uuid.New(), Name: key.Name, Subject: key.Subject, Scopes: key.Scopes}, auth.HashAPIKey("sk_other"))
+	require.NoError(t, err)
+}
+
+func TestRateLimitRepositorySharesBuckets(t *testing.T) {
+	pool := newTestPool(t)
+	repository := NewRateLimitRepository(pool)
+	limit := httpserver.RateLimit{Burst: 2, PerMinute: 1}
+
+	for _, wantAllowed := range []bool{true, true, false} {
+		decision, err := repository.Take(t.Context(), "getUser:subject:user-123", limit)
+		require.NoError(t, err)
+		assert.Equal(t, wantAllowed, decision.Allowed)
+	}
+	// Another replica sees the same bucket.
+	decision, err := NewRateLimitRepository(pool).Take(t.Context(), "getUser:subject:user-123", limit)
+	require.NoError(t, err)
+	assert.False(t, decision.Allowed)
+	assert.Less(t, decision.Tokens, 1.0)
+
+	deleted, err := repository.Cleanup(t.Context(), time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+	decision, err = repository.Take(t.Context(), "getUser:subject:user-123", limit)
+	require.NoError(t, err)
+	assert.True(t, decision.Allowed)
 }
 
 func TestImportRepositoryIntegration(t *testing.T) {
 	pool := newTestPool(t)
 	jobClient, err := river.NewClient(riverpgxv5.New(pool), &river.Config{})
 	require.NoError(t, err)
-	repository := NewImportRepository(pool, usersjobs.NewEnqueuer(jobClient))
+	repository := NewImportRepository(pool, newTestEnqueuer(pool, jobClient))
 
 	importID := uuid.MustParse("0198a1f7-30b7-7df1-8491-c47f6033525b")
 	entries := []users.ImportEntry{
@@ -128,9 +343,216 @@ func TestImportRepositoryIntegration(t *testing.T) {
 		require.NoError(t, err)
 		assert.Equal(t, entry.Email, user.Email)
 	}
-	publicationJobs, err := jobClient.JobList(t.Context(), river.NewJobListParams().Kinds("users.publish-created"))
+	assert.Len(t, outboxMessages(t, pool, "user.created"), 2)
+}
+
+func TestImportRepositoryProcessesStreamedImportInBatches(t *testing.T) {
+	pool := newTestPool(t)
+	jobClient, err := river.NewClient(riverpgxv5.New(pool), &river.Config{})
+	require.NoError(t, err)
+	repository := NewImportRepository(pool, newTestEnqueuer(pool, jobClient))
+
+	const total = 2*importBatchSize + 1
+	chunks := make([][]users.ImportEntry, 0, 3)
+	for index := range total {
+		if index%importBatchSize == 0 {
+			chunks = append(chunks, nil)
+		}
+		chunks[len(chunks)-1] = append(chunks[len(chunks)-1], users.ImportEntry{
+			UserID: uuid.New(), Email: fmt.Sprintf("streamed-%d@example.com", index),
+		})
+	}
+	importID := uuid.MustParse("0198a1f7-30b7-7df7-8491-c47f6033525b")
+	created, err := repository.CreateStreamedImport(t.Context(), users.Import{ID: importID}, func() ([]users.ImportEntry, error) {
+		if len(chunks) == 0 {
+			return nil, io.EOF
+		}
+		chunk := chunks[0]
+		chunks = chunks[1:]
+		return chunk, nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, total, created.TotalCount)
+
+	processed, err := repository.processImportBatch(t.Context(), importID)
+	require.NoError(t, err)
+	assert.Equal(t, importBatchSize, processed)
+	progress, err := repository.GetImport(t.Context(), importID)
+	require.NoError(t, err)
+	assert.Equal(t, importBatchSize, progress.CompletedCount)
+
+	require.NoError(t, repository.ProcessImport(t.Context(), importID))
+	completed, err := repository.GetImport(t.Context(), importID)
+	require.NoError(t, err)
+	assert.Equal(t, users.ImportStateCompleted, completed.State)
+	assert.Equal(t, total, completed.CompletedCount)
+	assert.Zero(t, completed.FailedCount)
+}
+
+func TestImportRepositoryRollsBackStreamedImportOnChunkError(t *testing.T) {
+	pool := newTestPool(t)
+	jobClient, err := river.NewClient(riverpgxv5.New(pool), &river.Config{})
 	require.NoError(t, err)
-	assert.Len(t, publicationJobs.Jobs, 2)
+	repository := NewImportRepository(pool, newTestEnqueuer(pool, jobClient))
+	importID := uuid.MustParse("0198a1f7-30b7-7df8-8491-c47f6033525b")
+
+	calls := 0
+	_, err = repository.CreateStreamedImport(t.Context(), users.Import{ID: importID}, func() ([]users.ImportEntry, error) {
+		calls++
+		if calls == 1 {
+			return []users.ImportEntry{{UserID: uuid.New(), Email: "partial@example.com"}}, nil
+		}
+		return nil, &users.ImportLineError{Line: 3, Reason: "invalid email address"}
+	})
+	require.ErrorIs(t, err, users.ErrInvalidImport)
+	_, err = repository.GetImport(t.Context(), importID)
+	require.ErrorIs(t, err, users.ErrNotFound)
+}
+
+func TestImportRepositoryCancelsImportAndJob(t *testing.T) {
+	pool := newTestPool(t)
+	jobClient, err := river.NewClient(riverpgxv5.New(pool), &river.Config{})
+	require.NoError(t, err)
+	repository := NewImportRepository(pool, newTestEnqueuer(pool, jobClient))
+	importID := uuid.MustParse("0198a1f7-30b7-7df9-8491-c47f6033525b")
+	_, err = repository.CreateImport(t.Context(), users.Import{
+		ID: importID, Entries: []users.ImportEntry{{UserID: uuid.New(), Email: "cancelled@example.com"}},
+	})
+	require.NoError(t, err)
+
+	cancelled, err := repository.CancelImport(t.Context(), importID)
+	require.NoError(t, err)
+	assert.Equal(t, users.ImportStateCancelled, cancelled.State)
+	assert.NotNil(t, cancelled.FinishedAt)
+	jobs, err := jobClient.JobList(t.Context(), river.NewJobListParams().Kinds("users.import"))
+	require.NoError(t, err)
+	require.Len(t, jobs.Jobs, 1)
+	assert.Equal(t, rivertype.JobStateCancelled, jobs.Jobs[0].State)
+
+	require.NoError(t, repository.ProcessImport(t.Context(), importID))
+	stored, err := repository.GetImport(t.Context(), importID)
+	require.NoError(t, err)
+	assert.Equal(t, users.ImportStateCancelled, stored.State)
+	assert.Zero(t, stored.CompletedCount)
+
+	_, err = repository.CancelImport(t.Context(), importID)
+	require.ErrorIs(t, err, users.ErrImportFinished)
+	_, err = repository.CancelImport(t.Context(), uuid.New())
+	require.ErrorIs(t, err, users.ErrNotFound)
+}
+
+func TestImportRepositoryEnqueuesCallbackAndRecordsAttempts(t *testing.T) {
+	pool := newTestPool(t)
+	jobClient, err := river.NewClient(riverpgxv5.New(pool), &river.Config{})
+	require.NoError(t, err)
+	repository := NewImportRepository(pool, newTestEnqueuer(pool, jobClient))
+	importID := uuid.MustParse("0198a1f7-30b7-7dfa-8491-c47f6033525b")
+	_, err = repository.CreateImport(t.Context(), users.Import{
+		ID:          importID,
+		CallbackURL: "https://hooks.example.com/imports",
+		Entries:     []users.ImportEntry{{UserID: uuid.New(), Email: "callback@example.com"}},
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, repository.ProcessImport(t.Context(), importID))
+	require.NoError(t, repository.ProcessImport(t.Context(), importID))
+	jobs, err := jobClient.JobList(t.Context(), river.NewJobListParams().Kinds("users.deliver-import-callback"))
+	require.NoError(t, err)
+	require.Len(t, jobs.Jobs, 1)
+	assert.Equal(t, usersjobs.QueueCallbacks, jobs.Jobs[0].Queue)
+
+	require.NoError(t, repository.RecordCallbackAttempt(t.Context(), importID, users.CallbackAttempt{Error: "connection refused"}))
+	require.NoError(t, repository.RecordCallbackAttempt(t.Context(), importID, users.CallbackAttempt{StatusCode: 204}))
+	stored, err := repository.GetImport(t.Context(), importID)
+	require.NoError(t, err)
+	assert.Equal(t, "https://hooks.example.com/imports", stored.CallbackURL)
+	require.Len(t, stored.CallbackAttempts, 2)
+	assert.Equal(t, 1, stored.CallbackAttempts[0].Attempt)
+	assert.Equal(t, "connection refused", stored.CallbackAttempts[0].Error)
+	assert.Equal(t, 2, stored.CallbackAttempts[1].Attempt)
+	assert.Equal(t, 204, stored.CallbackAttempts[1].StatusCode)
+}
+
+func TestImportNotifierSignalsCommittedProgress(t *testing.T) {
+	pool := newTestPool(t)
+	jobClient, err := river.NewClient(riverpgxv5.New(pool), &river.Config{})
+	require.NoError(t, err)
+	repository := NewImportRepository(pool, newTestEnqueuer(pool, jobClient))
+	importID := uuid.MustParse("0198a1f7-30b7-7dfb-8491-c47f6033525b")
+	_, err = repository.CreateImport(t.Context(), users.Import{
+		ID: importID, Entries: []users.ImportEntry{{UserID: uuid.New(), Email: "progress@example.com"}},
+	})
+	require.NoError(t, err)
+
+	notifier := NewImportNotifier(pool, slog.New(slog.DiscardHandler))
+	changes, unsubscribe := notifier.SubscribeImport(importID)
+	defer unsubscribe()
+	ctx, cancel := context.WithCancel(t.Context())
+	stopped := make(chan struct{})
+	go func() {
+		notifier.Run(ctx)
+		close(stopped)
+	}()
+	waitForSignal := func() {
+		t.Helper()
+		select {
+		case _, open := <-changes:
+			require.True(t, open)
+		case <-time.After(10 * time.Second):
+			t.Fatal("timed out waiting for import notification")
+		}
+	}
+	// Subscribers are signalled once the notifier is listening.
+	waitForSignal()
+
+	var watched []users.Import
+	require.NoError(t, repository.ProcessImport(t.Context(), importID))
+	waitForSignal()
+	require.NoError(t, users.NewImportProgress(repository, notifier).Watch(t.Context(), importID, func(userImport users.Import) error {
+		watched = append(watched, userImport)
+		return nil
+	}))
+	require.Len(t, watched, 1)
+	assert.Equal(t, users.ImportStateCompleted, watched[0].State)
+
+	cancel()
+	<-stopped
+	_, open := <-changes
+	assert.False(t, open)
+}
+
+func TestImportServiceRetriesFailedEntries(t *testing.T) {
+	pool := newTestPool(t)
+	jobClient, err := river.NewClient(riverpgxv5.New(pool), &river.Config{})
+	require.NoError(t, err)
+	repository := NewImportRepository(pool, newTestEnqueuer(pool, jobClient))
+	existingID := uuid.New()
+	_, err = NewUserRepository(pool, newTestEnqueuer(pool, jobClient)).Create(t.Context(), users.User{ID: existingID, Email: "taken@example.com"})
+	require.NoError(t, err)
+
+	sourceID := uuid.MustParse("0198a1f7-30b7-7dfa-8491-c47f6033525b")
+	_, err = repository.CreateImport(t.Context(), users.Import{ID: sourceID, Entries: []users.ImportEntry{
+		{UserID: uuid.New(), Email: "taken@example.com"},
+		{UserID: uuid.New(), Email: "free@example.com"},
+	}})
+	require.NoError(t, err)
+	require.NoError(t, repository.ProcessImport(t.Context(), sourceID))
+	_, err = pool.Exec(t.Context(), "UPDATE users SET deleted_at = now() WHERE id = $1", existingID)
+	require.NoError(t, err)
+
+	service := users.NewImportService(repository)
+	retried, err := service.RetryFailed(t.Context(), sourceID)
+	require.NoError(t, err)
+	assert.Equal(t, &sourceID, retried.RetryOf)
+	assert.Equal(t, 1, retried.TotalCount)
+	require.NoError(t, repository.ProcessImport(t.Context(), retried.ID))
+	completed, err := repository.GetImport(t.Context(), retried.ID)
+	require.NoError(t, err)
+	assert.Equal(t, users.ImportStateCompleted, completed.State)
+	assert.Equal(t, &sourceID, completed.RetryOf)
+
+	_, err = service.RetryFailed(t.Context(), retried.ID)
+	require.ErrorIs(t, err, users.ErrImportNotRetryable)
 }
 
 func TestUserRepositoryRollsBackWhenPublicationEnqueueFails(t *testing.T) {
@@ -144,6 +566,86 @@ func TestUserRepositoryRollsBackWhenPublicationEnqueueFails(t *testing.T) {
 	require.ErrorIs(t, err, pgx.ErrNoRows)
 }
 
+func TestAuditRepositoryRecordsChangesAndRejections(t *testing.T) {
+	pool := newTestPool(t)
+	jobClient, err := river.NewClient(riverpgxv5.New(pool), &river.Config{})
+	require.NoError(t, err)
+	ctx := users.WithAuditActor(messaging.WithCorrelationID(t.Context(), "request-audit"), "operator-1")
+
+	userID := uuid.MustParse("0198a1f7-30b7-7df8-8491-c47f6033525b")
+	userRepository := NewUserRepository(pool, newTestEnqueuer(pool, jobClient))
+	_, err = userRepository.Create(ctx, users.User{ID: userID, Email: "audited@example.com"})
+	require.NoError(t, err)
+	_, err = NewUserRepository(pool, failingImportEnqueuer{}).Create(ctx, users.User{ID: uuid.New(), Email: "rolled-back@example.com"})
+	require.Error(t, err)
+	duplicateID := uuid.MustParse("0198a1f7-30b7-7dfa-8491-c47f6033525b")
+	_, err = userRepository.Create(ctx, users.User{ID: duplicateID, Email: "audited@example.com"})
+	require.ErrorIs(t, err, users.ErrConflict)
+	_, err = userRepository.Update(ctx, users.User{ID: userID, Email: "audited@example.com"}, nil)
+	require.NoError(t, err)
+	_, err = userRepository.Update(ctx, users.User{ID: userID, Email: "renamed@example.com"}, nil)
+	require.NoError(t, err)
+	rejectAll := func(users.User) bool { return false }
+	_, err = userRepository.Update(ctx, users.User{ID: userID, Email: "refused@example.com"}, rejectAll)
+	require.ErrorIs(t, err, users.ErrPreconditionFailed)
+	importID := uuid.MustParse("0198a1f7-30b7-7df9-8491-c47f6033525b")
+	importRepository := NewImportRepository(pool, newTestEnqueuer(pool, jobClient))
+	_, err = importRepository.CreateImport(ctx, users.Import{
+		ID: importID, State: users.ImportStatePending, TotalCount: 1,
+		Entries: []users.ImportEntry{{UserID: uuid.New(), Email: "imported@example.com"}},
+	})
+	require.NoError(t, err)
+	_, err = importRepository.CancelImport(ctx, importID)
+	require.NoError(t, err)
+	_, err = importRepository.CancelImport(ctx, importID)
+	require.ErrorIs(t, err, users.ErrImportFinished)
+	eventCtx := users.WithAuditActor(messaging.WithCorrelationID(t.Context(), "event-correlation"), "permissions")
+	permissions := NewPermissionRepository(pool)
+	_, err = permissions.ApplyPermissionChange(eventCtx, users.PermissionChange{EventID: "audit-1", UserID: userID, Revision: 2, Permissions: []string{"users.read"}})
+	require.NoError(t, err)
+	_, err = permissions.ApplyPermissionChange(eventCtx, users.PermissionChange{EventID: "audit-2", UserID: userID, Revision: 1, Permissions: []string{"users.read"}})
+	require.NoError(t, err)
+	_, err = permissions.ApplyPermissionChange(eventCtx, users.PermissionChange{EventID: "audit-2", UserID: userID, Revision: 1, Permissions: []string{"users.read"}})
+	require.NoError(t, err)
+	require.NoError(t, userRepository.Delete(ctx, userID, nil))
+
+	repository := NewAuditRepository(pool)
+	listed, err := repository.ListAuditEvents(t.Context(), users.AuditQuery{Limit: 20})
+	require.NoError(t, err)
+	require.Len(t, listed, 10, "rolled back, unchanged and duplicate changes are not audited")
+	type recorded struct {
+		actor, target, correlationID, outcome string
+		action                                users.AuditAction
+	}
+	got := make([]recorded, 0, len(listed))
+	for _, event := range listed {
+		got = append(got, recorded{event.Actor, event.TargetID, event.CorrelationID, event.Outcome, event.Action})
+	}
+	assert.Equal(t, []recorded{
+		{"operator-1", userID.String(), "request-audit", "succeeded", users.AuditActionCreateUser},
+		{"operator-1", duplicateID.String(), "request-audit", "rejected", users.AuditActionCreateUser},
+		{"operator-1", userID.String(), "request-audit", "succeeded", users.AuditActionUpdateUser},
+		{"operator-1", userID.String(), "request-audit", "rejected", users.AuditActionUpdateUser},
+		{"operator-1", importID.String(), "request-audit", "succeeded", users.AuditActionCreateUserImport},
+		{"operator-1", importID.String(), "request-audit", "succeeded", users.AuditActionCancelUserImport},
+		{"operator-1", importID.String(), "request-audit", "rejected", users.AuditActionCancelUserImport},
+		{"permissions", userID.String(), "event-correlation", "applied", users.AuditActionApplyPermissions},
+		{"permissions", userID.String(), "event-correlation", "stale", users.AuditActionApplyPermissions},
+		{"operator-1", userID.String(), "request-audit", "succeeded", users.AuditActionDeleteUser},
+	}, got)
+
+	filtered, err := repository.ListAuditEvents(t.Context(), users.AuditQuery{
+		Limit: 10, Actor: "permissions", TargetID: userID.String(), AfterID: listed[7].ID,
+	})
+	require.NoError(t, err)
+	require.Len(t, filtered, 1)
+	assert.Equal(t, listed[8].ID, filtered[0].ID)
+	before := listed[0].OccurredAt
+	filtered, err = repository.ListAuditEvents(t.Context(), users.AuditQuery{Limit: 10, OccurredBefore: &before})
+	require.NoError(t, err)
+	assert.Empty(t, filtered)
+}
+
 func TestImportRepositoryRollsBackWhenEnqueueFails(t *testing.T) {
 	pool := newTestPool(t)
 	repository := NewImportRepository(pool, failingImportEnqueuer{})
@@ -162,8 +664,8 @@ func TestImportRepositoryMarksConflictingEmailFailedAndCleansItUp(t *testing.T)
 	pool := newTestPool(t)
 	jobClient, err := river.NewClient(riverpgxv5.New(pool), &river.Config{})
 	require.NoError(t, err)
-	repository := NewImportRepository(pool, usersjobs.NewEnqueuer(jobClient))
-	_, err = NewUserRepository(pool, usersjobs.NewEnqueuer(jobClient)).Create(t.Context(), users.User{ID: uuid.New(), Email: "existing@example.com"})
+	repository := NewImportRepository(pool, newTestEnqueuer(pool, jobClient))
+	_, err = NewUserRepository(pool, newTestEnqueuer(pool, jobClient)).Create(t.Context(), users.User{ID: uuid.New(), Email: "existing@example.com"})
 	require.NoError(t, err)
 
 	importID := uuid.MustParse("0198a1f7-30b7-7df5-8491-c47f6033525b")
@@ -180,6 +682,17 @@ func TestImportRepositoryMarksConflictingEmailFailedAndCleansItUp(t *testing.T)
 	assert.Zero(t, failed.CompletedCount)
 	assert.Equal(t, 1, failed.FailedCount)
 
+	entries, err := repository.ListImportEntries(t.Context(), importID, users.EntryQuery{Limit: 10, State: users.ImportEntryStateFailed})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "existing@example.com", entries[0].Email)
+	assert.Equal(t, users.ImportFailureEmailTaken, entries[0].FailureReason)
+	entries, err = repository.ListImportEntries(t.Context(), importID, users.EntryQuery{Limit: 10, State: users.ImportEntryStateCompleted})
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+	_, err = repository.ListImportEntries(t.Context(), uuid.New(), users.EntryQuery{Limit: 10})
+	require.ErrorIs(t, err, users.ErrNotFound)
+
 	_, err = pool.Exec(t.Context(), "UPDATE user_imports SET finished_at = $2 WHERE id = $1", importID, time.Now().Add(-8*24*time.Hour))
 	require.NoError(t, err)
 	deleted, err := repository.DeleteFinishedImportsBefore(t.Context(), time.Now().Add(-7*24*time.Hour))
@@ -195,7 +708,7 @@ func TestImportJobReachesFinalFailureState(t *testing.T) {
 		Workers: workers,
 	})
 	require.NoError(t, err)
-	repository := NewImportRepository(pool, usersjobs.NewEnqueuer(client))
+	repository := NewImportRepository(pool, newTestEnqueuer(pool, client))
 	service := users.NewImportService(repository)
 	river.AddWorker(workers, usersjobs.NewImportWorker(service))
 
@@ -225,7 +738,15 @@ func TestImportJobReachesFinalFailureState(t *testing.T) {
 
 type failingImportEnqueuer struct{}
 
-func (failingImportEnqueuer) EnqueueImport(context.Context, pgx.Tx, uuid.UUID) error {
+func (failingImportEnqueuer) EnqueueImport(context.Context, pgx.Tx, uuid.UUID) (int64, error) {
+	return 0, errors.New("enqueue failed")
+}
+
+func (failingImportEnqueuer) CancelImport(context.Context, pgx.Tx, int64) error {
+	return errors.New("cancel failed")
+}
+
+func (failingImportEnqueuer) EnqueueImportCallback(context.Context, pgx.Tx, uuid.UUID) error {
 	return errors.New("enqueue failed")
 }
 
@@ -233,6 +754,211 @@ func (failingImportEnqueuer) EnqueueUserCreated(context.Context, pgx.Tx, uuid.UU
 	return errors.New("enqueue failed")
 }
 
+func (failingImportEnqueuer) EnqueueUserUpdated(context.Context, pgx.Tx, uuid.UUID) error {
+	return errors.New("enqueue failed")
+}
+
+func (failingImportEnqueuer) EnqueueUserDeleted(context.Context, pgx.Tx, uuid.UUID) error {
+	return errors.New("enqueue failed")
+}
+
+func newTestEnqueuer(pool *pgxpool.Pool, client *river.Client[pgx.Tx]) *usersjobs.Enqueuer {
+	return usersjobs.NewEnqueuer(client, NewOutboxRepository(pool), "go-service-template")
+}
+
+func outboxMessages(t *testing.T, pool *pgxpool.Pool, eventType string) []OutboxMessage {
+	t.Helper()
+	rows, err := pool.Query(t.Context(), "SELECT * FROM outbox_messages WHERE event_type = $1 ORDER BY sequence", eventType)
+	require.NoError(t, err)
+	messages, err := pgx.CollectRows(rows, pgx.RowToStructByPos[OutboxMessage])
+	require.NoError(t, err)
+	return messages
+}
+
+func TestOutboxRepositoryRelaysInSequenceAndRetriesFailures(t *testing.T) {
+	pool := newTestPool(t)
+	repository := NewOutboxRepository(pool)
+	for _, eventID := range []string{"event-1", "event-2", "event-3"} {
+		tx, err := pool.Begin(t.Context())
+		require.NoError(t, err)
+		require.NoError(t, repository.AddToOutbox(t.Context(), tx, messaging.OutboxMessage{EventID: eventID, Type: "user.created", Body: []byte(eventID)}))
+		require.NoError(t, tx.Commit(t.Context()))
+	}
+
+	var relayed []string
+	count, err := repository.RelayOutbox(t.Context(), 2, func(_ context.Context, messages []messaging.OutboxMessage) map[int64]error {
+		for _, message := range messages {
+			relayed = append(relayed, message.EventID)
+		}
+		return map[int64]error{messages[1].Sequence: errors.New("throttled")}
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+	assert.Equal(t, []string{"event-1", "event-2"}, relayed)
+
+	remaining := outboxMessages(t, pool, "user.created")
+	require.Len(t, remaining, 2)
+	assert.Equal(t, "event-2", remaining[0].EventID)
+	assert.Equal(t, int32(1), remaining[0].Attempts)
+	assert.Equal(t, "throttled", remaining[0].LastError.String)
+	assert.True(t, remaining[0].AvailableAt.After(time.Now()))
+
+	relayed = nil
+	_, err = repository.RelayOutbox(t.Context(), 2, func(_ context.Context, messages []messaging.OutboxMessage) map[int64]error {
+		for _, message := range messages {
+			relayed = append(relayed, message.EventID)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"event-3"}, relayed, "messages waiting for a retry are not due")
+
+	lag, err := repository.OutboxLag(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), lag.Pending)
+	assert.Equal(t, 1, lag.MaxAttempts)
+	assert.Positive(t, lag.OldestAge)
+}
+
+func TestOutboxRepositoryHoldsGroupsBehindRetries(t *testing.T) {
+	pool := newTestPool(t)
+	repository := NewOutboxRepository(pool)
+	for _, message := range []messaging.OutboxMessage{
+		{
+			EventID: "created", Type: "user.created", SchemaVersion: "1.0.0", GroupID: "user-1", Body: []byte("created"),
+			TraceContext: map[string]string{"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"},
+		},
+		{EventID: "updated", Type: "user.updated", GroupID: "user-1", Body: []byte("updated")},
+		{EventID: "other", Type: "user.created", GroupID: "user-2", Body: []byte("other")},
+	} {
+		tx, err := pool.Begin(t.Context())
+		require.NoError(t, err)
+		require.NoError(t, repository.AddToOutbox(t.Context(), tx, message))
+		require.NoError(t, tx.Commit(t.Context()))
+	}
+
+	_, err := repository.RelayOutbox(t.Context(), 1, func(_ context.Context, messages []messaging.OutboxMessage) map[int64]error {
+		assert.Equal(t, "user-1", messages[0].GroupID)
+		assert.Equal(t, "1.0.0", messages[0].SchemaVersion)
+		assert.Equal(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", messages[0].TraceContext["traceparent"])
+		return map[int64]error{messages[0].Sequence: errors.New("throttled")}
+	})
+	require.NoError(t, err)
+
+	var relayed []string
+	_, err = repository.RelayOutbox(t.Context(), 10, func(_ context.Context, messages []messaging.OutboxMessage) map[int64]error {
+		for _, message := range messages {
+			relayed = append(relayed, message.EventID)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"other"}, relayed, "a group waits for its earliest message to be retried")
+}
+
+func TestOutboxRepositoryParksMessagesAfterMaxAttempts(t *testing.T) {
+	pool := newTestPool(t)
+	repository := NewOutboxRepository(pool)
+	for _, message := range []messaging.OutboxMessage{
+		{EventID: "created", Type: "user.created", GroupID: "user-1", Body: []byte("created")},
+		{EventID: "updated", Type: "user.updated", GroupID: "user-1", Body: []byte("updated")},
+	} {
+		tx, err := pool.Begin(t.Context())
+		require.NoError(t, err)
+		require.NoError(t, repository.AddToOutbox(t.Context(), tx, message))
+		require.NoError(t, tx.Commit(t.Context()))
+	}
+	_, err := pool.Exec(t.Context(), "UPDATE outbox_messages SET attempts = $1 WHERE event_id = 'created'", messaging.OutboxMaxAttempts-1)
+	require.NoError(t, err)
+
+	_, err = repository.RelayOutbox(t.Context(), 1, func(_ context.Context, messages []messaging.OutboxMessage) map[int64]error {
+		return map[int64]error{messages[0].Sequence: errors.New("invalid parameter")}
+	})
+	require.NoError(t, err)
+	parked := outboxMessages(t, pool, "user.created")
+	require.Len(t, parked, 1)
+	assert.True(t, parked[0].ParkedAt.Valid)
+
+	var relayed []string
+	_, err = repository.RelayOutbox(t.Context(), 10, func(_ context.Context, messages []messaging.OutboxMessage) map[int64]error {
+		for _, message := range messages {
+			relayed = append(relayed, message.EventID)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"updated"}, relayed, "a parked message is not published and does not hold back its group")
+
+	lag, err := repository.OutboxLag(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, messaging.OutboxLag{Parked: 1}, lag)
+}
+
+func TestOutboxRepositoryRelaysOnlyTheEarliestMessageOfEachGroup(t *testing.T) {
+	pool := newTestPool(t)
+	repository := NewOutboxRepository(pool)
+	for _, message := range []messaging.OutboxMessage{
+		{EventID: "created", Type: "user.created", GroupID: "user-1", Body: []byte("created")},
+		{EventID: "updated", Type: "user.updated", GroupID: "user-1", Body: []byte("updated")},
+		{EventID: "other", Type: "user.created", GroupID: "user-2", Body: []byte("other")},
+		{EventID: "ungrouped-1", Type: "user.deleted", Body: []byte("ungrouped-1")},
+		{EventID: "ungrouped-2", Type: "user.deleted", Body: []byte("ungrouped-2")},
+	} {
+		tx, err := pool.Begin(t.Context())
+		require.NoError(t, err)
+		require.NoError(t, repository.AddToOutbox(t.Context(), tx, message))
+		require.NoError(t, tx.Commit(t.Context()))
+	}
+
+	var relayed []string
+	_, err := repository.RelayOutbox(t.Context(), 10, func(_ context.Context, messages []messaging.OutboxMessage) map[int64]error {
+		for _, message := range messages {
+			relayed = append(relayed, message.EventID)
+		}
+		return map[int64]error{messages[0].Sequence: errors.New("throttled")}
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"created", "other", "ungrouped-1", "ungrouped-2"}, relayed,
+		"a message is not published in the batch of an earlier message of its group")
+
+	updated := outboxMessages(t, pool, "user.updated")
+	require.Len(t, updated, 1)
+	assert.Equal(t, int32(0), updated[0].Attempts, "a held message has not been attempted")
+	assert.Len(t, outboxMessages(t, pool, "user.created"), 1, "the failed message stays for a retry")
+}
+
+func TestOutboxRepositoryPublishesClaimedMessagesOutsideATransaction(t *testing.T) {
+	pool := newTestPool(t)
+	repository := NewOutboxRepository(pool)
+	for _, eventID := range []string{"event-1", "event-2"} {
+		tx, err := pool.Begin(t.Context())
+		require.NoError(t, err)
+		require.NoError(t, repository.AddToOutbox(t.Context(), tx, messaging.OutboxMessage{EventID: eventID, Type: "user.created", Body: []byte(eventID)}))
+		require.NoError(t, tx.Commit(t.Context()))
+	}
+
+	count, err := repository.RelayOutbox(t.Context(), 1, func(ctx context.Context, messages []messaging.OutboxMessage) map[int64]error {
+		assert.Zero(t, pool.Stat().AcquiredConns(), "no connection is held while publishing")
+		deadline, ok := ctx.Deadline()
+		assert.True(t, ok)
+		assert.WithinDuration(t, time.Now().Add(outboxPublishTimeout), deadline, time.Second)
+
+		var relayed []string
+		_, err := repository.RelayOutbox(t.Context(), 10, func(_ context.Context, messages []messaging.OutboxMessage) map[int64]error {
+			for _, message := range messages {
+				relayed = append(relayed, message.EventID)
+			}
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"event-2"}, relayed, "a claimed message is not relayed twice")
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+	assert.Empty(t, outboxMessages(t, pool, "user.created"))
+}
+
 func newTestPool(t *testing.T) *pgxpool.Pool {
 	t.Helper()
 
@@ -256,3 +982,54 @@ func newTestPool(t *testing.T) *pgxpool.Pool {
 
 	return pool
 }
+
+func TestIdempotencyRepositoryClaimsRecordsAndExpires(t *testing.T) {
+	pool := newTestPool(t)
+	repository := NewIdempotencyRepository(pool)
+	key := httpserver.IdempotencyKey{Subject: "user-123", Key: "retry-1"}
+	fingerprint := []byte("fingerprint")
+
+	_, claimed, err := repository.Claim(t.Context(), key)
+	require.NoError(t, err)
+	require.True(t, claimed)
+	inProgress, claimed, err := repository.Claim(t.Context(), key)
+	require.NoError(t, err)
+	assert.False(t, claimed)
+	assert.Nil(t, inProgress.Fingerprint)
+	assert.Nil(t, inProgress.Response)
+	require.NoError(t, repository.Extend(t.Context(), key))
+
+	response := httpserver.IdempotentResponse{
+		StatusCode: 201,
+		Header:     http.Header{"Content-Type": {"application/json"}},
+		Body:       []byte(`{"id":"1"}`),
+	}
+	require.NoError(t, repository.Complete(t.Context(), key, fingerprint, response))
+	require.NoError(t, repository.Release(t.Context(), key))
+	stored, claimed, err := repository.Claim(t.Context(), key)
+	require.NoError(t, err)
+	assert.False(t, claimed)
+	assert.Equal(t, fingerprint, stored.Fingerprint)
+	assert.Equal(t, &response, stored.Response)
+
+	_, claimed, err = repository.Claim(t.Context(), httpserver.IdempotencyKey{Subject: "user-456", Key: key.Key})
+	require.NoError(t, err)
+	assert.True(t, claimed)
+
+	deleted, err := repository.Cleanup(t.Context(), time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), deleted)
+}
+
+type permissionInvalidatorFunc struct {
+	invalidate func(string, int64)
+	clear      func()
+}
+
+func (f permissionInvalidatorFunc) Invalidate(userID string, revision int64) {
+	f.invalidate(userID, revision)
+}
+
+func (f permissionInvalidatorFunc) Clear() {
+	f.clear()
+}
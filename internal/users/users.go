@@ -2,6 +2,7 @@ package users
 
 import (
 	"context"
+	"encoding/base64"
 	"errors"
 	"fmt"
 	"net/mail"
@@ -12,20 +13,63 @@ import (
 )
 
 var (
-	ErrConflict     = errors.New("user already exists")
-	ErrInvalidEmail = errors.New("invalid email")
-	ErrNotFound     = errors.New("user not found")
+	ErrConflict           = errors.New("user already exists")
+	ErrInvalidEmail       = errors.New("invalid email")
+	ErrNotFound           = errors.New("user not found")
+	ErrInvalidCursor      = errors.New("invalid user cursor")
+	ErrInvalidListFilter  = errors.New("invalid user list filter")
+	ErrPreconditionFailed = errors.New("user precondition failed")
+)
+
+const (
+	DefaultListLimit = 50
+	MaxListLimit     = 100
 )
 
 type User struct {
 	ID        uuid.UUID
 	Email     string
 	CreatedAt time.Time
+	Version   int64
+}
+
+// Precondition reports whether a mutation may proceed against the current
+// state of a user. Repositories evaluate it while the user is locked and return
+// ErrPreconditionFailed when it rejects the user. A nil Precondition allows
+// every mutation.
+type Precondition func(User) bool
+
+// ListFilter selects one page of users ordered by ID. Cursor is the opaque
+// value returned as Page.NextCursor by the previous page.
+type ListFilter struct {
+	Cursor        string
+	Limit         int
+	EmailPrefix   string
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+}
+
+type Page struct {
+	Users      []User
+	NextCursor string
+}
+
+// ListQuery is the decoded keyset query passed to the repository. A nil
+// AfterID starts from the first user.
+type ListQuery struct {
+	AfterID       uuid.UUID
+	Limit         int
+	EmailPrefix   string
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
 }
 
 type Repository interface {
 	Create(context.Context, User) (User, error)
 	Get(context.Context, uuid.UUID) (User, error)
+	List(context.Context, ListQuery) ([]User, error)
+	Update(context.Context, User, Precondition) (User, error)
+	Delete(context.Context, uuid.UUID, Precondition) error
 }
 
 type Service struct {
@@ -75,3 +119,75 @@ func (s *Service) Get(ctx context.Context, id uuid.UUID) (User, error) {
 
 	return user, nil
 }
+
+// Update changes the email of an existing user. Updating a user to the email
+// it already has succeeds without emitting a change.
+func (s *Service) Update(ctx context.Context, id uuid.UUID, email string, precondition Precondition) (User, error) {
+	normalizedEmail, err := normalizeEmail(email)
+	if err != nil {
+		return User{}, ErrInvalidEmail
+	}
+
+	user, err := s.repository.Update(ctx, User{ID: id, Email: normalizedEmail}, precondition)
+	if err != nil {
+		return User{}, fmt.Errorf("update user: %w", err)
+	}
+	return user, nil
+}
+
+func (s *Service) Delete(ctx context.Context, id uuid.UUID, precondition Precondition) error {
+	if err := s.repository.Delete(ctx, id, precondition); err != nil {
+		return fmt.Errorf("delete user: %w", err)
+	}
+	return nil
+}
+
+func (s *Service) List(ctx context.Context, filter ListFilter) (Page, error) {
+	if filter.Limit == 0 {
+		filter.Limit = DefaultListLimit
+	}
+	if filter.Limit < 1 || filter.Limit > MaxListLimit {
+		return Page{}, ErrInvalidListFilter
+	}
+	afterID, err := decodeCursor(filter.Cursor)
+	if err != nil {
+		return Page{}, err
+	}
+
+	listed, err := s.repository.List(ctx, ListQuery{
+		AfterID:       afterID,
+		Limit:         filter.Limit + 1,
+		EmailPrefix:   strings.ToLower(strings.TrimSpace(filter.EmailPrefix)),
+		CreatedAfter:  filter.CreatedAfter,
+		CreatedBefore: filter.CreatedBefore,
+	})
+	if err != nil {
+		return Page{}, fmt.Errorf("list users: %w", err)
+	}
+
+	page := Page{Users: listed}
+	if len(listed) > filter.Limit {
+		page.Users = listed[:filter.Limit]
+		page.NextCursor = encodeCursor(page.Users[filter.Limit-1].ID)
+	}
+	return page, nil
+}
+
+func encodeCursor(id uuid.UUID) string {
+	return base64.RawURLEncoding.EncodeToString(id[:])
+}
+
+func decodeCursor(cursor string) (uuid.UUID, error) {
+	if cursor == "" {
+		return uuid.Nil, nil
+	}
+	decoded, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return uuid.Nil, ErrInvalidCursor
+	}
+	id, err := uuid.FromBytes(decoded)
+	if err != nil || id == uuid.Nil {
+		return uuid.Nil, ErrInvalidCursor
+	}
+	return id, nil
+}
@@ -30,9 +30,73 @@ func TestServiceCreateRejectsInvalidEmail(t *testing.T) {
 	require.ErrorIs(t, err, ErrInvalidEmail)
 }
 
+func TestServiceUpdateNormalizesEmail(t *testing.T) {
+	t.Parallel()
+
+	repository := &repositoryStub{}
+	userID := uuid.MustParse("0198a1f7-30b7-7df1-8491-c47f6033525b")
+	updated, err := NewService(repository).Update(t.Context(), userID, " New@Example.COM ", nil)
+	require.NoError(t, err)
+	assert.Equal(t, User{ID: userID, Email: "new@example.com"}, updated)
+
+	_, err = NewService(repository).Update(t.Context(), userID, "not-an-email", nil)
+	require.ErrorIs(t, err, ErrInvalidEmail)
+}
+
+func TestServiceListPaginatesWithOpaqueCursor(t *testing.T) {
+	t.Parallel()
+
+	listed := []User{
+		{ID: uuid.MustParse("0198a1f7-30b7-7df1-8491-c47f6033525b")},
+		{ID: uuid.MustParse("0198a1f7-30b7-7df2-8491-c47f6033525b")},
+		{ID: uuid.MustParse("0198a1f7-30b7-7df3-8491-c47f6033525b")},
+	}
+	repository := &repositoryStub{listed: listed}
+	service := NewService(repository)
+
+	page, err := service.List(t.Context(), ListFilter{Limit: 2, EmailPrefix: " Person@ "})
+	require.NoError(t, err)
+	assert.Equal(t, listed[:2], page.Users)
+	require.NotEmpty(t, page.NextCursor)
+	assert.Equal(t, ListQuery{Limit: 3, EmailPrefix: "person@"}, repository.query)
+
+	repository.listed = listed[2:]
+	page, err = service.List(t.Context(), ListFilter{Cursor: page.NextCursor, Limit: 2})
+	require.NoError(t, err)
+	assert.Equal(t, listed[1].ID, repository.query.AfterID)
+	assert.Equal(t, listed[2:], page.Users)
+	assert.Empty(t, page.NextCursor)
+}
+
+func TestServiceListRejectsInvalidInput(t *testing.T) {
+	t.Parallel()
+
+	service := NewService(&repositoryStub{})
+	for name, testCase := range map[string]struct {
+		filter ListFilter
+		want   error
+	}{
+		"malformed cursor":  {filter: ListFilter{Cursor: "%%%"}, want: ErrInvalidCursor},
+		"short cursor":      {filter: ListFilter{Cursor: "AAAA"}, want: ErrInvalidCursor},
+		"nil cursor":        {filter: ListFilter{Cursor: encodeCursor(uuid.Nil)}, want: ErrInvalidCursor},
+		"limit below range": {filter: ListFilter{Limit: -1}, want: ErrInvalidListFilter},
+		"limit above range": {filter: ListFilter{Limit: MaxListLimit + 1}, want: ErrInvalidListFilter},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			_, err := service.List(t.Context(), testCase.filter)
+			require.ErrorIs(t, err, testCase.want)
+		})
+	}
+}
+
 type repositoryStub struct {
 	createErr error
 	getErr    error
+	updateErr error
+	deleteErr error
+	listed    []User
+	query     ListQuery
 }
 
 func (r *repositoryStub) Create(_ context.Context, user User) (User, error) {
@@ -42,3 +106,16 @@ func (r *repositoryStub) Create(_ context.Context, user User) (User, error) {
 func (r *repositoryStub) Get(_ context.Context, id uuid.UUID) (User, error) {
 	return User{ID: id}, r.getErr
 }
+
+func (r *repositoryStub) List(_ context.Context, query ListQuery) ([]User, error) {
+	r.query = query
+	return r.listed, nil
+}
+
+func (r *repositoryStub) Update(_ context.Context, user User, _ Precondition) (User, error) {
+	return user, r.updateErr
+}
+
+func (r *repositoryStub) Delete(context.Context, uuid.UUID, Precondition) error {
+	return r.deleteErr
+}
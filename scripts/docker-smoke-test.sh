@@ -110,6 +110,7 @@ docker run -d --name "$worker_container" --network "$network" -p 18081:8080 \
   -e AWS_ENDPOINT_URL=http://localstack:4566 \
   -e USER_EVENTS_TOPIC_ARN="$user_events_topic_arn" \
   -e PERMISSIONS_QUEUE_URL="$permissions_queue_url" \
+  -e IMPORT_CALLBACK_SECRET=smoke-test-import-callback-secret \
   "$image" worker >/dev/null
 
 attempt=0